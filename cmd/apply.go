@@ -23,7 +23,14 @@ Examples:
   goru apply --dir /path/to/shows --type tv --recursive --dry-run --api-key YOUR_API_KEY
   
   # Interactive mode for manual confirmation
-  goru apply --interactive --api-key YOUR_API_KEY`,
+  goru apply --interactive --api-key YOUR_API_KEY
+
+  # Machine-readable result for automation
+  goru apply --auto-approve --output json
+
+Exit codes:
+  0  Succeeded, every change was applied
+  1  Error, or at least one change failed`,
 
 	Run: apply.Run,
 }
@@ -33,4 +40,5 @@ func init() {
 
 	applyCmd.Flags().Bool("auto-approve", false, "Will not prompt for confirmation before applying changes")
 	applyCmd.Flags().BoolP("interactive", "i", false, "Interactive mode for manual confirmation")
+	applyCmd.Flags().StringP("output", "o", "table", "Output format: table, plain, json or ndjson (requires --auto-approve)")
 }
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"goru/internal/cmd/audit"
+
+	"github.com/spf13/cobra"
+)
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check the library for problems without renaming anything",
+	Long: `Audit walks the configured directories, or the one given by --dir, and lists
+their problems with a suggested fix:
+- unmatched     Videos no provider matched, to rename by hand
+- misnamed      Videos whose name does not follow the format, renamed
+- broken_symlink, empty, truncated, sample, junk
+                Broken links, empty or cut videos, samples and trailers, and files
+                that are not media (.txt, .exe, orphan .nfo), moved to the quarantine
+- orphan_subtitle
+                Subtitles named after no video, renamed after the only video of
+                their folder or moved to the quarantine
+
+The quarantine is the .goru-quarantine folder of each directory, or the folder given
+by --quarantine. The fixes are renames tracked in the state, reverted with
+'goru state revert'.
+
+Examples:
+  # List the problems of the library
+  goru audit
+
+  # Move the junk files and the samples to the quarantine
+  goru audit --fix junk,sample
+
+  # Choose the fixes one by one
+  goru audit --fix all --interactive
+
+Exit codes:
+  0  Succeeded, no problem found (or --detailed-exitcode not set)
+  1  Error, or at least one fix failed
+  2  Succeeded, problems were found and left as they are (with --detailed-exitcode)`,
+	Args: cobra.NoArgs,
+	Run:  audit.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().String("directory", "", "Name of the configured directory to audit")
+	auditCmd.Flags().StringSlice("fix", nil, "Kinds of problems to fix, or all")
+	auditCmd.Flags().String("quarantine", "", "Folder the problematic files are moved to (default .goru-quarantine in each directory)")
+	auditCmd.Flags().Bool("auto-approve", false, "Will not prompt for confirmation before applying the fixes")
+	auditCmd.Flags().BoolP("interactive", "i", false, "Confirm each fix")
+	auditCmd.Flags().StringP("output", "o", "table", "Output format: table or json")
+	auditCmd.Flags().Bool("detailed-exitcode", false, "Return exit code 2 when problems are left")
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"goru/internal/cmd/auth/hashpassword"
+
+	"github.com/spf13/cobra"
+)
+
+// authCmd represents the auth command
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage the authentication of the web server",
+	Long: `Manage the authentication of the web server.
+
+Examples:
+  # Hash the password of a local user, for the server.auth.users configuration
+  goru auth hash-password`,
+}
+
+// authHashPasswordCmd represents the auth hash-password command
+var authHashPasswordCmd = &cobra.Command{
+	Use:   "hash-password",
+	Short: "Hash a password with bcrypt",
+	Long: `Read a password from the standard input and print its bcrypt hash.
+
+The hash goes in the password_hash field of a local user:
+
+  server:
+    auth:
+      users:
+        - username: alice
+          password_hash: $2a$10$...
+          role: admin`,
+	Run: hashpassword.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authHashPasswordCmd)
+}
@@ -15,11 +15,22 @@ and displaying the proposed changes. This is similar to 'terraform plan' - it sh
 what would happen without making any actual changes.
 
 The command will scan for video files, attempt to match them with movies or TV shows
-from TMDB, and display the current filename alongside the proposed new filename.`,
+from TMDB, and display the current filename alongside the proposed new filename.
+
+Use --output json or --output ndjson to get a machine-readable plan on stdout,
+logs and progress are always written to stderr.
+
+Exit codes:
+  0  Succeeded, no changes to apply (or --detailed-exitcode not set)
+  1  Error
+  2  Succeeded, there are changes to apply (with --detailed-exitcode)`,
 
 	Run: plan.Run,
 }
 
 func init() {
 	rootCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringP("output", "o", "table", "Output format: table, plain, json or ndjson")
+	planCmd.Flags().Bool("detailed-exitcode", false, "Return exit code 2 when there are changes to apply")
 }
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// pluginCmd represents the plugin command
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Develop external plugins",
+	Long: `Develop the external plugins, executables speaking JSON-RPC with goru on their
+standard input and output.
+
+Examples:
+  # Check that an executable follows the plugin protocol
+  goru plugin check -- python3 ./catalog.py
+
+  # Check a plugin of the configuration
+  goru plugin check catalog`,
+}
+
+func init() {
+	rootCmd.AddCommand(pluginCmd)
+	pluginCmd.AddCommand(pluginCheckCmd)
+}
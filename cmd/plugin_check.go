@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"goru/internal/cmd/plugin/check"
+
+	"github.com/spf13/cobra"
+)
+
+// pluginCheckCmd represents the plugin check command
+var pluginCheckCmd = &cobra.Command{
+	Use:   "check [name | -- command [args...]]",
+	Short: "Check that an external plugin follows the protocol",
+	Long: `Launch an external plugin and check that it follows the plugin protocol: the
+handshake, the errors, the declared capabilities and hooks, the concurrent
+requests and the shutdown.
+
+The plugin is either a plugin of the configuration, given by name, or the
+command given after --.
+
+Examples:
+  goru plugin check -- python3 ./catalog.py --verbose
+  goru plugin check -- ./tagger --option library=movies
+  goru plugin check catalog`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  check.Run,
+}
+
+func init() {
+	pluginCheckCmd.Flags().StringToString("option", nil, "Option sent to the plugin in the handshake, e.g. --option catalog=./catalog.json")
+	pluginCheckCmd.Flags().Duration("timeout", 0, "Timeout of each call (default 10s)")
+	pluginCheckCmd.Flags().StringP("output", "o", "table", "Output format: table or json")
+}
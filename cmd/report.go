@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report on the library",
+	Long: `Compare the library on disk with the providers.
+
+Examples:
+  # List the missing episodes of the TV show directories
+  goru report missing`,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportMissingCmd)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"goru/internal/cmd/report/missing"
+
+	"github.com/spf13/cobra"
+)
+
+// reportMissingCmd represents the report missing command
+var reportMissingCmd = &cobra.Command{
+	Use:   "missing",
+	Short: "Report the missing, extra and duplicated episodes of the shows",
+	Long: `Compare the episodes on disk with the seasons listed by the provider, show by
+show. The report lists:
+- The missing episodes
+- The extra episodes, not listed by the provider, such as the specials numbered
+  after another database
+- The episodes found in several files
+- The files whose show or episode cannot be told
+
+The specials are never missing. The report covers the TV show directories of the
+configuration, the one given by --directory, or the directory given by --dir.
+
+Examples:
+  # Report on every TV show directory
+  goru report missing
+
+  # Only expect the episodes already aired, in the "tv" directory
+  goru report missing --directory tv --aired
+
+  # Export the report as CSV
+  goru report missing -o csv > missing.csv`,
+	Args: cobra.NoArgs,
+	Run:  missing.Run,
+}
+
+func init() {
+	reportMissingCmd.Flags().String("directory", "", "Name of the configured directory to report on")
+	reportMissingCmd.Flags().Bool("aired", false, "Only expect the episodes already aired")
+	reportMissingCmd.Flags().StringP("output", "o", "table", "Output format: table, json or csv")
+}
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"goru/pkg/log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -24,7 +27,11 @@ with movie or TV show information.`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+	// Cancel the running operations on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
 		os.Exit(1)
 	}
@@ -34,28 +41,39 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.goru.yaml)")
-	rootCmd.PersistentFlags().String("dir", "d", "Directory to scan for video files")
+	rootCmd.PersistentFlags().StringP("dir", "d", "", "Directory to scan for video files")
 	rootCmd.PersistentFlags().BoolP("recursive", "r", false, "Scan directories recursively")
 	rootCmd.PersistentFlags().StringP("type", "t", "auto", "Media type: movie, tv, or auto")
-	rootCmd.PersistentFlags().String("provider", "tmdb", "Database provider: tmdb, tvdb or anidb")
-	rootCmd.PersistentFlags().String("conflict", "append", "Conflict resolution strategy: skip, append, timestamp, prompt, overwrite, backup")
+	rootCmd.PersistentFlags().String("provider", "tmdb", "Database provider: tmdb, nfo, or nfo,tmdb to read the .nfo files first")
+	rootCmd.PersistentFlags().String("conflict", "append", "Conflict resolution strategy: skip, append, timestamp, prompt, overwrite, best")
 	rootCmd.PersistentFlags().String("format", "plex", "Format for the output files")
-	rootCmd.PersistentFlags().Bool("subtitles", false, "Enable subtitles download")
+	rootCmd.PersistentFlags().Bool("subtitles", false, "Download the subtitles of the renamed files")
+	rootCmd.PersistentFlags().StringSlice("subtitle-languages", nil, "Languages of the subtitles by priority, e.g. en,fr (default en)")
+	rootCmd.PersistentFlags().String("sanitize", "windows-safe", "Filename sanitization profile: posix, windows-safe, smb or ascii")
 	rootCmd.PersistentFlags().Int("parallelism", 10, "Maximum number of concurrent file processing operations")
 
+	rootCmd.PersistentFlags().String("remote", "", "URL of a goru server to drive instead of the local filesystem, e.g. https://nas:8080")
+
 	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug mode")
 
 	// Bind flags to viper
+	viper.BindPFlag("dir", rootCmd.PersistentFlags().Lookup("dir"))
+	viper.BindPFlag("recursive", rootCmd.PersistentFlags().Lookup("recursive"))
 	viper.BindPFlag("type", rootCmd.PersistentFlags().Lookup("type"))
 	viper.BindPFlag("provider", rootCmd.PersistentFlags().Lookup("provider"))
 	viper.BindPFlag("conflict", rootCmd.PersistentFlags().Lookup("conflict"))
-	viper.BindPFlag("subtitles", rootCmd.PersistentFlags().Lookup("subtitles"))
+	viper.BindPFlag("subtitles.enabled", rootCmd.PersistentFlags().Lookup("subtitles"))
+	viper.BindPFlag("subtitles.languages", rootCmd.PersistentFlags().Lookup("subtitle-languages"))
+	viper.BindPFlag("sanitize.profile", rootCmd.PersistentFlags().Lookup("sanitize"))
 	viper.BindPFlag("parallelism", rootCmd.PersistentFlags().Lookup("parallelism"))
 	viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+	viper.BindPFlag("remote.url", rootCmd.PersistentFlags().Lookup("remote"))
 	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
 
 	// Env
 	viper.BindEnv("tmdb.api_key", "TMDB_API_KEY")
+	viper.BindEnv("remote.api_key", "GORU_REMOTE_API_KEY")
+	viper.BindEnv("remote.password", "GORU_REMOTE_PASSWORD")
 
 	// Defaults
 	viper.SetDefault("debug", false)
@@ -64,6 +82,8 @@ func init() {
 	viper.SetDefault("provider", "tmdb")
 	viper.SetDefault("parallelism", 10)
 	viper.SetDefault("format", "plex")
+	viper.SetDefault("sanitize.profile", "windows-safe")
+	viper.SetDefault("sanitize.normalization", "nfc")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -20,11 +20,15 @@ func init() {
 	serverCmd.Flags().String("host", "", "Host to bind the server to")
 	serverCmd.Flags().String("directory", "", "Default directory to scan")
 	serverCmd.Flags().String("db_file", "", "Path to the database file")
+	serverCmd.Flags().String("ui-dir", "", "Serve the web UI from this directory instead of the embedded bundle")
+	serverCmd.Flags().StringSlice("allowed-origins", nil, "Origins allowed to call the API from a browser")
 
 	viper.BindPFlag("host", serverCmd.Flags().Lookup("host"))
 	viper.BindPFlag("port", serverCmd.Flags().Lookup("port"))
 	viper.BindPFlag("directory", serverCmd.Flags().Lookup("directory"))
 	viper.BindPFlag("db_file", serverCmd.Flags().Lookup("db_file"))
+	viper.BindPFlag("server.ui_dir", serverCmd.Flags().Lookup("ui-dir"))
+	viper.BindPFlag("server.allowed_origins", serverCmd.Flags().Lookup("allowed-origins"))
 
 	viper.SetDefault("host", "localhost")
 	viper.SetDefault("port", "8080")
@@ -31,11 +31,15 @@ Examples:
   goru state ls --active
   
   # List only the last 10 operations
-  goru state ls --limit 10`,
+  goru state ls --limit 10
+
+  # Export the state as JSON
+  goru state ls --output json`,
 	Run: ls.Run,
 }
 
 func init() {
 	stateLsCmd.Flags().BoolVar(&stateLsActive, "active", false, "Show only active (non-reverted) operations")
 	stateLsCmd.Flags().IntVarP(&stateLsLimit, "limit", "l", 0, "Limit the number of operations to show (0 for all)")
+	stateLsCmd.Flags().StringP("output", "o", "table", "Output format: table, plain, json or ndjson")
 }
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// subtitlesCmd represents the subtitles command
+var subtitlesCmd = &cobra.Command{
+	Use:   "subtitles",
+	Short: "Work on subtitle files",
+	Long: `Work on the subtitle files, the ones downloaded by goru or any other.
+
+Examples:
+  # Convert a Latin-1 ASS subtitle to SRT in UTF-8
+  goru subtitles convert "Movie (1999).fr.ass" --to srt
+
+  # Delay a subtitle by 1.5 seconds
+  goru subtitles convert "Movie (1999).en.srt" --shift 1.5s`,
+}
+
+func init() {
+	rootCmd.AddCommand(subtitlesCmd)
+	subtitlesCmd.AddCommand(subtitlesConvertCmd)
+}
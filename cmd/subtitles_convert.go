@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"goru/internal/cmd/subtitles/convert"
+
+	"github.com/spf13/cobra"
+)
+
+// subtitlesConvertCmd represents the subtitles convert command
+var subtitlesConvertCmd = &cobra.Command{
+	Use:   "convert file...",
+	Short: "Convert subtitles to UTF-8 and between formats",
+	Long: `Convert subtitle files to UTF-8, and between SRT, WebVTT and ASS. The italics,
+the bold, the underline, the colors and the positions are kept. MicroDVD (.sub)
+subtitles are read and written as SRT.
+
+The encoding is detected, from the language in the filename for the legacy code
+pages, such as Windows-1251 for Movie.ru.srt. The converted file is written next
+to the original one with the extension of its format, replacing it when the
+format is kept.
+
+Examples:
+  # Re-encode a subtitle to UTF-8
+  goru subtitles convert "Movie (1999).fr.srt"
+
+  # Convert all the subtitles of a show to WebVTT
+  goru subtitles convert Show/*.srt --to vtt
+
+  # Resync a subtitle made for a PAL release, then bring it 2 seconds forward
+  goru subtitles convert "Movie (1999).en.srt" --fps 25:23.976 --shift=-2s`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  convert.Run,
+}
+
+func init() {
+	subtitlesConvertCmd.Flags().String("to", "", "Format written: srt, vtt or ass (default the format read, srt for MicroDVD)")
+	subtitlesConvertCmd.Flags().String("encoding", "", "Encoding read, e.g. windows-1252 (default detected)")
+	subtitlesConvertCmd.Flags().String("language", "", "Language of the subtitles telling their legacy encoding (default from the filename)")
+	subtitlesConvertCmd.Flags().Duration("shift", 0, "Offset of the timings, e.g. 1.5s or -500ms")
+	subtitlesConvertCmd.Flags().String("fps", "", "Resample the timings from a frame rate to another, e.g. 25:23.976")
+	subtitlesConvertCmd.Flags().Float64("input-fps", 0, "Frame rate of the MicroDVD subtitles not giving theirs (default 23.976)")
+	subtitlesConvertCmd.Flags().Bool("force", false, "Replace the existing files of the format written")
+}
@@ -0,0 +1,145 @@
+#!/usr/bin/env python3
+"""Reference goru plugin, speaking the protocol version 1 on stdin/stdout.
+
+It declares every capability:
+- parser: strips the release group tags, e.g. "[Group] Show - 01x02.mkv"
+- provider: looks the titles up in a catalog, given by the "catalog" option
+- hooks: leaves the partial downloads out, and keeps the renames of the
+  protected files
+
+Check it with: goru plugin check -- python3 docs/plugins/reference.py
+"""
+
+import json
+import re
+import sys
+
+PROTOCOL_VERSION = 1
+
+# JSON-RPC error codes
+METHOD_NOT_FOUND = -32601
+INVALID_PARAMS = -32602
+NOT_FOUND = 1
+
+DEFAULT_CATALOG = {
+    "the matrix": {"id": "603", "title": "The Matrix", "release_date": "1999-03-31T00:00:00Z"},
+}
+
+catalog = {}
+
+
+def log(message):
+    # The standard output is reserved to the protocol
+    print(message, file=sys.stderr, flush=True)
+
+
+def initialize(params):
+    if params.get("protocol_version") != PROTOCOL_VERSION:
+        raise RPCError(INVALID_PARAMS, "unsupported protocol version")
+
+    options = params.get("options") or {}
+    catalog.update(DEFAULT_CATALOG)
+    if "catalog" in options:
+        with open(options["catalog"]) as f:
+            catalog.update({title.lower(): movie for title, movie in json.load(f).items()})
+
+    return {
+        "name": "reference",
+        "version": "1.0.0",
+        "protocol_version": PROTOCOL_VERSION,
+        "capabilities": ["parser", "provider", "hooks"],
+        "hooks": ["on_scan", "before_apply"],
+    }
+
+
+def parse(params):
+    name = re.sub(r"\[[^\]]*\]", "", params["filename"])
+    name = re.sub(r"\.[A-Za-z0-9]{2,4}$", "", name)
+    name = re.sub(r"[._]", " ", name)
+
+    episode = re.search(r"(?i)\bS?(\d{1,2})[xE](\d{1,3})\b", name)
+    if episode:
+        title = name[: episode.start()].strip(" -")
+        return {"media_type": "tvshow", "title": title, "season": int(episode.group(1)), "episode": int(episode.group(2))}
+
+    year = re.search(r"\b(19|20)\d{2}\b", name)
+    if year:
+        return {"media_type": "movie", "title": name[: year.start()].strip(" -("), "year": int(year.group(0))}
+
+    return {}
+
+
+def provide(params):
+    file = params["file"]
+    query = file.get("query") or parse({"filename": file["filename"]})
+    movie = catalog.get(query.get("title", "").lower())
+    if movie is None:
+        raise RPCError(NOT_FOUND, "not in the catalog")
+
+    return {
+        "movie": movie,
+        "external_ids": {"tmdb_id": movie["id"], "tvdb_id": "", "anidb_id": ""},
+        "confidence": {"score": 1},
+    }
+
+
+def on_scan(params):
+    if params["file"]["filename"].endswith((".part", ".!qB")):
+        return {"veto": "partial download"}
+    return {}
+
+
+def before_apply(params):
+    if ".protected" in params["change"]["before"]["path"]:
+        return {"veto": "protected file"}
+    return {}
+
+
+def shutdown(params):
+    return None
+
+
+METHODS = {
+    "initialize": initialize,
+    "parse": parse,
+    "provider/provide": provide,
+    "hooks/on_scan": on_scan,
+    "hooks/before_apply": before_apply,
+    "shutdown": shutdown,
+}
+
+
+class RPCError(Exception):
+    def __init__(self, code, message):
+        super().__init__(message)
+        self.code = code
+        self.message = message
+
+
+def handle(request):
+    method = METHODS.get(request.get("method"))
+    if method is None:
+        raise RPCError(METHOD_NOT_FOUND, "method not found: %s" % request.get("method"))
+    return method(request.get("params") or {})
+
+
+def main():
+    # The plugin exits when goru closes its standard input
+    for line in sys.stdin:
+        request = json.loads(line)
+        response = {"jsonrpc": "2.0", "id": request.get("id")}
+        try:
+            response["result"] = handle(request)
+        except RPCError as e:
+            response["error"] = {"code": e.code, "message": e.message}
+        except Exception as e:  # a bug must not crash the plugin
+            log("error: %r" % e)
+            response["error"] = {"code": -32603, "message": str(e)}
+
+        # Notifications, without ID, are not answered
+        if request.get("id") is not None:
+            print(json.dumps(response), flush=True)
+
+
+if __name__ == "__main__":
+    main()
@@ -2,17 +2,16 @@ package apply
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
+	"goru/internal/cmd/backend"
 	"goru/internal/cmd/common"
 	"goru/internal/models"
-	"goru/internal/services/files"
-	"goru/internal/services/formatters"
 	"goru/internal/services/plans"
-	"goru/internal/services/states"
-	"goru/internal/services/subtitles/opensubtitles"
 	"goru/pkg/log"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -21,6 +20,21 @@ import (
 func Run(cmd *cobra.Command, args []string) {
 	log.Debug("goru is starting", zap.String("command", "apply"))
 
+	// Parse the output format
+	outputFlag, _ := cmd.Flags().GetString("output")
+	output, err := common.ParseOutputFormat(outputFlag)
+	if err != nil {
+		log.Fatal("invalid output format", zap.Error(err))
+	}
+	if output == common.OutputPlain {
+		color.NoColor = true
+	}
+
+	autoApprove, _ := cmd.Flags().GetBool("auto-approve")
+	if output.IsMachineReadable() && !autoApprove {
+		log.Fatal("--auto-approve is required with a machine-readable output", zap.String("output", string(output)))
+	}
+
 	// Unmarshal configuration
 	var config models.Config
 	if err := viper.Unmarshal(&config); err != nil {
@@ -30,25 +44,32 @@ func Run(cmd *cobra.Command, args []string) {
 		log.Fatal("invalid config", zap.Error(err))
 	}
 
-	// Create file service
-	fileService := files.NewFileService("", "", viper.GetStringSlice("filters"))
-
-	// Create the formatter service
-	formatterService := formatters.NewFormatterService(viper.GetString("format"), viper.GetString("format"))
-
-	// Create the subtitles provider
-	subtitleProvider := opensubtitles.New(viper.GetString("providers.opensubtitles.api_key"))
+	// Apply locally or on the remote server
+	b, err := backend.New(config)
+	if err != nil {
+		log.Fatal("failed to create the backend", zap.Error(err))
+	}
 
 	// Run plan before applying changes
-	plan, err := common.RunPlan(fileService, formatterService, config, subtitleProvider)
-	if err != nil {
-		log.Fatal("failed to run plan", zap.Error(err))
+	plan, err := b.Plan(cmd.Context())
+	if err != nil && err != common.ErrNoFilesFound {
+		common.Fail("failed to run plan", err)
+	}
+	if err == common.ErrNoFilesFound {
+		common.Yellow.Fprintln(os.Stderr, "No video files found.")
+		plan = plans.NewEmptyPlan()
 	}
 
 	// Display results
-	common.DisplayPlanResults(plan)
+	if !output.IsMachineReadable() {
+		common.DisplayPlanResults(plan)
+
+		if !plan.Pending() {
+			return
+		}
+	}
 
-	if !viper.GetBool("auto-approve") {
+	if !autoApprove {
 		fmt.Println()
 		fmt.Println()
 		fmt.Println("Do you want to perform these actions?")
@@ -65,44 +86,20 @@ func Run(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	// Initialize state service for tracking renames
-	stateService, err := states.NewStateService()
+	result, err := b.Apply(cmd.Context(), plan)
 	if err != nil {
-		log.Error("failed to initialize state service", zap.Error(err))
-		return
+		common.Fail("failed to apply plan", err)
 	}
 
-	fmt.Println("\nApplying renames...")
-	for _, change := range plan.Changes {
-		if change.Action == plans.ActionRename && !change.IsConflicting() {
-			oldPath := change.Before.Path
-			newPath := change.After.Path
-
-			err := fileService.RenameFile(oldPath, newPath)
-			if err != nil {
-				fmt.Print("  ")
-				common.Red.Print("✗ ")
-				fmt.Printf("Failed to rename %s: %v\n", change.Before.Filename, err)
-			} else {
-				fmt.Print("  ")
-				common.Green.Print("✓ ")
-				fmt.Printf("Renamed: %s", newPath)
-				fmt.Println()
-
-				// Add to state
-				if err := stateService.AddRenameOperation(
-					oldPath,
-					newPath,
-					change.Before.Filename,
-					change.After.Filename,
-					nil, // TODO: MediaInfo needs to be handled differently in new structure
-				); err != nil {
-					log.Error("failed to add rename to state", zap.Error(err))
-					common.Yellow.Printf("    Warning: Failed to track rename in state\n")
-				}
-
-			}
+	if output.IsMachineReadable() {
+		if err := common.WriteApplyResult(os.Stdout, output, plan, result); err != nil {
+			log.Fatal("failed to write apply result", zap.Error(err))
 		}
+	} else {
+		common.DisplayApplyResults(plan, result)
 	}
 
+	if code := common.ApplyExitCode(result); code != common.ExitCodeOK {
+		os.Exit(code)
+	}
 }
@@ -0,0 +1,227 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"goru/internal/cmd/backend"
+	"goru/internal/cmd/common"
+	"goru/internal/models"
+	"goru/internal/services/audit"
+	"goru/internal/services/plans"
+	"goru/pkg/log"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// titles are the titles of the kinds of problems in the report
+var titles = map[audit.Kind]string{
+	audit.KindUnmatched:      "Unmatched videos",
+	audit.KindMisnamed:       "Misnamed videos",
+	audit.KindBrokenSymlink:  "Broken links",
+	audit.KindEmpty:          "Empty videos",
+	audit.KindTruncated:      "Truncated videos",
+	audit.KindSample:         "Samples",
+	audit.KindOrphanSubtitle: "Orphan subtitles",
+	audit.KindJunk:           "Junk files",
+	audit.KindUnreadable:     "Unreadable paths",
+}
+
+func Run(cmd *cobra.Command, args []string) {
+	log.Debug("goru audit is starting", zap.String("command", "audit"))
+
+	outputFlag, _ := cmd.Flags().GetString("output")
+	if outputFlag != "table" && outputFlag != "json" {
+		log.Fatal("invalid output format", zap.String("output", outputFlag))
+	}
+
+	fixes, err := parseFixes(cmd)
+	if err != nil {
+		log.Fatal("invalid fixes", zap.Error(err))
+	}
+	if outputFlag == "json" && len(fixes) > 0 {
+		log.Fatal("--fix cannot be used with a machine-readable output")
+	}
+
+	// Unmarshal configuration
+	var config models.Config
+	if err := viper.Unmarshal(&config); err != nil {
+		log.Fatal("failed to unmarshal config", zap.Error(err))
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatal("invalid config", zap.Error(err))
+	}
+	if config.Remote.URL != "" {
+		log.Fatal("goru audit only runs on the local filesystem", zap.String("remote", config.Remote.URL))
+	}
+
+	local, err := backend.NewLocal(config)
+	if err != nil {
+		log.Fatal("failed to create the backend", zap.Error(err))
+	}
+
+	directory, _ := cmd.Flags().GetString("directory")
+	quarantine, _ := cmd.Flags().GetString("quarantine")
+	report, err := local.Audit(cmd.Context(), directory, audit.Options{Quarantine: quarantine, Filters: config.Filters})
+	if err == common.ErrNoFilesFound {
+		common.Yellow.Fprintln(os.Stderr, "No directory to audit, give one with --dir or in the config file.")
+		return
+	}
+	if err != nil {
+		log.Fatal("failed to audit the library", zap.Error(err))
+	}
+
+	detailedExitCode, _ := cmd.Flags().GetBool("detailed-exitcode")
+	if outputFlag == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatal("failed to write the report", zap.Error(err))
+		}
+		if detailedExitCode && len(report.Findings) > 0 {
+			os.Exit(common.ExitCodeChangesPending)
+		}
+		return
+	}
+
+	displayReport(report)
+	if len(report.Findings) == 0 {
+		return
+	}
+	if len(fixes) == 0 {
+		if report.Fixes() > 0 {
+			fmt.Println()
+			fmt.Println("Run goru audit --fix <kinds> to apply the fixes, e.g. --fix junk,sample or --fix all.")
+		}
+		if detailedExitCode {
+			os.Exit(common.ExitCodeChangesPending)
+		}
+		return
+	}
+
+	// Only the fixes of the chosen kinds are applied, confirmed one by one when interactive
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	reader := bufio.NewReader(os.Stdin)
+	if interactive {
+		fmt.Println()
+	}
+	err = report.Select(func(finding audit.Finding) bool {
+		if !fixes[finding.Kind] {
+			return false
+		}
+		if !interactive {
+			return true
+		}
+		change, _ := report.Plan.GetChange(finding.ChangeID)
+		fmt.Printf("%s → %s [y/N] ", finding.Path, change.After.Path)
+		response, _ := reader.ReadString('\n')
+		response = strings.ToLower(strings.TrimSpace(response))
+		return response == "y" || response == "yes"
+	})
+	if err != nil {
+		log.Fatal("failed to select the fixes", zap.Error(err))
+	}
+
+	if !report.Plan.Pending() {
+		common.Yellow.Println("\nNo fix to apply.")
+		if detailedExitCode {
+			os.Exit(common.ExitCodeChangesPending)
+		}
+		return
+	}
+
+	autoApprove, _ := cmd.Flags().GetBool("auto-approve")
+	if !autoApprove && !interactive {
+		fmt.Println()
+		fmt.Println("Do you want to apply these fixes?")
+		fmt.Println("Only 'yes' will be accepted to approve.")
+		fmt.Println()
+
+		fmt.Print("Enter a value: ")
+		response, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(response)) != "yes" {
+			fmt.Println("Operation cancelled.")
+			return
+		}
+	}
+
+	result, err := local.Apply(cmd.Context(), report.Plan)
+	if err != nil {
+		log.Fatal("failed to apply the fixes", zap.Error(err))
+	}
+	common.DisplayApplyResults(report.Plan, result)
+
+	if result.Failed > 0 {
+		os.Exit(common.ExitCodeError)
+	}
+	if detailedExitCode && result.Applied < len(report.Findings) {
+		os.Exit(common.ExitCodeChangesPending)
+	}
+}
+
+// parseFixes returns the kinds of problems to fix
+func parseFixes(cmd *cobra.Command) (map[audit.Kind]bool, error) {
+	values, _ := cmd.Flags().GetStringSlice("fix")
+	fixes := make(map[audit.Kind]bool)
+	for _, value := range values {
+		if value == "all" {
+			for _, kind := range audit.Kinds {
+				fixes[kind] = true
+			}
+			continue
+		}
+
+		kind, err := audit.ParseKind(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		fixes[kind] = true
+	}
+	return fixes, nil
+}
+
+func displayReport(report *audit.Report) {
+	if len(report.Findings) == 0 {
+		common.Green.Println("No problem found.")
+		return
+	}
+
+	var kind audit.Kind
+	counts := make(map[audit.Kind]int)
+	for _, finding := range report.Findings {
+		counts[finding.Kind]++
+	}
+
+	for _, finding := range report.Findings {
+		if finding.Kind != kind {
+			kind = finding.Kind
+			fmt.Println()
+			common.Cyan.Printf("%s (%d)\n", titles[kind], counts[kind])
+		}
+
+		fmt.Printf("  %s ", finding.Path)
+		common.Gray.Printf("(%s)\n", finding.Message)
+
+		change, err := report.Plan.GetChange(finding.ChangeID)
+		if err != nil {
+			common.Yellow.Printf("    fix: %s\n", finding.Fix)
+			continue
+		}
+		fmt.Printf("    fix: %s → %s", finding.Fix, change.After.Path)
+		switch {
+		case change.IsConflicting():
+			common.Red.Print(" (CONFLICT)")
+		case change.Action == plans.ActionSkip:
+			common.Yellow.Print(" (SKIPPED)")
+		}
+		fmt.Println()
+	}
+
+	fmt.Println()
+	fmt.Printf("%d problem(s) found, %d with a fix.\n", len(report.Findings), report.Fixes())
+}
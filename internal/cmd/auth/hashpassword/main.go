@@ -0,0 +1,37 @@
+package hashpassword
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"goru/internal/services/auth"
+	"goru/pkg/log"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func Run(cmd *cobra.Command, args []string) {
+	log.Debug("goru auth hash-password is starting", zap.String("command", "auth hash-password"))
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		log.Fatal("failed to read password", zap.Error(err))
+	}
+	fmt.Fprintln(os.Stderr)
+
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		log.Fatal("password cannot be empty")
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Fatal("failed to hash password", zap.Error(err))
+	}
+
+	fmt.Println(hash)
+}
@@ -0,0 +1,107 @@
+// Package backend runs the commands of the CLI, either on the local filesystem or
+// through the API of a remote goru server.
+package backend
+
+import (
+	"context"
+	"errors"
+
+	"goru/internal/cmd/common"
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/internal/services/reports"
+)
+
+// ErrAlreadyReverted is returned when reverting a rename operation that was already reverted
+var ErrAlreadyReverted = errors.New("the operation is already reverted")
+
+// ErrNothingToRevert is returned when there is no active rename operation to revert
+var ErrNothingToRevert = errors.New("no active operation to revert")
+
+// Backend plans, applies and reverts renames
+type Backend interface {
+	// Plan plans the directory given by the flags, or the configured directories.
+	// It returns common.ErrNoFilesFound when there is no video file.
+	Plan(ctx context.Context) (*plans.Plan, error)
+
+	// Apply applies a plan returned by Plan
+	Apply(ctx context.Context, plan *plans.Plan) (*plans.ApplyResult, error)
+
+	// State lists the rename operations, newest first
+	State(ctx context.Context, query StateQuery) (*common.StateOutput, error)
+
+	// Revert reverts rename operations. The result may be returned along with an error,
+	// with the operations reverted before it.
+	Revert(ctx context.Context, selection RevertSelection) (*RevertResult, error)
+
+	// Missing reports the missing, extra and duplicated episodes of the shows
+	Missing(ctx context.Context, query MissingQuery) (*reports.MissingReport, error)
+}
+
+// StateQuery filters the rename operations
+type StateQuery struct {
+	Active bool
+	Limit  int
+}
+
+// MissingQuery scopes the report of the missing episodes
+type MissingQuery struct {
+	// Directory is the name of a configured directory, all the TV show directories
+	// when empty
+	Directory string
+
+	// Aired only expects the episodes already aired
+	Aired bool
+}
+
+// RevertSelection selects the rename operations to revert, exactly one field must be set
+type RevertSelection struct {
+	ID   string
+	Last bool
+	All  bool
+}
+
+// Validate checks that exactly one field is set
+func (s RevertSelection) Validate() error {
+	count := 0
+	if s.ID != "" {
+		count++
+	}
+	if s.Last {
+		count++
+	}
+	if s.All {
+		count++
+	}
+
+	switch count {
+	case 0:
+		return errors.New("you must specify one of id, last or all")
+	case 1:
+		return nil
+	default:
+		return errors.New("you can only specify one of id, last or all")
+	}
+}
+
+// RevertResult is the result of a revert
+type RevertResult struct {
+	RevertedIDs []string
+	Failed      []RevertFailure
+	Total       int
+}
+
+// RevertFailure describes a rename operation that could not be reverted
+type RevertFailure struct {
+	ID     string
+	Reason string
+}
+
+// New creates the remote backend when a server is configured, the local one otherwise
+func New(config models.Config) (Backend, error) {
+	if config.Remote.URL != "" {
+		return NewRemote(config.Remote)
+	}
+
+	return NewLocal(config)
+}
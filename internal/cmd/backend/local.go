@@ -0,0 +1,230 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"goru/internal/cmd/common"
+	"goru/internal/models"
+	"goru/internal/plugins"
+	"goru/internal/services/audit"
+	"goru/internal/services/files"
+	"goru/internal/services/formatters"
+	"goru/internal/services/integrations"
+	"goru/internal/services/plans"
+	"goru/internal/services/reports"
+	"goru/internal/services/sidecars"
+	"goru/internal/services/states"
+	"goru/internal/services/subtitles"
+	"goru/pkg/log"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// Local renames the files of the local filesystem
+type Local struct {
+	config           models.Config
+	fileService      *files.FileService
+	formatterService *formatters.FormatterService
+	stateService     *states.StateService
+	integrations     *integrations.Integrations
+	sidecars         *sidecars.Writer
+	subtitles        *subtitles.Service
+	plugins          *plugins.PluginManager
+}
+
+// NewLocal creates the local backend
+func NewLocal(config models.Config) (*Local, error) {
+	formatterService := formatters.NewFormatterService(viper.GetString("format"), viper.GetString("format"))
+	sanitizer, err := common.NewSanitizer(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the filename sanitizer: %w", err)
+	}
+	formatterService.SetSanitizer(sanitizer)
+
+	stateService, err := states.NewStateService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize state service: %w", err)
+	}
+
+	subtitleService, err := common.NewSubtitles(config)
+	if err != nil {
+		return nil, err
+	}
+
+	pluginManager, err := plugins.NewPluginManager(config.Plugins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable the plugins: %w", err)
+	}
+
+	return &Local{
+		config:           config,
+		fileService:      files.NewFileService("", "", config.Filters),
+		formatterService: formatterService,
+		stateService:     stateService,
+		integrations:     integrations.New(config.Integrations),
+		sidecars:         sidecars.New(config.Sidecars, formatterService.Preset()),
+		subtitles:        subtitleService,
+		plugins:          pluginManager,
+	}, nil
+}
+
+// Plan scans and plans the directories
+func (l *Local) Plan(ctx context.Context) (*plans.Plan, error) {
+	return common.RunPlan(ctx, l.fileService, l.formatterService, l.config, l.subtitles, l.plugins)
+}
+
+// Apply renames the files, records the renames in the state, writes the sidecar files,
+// downloads the subtitles and refreshes the media servers
+func (l *Local) Apply(ctx context.Context, plan *plans.Plan) (*plans.ApplyResult, error) {
+	l.plugins.BeforeApply(ctx, plan, nil)
+	result := plan.Apply(ctx, l.fileService, l.stateService, nil)
+	l.sidecars.Write(ctx, plan, result, l.stateService)
+	l.subtitles.Download(ctx, plan, result, l.stateService)
+	l.integrations.Refresh(ctx, result)
+	l.plugins.AfterApply(ctx, plan, result)
+
+	return result, nil
+}
+
+// State loads the rename operations from the state file
+func (l *Local) State(ctx context.Context, query StateQuery) (*common.StateOutput, error) {
+	state, err := l.stateService.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	activeCount := 0
+	entries := []states.StateEntry{}
+	for _, entry := range state.Entries {
+		if !entry.Reverted {
+			activeCount++
+		}
+		if !query.Active || !entry.Reverted {
+			entries = append(entries, entry)
+		}
+	}
+
+	// Sort entries by timestamp (newest first)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[:query.Limit]
+	}
+
+	return &common.StateOutput{
+		Version:     state.Version,
+		Entries:     entries,
+		ActiveCount: activeCount,
+		TotalCount:  len(state.Entries),
+	}, nil
+}
+
+// Revert renames the files back to their original names
+func (l *Local) Revert(ctx context.Context, selection RevertSelection) (*RevertResult, error) {
+	return NewReverter(l.stateService, l.fileService, l.plugins).Revert(ctx, selection)
+}
+
+// Missing scans the directory given by the flags, or the configured directories, and
+// compares their episodes with the provider
+func (l *Local) Missing(ctx context.Context, query MissingQuery) (*reports.MissingReport, error) {
+	directories := l.config.Directories
+	if dir := viper.GetString("dir"); dir != "" {
+		directories = []models.Directory{{Name: "root", Path: dir, Type: viper.GetString("type"), Recursive: viper.GetBool("recursive")}}
+	}
+	directories, err := reports.SelectDirectories(directories, query.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := common.NewProvider(viper.GetString("provider"), l.plugins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize the provider: %w", err)
+	}
+
+	videoFiles, scanErrors, err := reports.Scan(l.fileService, directories)
+	if err != nil {
+		return nil, err
+	}
+	report, err := reports.Missing(ctx, provider, videoFiles, reports.MissingOptions{Aired: query.Aired})
+	if err != nil {
+		return nil, err
+	}
+	report.AddScanErrors(scanErrors)
+	return report, nil
+}
+
+// Audit walks the directory given by the flags, or the configured directories, and
+// lists their problems with a plan fixing them. The directory may be given by name.
+func (l *Local) Audit(ctx context.Context, name string, options audit.Options) (*audit.Report, error) {
+	directories := l.config.Directories
+	if dir := viper.GetString("dir"); dir != "" {
+		directories = []models.Directory{{Name: "root", Path: dir, Type: viper.GetString("type"), Recursive: viper.GetBool("recursive")}}
+	}
+	if name != "" {
+		selected := directories[:0:0]
+		for _, directory := range directories {
+			if strings.EqualFold(directory.Name, name) {
+				selected = append(selected, directory)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("unknown directory %q", name)
+		}
+		directories = selected
+	}
+	if len(directories) == 0 {
+		return nil, common.ErrNoFilesFound
+	}
+
+	var (
+		scans  []*audit.Scan
+		videos []*models.VideoFile
+	)
+	for _, directory := range directories {
+		fmt.Fprintf(os.Stderr, "Auditing directory: %s\n", directory.Path)
+		scan, err := audit.ScanDirectory(directory, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", directory.Path, err)
+		}
+		scans = append(scans, scan)
+
+		providerName := directory.Provider
+		if providerName == "" {
+			providerName = viper.GetString("provider")
+		}
+		provider, err := common.NewProvider(providerName, l.plugins)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize the provider %s: %w", providerName, err)
+		}
+
+		// The subtitles are not looked for, only the names are checked
+		matched, err := common.ProcessFilesConcurrently(ctx, scan.Videos, provider, nil, l.plugins, viper.GetInt("parallelism"), nil)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			log.Debug("some files were not matched", zap.Error(err))
+		}
+		videos = append(videos, matched...)
+	}
+
+	plan, err := plans.NewPlan(l.plugins.BeforeFormat(ctx, videos), l.formatterService)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plan: %w", err)
+	}
+	report := audit.NewReport(plan, scans...)
+	if len(plan.Conflicts) > 0 {
+		if err := plan.ResolveConflicts(models.DefaultConflictStrategy); err != nil {
+			return nil, fmt.Errorf("failed to resolve conflicts: %w", err)
+		}
+	}
+
+	return report, nil
+}
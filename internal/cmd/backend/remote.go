@@ -0,0 +1,275 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"goru/internal/cmd/common"
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/internal/services/reports"
+	"goru/internal/services/states"
+	"goru/pkg/client"
+	"goru/pkg/log"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// Remote drives a goru server through its API. The paths are the ones of the server.
+type Remote struct {
+	config   models.Remote
+	client   *client.Client
+	loggedIn bool
+}
+
+// NewRemote creates the backend driving the configured server
+func NewRemote(config models.Remote) (*Remote, error) {
+	var opts []client.Option
+	if config.APIKey != "" {
+		opts = append(opts, client.WithAPIKey(config.APIKey))
+	}
+
+	c, err := client.New(config.URL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Remote{
+		config: config,
+		client: c,
+	}, nil
+}
+
+// login opens a session when the server is accessed with a username and a password
+func (r *Remote) login(ctx context.Context) error {
+	if r.loggedIn || r.config.Username == "" {
+		return nil
+	}
+
+	if _, err := r.client.Login(ctx, r.config.Username, r.config.Password); err != nil {
+		return fmt.Errorf("failed to log in to %s: %w", r.config.URL, err)
+	}
+	r.loggedIn = true
+
+	return nil
+}
+
+// Plan plans the directory given by the flags, or the default directory of the server
+func (r *Remote) Plan(ctx context.Context) (*plans.Plan, error) {
+	if err := r.login(ctx); err != nil {
+		return nil, err
+	}
+
+	directory := viper.GetString("dir")
+	if directory == "" {
+		var err error
+		if directory, err = r.client.DefaultDirectory(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get the default directory: %w", err)
+		}
+	}
+
+	// Progress goes to stderr so that stdout only holds the results
+	fmt.Fprintf(os.Stderr, "Scanning directory: %s (on %s)\n", directory, r.config.URL)
+
+	job, err := r.client.CreateJob(ctx, client.JobRequest{
+		Kind: string(client.JobKindPlan),
+		Lookup: &client.PlanRequest{
+			Directory: directory,
+			Type:      viper.GetString("type"),
+			Provider:  viper.GetString("provider"),
+			Recursive: viper.GetBool("recursive"),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the plan: %w", err)
+	}
+
+	snapshot, err := r.watch(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Plan == nil {
+		return nil, fmt.Errorf("job %s did not return a plan", job.ID)
+	}
+
+	var plan *plans.Plan
+	if err := convert(snapshot.Plan, &plan); err != nil {
+		return nil, err
+	}
+
+	if len(plan.Changes) == 0 && len(plan.Errors) == 0 {
+		return nil, common.ErrNoFilesFound
+	}
+
+	fmt.Fprintf(os.Stderr, "Found %d video file(s)\n\n", len(plan.Changes)+len(plan.Errors))
+
+	return plan, nil
+}
+
+// Apply applies a plan stored by the server
+func (r *Remote) Apply(ctx context.Context, plan *plans.Plan) (*plans.ApplyResult, error) {
+	if err := r.login(ctx); err != nil {
+		return nil, err
+	}
+
+	if !plan.Pending() {
+		return &plans.ApplyResult{PlanID: plan.ID, Changes: []plans.ChangeResult{}}, nil
+	}
+	if plan.ID == "" {
+		return nil, fmt.Errorf("the plan was not stored by the server")
+	}
+
+	job, err := r.client.CreateJob(ctx, client.JobRequest{
+		Kind:   string(client.JobKindApply),
+		PlanID: plan.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the apply: %w", err)
+	}
+
+	snapshot, err := r.watch(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Result == nil {
+		return nil, fmt.Errorf("job %s did not return a result", job.ID)
+	}
+
+	var result *plans.ApplyResult
+	if err := convert(snapshot.Result, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// State lists the rename operations recorded by the server
+func (r *Remote) State(ctx context.Context, query StateQuery) (*common.StateOutput, error) {
+	if err := r.login(ctx); err != nil {
+		return nil, err
+	}
+
+	state, err := r.client.State(ctx, client.StateOptions{Active: query.Active, Limit: query.Limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	var entries []states.StateEntry
+	if err := convert(state.Entries, &entries); err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []states.StateEntry{}
+	}
+
+	return &common.StateOutput{
+		Version:     state.Version,
+		Entries:     entries,
+		ActiveCount: state.ActiveCount,
+		TotalCount:  state.TotalCount,
+	}, nil
+}
+
+// Revert reverts rename operations on the server
+func (r *Remote) Revert(ctx context.Context, selection RevertSelection) (*RevertResult, error) {
+	if err := selection.Validate(); err != nil {
+		return nil, err
+	}
+	if err := r.login(ctx); err != nil {
+		return nil, err
+	}
+
+	response, err := r.client.Revert(ctx, client.RevertRequest{
+		ID:   selection.ID,
+		Last: selection.Last,
+		All:  selection.All,
+	})
+	if response == nil {
+		if err == nil {
+			err = fmt.Errorf("the server did not return the result of the revert")
+		}
+		return nil, err
+	}
+
+	result := &RevertResult{
+		RevertedIDs: response.RevertedIDs,
+		Total:       response.TotalCount,
+	}
+	for _, failure := range response.Failed {
+		result.Failed = append(result.Failed, RevertFailure{ID: failure.ID, Reason: failure.Reason})
+	}
+
+	// The result is returned along with the error when no operation could be reverted
+	return result, err
+}
+
+// Missing reports the missing episodes of the library of the server
+func (r *Remote) Missing(ctx context.Context, query MissingQuery) (*reports.MissingReport, error) {
+	if err := r.login(ctx); err != nil {
+		return nil, err
+	}
+
+	response, err := r.client.MissingReport(ctx, client.MissingReportOptions{Directory: query.Directory, Aired: query.Aired})
+	if err != nil {
+		return nil, err
+	}
+
+	var report *reports.MissingReport
+	if err := convert(response, &report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// watch follows the events of a job until it is finished, and returns the snapshot of
+// the finished job. The job is cancelled when the context is cancelled.
+func (r *Remote) watch(ctx context.Context, id string) (*client.Job, error) {
+	err := r.client.WatchJob(ctx, id, func(event client.JobEvent) {
+		if event.Type == client.JobEventError {
+			log.Debug("remote job reported an error", zap.String("file", event.File), zap.String("message", event.Message))
+		}
+	})
+	if ctx.Err() != nil {
+		if _, err := r.client.CancelJob(context.Background(), id); err != nil {
+			log.Warn("failed to cancel the remote job", zap.String("job", id), zap.Error(err))
+		}
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to follow job %s: %w", id, err)
+	}
+
+	// The outcome is read from the job rather than from the events, which a slow
+	// client may miss
+	snapshot, err := r.client.GetJob(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job %s: %w", id, err)
+	}
+
+	switch snapshot.Status {
+	case client.JobStatusSucceeded:
+		return snapshot, nil
+	case client.JobStatusFailed:
+		return nil, fmt.Errorf("job %s failed: %s", id, snapshot.Error)
+	default:
+		return nil, fmt.Errorf("job %s ended with status %s", id, snapshot.Status)
+	}
+}
+
+// convert converts a type of the client to its counterpart of the services, both having
+// the same JSON representation
+func convert(from, to any) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return fmt.Errorf("failed to convert the response of the server: %w", err)
+	}
+	if err := json.Unmarshal(data, to); err != nil {
+		return fmt.Errorf("failed to convert the response of the server: %w", err)
+	}
+
+	return nil
+}
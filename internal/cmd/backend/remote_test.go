@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goru/internal/models"
+	"goru/internal/services/jobs"
+	"goru/internal/services/plans"
+
+	"github.com/spf13/viper"
+)
+
+func TestRemote_PlanAndApply(t *testing.T) {
+	var lookup struct {
+		Kind   string `json:"kind"`
+		PlanID string `json:"plan_id"`
+		Lookup struct {
+			Directory string `json:"directory"`
+		} `json:"lookup"`
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/jobs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "0123456789abcdef" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&lookup)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"id":%q,"kind":%q,"status":"pending"}`, lookup.Kind, lookup.Kind)
+	})
+	// The stream of the plan ends before the plan, which is read from the job
+	mux.HandleFunc("/api/v1/jobs/plan/events", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"type\":\"status\",\"status\":\"running\"}\n\n")
+	})
+	mux.HandleFunc("/api/v1/jobs/plan", func(w http.ResponseWriter, r *http.Request) {
+		plan := plans.Plan{ID: "p1", Changes: []plans.Change{{ID: "c1", Action: plans.ActionRename}}}
+		json.NewEncoder(w).Encode(jobs.Snapshot{ID: "plan", Kind: jobs.KindPlan, Status: jobs.StatusSucceeded, Plan: &plan})
+	})
+	mux.HandleFunc("/api/v1/jobs/apply/events", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"type\":\"result\",\"result\":{\"plan_id\":\"p1\",\"applied\":1}}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"status\",\"status\":\"failed\",\"message\":\"disk full\"}\n\n")
+	})
+	mux.HandleFunc("/api/v1/jobs/apply", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jobs.Snapshot{ID: "apply", Kind: jobs.KindApply, Status: jobs.StatusFailed, Error: "disk full"})
+	})
+	mux.HandleFunc("/api/v1/state/revert", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"success":false,"message":"failed to revert","failed":[{"id":"s1","reason":"missing"}],"total_count":1}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	viper.Set("dir", "/media/movies")
+	defer viper.Set("dir", "")
+
+	remote, err := NewRemote(models.Remote{URL: server.URL, APIKey: "0123456789abcdef"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	plan, err := remote.Plan(ctx)
+	if err != nil {
+		t.Fatalf("Plan() returned an error: %v", err)
+	}
+	if plan.ID != "p1" || len(plan.Changes) != 1 || plan.Changes[0].Action != plans.ActionRename || lookup.Lookup.Directory != "/media/movies" {
+		t.Errorf("Plan() = %+v for directory %q", plan, lookup.Lookup.Directory)
+	}
+
+	if _, err := remote.Apply(ctx, plan); err == nil || !strings.Contains(err.Error(), "disk full") || lookup.PlanID != "p1" {
+		t.Errorf("Apply() of plan %q returned %v, want the failure of the job", lookup.PlanID, err)
+	}
+
+	// The failures are returned along with the error
+	result, err := remote.Revert(ctx, RevertSelection{ID: "s1"})
+	if err == nil || result == nil || len(result.Failed) != 1 || result.Total != 1 {
+		t.Errorf("Revert() = %+v, %v, want the failure and an error", result, err)
+	}
+}
@@ -0,0 +1,131 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"goru/internal/plugins"
+	"goru/internal/services/files"
+	"goru/internal/services/metrics"
+	"goru/internal/services/sidecars"
+	"goru/internal/services/states"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// Reverter renames the files of the rename operations back to their original names.
+// It is shared by the local backend and the server.
+type Reverter struct {
+	stateService *states.StateService
+	fileService  *files.FileService
+	plugins      *plugins.PluginManager
+}
+
+// NewReverter creates a reverter calling the revert hooks of the plugins, which may be nil
+func NewReverter(stateService *states.StateService, fileService *files.FileService, plugins *plugins.PluginManager) *Reverter {
+	return &Reverter{
+		stateService: stateService,
+		fileService:  fileService,
+		plugins:      plugins,
+	}
+}
+
+// Revert reverts the selected rename operations. A failing revert does not stop the
+// others, a cancelled context does.
+func (r *Reverter) Revert(ctx context.Context, selection RevertSelection) (*RevertResult, error) {
+	if err := selection.Validate(); err != nil {
+		return nil, err
+	}
+
+	var entriesToRevert []states.StateEntry
+	switch {
+	case selection.ID != "":
+		entry, err := r.stateService.GetEntryByID(selection.ID)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Reverted {
+			return nil, ErrAlreadyReverted
+		}
+
+		entriesToRevert = []states.StateEntry{*entry}
+
+	case selection.Last:
+		entry, err := r.stateService.GetLastActiveEntry()
+		if err != nil {
+			return nil, err
+		}
+
+		entriesToRevert = []states.StateEntry{*entry}
+
+	case selection.All:
+		entries, err := r.stateService.GetActiveEntries()
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			return nil, ErrNothingToRevert
+		}
+
+		entriesToRevert = entries
+	}
+
+	result := &RevertResult{Total: len(entriesToRevert)}
+	for _, entry := range entriesToRevert {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		log.Debug("reverting entry", zap.String("id", entry.ID), zap.String("from", entry.NewName), zap.String("to", entry.OriginalName))
+
+		reason := ""
+		if err := r.plugins.OnRevert(ctx, &entry); err != nil {
+			reason = err.Error()
+		} else {
+			reason = r.revertEntry(entry)
+		}
+		if reason != "" {
+			log.Warn("failed to revert entry", zap.String("id", entry.ID), zap.String("reason", reason))
+			result.Failed = append(result.Failed, RevertFailure{ID: entry.ID, Reason: reason})
+			metrics.Renames.WithLabelValues(metrics.RenameRevertFailed).Inc()
+			continue
+		}
+
+		metrics.Renames.WithLabelValues(metrics.RenameReverted).Inc()
+		result.RevertedIDs = append(result.RevertedIDs, entry.ID)
+	}
+
+	return result, nil
+}
+
+// revertEntry reverts a rename operation and returns the reason of the failure, if any
+func (r *Reverter) revertEntry(entry states.StateEntry) string {
+	// Check if the new file still exists, a broken link being moved as well
+	if _, err := os.Lstat(entry.NewPath); os.IsNotExist(err) {
+		return fmt.Sprintf("file not found: %s", entry.NewPath)
+	}
+
+	// Check if original path would conflict
+	originalPath := filepath.Join(filepath.Dir(entry.OriginalPath), entry.OriginalName)
+	if _, err := os.Stat(originalPath); err == nil {
+		return fmt.Sprintf("original file already exists: %s", originalPath)
+	}
+
+	if err := r.fileService.RenameFile(entry.NewPath, originalPath); err != nil {
+		return fmt.Sprintf("failed to revert: %v", err)
+	}
+
+	if err := sidecars.Remove(entry); err != nil {
+		log.Warn("failed to remove the sidecar files", zap.String("id", entry.ID), zap.Error(err))
+	}
+
+	// Mark as reverted in state
+	if err := r.stateService.MarkAsReverted(entry.ID); err != nil {
+		return fmt.Sprintf("file reverted but failed to update state: %v", err)
+	}
+
+	return ""
+}
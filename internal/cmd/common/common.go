@@ -5,13 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"goru/internal/models"
+	"goru/internal/plugins"
 	"goru/internal/services/files"
 	"goru/internal/services/formatters"
+	"goru/internal/services/mediainfo"
 	"goru/internal/services/plans"
 	"goru/internal/services/providers"
+	"goru/internal/services/providers/nfo"
 	"goru/internal/services/providers/tmdb"
 	"goru/internal/services/subtitles"
+	_ "goru/internal/services/subtitles/addic7ed"
+	_ "goru/internal/services/subtitles/local"
+	_ "goru/internal/services/subtitles/opensubtitles"
 	"goru/pkg/log"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/fatih/color"
@@ -31,56 +40,125 @@ var (
 
 var ErrNoFilesFound = errors.New("no video files found")
 
-func RunPlan(fileService *files.FileService, formatterService *formatters.FormatterService, config models.Config, subtitleProvider subtitles.SubtitleProvider) (*plans.Plan, error) {
+// NewProvider creates a provider by name. The nfo provider can come first, as in
+// "nfo,tmdb", to look up the IDs of the .nfo files with the next one.
+func NewProvider(name string, hooks *plugins.PluginManager) (providers.Provider, error) {
+	first, rest, chained := strings.Cut(name, ",")
+	first = strings.TrimSpace(first)
+
+	if strings.EqualFold(first, "nfo") {
+		var next providers.Provider
+		if chained {
+			var err error
+			if next, err = NewProvider(rest, hooks); err != nil {
+				return nil, err
+			}
+		}
+		return nfo.New(next), nil
+	}
+	if chained {
+		return nil, fmt.Errorf("only the nfo provider can come before another one: %s", name)
+	}
+
+	switch strings.ToLower(first) {
+	case "tmdb":
+		provider, err := tmdb.New(viper.GetString("providers.tmdb.api_key"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize TMDB provider: %w", err)
+		}
+		return provider, nil
+	case "tvdb":
+		return nil, errors.New("tvdb not implemented yet")
+	}
+
+	pluginProvider, ok := hooks.Provider(first)
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", first)
+	}
+	return pluginProvider, nil
+}
+
+// NewSubtitles creates the subtitle service described by the configuration, nil when
+// the subtitles are disabled
+func NewSubtitles(config models.Config) (*subtitles.Service, error) {
+	if !config.Subtitles.Enabled {
+		return nil, nil
+	}
+
+	names := config.Subtitles.Providers
+	if len(names) == 0 {
+		names = subtitles.DefaultProviders
+	}
+
+	var sources []subtitles.SubtitleProvider
+	for _, name := range names {
+		provider, err := subtitles.Create(name, config.Providers[name])
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, provider)
+	}
+	return subtitles.New(config.Subtitles, sources...), nil
+}
+
+func RunPlan(ctx context.Context, fileService *files.FileService, formatterService *formatters.FormatterService, config models.Config, subtitleService *subtitles.Service, hooks *plugins.PluginManager) (*plans.Plan, error) {
 	// Determine directories to scan (whether user is giving a single dir or multiple dirs with config file)
 	var directories []models.Directory
 	if viper.GetString("dir") != "" {
+		conflictStrategy, err := models.ParseConflictStrategy(viper.GetString("conflict"))
+		if err != nil {
+			return nil, err
+		}
+
 		directories = append(directories, models.Directory{
-			Name:      "root",
-			Path:      viper.GetString("dir"),
-			Type:      viper.GetString("type"),
-			Provider:  viper.GetString("provider"),
-			Recursive: viper.GetBool("recursive"),
-			Format:    viper.GetString("format"),
+			Name:             "root",
+			Path:             viper.GetString("dir"),
+			Type:             viper.GetString("type"),
+			Provider:         viper.GetString("provider"),
+			Recursive:        viper.GetBool("recursive"),
+			Format:           viper.GetString("format"),
+			ConflictStrategy: conflictStrategy,
 		})
 	} else {
 		directories = config.Directories
 	}
 
 	// Scan each directory for video files
-	var videoFiles []*models.VideoFile
-	var subtitleFiles []string
+	var (
+		videoFiles []*models.VideoFile
+		scanErrors []files.ScanError
+	)
 	for _, dir := range directories {
 		if string(dir.ConflictStrategy) == "" {
 			dir.ConflictStrategy = models.DefaultConflictStrategy
 		}
 
-		fmt.Printf("Scanning directory: %s\n", dir.Path)
-		fmt.Printf("Conflict resolution strategy: %s\n", dir.ConflictStrategy)
-		currentFiles, err := fileService.ScanDirectory(dir.Path, dir.Recursive, dir.Type)
+		// Progress goes to stderr so that stdout only holds the results
+		fmt.Fprintf(os.Stderr, "Scanning directory: %s\n", dir.Path)
+		fmt.Fprintf(os.Stderr, "Conflict resolution strategy: %s\n", dir.ConflictStrategy)
+		currentFiles, unreadable, err := fileService.ScanDirectory(dir)
 		if err != nil {
-			log.Fatal("failed to scan directory", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan directory %s: %w", dir.Path, err)
+		}
+		for _, scanError := range unreadable {
+			log.Warn("failed to read a path of the directory", zap.String("path", scanError.Path), zap.Error(scanError.Err))
 		}
+		scanErrors = append(scanErrors, unreadable...)
 
+		currentFiles = hooks.OnScan(ctx, currentFiles)
 		if len(currentFiles) == 0 {
-			color.Yellow("No video files found in the specified directory.")
+			Yellow.Fprintln(os.Stderr, "No video files found in the specified directory.")
 			continue
 		}
 
 		// Initialize provider
-		var provider providers.Provider
-		switch viper.GetString("provider") {
-		case "tmdb":
-			tmdbProvider, err := tmdb.New(viper.GetString("providers.tmdb.api_key"))
-			if err != nil {
-				log.Fatal("failed to initialize TMDB service", zap.Error(err))
-			}
-
-			provider = tmdbProvider
-		case "tvdb":
-			log.Fatal("tvdb not implemented yet")
-		default:
-			log.Fatal("unsupported database type", zap.String("provider", viper.GetString("provider")))
+		providerName := dir.Provider
+		if providerName == "" {
+			providerName = viper.GetString("provider")
+		}
+		provider, err := NewProvider(providerName, hooks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize the provider %s: %w", providerName, err)
 		}
 
 		for _, file := range currentFiles {
@@ -89,45 +167,75 @@ func RunPlan(fileService *files.FileService, formatterService *formatters.Format
 		}
 
 		// Process files concurrently
-		processedFiles, processedSubtitles, err := ProcessFilesConcurrently(currentFiles, provider, subtitleProvider, viper.GetInt("parallelism"))
+		processedFiles, err := ProcessFilesConcurrently(ctx, currentFiles, provider, subtitleService, hooks, viper.GetInt("parallelism"), nil)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if err != nil {
 			log.Error("failed to process files concurrently", zap.Error(err))
 		}
 
 		videoFiles = append(videoFiles, processedFiles...)
-		subtitleFiles = append(subtitleFiles, processedSubtitles...)
 	}
 
-	if len(videoFiles) == 0 {
+	if len(videoFiles) == 0 && len(scanErrors) == 0 {
 		return nil, ErrNoFilesFound
 	}
 
-	fmt.Printf("Found %d video file(s)\n\n", len(videoFiles))
+	fmt.Fprintf(os.Stderr, "Found %d video file(s)\n\n", len(videoFiles))
 
 	// Create the plan
-	plan, err := plans.NewPlan(videoFiles, subtitleFiles, formatterService)
+	videoFiles = hooks.BeforeFormat(ctx, videoFiles)
+	plan, err := plans.NewPlan(videoFiles, formatterService)
 	if err != nil {
-		log.Fatal("failed to create plan", zap.Error(err))
+		return nil, fmt.Errorf("failed to create plan: %w", err)
 	}
+	plan.AddScanErrors(scanErrors)
 
-	// Resolve conflicts
-	// TODO: fix with new strategy !!!!!!!!!!!!!!!
+	// Resolve conflicts with the strategies of the directories
 	if len(plan.Conflicts) > 0 {
 		log.Debug("conflicts detected", zap.Int("nb_conflicts", len(plan.Conflicts)))
-		err := plan.ResolveConflicts(models.ConflictStrategyAppendNumber)
+		err := plan.ResolveConflicts(models.DefaultConflictStrategy)
 		if err != nil {
-			log.Fatal("failed to resolve conflicts", zap.Error(err))
+			return nil, fmt.Errorf("failed to resolve conflicts: %w", err)
 		}
 	}
 
+	if err := hooks.OnPlan(ctx, plan); err != nil {
+		return nil, err
+	}
+
 	return plan, nil
 }
 
+// NewSanitizer creates the filename sanitizer described by the configuration
+func NewSanitizer(config models.Config) (*formatters.Sanitizer, error) {
+	return formatters.NewSanitizer(formatters.SanitizerOptions{
+		Profile:       formatters.Profile(config.Sanitize.Profile),
+		Normalization: formatters.Normalization(config.Sanitize.Normalization),
+		Transliterate: config.Sanitize.Transliterate,
+		MaxBytes:      config.Sanitize.MaxBytes,
+	})
+}
+
+// targetName returns the filename of the target of a change, with its folder when the
+// file is moved to another one, such as the extras moved to the folders of Plex
+func targetName(change plans.Change) string {
+	if filepath.Dir(change.Before.Path) == filepath.Dir(change.After.Path) {
+		return change.After.Filename
+	}
+	if rel, err := filepath.Rel(filepath.Dir(change.Before.Path), change.After.Path); err == nil {
+		return rel
+	}
+	return change.After.Path
+}
+
 // DisplayPlanResults displays the results of a rename plan
 func DisplayPlanResults(plan *plans.Plan) {
 	alreadyCorrectCount := 0
 	needsRenameCount := 0
 	skippedCount := 0
+	subtitleCount := 0
 
 	fmt.Println("Goru will perform the following actions:")
 	fmt.Println()
@@ -138,24 +246,33 @@ func DisplayPlanResults(plan *plans.Plan) {
 			if change.IsConflicting() {
 				// Conflicted change
 				needsRenameCount++
-				fmt.Printf("%c %s → %s %s\n", change.Action, change.Before.Filename, Yellow.Sprint(change.After.Filename), Red.Sprint("(CONFLICT)"))
+				fmt.Printf("%c %s → %s %s%s\n", change.Action, change.Before.Filename, Yellow.Sprint(targetName(change)), Red.Sprint("(CONFLICT)"), runtimeWarning(change))
 			} else {
 				// Ready to be renamed
 				needsRenameCount++
 				Yellow.Printf("%c", change.Action)
-				fmt.Printf(" %s → %s\n", change.Before.Filename, Yellow.Sprint(change.After.Filename))
+				fmt.Printf(" %s → %s%s\n", change.Before.Filename, Yellow.Sprint(targetName(change)), runtimeWarning(change))
 			}
 
 		case plans.ActionNoop:
 			// File is already correctly named
 			alreadyCorrectCount++
 			Green.Printf("%c", change.Action)
-			fmt.Printf("%s\n", Green.Sprint(change.Before.Filename))
+			fmt.Printf("%s%s\n", Green.Sprint(change.Before.Filename), runtimeWarning(change))
 
 		case plans.ActionSkip:
 			skippedCount++
 			Blue.Printf("%c", change.Action)
 			fmt.Printf(" %s: %s\n", change.Before.Filename, Blue.Sprint("skipped"))
+
+		case plans.ActionCreate:
+			subtitleCount++
+			note := "subtitle for " + change.Before.Filename
+			if sub := change.Subtitle; sub != nil && sub.Convert != "" && sub.Convert != sub.Format {
+				note += ", converted from " + sub.Format
+			}
+			Cyan.Printf("%c", change.Action)
+			fmt.Printf(" %s %s\n", Cyan.Sprint(change.After.Filename), Gray.Sprintf("(%s)", note))
 		}
 	}
 
@@ -165,11 +282,19 @@ func DisplayPlanResults(plan *plans.Plan) {
 	}
 
 	// Summary
-	printPlanSummary(plan, alreadyCorrectCount, needsRenameCount, len(plan.Errors), skippedCount)
+	printPlanSummary(plan, alreadyCorrectCount, needsRenameCount, len(plan.Errors), skippedCount, subtitleCount)
+}
+
+// runtimeWarning flags the changes whose file does not last as long as their match
+func runtimeWarning(change plans.Change) string {
+	if change.RuntimeMismatch == nil {
+		return ""
+	}
+	return " " + Red.Sprintf("(RUNTIME MISMATCH: %s)", change.RuntimeMismatch)
 }
 
 // printPlanSummary prints a summary of the plan results
-func printPlanSummary(plan *plans.Plan, alreadyCorrectCount, needsRenameCount, errorCount, skippedCount int) {
+func printPlanSummary(plan *plans.Plan, alreadyCorrectCount, needsRenameCount, errorCount, skippedCount, subtitleCount int) {
 	fmt.Println()
 	fmt.Println(color.HiBlackString("─────────────────────────────────────────────────────────────"))
 	fmt.Printf("Plan Summary: ")
@@ -181,6 +306,10 @@ func printPlanSummary(plan *plans.Plan, alreadyCorrectCount, needsRenameCount, e
 		Blue.Printf("%d skipped", skippedCount)
 		fmt.Print(", ")
 	}
+	if subtitleCount > 0 {
+		Cyan.Printf("%d subtitles to download", subtitleCount)
+		fmt.Print(", ")
+	}
 	if errorCount > 0 {
 		Red.Printf("%d errors", errorCount)
 	} else {
@@ -192,22 +321,96 @@ func printPlanSummary(plan *plans.Plan, alreadyCorrectCount, needsRenameCount, e
 		fmt.Printf("Conflicts: %d total, %d resolved\n", len(plan.Conflicts), plan.Summary().ResolvedConflicts)
 	}
 
-	if needsRenameCount > 0 {
+	if mismatches := plan.Summary().RuntimeMismatches; mismatches > 0 {
+		Red.Printf("Runtime mismatches: %d, check these matches before applying\n", mismatches)
+	}
+
+	if needsRenameCount > 0 || subtitleCount > 0 {
 		fmt.Println()
 		Yellow.Println("To apply these changes, run: goru apply")
 	}
 }
 
+// DisplayApplyResults displays the results of an applied plan
+func DisplayApplyResults(plan *plans.Plan, result *plans.ApplyResult) {
+	changes := make(map[string]plans.Change, len(plan.Changes))
+	for _, change := range plan.Changes {
+		changes[change.ID] = change
+	}
+
+	fmt.Println("\nApplying renames...")
+	for _, change := range result.Changes {
+		fmt.Print("  ")
+		if changes[change.ChangeID].Action == plans.ActionCreate {
+			if change.Status == plans.ChangeStatusFailed {
+				Red.Print("✗ ")
+				fmt.Printf("Failed to download %s: %s\n", filepath.Base(change.After), change.Error)
+				continue
+			}
+			Green.Print("✓ ")
+			fmt.Printf("Downloaded: %s\n", change.After)
+			continue
+		}
+
+		if change.Status == plans.ChangeStatusFailed {
+			Red.Print("✗ ")
+			fmt.Printf("Failed to rename %s: %s\n", changes[change.ChangeID].Before.Filename, change.Error)
+			continue
+		}
+
+		Green.Print("✓ ")
+		fmt.Printf("Renamed: %s\n", change.After)
+		if change.StateID == "" {
+			Yellow.Printf("    Warning: Failed to track rename in state\n")
+		}
+		for _, sidecar := range change.Sidecars {
+			Gray.Printf("    + %s\n", filepath.Base(sidecar))
+		}
+	}
+
+	if len(result.Integrations) > 0 {
+		fmt.Println("\nRefreshing media servers...")
+		for _, integration := range result.Integrations {
+			fmt.Print("  ")
+			if integration.Error != "" {
+				Red.Print("✗ ")
+				fmt.Printf("Failed to refresh %s: %s\n", integration.Name, integration.Error)
+				continue
+			}
+
+			Green.Print("✓ ")
+			fmt.Printf("Refreshed %s: %d folder(s)\n", integration.Name, len(integration.Folders))
+		}
+	}
+
+	fmt.Println()
+	if result.Downloaded > 0 {
+		fmt.Printf("Apply complete! %d renamed, %d failed, %d subtitles downloaded.\n", result.Applied, result.Failed, result.Downloaded)
+		return
+	}
+	fmt.Printf("Apply complete! %d renamed, %d failed.\n", result.Applied, result.Failed)
+}
+
 // FileProcessResult holds the result of processing a single file
 type FileProcessResult struct {
-	File     *models.VideoFile
-	Subtitle string
-	Error    error
+	File  *models.VideoFile
+	Error error
+
+	// Vetoed is set when a plugin left the file out of the plan
+	Vetoed bool
 }
 
-func ProcessFilesConcurrently(files []*models.VideoFile, provider providers.Provider, subtitleProvider subtitles.SubtitleProvider, maxConcurrent int) ([]*models.VideoFile, []string, error) {
+// ProgressFunc is called each time a file has been processed. It may be nil.
+type ProgressFunc func(result FileProcessResult, processed, total int)
+
+// ProcessFilesConcurrently reads the media info, looks up the metadata and finds the
+// subtitles of the files.
+// When the context is cancelled, the files not yet started are left out and the context
+// error is returned.
+// The files vetoed by the match hooks of the plugins are left out.
+func ProcessFilesConcurrently(ctx context.Context, files []*models.VideoFile, provider providers.Provider, subtitleService *subtitles.Service, hooks *plugins.PluginManager, maxConcurrent int, onProgress ProgressFunc) ([]*models.VideoFile, error) {
 	if len(files) == 0 {
-		return nil, nil, nil
+		return nil, nil
 	}
 
 	sem := semaphore.NewWeighted(int64(maxConcurrent))
@@ -216,34 +419,59 @@ func ProcessFilesConcurrently(files []*models.VideoFile, provider providers.Prov
 
 	for _, f := range files {
 		// Acquire semaphore to limit concurrency
-		if err := sem.Acquire(context.Background(), 1); err != nil {
-			continue
+		if err := sem.Acquire(ctx, 1); err != nil {
+			break
 		}
 
 		wg.Add(1)
 		go func(f *models.VideoFile) {
 			defer wg.Done()
+			defer sem.Release(1)
 
 			result := FileProcessResult{File: f}
 
+			// The extras routed to the folders of Plex are not matched
+			if f.Extra != "" {
+				results <- result
+				return
+			}
+
+			// The technical metadata is read first, for the hooks and the providers
+			if f.MediaInfo == nil {
+				info, err := mediainfo.Probe(f.Path)
+				if err != nil {
+					log.Debug("failed to read the media info", zap.String("file", f.Path), zap.Error(err))
+				}
+				f.MediaInfo = info
+			}
+
+			if err := hooks.BeforeMatch(ctx, f); err != nil {
+				result.Vetoed = true
+				results <- result
+				return
+			}
+
 			// Process file synchronously
 			log.Debug("providing metadata", zap.String("file", f.Filename))
-			err := provider.Provide(f)
+			err := provider.Provide(ctx, f)
 			if err != nil {
 				log.Error("failed to provide metadata", zap.Error(err), zap.String("file", f.Path))
 				result.Error = err
 			}
 
-			log.Debug("checking for subtitles", zap.String("file", f.Filename))
-			if viper.GetBool("subtitles") {
-				sub, err := subtitleProvider.Get(f, viper.GetString("subtitles.language"))
-				if err == nil {
-					result.Subtitle = sub
-				}
+			if err := hooks.AfterMatch(ctx, f); err != nil {
+				result.Vetoed = true
+				result.Error = nil
+				results <- result
+				return
+			}
+
+			// Only the matched files get subtitles, named after their target
+			if result.Error == nil && ctx.Err() == nil {
+				f.Subtitles = subtitleService.Find(ctx, f)
 			}
 
 			results <- result
-			sem.Release(1)
 		}(f)
 	}
 
@@ -255,23 +483,32 @@ func ProcessFilesConcurrently(files []*models.VideoFile, provider providers.Prov
 
 	// Collect results
 	var processedFiles []*models.VideoFile
-	var subtitleFiles []string
 	var hasErrors bool
+	processed := 0
 
 	for result := range results {
-		processedFiles = append(processedFiles, result.File)
-		if result.Subtitle != "" {
-			subtitleFiles = append(subtitleFiles, result.Subtitle)
+		processed++
+		if onProgress != nil {
+			onProgress(result, processed, len(files))
 		}
+		if result.Vetoed {
+			continue
+		}
+
+		processedFiles = append(processedFiles, result.File)
 		if result.Error != nil {
 			hasErrors = true
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return processedFiles, err
+	}
+
 	var err error
 	if hasErrors {
 		err = fmt.Errorf("some files failed to process (check logs for details)")
 	}
 
-	return processedFiles, subtitleFiles, err
+	return processedFiles, err
 }
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"goru/internal/models"
+	"goru/internal/services/files"
+
+	"github.com/spf13/viper"
+)
+
+func TestRunPlan_ScanError(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	viper.Set("dir", missing)
+	viper.Set("conflict", string(models.DefaultConflictStrategy))
+	defer viper.Set("dir", "")
+	defer viper.Set("conflict", "")
+
+	// The scan error is returned to the command, which exits with ExitCodeError
+	fileService := files.NewFileService("", "", models.Filters{})
+	plan, err := RunPlan(context.Background(), fileService, nil, models.Config{}, nil, nil)
+	if !errors.Is(err, fs.ErrNotExist) || plan != nil {
+		t.Fatalf("RunPlan() on a missing directory = %v, %v, want the scan error", plan, err)
+	}
+}
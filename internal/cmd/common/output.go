@@ -0,0 +1,194 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"goru/internal/services/plans"
+	"goru/internal/services/states"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// OutputFormat is the format used by the commands to print their results
+type OutputFormat string
+
+const (
+	// OutputTable is the default, colored, human-readable output
+	OutputTable OutputFormat = "table"
+
+	// OutputPlain is the human-readable output without colors
+	OutputPlain OutputFormat = "plain"
+
+	// OutputJSON prints a single JSON document
+	OutputJSON OutputFormat = "json"
+
+	// OutputNDJSON prints one JSON record per line
+	OutputNDJSON OutputFormat = "ndjson"
+)
+
+// Exit codes of the commands, similar to `terraform plan -detailed-exitcode`
+const (
+	// ExitCodeOK means the command succeeded and there is nothing left to do
+	ExitCodeOK = 0
+
+	// ExitCodeError means the command failed
+	ExitCodeError = 1
+
+	// ExitCodeChangesPending means the plan succeeded and contains changes to apply
+	ExitCodeChangesPending = 2
+)
+
+// ParseOutputFormat parses the value of the --output flag
+func ParseOutputFormat(value string) (OutputFormat, error) {
+	switch format := OutputFormat(value); format {
+	case OutputTable, OutputPlain, OutputJSON, OutputNDJSON:
+		return format, nil
+	case "":
+		return OutputTable, nil
+	default:
+		return "", fmt.Errorf("unsupported output format %q (must be one of table, plain, json or ndjson)", value)
+	}
+}
+
+// PlanExitCode returns the exit code of a plan, ExitCodeChangesPending when it holds
+// changes to apply and the detailed exit codes are asked for
+func PlanExitCode(plan *plans.Plan, detailed bool) int {
+	if detailed && plan.Pending() {
+		return ExitCodeChangesPending
+	}
+	return ExitCodeOK
+}
+
+// ApplyExitCode returns the exit code of an apply, ExitCodeError when a rename failed
+func ApplyExitCode(result *plans.ApplyResult) int {
+	if result.Failed > 0 {
+		return ExitCodeError
+	}
+	return ExitCodeOK
+}
+
+// Fail logs the error on stderr and exits with ExitCodeError, whatever the output format
+func Fail(msg string, err error) {
+	log.Error(msg, zap.Error(err))
+	os.Exit(ExitCodeError)
+}
+
+// IsMachineReadable returns true if the output is meant to be parsed
+func (o OutputFormat) IsMachineReadable() bool {
+	return o == OutputJSON || o == OutputNDJSON
+}
+
+// PlanOutput is the machine-readable representation of a plan
+type PlanOutput struct {
+	Plan    *plans.Plan       `json:"plan"`
+	Summary plans.PlanSummary `json:"summary"`
+}
+
+// ApplyOutput is the machine-readable representation of an applied plan
+type ApplyOutput struct {
+	Plan    *plans.Plan        `json:"plan"`
+	Summary plans.PlanSummary  `json:"summary"`
+	Result  *plans.ApplyResult `json:"result"`
+}
+
+// StateOutput is the machine-readable representation of the state
+type StateOutput struct {
+	Version     string              `json:"version"`
+	Entries     []states.StateEntry `json:"entries"`
+	ActiveCount int                 `json:"active_count"`
+	TotalCount  int                 `json:"total_count"`
+}
+
+// record is a single line of the NDJSON output
+type record struct {
+	Type     string              `json:"type"`
+	Action   string              `json:"action,omitempty"`
+	Change   *plans.Change       `json:"change,omitempty"`
+	Conflict *plans.Conflict     `json:"conflict,omitempty"`
+	Error    *plans.Error        `json:"error,omitempty"`
+	Result   *plans.ChangeResult `json:"result,omitempty"`
+	Entry    *states.StateEntry  `json:"entry,omitempty"`
+	Summary  any                 `json:"summary,omitempty"`
+}
+
+// WritePlan writes the plan in a machine-readable format
+func WritePlan(w io.Writer, format OutputFormat, plan *plans.Plan) error {
+	if format == OutputJSON {
+		return writeJSON(w, PlanOutput{Plan: plan, Summary: plan.Summary()})
+	}
+
+	return writeRecords(w, planRecords(plan, true))
+}
+
+// WriteApplyResult writes the plan and the result of its application in a machine-readable format
+func WriteApplyResult(w io.Writer, format OutputFormat, plan *plans.Plan, result *plans.ApplyResult) error {
+	if format == OutputJSON {
+		return writeJSON(w, ApplyOutput{Plan: plan, Summary: plan.Summary(), Result: result})
+	}
+
+	records := planRecords(plan, false)
+	for i := range result.Changes {
+		records = append(records, record{Type: "result", Result: &result.Changes[i]})
+	}
+	records = append(records, record{Type: "summary", Summary: result})
+
+	return writeRecords(w, records)
+}
+
+// WriteState writes the state entries in a machine-readable format
+func WriteState(w io.Writer, format OutputFormat, output StateOutput) error {
+	if format == OutputJSON {
+		return writeJSON(w, output)
+	}
+
+	records := make([]record, 0, len(output.Entries)+1)
+	for i := range output.Entries {
+		records = append(records, record{Type: "entry", Entry: &output.Entries[i]})
+	}
+	records = append(records, record{Type: "summary", Summary: map[string]int{
+		"active_count": output.ActiveCount,
+		"total_count":  output.TotalCount,
+	}})
+
+	return writeRecords(w, records)
+}
+
+// planRecords converts a plan into NDJSON records
+func planRecords(plan *plans.Plan, withSummary bool) []record {
+	records := make([]record, 0, len(plan.Changes)+len(plan.Conflicts)+len(plan.Errors)+1)
+
+	for i := range plan.Changes {
+		records = append(records, record{Type: "change", Action: plan.Changes[i].Action.String(), Change: &plan.Changes[i]})
+	}
+	for i := range plan.Conflicts {
+		records = append(records, record{Type: "conflict", Conflict: &plan.Conflicts[i]})
+	}
+	for i := range plan.Errors {
+		records = append(records, record{Type: "error", Error: &plan.Errors[i]})
+	}
+	if withSummary {
+		records = append(records, record{Type: "summary", Summary: plan.Summary()})
+	}
+
+	return records
+}
+
+func writeJSON(w io.Writer, data any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+func writeRecords(w io.Writer, records []record) error {
+	encoder := json.NewEncoder(w)
+	for _, r := range records {
+		if err := encoder.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    OutputFormat
+		wantErr bool
+	}{
+		{value: "", want: OutputTable},
+		{value: "table", want: OutputTable},
+		{value: "plain", want: OutputPlain},
+		{value: "json", want: OutputJSON},
+		{value: "ndjson", want: OutputNDJSON},
+		{value: "yaml", wantErr: true},
+		{value: "JSON", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseOutputFormat(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseOutputFormat(%q) = %q, %v", tt.value, got, err)
+		}
+	}
+
+	for format, machine := range map[OutputFormat]bool{OutputTable: false, OutputPlain: false, OutputJSON: true, OutputNDJSON: true} {
+		if format.IsMachineReadable() != machine {
+			t.Errorf("%s.IsMachineReadable() = %v", format, !machine)
+		}
+	}
+}
+
+// newTestPlan returns a plan with a rename, a noop, a conflict and an error
+func newTestPlan() *plans.Plan {
+	plan := plans.NewEmptyPlan()
+	plan.AddRename("/media/a.mkv", "/media/Movie (2020).mkv")
+	plan.AddRename("/media/b.mkv", "/media/Other (2021).mkv")
+	plan.AddRename("/media/c.mkv", "/media/Other (2021).mkv")
+	plan.Changes = append(plan.Changes, plans.Change{
+		ID:     "noop",
+		Action: plans.ActionNoop,
+		Before: models.VideoFile{Path: "/media/Ok (2000).mkv", Filename: "Ok (2000).mkv"},
+		After:  models.VideoFile{Path: "/media/Ok (2000).mkv", Filename: "Ok (2000).mkv"},
+	})
+	plan.Errors = append(plan.Errors, plans.Error{File: "/media/unknown.mkv", Message: "no match"})
+	plan.RefreshConflicts()
+	return plan
+}
+
+// recordTypes decodes NDJSON lines and returns their types
+func recordTypes(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	var types []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var r map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, r["type"].(string))
+	}
+	return types
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWritePlan(t *testing.T) {
+	plan := newTestPlan()
+
+	var buf bytes.Buffer
+	if err := WritePlan(&buf, OutputNDJSON, plan); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"change", "change", "change", "change", "conflict", "error", "summary"}
+	if got := recordTypes(t, buf.Bytes()); !equal(got, want) {
+		t.Errorf("got records %v, want %v", got, want)
+	}
+
+	buf.Reset()
+	if err := WritePlan(&buf, OutputJSON, plan); err != nil {
+		t.Fatal(err)
+	}
+	var output PlanOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatal(err)
+	}
+	summary := output.Summary
+	if summary.TotalChanges != 4 || summary.ReadyChanges != 1 || summary.ConflictedChanges != 2 || summary.NoopChanges != 1 || summary.ErrorChanges != 1 {
+		t.Errorf("got summary %+v", summary)
+	}
+}
+
+func TestWriteApplyResult(t *testing.T) {
+	plan := newTestPlan()
+	result := &plans.ApplyResult{
+		PlanID:  plan.ID,
+		Applied: 1,
+		Failed:  1,
+		Changes: []plans.ChangeResult{
+			{ChangeID: plan.Changes[0].ID, Status: plans.ChangeStatusApplied},
+			{ChangeID: "other", Status: plans.ChangeStatusFailed, Error: "permission denied"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteApplyResult(&buf, OutputNDJSON, plan, result); err != nil {
+		t.Fatal(err)
+	}
+
+	// The plan summary is replaced by the summary of the result
+	want := []string{"change", "change", "change", "change", "conflict", "error", "result", "result", "summary"}
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if got := recordTypes(t, buf.Bytes()); !equal(got, want) {
+		t.Fatalf("got records %v, want %v", got, want)
+	}
+
+	var last struct {
+		Summary plans.ApplyResult `json:"summary"`
+	}
+	if err := json.Unmarshal(lines[len(lines)-1], &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Summary.Applied != 1 || last.Summary.Failed != 1 {
+		t.Errorf("got summary %+v", last.Summary)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	pending := newTestPlan()
+	done := plans.NewEmptyPlan()
+
+	tests := []struct {
+		name     string
+		plan     *plans.Plan
+		detailed bool
+		want     int
+	}{
+		{name: "pending", plan: pending, detailed: true, want: ExitCodeChangesPending},
+		{name: "pending without detailed exit codes", plan: pending, want: ExitCodeOK},
+		{name: "nothing to apply", plan: done, detailed: true, want: ExitCodeOK},
+	}
+	for _, tt := range tests {
+		if got := PlanExitCode(tt.plan, tt.detailed); got != tt.want {
+			t.Errorf("%s: got exit code %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if got := ApplyExitCode(&plans.ApplyResult{Applied: 2}); got != ExitCodeOK {
+		t.Errorf("got exit code %d for a successful apply", got)
+	}
+	if got := ApplyExitCode(&plans.ApplyResult{Applied: 1, Failed: 1}); got != ExitCodeError {
+		t.Errorf("got exit code %d for a failed rename", got)
+	}
+}
@@ -1,11 +1,12 @@
 package plan
 
 import (
+	"os"
+
+	"goru/internal/cmd/backend"
 	"goru/internal/cmd/common"
 	"goru/internal/models"
-	"goru/internal/services/files"
-	"goru/internal/services/formatters"
-	"goru/internal/services/subtitles/opensubtitles"
+	"goru/internal/services/plans"
 	"goru/pkg/log"
 
 	"github.com/fatih/color"
@@ -17,6 +18,16 @@ import (
 func Run(cmd *cobra.Command, args []string) {
 	log.Debug("goru is starting", zap.String("command", "plan"))
 
+	// Parse the output format
+	outputFlag, _ := cmd.Flags().GetString("output")
+	output, err := common.ParseOutputFormat(outputFlag)
+	if err != nil {
+		log.Fatal("invalid output format", zap.Error(err))
+	}
+	if output == common.OutputPlain {
+		color.NoColor = true
+	}
+
 	// Unmarshal configuration
 	var config models.Config
 	if err := viper.Unmarshal(&config); err != nil {
@@ -26,25 +37,33 @@ func Run(cmd *cobra.Command, args []string) {
 		log.Fatal("invalid config", zap.Error(err))
 	}
 
-	// Create file service
-	fileService := files.NewFileService("", "", viper.GetStringSlice("filters"))
-
-	// Create the formatter service
-	formatterService := formatters.NewFormatterService(viper.GetString("format"), viper.GetString("format"))
-
-	// Create the subtitles provider
-	subtitleProvider := opensubtitles.New(viper.GetString("providers.opensubtitles.api_key"))
+	// Plan locally or on the remote server
+	b, err := backend.New(config)
+	if err != nil {
+		log.Fatal("failed to create the backend", zap.Error(err))
+	}
 
-	// Determine directories to scan (whether user is giving a single dir or multiple dirs with config file)
-	plan, err := common.RunPlan(fileService, formatterService, config, subtitleProvider)
+	plan, err := b.Plan(cmd.Context())
 	if err != nil && err != common.ErrNoFilesFound {
-		log.Fatal("failed to run plan", zap.Error(err))
+		common.Fail("failed to run plan", err)
 	}
 	if err == common.ErrNoFilesFound {
 		// No video files found, handle accordingly
-		color.Yellow("No video files found.")
+		common.Yellow.Fprintln(os.Stderr, "No video files found.")
+		plan = plans.NewEmptyPlan()
 	}
 
 	// Display results
-	common.DisplayPlanResults(plan)
+	if output.IsMachineReadable() {
+		if err := common.WritePlan(os.Stdout, output, plan); err != nil {
+			log.Fatal("failed to write plan", zap.Error(err))
+		}
+	} else {
+		common.DisplayPlanResults(plan)
+	}
+
+	detailedExitCode, _ := cmd.Flags().GetBool("detailed-exitcode")
+	if code := common.PlanExitCode(plan, detailedExitCode); code != common.ExitCodeOK {
+		os.Exit(code)
+	}
 }
@@ -0,0 +1,122 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"goru/internal/cmd/common"
+	"goru/internal/models"
+	"goru/internal/plugins"
+	"goru/pkg/log"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func Run(cmd *cobra.Command, args []string) {
+	log.Debug("goru plugin check is starting", zap.String("command", "plugin check"))
+
+	outputFlag, _ := cmd.Flags().GetString("output")
+	if outputFlag != "table" && outputFlag != "json" {
+		log.Fatal("invalid output format", zap.String("output", outputFlag))
+	}
+
+	config, err := pluginConfig(cmd, args)
+	if err != nil {
+		log.Fatal("invalid plugin", zap.Error(err))
+	}
+
+	checks := plugins.CheckConformance(cmd.Context(), config)
+	passed := plugins.Passed(checks)
+
+	if outputFlag == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(struct {
+			Passed bool                       `json:"passed"`
+			Checks []plugins.ConformanceCheck `json:"checks"`
+		}{passed, checks}); err != nil {
+			log.Fatal("failed to write the checks", zap.Error(err))
+		}
+	} else {
+		for _, check := range checks {
+			if check.Error != "" {
+				common.Red.Print("✗ ")
+				fmt.Printf("%s: %s\n", check.Name, check.Error)
+				continue
+			}
+			common.Green.Print("✓ ")
+			fmt.Println(check.Name)
+		}
+
+		fmt.Println()
+		if passed {
+			common.Green.Println("The plugin follows the protocol.")
+		} else {
+			common.Red.Println("The plugin does not follow the protocol.")
+		}
+	}
+
+	if !passed {
+		os.Exit(1)
+	}
+}
+
+// pluginConfig returns the plugin of the configuration with the given name, or the
+// command given after --
+func pluginConfig(cmd *cobra.Command, args []string) (models.Plugin, error) {
+	options, _ := cmd.Flags().GetStringToString("option")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	if cmd.ArgsLenAtDash() == -1 {
+		if len(args) != 1 {
+			return models.Plugin{}, fmt.Errorf("give the name of a configured plugin, or the command after --")
+		}
+
+		var config models.Config
+		if err := viper.Unmarshal(&config); err != nil {
+			return models.Plugin{}, fmt.Errorf("failed to unmarshal config: %w", err)
+		}
+
+		for _, plugin := range config.Plugins {
+			if plugin.Name != args[0] {
+				continue
+			}
+			if plugin.Command == "" {
+				return models.Plugin{}, fmt.Errorf("plugin %q is a built-in plugin", args[0])
+			}
+			for key, value := range options {
+				if plugin.Options == nil {
+					plugin.Options = make(map[string]interface{})
+				}
+				plugin.Options[key] = value
+			}
+			if timeout != 0 {
+				plugin.Timeout = timeout
+			}
+			return plugin, nil
+		}
+
+		return models.Plugin{}, fmt.Errorf("plugin %q is not configured", args[0])
+	}
+
+	command := args[cmd.ArgsLenAtDash():]
+	if len(command) == 0 {
+		return models.Plugin{}, fmt.Errorf("the command is required after --")
+	}
+
+	plugin := models.Plugin{
+		Name:    command[0],
+		Command: command[0],
+		Args:    command[1:],
+		Timeout: timeout,
+		Options: make(map[string]interface{}, len(options)),
+	}
+	for key, value := range options {
+		plugin.Options[key] = value
+	}
+
+	return plugin, nil
+}
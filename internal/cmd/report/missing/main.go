@@ -0,0 +1,120 @@
+package missing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"goru/internal/cmd/backend"
+	"goru/internal/cmd/common"
+	"goru/internal/models"
+	"goru/internal/services/reports"
+	"goru/pkg/log"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func Run(cmd *cobra.Command, args []string) {
+	log.Debug("goru report missing is starting", zap.String("command", "report missing"))
+
+	outputFlag, _ := cmd.Flags().GetString("output")
+	if outputFlag != "table" && outputFlag != "json" && outputFlag != "csv" {
+		log.Fatal("invalid output format", zap.String("output", outputFlag))
+	}
+
+	// Unmarshal configuration
+	var config models.Config
+	if err := viper.Unmarshal(&config); err != nil {
+		log.Fatal("failed to unmarshal config", zap.Error(err))
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatal("invalid config", zap.Error(err))
+	}
+
+	// Report locally or on the remote server
+	b, err := backend.New(config)
+	if err != nil {
+		log.Fatal("failed to create the backend", zap.Error(err))
+	}
+
+	directory, _ := cmd.Flags().GetString("directory")
+	aired, _ := cmd.Flags().GetBool("aired")
+	report, err := b.Missing(cmd.Context(), backend.MissingQuery{Directory: directory, Aired: aired})
+	if err != nil {
+		log.Fatal("failed to create the report", zap.Error(err))
+	}
+
+	switch outputFlag {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatal("failed to write the report", zap.Error(err))
+		}
+	case "csv":
+		if err := reports.WriteMissingCSV(os.Stdout, report); err != nil {
+			log.Fatal("failed to write the report", zap.Error(err))
+		}
+	default:
+		printReport(report)
+	}
+}
+
+func printReport(report *reports.MissingReport) {
+	if len(report.Shows) == 0 && len(report.Unknown) == 0 {
+		common.Yellow.Println("No episode found.")
+		return
+	}
+
+	complete, missing := 0, 0
+	for _, show := range report.Shows {
+		if show.Complete() {
+			complete++
+			common.Green.Print("✓ ")
+		} else {
+			common.Red.Print("✗ ")
+		}
+		fmt.Print(show.Show.Name)
+		if !show.Show.FirstAirDate.IsZero() {
+			fmt.Printf(" (%d)", show.Show.FirstAirDate.Year())
+		}
+		common.Gray.Printf(" %d/%d episodes\n", show.Episodes, show.Expected)
+
+		for _, episode := range show.Missing {
+			common.Red.Printf("    missing    %s", episodeCode(episode.Season, episode.Episode))
+			fmt.Printf("  %s", episode.Title)
+			if episode.AirDate != "" {
+				common.Gray.Printf(" (%s)", episode.AirDate)
+			}
+			fmt.Println()
+		}
+		for _, episode := range show.Extra {
+			common.Yellow.Printf("    extra      %s", episodeCode(episode.Season, episode.Episode))
+			fmt.Printf("  %s\n", episode.Path)
+		}
+		for _, duplicate := range show.Duplicates {
+			common.Yellow.Printf("    duplicate  %s", episodeCode(duplicate.Season, duplicate.Episode))
+			fmt.Printf("  %s\n", strings.Join(duplicate.Paths, "\n                       "))
+		}
+		missing += len(show.Missing)
+	}
+
+	if len(report.Unknown) > 0 {
+		fmt.Println()
+		common.Yellow.Printf("%d file(s) left out:\n", len(report.Unknown))
+		for _, file := range report.Unknown {
+			fmt.Printf("    %s ", file.Path)
+			common.Gray.Printf("(%s)\n", file.Reason)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%d show(s), %d complete, %d missing episode(s)\n", len(report.Shows), complete, missing)
+}
+
+func episodeCode(season, episode int) string {
+	return fmt.Sprintf("S%02dE%02d", season, episode)
+}
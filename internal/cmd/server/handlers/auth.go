@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"goru/internal/services/auth"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// LoginRequest represents the request body to log in
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type AuthHandler struct {
+	service *auth.Service
+}
+
+// NewAuthHandler creates a new authentication handler
+func NewAuthHandler(service *auth.Service) AuthHandler {
+	return AuthHandler{
+		service: service,
+	}
+}
+
+// Authenticate is a middleware rejecting the requests without a valid identity,
+// and storing the identity in the request context.
+func (h *AuthHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Let the CORS preflight requests through
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity, err := h.service.Authenticate(r)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+	})
+}
+
+// Identify is a middleware storing the identity of the request in its context when it
+// is valid, and letting the anonymous requests through
+func (h *AuthHandler) Identify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if identity, err := h.service.Authenticate(r); err == nil {
+			r = r.WithContext(auth.WithIdentity(r.Context(), identity))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin wraps a handler so that it can only be called by admins
+func (h *AuthHandler) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorize(w, r, auth.RoleAdmin) {
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// authorize checks that the identity of the request has the required role, and writes
+// the error response otherwise.
+func authorize(w http.ResponseWriter, r *http.Request, required auth.Role) bool {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		writeError(w, auth.ErrUnauthenticated.Error(), http.StatusUnauthorized)
+		return false
+	}
+
+	if !identity.Role.Allows(required) {
+		log.Warn("forbidden request", zap.String("user", identity.Name), zap.String("role", string(identity.Role)), zap.String("path", r.URL.Path))
+		writeError(w, auth.ErrForbidden.Error(), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// Login handles POST /api/auth/login, opening a session stored in a cookie
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.service.Login(req.Username, req.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		log.Warn("failed login", zap.String("username", req.Username), zap.String("remote_addr", r.RemoteAddr))
+		writeError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	writeJSON(w, auth.Identity{Name: session.Username, Role: session.Role, Method: auth.MethodSession})
+}
+
+// Logout handles POST /api/auth/logout
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(auth.SessionCookie); err == nil {
+		if err := h.service.Logout(cookie.Value); err != nil {
+			log.Error("failed to delete session", zap.Error(err))
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me handles GET /api/auth/me, returning the identity of the caller
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		writeError(w, auth.ErrUnauthenticated.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, identity)
+}
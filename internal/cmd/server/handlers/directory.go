@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"goru/internal/models"
+	"goru/internal/services/files"
+	"goru/internal/services/plans"
+	"goru/internal/services/states"
 	"goru/pkg/log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 // Response structures
@@ -22,15 +28,66 @@ type FileInfo struct {
 	IsDir   bool   `json:"isDir"`
 	Size    int64  `json:"size"`
 	ModTime string `json:"modTime"`
+
+	// What goru knows about the video files
+	FileType  *models.FileType   `json:"fileType,omitempty"`
+	MediaType *models.MediaType  `json:"mediaType,omitempty"`
+	State     *states.StateEntry `json:"state,omitempty"`
+	Match     *FileMatch         `json:"match,omitempty"`
+}
+
+// FileMatch is the pending change of a stored plan for a file
+type FileMatch struct {
+	PlanID   string         `json:"planId"`
+	ChangeID string         `json:"changeId"`
+	Action   string         `json:"action"`
+	Target   string         `json:"target"`
+	Decision plans.Decision `json:"decision,omitempty"`
+}
+
+// RootsResponse represents the response listing the library roots
+type RootsResponse struct {
+	Roots []string `json:"roots"`
+}
+
+type DirectoryHandler struct {
+	roots            *files.Roots
+	defaultDirectory string
+	store            *plans.Store
+	stateService     *states.StateService
+}
+
+// NewDirectoryHandler creates a new directory handler, only browsing the library roots
+func NewDirectoryHandler(roots *files.Roots, defaultDirectory string, store *plans.Store, stateService *states.StateService) DirectoryHandler {
+	return DirectoryHandler{
+		roots:            roots,
+		defaultDirectory: defaultDirectory,
+		store:            store,
+		stateService:     stateService,
+	}
 }
 
 // Directory handles directory listing requests
-func Directory(w http.ResponseWriter, r *http.Request) {
-	directory := r.URL.Query().Get("path")
+func (h *DirectoryHandler) Directory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	directory := query.Get("path")
 	if directory == "" {
 		writeError(w, "directory path is required", http.StatusBadRequest)
 		return
 	}
+	hideDotfiles := query.Get("hide_dotfiles") == "true"
+	hideUnsupported := query.Get("hide_unsupported") == "true"
+
+	// Only the library roots can be browsed
+	directory, err := h.roots.Resolve(directory)
+	if errors.Is(err, files.ErrOutsideRoots) {
+		writeError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Sprintf("invalid directory path: %v", err), http.StatusBadRequest)
+		return
+	}
 
 	// Check if directory exists
 	if _, err := os.Stat(directory); os.IsNotExist(err) {
@@ -45,20 +102,51 @@ func Directory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var files []FileInfo
+	stateEntries := h.stateEntriesByPath()
+	matches := h.matchesByPath()
+
+	files := []FileInfo{}
 	for _, entry := range entries {
-		info, err := entry.Info()
+		if hideDotfiles && strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(directory, entry.Name())
+
+		// Symlinks leading outside of the roots are not listed
+		if entry.Type()&os.ModeSymlink != 0 && !h.roots.Contains(path) {
+			continue
+		}
+
+		info, err := os.Stat(path)
 		if err != nil {
 			continue
 		}
 
-		files = append(files, FileInfo{
+		file := FileInfo{
 			Name:    entry.Name(),
-			Path:    filepath.Join(directory, entry.Name()),
-			IsDir:   entry.IsDir(),
+			Path:    path,
+			IsDir:   info.IsDir(),
 			Size:    info.Size(),
 			ModTime: info.ModTime().Format("2006-01-02 15:04:05"),
-		})
+		}
+
+		if !file.IsDir {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if models.IsSupportedExtension(ext) {
+				fileType := models.GetFileTypeFromExtension(ext)
+				mediaType := models.GuessMediaType(entry.Name())
+
+				file.FileType = &fileType
+				file.MediaType = &mediaType
+				file.State = stateEntries[path]
+				file.Match = matches[path]
+			} else if hideUnsupported {
+				continue
+			}
+		}
+
+		files = append(files, file)
 	}
 
 	response := DirectoryResponse{
@@ -70,9 +158,72 @@ func Directory(w http.ResponseWriter, r *http.Request) {
 }
 
 // DefaultDirectory handles current directory request
-func DefaultDirectory(w http.ResponseWriter, r *http.Request) {
+func (h *DirectoryHandler) DefaultDirectory(w http.ResponseWriter, r *http.Request) {
 	log.Info("Default directory requested")
 
-	// Return default directory
-	writeJSON(w, viper.GetString("directory"))
+	// Return default directory, or the first root if it cannot be browsed
+	directory, err := h.roots.Resolve(h.defaultDirectory)
+	if err != nil {
+		if paths := h.roots.Paths(); len(paths) > 0 {
+			directory = paths[0]
+		}
+	}
+
+	writeJSON(w, directory)
+}
+
+// Roots handles GET /api/directory/roots
+func (h *DirectoryHandler) Roots(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, RootsResponse{Roots: h.roots.Paths()})
+}
+
+// stateEntriesByPath indexes the active state entries by the current path of the files
+func (h *DirectoryHandler) stateEntriesByPath() map[string]*states.StateEntry {
+	index := make(map[string]*states.StateEntry)
+
+	entries, err := h.stateService.GetActiveEntries()
+	if err != nil {
+		log.Error("failed to load state entries", zap.Error(err))
+		return index
+	}
+
+	for i := range entries {
+		index[entries[i].NewPath] = &entries[i]
+	}
+
+	return index
+}
+
+// matchesByPath indexes the changes of the stored plans not applied yet by the path
+// of the files, the newest plans first
+func (h *DirectoryHandler) matchesByPath() map[string]*FileMatch {
+	index := make(map[string]*FileMatch)
+
+	storedPlans, err := h.store.List()
+	if err != nil {
+		log.Error("failed to load plans", zap.Error(err))
+		return index
+	}
+
+	for _, plan := range storedPlans {
+		if plan.AppliedAt != nil {
+			continue
+		}
+
+		for _, change := range plan.Changes {
+			if _, ok := index[change.Before.Path]; ok || change.Action == plans.ActionCreate {
+				continue
+			}
+
+			index[change.Before.Path] = &FileMatch{
+				PlanID:   plan.ID,
+				ChangeID: change.ID,
+				Action:   change.Action.String(),
+				Target:   change.After.Path,
+				Decision: change.Decision,
+			}
+		}
+	}
+
+	return index
 }
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"goru/internal/services/files"
+	"goru/internal/services/plans"
+	"goru/internal/services/states"
+	"goru/pkg/log"
+
+	"github.com/tidwall/buntdb"
+)
+
+func init() {
+	log.Init(false)
+}
+
+// newTestDirectoryHandler returns a handler confined to a library root, next to a
+// folder outside of it
+func newTestDirectoryHandler(t *testing.T) (DirectoryHandler, string, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	base := t.TempDir()
+	root := filepath.Join(base, "library")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "Show"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{
+		filepath.Join(root, "Movie (2020).mkv"),
+		filepath.Join(root, ".hidden.mkv"),
+		filepath.Join(root, "notes.txt"),
+		filepath.Join(outside, "secret.mkv"),
+	} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.mkv"), filepath.Join(root, "secret.mkv")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "Show"), filepath.Join(root, "Shortcut")); err != nil {
+		t.Fatal(err)
+	}
+
+	roots, err := files.NewRoots(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := buntdb.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := plans.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stateService, err := states.NewStateService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewDirectoryHandler(roots, root, store, stateService), roots.Paths()[0], outside
+}
+
+func TestDirectoryHandler_Directory(t *testing.T) {
+	handler, root, outside := newTestDirectoryHandler(t)
+
+	tests := []struct {
+		name   string
+		query  url.Values
+		status int
+		want   []string
+	}{
+		{
+			// The symlinks leading outside of the roots are not listed
+			name:   "root",
+			query:  url.Values{"path": {root}},
+			status: http.StatusOK,
+			want:   []string{".hidden.mkv", "Movie (2020).mkv", "Shortcut", "Show", "notes.txt"},
+		},
+		{
+			name:   "hide dotfiles",
+			query:  url.Values{"path": {root}, "hide_dotfiles": {"true"}},
+			status: http.StatusOK,
+			want:   []string{"Movie (2020).mkv", "Shortcut", "Show", "notes.txt"},
+		},
+		{
+			name:   "hide unsupported",
+			query:  url.Values{"path": {root}, "hide_unsupported": {"true"}},
+			status: http.StatusOK,
+			want:   []string{".hidden.mkv", "Movie (2020).mkv", "Shortcut", "Show"},
+		},
+		{
+			name:   "path outside of every root",
+			query:  url.Values{"path": {outside}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "parent of the root",
+			query:  url.Values{"path": {filepath.Join(root, "..")}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "symlink leading outside of the roots",
+			query:  url.Values{"path": {filepath.Join(root, "escape")}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "missing path",
+			query:  url.Values{},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "missing folder",
+			query:  url.Values{"path": {filepath.Join(root, "missing")}},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.Directory(w, httptest.NewRequest("GET", "/api/directory?"+tt.query.Encode(), nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var response DirectoryResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, file := range response.Files {
+				names = append(names, file.Name)
+			}
+			sort.Strings(names)
+
+			if len(names) != len(tt.want) {
+				t.Fatalf("got files %v, want %v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Fatalf("got files %v, want %v", names, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,16 +1,196 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"goru/internal/services/auth"
+	"goru/internal/services/files"
+	"goru/internal/services/providers"
+	"goru/internal/services/states"
+
+	"github.com/tidwall/buntdb"
 )
 
-type HealthHandler struct{}
+// Statuses of the health checks
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+)
+
+// healthTimeout bounds the duration of the health checks
+const healthTimeout = 5 * time.Second
+
+// providerPingInterval is the minimum interval between two pings of a provider, so that
+// frequent probes do not count against its rate limit
+const providerPingInterval = 30 * time.Second
+
+// HealthResponse represents the readiness of the server
+type HealthResponse struct {
+	// Status is "ok" when every check succeeded, "fail" otherwise
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// CheckResult is the result of a health check
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+
+	// Error is only given to the authenticated clients, as it may hold paths
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
 
-func NewHealthHandler() HealthHandler {
-	return HealthHandler{}
+// healthCheck checks a dependency of the server
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
 }
 
-// Health checks the server health
+type HealthHandler struct {
+	checks []healthCheck
+}
+
+// NewHealthHandler creates the health handler checking the state store, the database,
+// the providers and the library roots. The roots are numbered in their order rather
+// than named after their paths, the health being public.
+func NewHealthHandler(stateService *states.StateService, db *buntdb.DB, providerList []providers.Provider, roots *files.Roots) HealthHandler {
+	checks := []healthCheck{
+		{name: "state_store", check: func(ctx context.Context) error {
+			return stateService.CheckWritable()
+		}},
+		{name: "database", check: func(ctx context.Context) error {
+			return db.View(func(tx *buntdb.Tx) error { return nil })
+		}},
+	}
+
+	for _, provider := range providerList {
+		if pinger, ok := provider.(providers.Pinger); ok {
+			checks = append(checks, healthCheck{name: "provider:" + provider.Name(), check: cachedPing(pinger)})
+		}
+	}
+
+	for i, root := range roots.Paths() {
+		root := root
+		checks = append(checks, healthCheck{name: "root:" + strconv.Itoa(i+1), check: func(ctx context.Context) error {
+			return checkRoot(root)
+		}})
+	}
+
+	return HealthHandler{checks: checks}
+}
+
+// Health handles GET /api/health, reporting the readiness of the server. It answers
+// 503 when a check failed. The errors of the checks are left out for the anonymous
+// clients.
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, map[string]string{"status": "ok"})
+	_, identified := auth.FromContext(r.Context())
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+
+	response := HealthResponse{
+		Status: HealthStatusOK,
+		Checks: make([]CheckResult, len(h.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check healthCheck) {
+			defer wg.Done()
+			response.Checks[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	statusCode := http.StatusOK
+	for i, result := range response.Checks {
+		if result.Status != HealthStatusOK {
+			response.Status = HealthStatusFail
+			statusCode = http.StatusServiceUnavailable
+		}
+		if !identified {
+			response.Checks[i].Error = ""
+		}
+	}
+
+	writeJSONWithStatus(w, response, statusCode)
+}
+
+// runCheck runs a check, giving up when the context is done
+func runCheck(ctx context.Context, check healthCheck) CheckResult {
+	start := time.Now()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", healthTimeout)
+	}
+
+	result := CheckResult{
+		Name:       check.name,
+		Status:     HealthStatusOK,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = HealthStatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// cachedPing pings the provider at most once per interval, reusing the last result
+func cachedPing(pinger providers.Pinger) func(ctx context.Context) error {
+	var mu sync.Mutex
+	var lastPing time.Time
+	var lastErr error
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if time.Since(lastPing) < providerPingInterval {
+			return lastErr
+		}
+
+		lastErr = pinger.Ping(ctx)
+		lastPing = time.Now()
+
+		return lastErr
+	}
+}
+
+// checkRoot checks that a library root can be listed. An empty root fails as well, as it
+// usually is the mount point of a share that is not mounted.
+func checkRoot(root string) error {
+	dir, err := os.Open(root)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	if _, err := dir.Readdirnames(1); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("directory is empty, the share may not be mounted")
+		}
+		return err
+	}
+
+	return nil
 }
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goru/internal/services/auth"
+	"goru/internal/services/files"
+	"goru/internal/services/states"
+
+	"github.com/tidwall/buntdb"
+)
+
+func TestHealthHandler_Health(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// An empty root fails, as an unmounted share
+	root := t.TempDir()
+	roots, err := files.NewRoots(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := buntdb.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	stateService, err := states.NewStateService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHealthHandler(stateService, db, nil, roots)
+
+	for _, identified := range []bool{false, true} {
+		r := httptest.NewRequest("GET", "/api/health", nil)
+		if identified {
+			r = r.WithContext(auth.WithIdentity(r.Context(), &auth.Identity{Name: "alice", Role: auth.RoleReadOnly}))
+		}
+		w := httptest.NewRecorder()
+		handler.Health(w, r)
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("got status %d, want %d", w.Code, http.StatusServiceUnavailable)
+		}
+		if strings.Contains(w.Body.String(), root) {
+			t.Errorf("the response leaks the path of the root: %s", w.Body)
+		}
+
+		var response HealthResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		check := response.Checks[len(response.Checks)-1]
+		if check.Name != "root:1" || check.Status != HealthStatusFail || (check.Error != "") != identified {
+			t.Errorf("got check %+v for an identified client: %v", check, identified)
+		}
+	}
+}
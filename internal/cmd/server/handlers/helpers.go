@@ -2,34 +2,126 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"goru/pkg/log"
 	"net/http"
+	"strings"
 
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
 	"go.uber.org/zap"
 )
 
+// ErrorCodeInvalidRequest is the code of the requests failing the validation
+const ErrorCodeInvalidRequest = "invalid_request"
+
+// ErrorResponse is the envelope of every error returned by the API
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes an error
+type ErrorBody struct {
+	// Code is derived from the status, e.g. "not_found"
+	Code    string   `json:"code"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
 }
 
 // writeJSON writes a JSON response
 func writeJSON(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		log.Error("failed to encode JSON response", zap.Error(err))
 	}
 }
 
+// writeJSONWithStatus writes a JSON response with the given status code
+func writeJSONWithStatus(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	writeJSON(w, data)
+}
+
 // writeError writes an error response
 func writeError(w http.ResponseWriter, message string, statusCode int) {
+	writeErrorBody(w, ErrorBody{Code: errorCode(statusCode), Message: message}, statusCode)
+}
+
+// writeErrorBody writes an error response with the given body
+func writeErrorBody(w http.ResponseWriter, body ErrorBody, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.WriteHeader(statusCode)
 
-	response := ErrorResponse{Error: message}
+	response := ErrorResponse{Error: body}
 	json.NewEncoder(w).Encode(response)
 }
+
+// errorCode derives the error code from the status, e.g. 404 gives "not_found"
+func errorCode(statusCode int) string {
+	text := http.StatusText(statusCode)
+	if text == "" {
+		return "error"
+	}
+
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// InvalidRequest writes the error response of a request failing the validation
+// against the OpenAPI specification, with one detail per failure.
+func InvalidRequest(w http.ResponseWriter, err error) {
+	var details []string
+
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		for _, e := range multi {
+			details = append(details, validationDetail(e))
+		}
+	} else {
+		details = append(details, validationDetail(err))
+	}
+
+	writeErrorBody(w, ErrorBody{
+		Code:    ErrorCodeInvalidRequest,
+		Message: "the request does not match the API specification",
+		Details: details,
+	}, http.StatusBadRequest)
+}
+
+// validationDetail describes a validation failure without the dump of the schema
+func validationDetail(err error) string {
+	var requestErr *openapi3filter.RequestError
+	if errors.As(err, &requestErr) {
+		var schemaErr *openapi3.SchemaError
+		if errors.As(requestErr.Err, &schemaErr) {
+			field := strings.Join(schemaErr.JSONPointer(), ".")
+			switch {
+			case requestErr.Parameter != nil && field != "":
+				return requestErr.Parameter.Name + "." + field + ": " + schemaErr.Reason
+			case requestErr.Parameter != nil:
+				return requestErr.Parameter.Name + ": " + schemaErr.Reason
+			case field != "":
+				return "body." + field + ": " + schemaErr.Reason
+			default:
+				return "body: " + schemaErr.Reason
+			}
+		}
+
+		reason := requestErr.Reason
+		if reason == "" && requestErr.Err != nil {
+			reason = requestErr.Err.Error()
+		}
+		if requestErr.Parameter != nil {
+			return requestErr.Parameter.Name + ": " + reason
+		}
+		return reason
+	}
+
+	return err.Error()
+}
+
+// NotFound writes the error response of unknown routes
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, "not found", http.StatusNotFound)
+}
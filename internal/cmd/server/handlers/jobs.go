@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"goru/internal/cmd/common"
+	"goru/internal/services/auth"
+	"goru/internal/services/jobs"
+	"goru/internal/services/plans"
+	"goru/pkg/log"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// JobRequest represents the request body to start a job
+type JobRequest struct {
+	Kind jobs.Kind `json:"kind"`
+
+	// Lookup describes the directory to plan, for plan jobs
+	Lookup *LookupRequest `json:"lookup,omitempty"`
+
+	// PlanID is the ID of the stored plan to apply, for apply jobs
+	PlanID string `json:"plan_id,omitempty"`
+}
+
+// JobListResponse represents the response listing the jobs
+type JobListResponse struct {
+	Jobs []jobs.Snapshot `json:"jobs"`
+}
+
+type JobHandler struct {
+	manager     *jobs.Manager
+	planHandler *PlanHandler
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(manager *jobs.Manager, planHandler *PlanHandler) JobHandler {
+	return JobHandler{
+		manager:     manager,
+		planHandler: planHandler,
+	}
+}
+
+// Create handles POST /api/jobs
+func (h *JobHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var run jobs.RunFunc
+	switch req.Kind {
+	case jobs.KindPlan:
+		if req.Lookup == nil {
+			writeError(w, "lookup is required for plan jobs", http.StatusBadRequest)
+			return
+		}
+
+		directory, err := h.planHandler.toDirectory(*req.Lookup)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		run = func(ctx context.Context, job *jobs.Job) error {
+			plan, err := h.planHandler.processDirectory(ctx, directory, func(result common.FileProcessResult, processed, total int) {
+				job.ReportProgress(result.File.Path, processed, total, result.Error)
+			})
+			if err != nil {
+				return err
+			}
+
+			job.SetPlan(plan)
+			return nil
+		}
+
+	case jobs.KindApply:
+		if !authorize(w, r, auth.RoleAdmin) {
+			return
+		}
+
+		if req.PlanID == "" {
+			writeError(w, "plan_id is required for apply jobs", http.StatusBadRequest)
+			return
+		}
+
+		if _, status, err := h.planHandler.loadApplicablePlan(req.PlanID); err != nil {
+			writeError(w, err.Error(), status)
+			return
+		}
+
+		planID := req.PlanID
+		run = func(ctx context.Context, job *jobs.Job) error {
+			result, err := h.planHandler.applyPlan(ctx, planID, func(change plans.ChangeResult, applied, total int) {
+				var err error
+				if change.Status == plans.ChangeStatusFailed {
+					err = errors.New(change.Error)
+				}
+				job.ReportProgress(change.Before, applied, total, err)
+			})
+			if err != nil {
+				return err
+			}
+
+			job.SetResult(result)
+			return nil
+		}
+
+	default:
+		writeError(w, fmt.Sprintf("unsupported job kind: %q", req.Kind), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.manager.Start(req.Kind, run)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Location", "/api/jobs/"+job.ID())
+	writeJSONWithStatus(w, job.Snapshot(), http.StatusAccepted)
+}
+
+// List handles GET /api/jobs
+func (h *JobHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, JobListResponse{Jobs: h.manager.List()})
+}
+
+// Get handles GET /api/jobs/{id}
+func (h *JobHandler) Get(w http.ResponseWriter, r *http.Request) {
+	job, err := h.manager.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, job.Snapshot())
+}
+
+// Cancel handles DELETE /api/jobs/{id}
+func (h *JobHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	switch err := h.manager.Cancel(id); {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, jobs.ErrJobNotRunning):
+		writeError(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	job, _ := h.manager.Get(id)
+	writeJSON(w, job.Snapshot())
+}
+
+// Events handles GET /api/jobs/{id}/events, streaming the job events as Server-Sent Events
+func (h *JobHandler) Events(w http.ResponseWriter, r *http.Request) {
+	job, err := h.manager.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Error("failed to encode job event", zap.Error(err))
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
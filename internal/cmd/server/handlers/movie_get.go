@@ -18,7 +18,7 @@ func (h *MovieHandler) Get(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	movie, err := h.provider.GetMovieByID(id)
+	movie, err := h.provider.GetMovieByID(r.Context(), id)
 	if err != nil {
 		log.Error("Failed to get movie", zap.Error(err), zap.String("id", id))
 		writeError(w, fmt.Sprintf("failed to get movie: %v", err), http.StatusInternalServerError)
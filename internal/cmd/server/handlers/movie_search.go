@@ -34,7 +34,7 @@ func (h *MovieHandler) Search(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	movies, err := h.provider.SearchMovies(query, year)
+	movies, err := h.provider.SearchMovies(r.Context(), query, year)
 	if err != nil {
 		log.Error("Movie search failed", zap.Error(err), zap.String("query", query))
 		writeError(w, fmt.Sprintf("search failed: %v", err), http.StatusInternalServerError)
@@ -1,19 +1,24 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"goru/internal/services/files"
+	"goru/internal/services/notifications"
 	"goru/internal/services/plans"
 	"goru/internal/services/states"
 	"goru/pkg/log"
 	"net/http"
+	"path/filepath"
 
 	"go.uber.org/zap"
 )
 
 // ApplyRequest represents the request body for plan application
 type ApplyRequest struct {
-	Plan *plans.Plan `json:"plan"`
+	PlanID string `json:"plan_id"`
 }
 
 // ApplyResponse represents the response for plan application
@@ -22,6 +27,9 @@ type ApplyResponse struct {
 	Applied int          `json:"applied"`
 	Errors  []ApplyError `json:"errors,omitempty"`
 	Summary string       `json:"summary"`
+
+	// Integrations are the refreshes of the media servers
+	Integrations []plans.IntegrationResult `json:"integrations,omitempty"`
 }
 
 // ApplyError represents an error that occurred during plan application
@@ -39,81 +47,154 @@ func (h *PlanHandler) Apply(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate request
-	if req.Plan == nil {
-		writeError(w, "plan is required", http.StatusBadRequest)
+	if req.PlanID == "" {
+		writeError(w, "plan_id is required", http.StatusBadRequest)
 		return
 	}
 
-	// Initialize services
-	stateService, err := states.NewStateService()
-	if err != nil {
-		log.Error("failed to initialize state service", zap.Error(err))
-		writeError(w, "failed to initialize state tracking", http.StatusInternalServerError)
+	h.apply(w, r, req.PlanID)
+}
+
+// apply applies the stored plan with the given ID and writes the response
+func (h *PlanHandler) apply(w http.ResponseWriter, r *http.Request, planID string) {
+	if _, status, err := h.loadApplicablePlan(planID); err != nil {
+		writeError(w, err.Error(), status)
 		return
 	}
 
 	// Apply the plan changes
-	appliedCount := 0
-	var applyErrors []ApplyError
+	result, err := h.applyPlan(r.Context(), planID, nil)
+	if errors.Is(err, plans.ErrPlanAlreadyApplied) {
+		writeError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, files.ErrOutsideRoots) {
+		writeError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.Error("failed to apply plan", zap.String("plan_id", planID), zap.Error(err))
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	for _, change := range req.Plan.Changes {
-		if change.Action == plans.ActionRename && !change.IsConflicting() {
-			oldPath := change.Before.Path
-			newPath := change.After.Path
-
-			err := h.fileService.RenameFile(oldPath, newPath)
-			if err != nil {
-				log.Error("failed to rename file",
-					zap.Error(err),
-					zap.String("oldPath", oldPath),
-					zap.String("newPath", newPath))
-
-				applyErrors = append(applyErrors, ApplyError{
-					File:    change.Before.Filename,
-					Message: fmt.Sprintf("Failed to rename file: %v", err),
-				})
-				continue
-			}
-
-			// Track rename operation in state
-			if err := stateService.AddRenameOperation(
-				oldPath,
-				newPath,
-				change.Before.Filename,
-				change.After.Filename,
-				nil, // MediaInfo - using nil for now as in CLI implementation
-			); err != nil {
-				log.Error("failed to add rename to state", zap.Error(err))
-				// Don't fail the operation, just log the warning
-			}
-
-			appliedCount++
-			log.Debug("successfully renamed file",
-				zap.String("from", change.Before.Filename),
-				zap.String("to", change.After.Filename))
+	appliedCount := result.Applied
+	var applyErrors []ApplyError
+	for _, change := range result.Changes {
+		switch {
+		case change.Status != plans.ChangeStatusFailed:
+		case change.Before == "":
+			applyErrors = append(applyErrors, ApplyError{
+				File:    filepath.Base(change.After),
+				Message: fmt.Sprintf("Failed to download subtitle: %v", change.Error),
+			})
+		default:
+			applyErrors = append(applyErrors, ApplyError{
+				File:    filepath.Base(change.Before),
+				Message: fmt.Sprintf("Failed to rename file: %v", change.Error),
+			})
 		}
 	}
 
 	// Create response
-	status := "success"
-	summary := fmt.Sprintf("Applied %d rename operations", appliedCount)
+	response := ApplyResponse{
+		Status:  "success",
+		Applied: appliedCount,
+		Errors:  applyErrors,
+		Summary: fmt.Sprintf("Applied %d rename operations", appliedCount),
+
+		Integrations: result.Integrations,
+	}
 
 	if len(applyErrors) > 0 {
 		if appliedCount == 0 {
-			status = "error"
-			summary = "Failed to apply any rename operations"
+			response.Status = "error"
+			response.Summary = "Failed to apply any rename operations"
 		} else {
-			status = "partial"
-			summary = fmt.Sprintf("Applied %d rename operations with %d errors", appliedCount, len(applyErrors))
+			response.Status = "partial"
+			response.Summary = fmt.Sprintf("Applied %d rename operations with %d errors", appliedCount, len(applyErrors))
 		}
 	}
 
-	response := ApplyResponse{
-		Status:  status,
-		Applied: appliedCount,
-		Errors:  applyErrors,
-		Summary: summary,
+	writeJSON(w, response)
+}
+
+// loadApplicablePlan loads a stored plan and checks that it can be applied. The returned
+// status is the HTTP status matching the error.
+func (h *PlanHandler) loadApplicablePlan(planID string) (*plans.Plan, int, error) {
+	plan, err := h.store.Get(planID)
+	if errors.Is(err, plans.ErrPlanNotFound) {
+		return nil, http.StatusNotFound, err
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
 	}
 
-	writeJSON(w, response)
+	if plan.AppliedAt != nil {
+		return nil, http.StatusConflict, plans.ErrPlanAlreadyApplied
+	}
+
+	if err := h.checkRoots(plan); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	return plan, http.StatusOK, nil
+}
+
+// checkRoots makes sure that every change of the plan stays inside the library roots
+func (h *PlanHandler) checkRoots(plan *plans.Plan) error {
+	for i := range plan.Changes {
+		if !plan.Changes[i].IsApplicable() {
+			continue
+		}
+
+		if err := h.checkChangeRoots(&plan.Changes[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkChangeRoots makes sure that a change stays inside the library roots
+func (h *PlanHandler) checkChangeRoots(change *plans.Change) error {
+	for _, path := range []string{change.Before.Path, change.After.Path} {
+		if _, err := h.roots.Resolve(path); err != nil {
+			return fmt.Errorf("change %s: %w", change.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// applyPlan marks the stored plan as applied, then applies it. The plan is the one
+// stored when it is marked, with the reviews made since it was loaded, and is checked
+// against the roots again.
+func (h *PlanHandler) applyPlan(ctx context.Context, planID string, onChange plans.ChangeFunc) (*plans.ApplyResult, error) {
+	stateService, err := states.NewStateService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize state tracking: %w", err)
+	}
+
+	plan, err := h.store.MarkApplied(planID, h.checkRoots)
+	if err != nil {
+		return nil, err
+	}
+
+	// The changes rewritten by the plugins must stay inside the roots as well
+	h.plugins.BeforeApply(ctx, plan, h.checkChangeRoots)
+
+	// The vetoed and rewritten changes are stored, the plan showing what was applied
+	if err := h.store.Save(plan); err != nil {
+		log.Warn("failed to save the plan reviewed by the plugins", zap.String("plan_id", plan.ID), zap.Error(err))
+	}
+
+	result := plan.Apply(ctx, h.fileService, stateService, onChange)
+	h.sidecars.Write(ctx, plan, result, stateService)
+	h.subtitles.Download(ctx, plan, result, stateService)
+	h.integrations.Refresh(ctx, result)
+	h.plugins.AfterApply(ctx, plan, result)
+	h.notifier.Notify(notifications.ApplyFinished(plan, result))
+
+	return result, nil
 }
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goru/internal/models"
+	"goru/internal/plugins"
+	"goru/internal/services/files"
+	"goru/internal/services/plans"
+
+	"github.com/tidwall/buntdb"
+)
+
+// guard vetoes the renames of the given file
+type guard struct{ path string }
+
+func (guard) Name() string    { return "guard" }
+func (guard) Version() string { return "0.0.1" }
+func (g guard) BeforeApply(ctx context.Context, change *plans.Change) error {
+	if change.Before.Path == g.path {
+		return plugins.Veto("%s is kept", change.Before.Path)
+	}
+	return nil
+}
+
+func TestPlanHandler_ApplyPlan_Plugins(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	db, err := buntdb.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store, err := plans.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	roots, err := files.NewRoots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := plans.NewEmptyPlan()
+	for _, name := range []string{"a.mkv", "b.mkv"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	renamed := plan.AddRename(filepath.Join(dir, "a.mkv"), filepath.Join(dir, "A.mkv"))
+	vetoed := plan.AddRename(filepath.Join(dir, "b.mkv"), filepath.Join(dir, "B.mkv"))
+	if err := store.Save(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := &plugins.PluginManager{}
+	manager.Add(guard{path: filepath.Join(dir, "b.mkv")})
+
+	handler := NewPlanHandler(files.NewFileService("", "", models.Filters{}), nil, nil, store, roots)
+	handler.SetPlugins(manager)
+
+	result, err := handler.applyPlan(context.Background(), plan.ID, nil)
+	if err != nil {
+		t.Fatalf("applyPlan() returned an error: %v", err)
+	}
+	if result.Applied != 1 {
+		t.Errorf("applied %d changes, want 1", result.Applied)
+	}
+
+	// The stored plan shows the vetoed change as rejected
+	stored, err := store.Get(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.AppliedAt == nil {
+		t.Error("the stored plan is not marked as applied")
+	}
+	if change, _ := stored.GetChange(renamed); change.Decision != plans.DecisionPending {
+		t.Errorf("decision of the applied change = %q, want %q", change.Decision, plans.DecisionPending)
+	}
+	if change, _ := stored.GetChange(vetoed); change.Decision != plans.DecisionRejected {
+		t.Errorf("decision of the vetoed change = %q, want %q", change.Decision, plans.DecisionRejected)
+	}
+}
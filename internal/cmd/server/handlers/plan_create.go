@@ -1,17 +1,21 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"goru/internal/cmd/common"
 	"goru/internal/models"
+	"goru/internal/plugins"
 	"goru/internal/services/files"
 	"goru/internal/services/formatters"
+	"goru/internal/services/integrations"
+	"goru/internal/services/notifications"
 	"goru/internal/services/plans"
 	"goru/internal/services/providers"
-	"goru/internal/services/providers/tmdb"
+	"goru/internal/services/sidecars"
+	"goru/internal/services/subtitles"
 	"goru/pkg/log"
 	"net/http"
-	"strings"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -20,7 +24,7 @@ import (
 type LookupRequest struct {
 	Directory string `json:"directory"`
 	Type      string `json:"type"`     // "movie", "tv", "auto"
-	Provider  string `json:"provider"` // "tmdb", "tvdb", "anidb"
+	Provider  string `json:"provider"` // "tmdb", "nfo", "nfo,tmdb" or a plugin
 	Recursive bool   `json:"recursive"`
 }
 
@@ -33,15 +37,52 @@ type LookupResponse struct {
 type PlanHandler struct {
 	fileService      *files.FileService
 	formatterService *formatters.FormatterService
+	provider         providers.Provider
+	store            *plans.Store
+	roots            *files.Roots
+	notifier         *notifications.Notifier
+	integrations     *integrations.Integrations
+	sidecars         *sidecars.Writer
+	subtitles        *subtitles.Service
+	plugins          *plugins.PluginManager
 }
 
-func NewPlanHandler(fileService *files.FileService, formatterService *formatters.FormatterService) PlanHandler {
+func NewPlanHandler(fileService *files.FileService, formatterService *formatters.FormatterService, provider providers.Provider, store *plans.Store, roots *files.Roots) PlanHandler {
 	return PlanHandler{
 		fileService:      fileService,
 		formatterService: formatterService,
+		provider:         provider,
+		store:            store,
+		roots:            roots,
 	}
 }
 
+// SetNotifier sets the notifier of the plan and apply events
+func (h *PlanHandler) SetNotifier(notifier *notifications.Notifier) {
+	h.notifier = notifier
+}
+
+// SetIntegrations sets the media servers refreshed after the renames
+func (h *PlanHandler) SetIntegrations(integrations *integrations.Integrations) {
+	h.integrations = integrations
+}
+
+// SetSidecars sets the writer of the files written next to the renamed files
+func (h *PlanHandler) SetSidecars(sidecars *sidecars.Writer) {
+	h.sidecars = sidecars
+}
+
+// SetSubtitles sets the service finding the subtitles and downloading them next to
+// the renamed files
+func (h *PlanHandler) SetSubtitles(subtitles *subtitles.Service) {
+	h.subtitles = subtitles
+}
+
+// SetPlugins sets the plugins whose hooks are called while planning and applying
+func (h *PlanHandler) SetPlugins(plugins *plugins.PluginManager) {
+	h.plugins = plugins
+}
+
 // Create handles plan creation for GET method only.
 func (h *PlanHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Extract parameters from query string
@@ -51,30 +92,14 @@ func (h *PlanHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate request
-	if req.Directory == "" {
-		writeError(w, "directory is required", http.StatusBadRequest)
+	directory, err := h.toDirectory(req)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if req.Type == "" {
-		req.Type = "auto"
-	}
-	if req.Provider == "" {
-		req.Provider = "tmdb"
-	}
-
-	// Create directory configuration
-	directory := models.Directory{
-		Name:      "web-request",
-		Path:      req.Directory,
-		Type:      req.Type,
-		Provider:  req.Provider,
-		Recursive: req.Recursive,
-	}
-
 	// Process the directory similar to how the CLI does it
-	plan, err := h.processDirectory(directory)
+	plan, err := h.processDirectory(r.Context(), directory, nil)
 	if err != nil {
 		writeError(w, fmt.Sprintf("failed to process directory: %v", err), http.StatusInternalServerError)
 		return
@@ -102,48 +127,102 @@ func (h *PlanHandler) parseGetRequest(r *http.Request) (LookupRequest, error) {
 	return req, nil
 }
 
+// toDirectory validates the request and converts it to a directory configuration.
+// The directory must be inside the library roots.
+func (h *PlanHandler) toDirectory(req LookupRequest) (models.Directory, error) {
+	if req.Directory == "" {
+		return models.Directory{}, fmt.Errorf("directory is required")
+	}
+
+	path, err := h.roots.Resolve(req.Directory)
+	if err != nil {
+		return models.Directory{}, err
+	}
+
+	if req.Type == "" {
+		req.Type = "auto"
+	}
+	if req.Provider == "" {
+		req.Provider = "tmdb"
+	}
+
+	return models.Directory{
+		Name:      "web-request",
+		Path:      path,
+		Type:      req.Type,
+		Provider:  req.Provider,
+		Recursive: req.Recursive,
+	}, nil
+}
+
 // processDirectory processes a directory and returns a plan (similar to CLI plan command)
-func (h *PlanHandler) processDirectory(directory models.Directory) (*plans.Plan, error) {
+func (h *PlanHandler) processDirectory(ctx context.Context, directory models.Directory, onProgress common.ProgressFunc) (*plans.Plan, error) {
 	log.Debug("Processing directory", zap.String("path", directory.Path), zap.String("type", directory.Type))
 
 	// Get video files from directory
-	videoFiles, err := h.fileService.ScanDirectory(directory.Path, directory.Recursive, directory.Type)
+	videoFiles, scanErrors, err := h.fileService.ScanDirectory(directory)
 	if err != nil {
+		h.notifier.Notify(notifications.Error(fmt.Sprintf("Failed to scan %s: %s", directory.Path, err)))
 		return nil, fmt.Errorf("failed to scan directory: %w", err)
 	}
+	for _, scanError := range scanErrors {
+		log.Warn("failed to read a path of the directory", zap.String("path", scanError.Path), zap.Error(scanError.Err))
+	}
 
+	videoFiles = h.plugins.OnScan(ctx, videoFiles)
 	if len(videoFiles) == 0 {
 		log.Debug("No video files found in directory", zap.String("directory", directory.Path))
 		// Return an empty plan instead of an error
-		return &plans.Plan{
-			Changes: []plans.Change{},
-			Errors:  []plans.Error{},
-		}, nil
+		plan := plans.NewEmptyPlan()
+		plan.AddScanErrors(scanErrors)
+		return h.savePlan(plan)
 	}
 
 	// Create provider
-	var provider providers.Provider
-	switch strings.ToLower(directory.Provider) {
-	case "tmdb":
-		provider, err = tmdb.New(viper.GetString("providers.tmdb.api_key"))
-		if err != nil {
-			return nil, fmt.Errorf("failed to initialize TMDB provider: %w", err)
-		}
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", directory.Provider)
+	provider, err := common.NewProvider(directory.Provider, h.plugins)
+	if err != nil {
+		return nil, err
 	}
 
 	// Lookup media information for each file concurrently
-	processedFiles, processedSubtitles, err := common.ProcessFilesConcurrently(videoFiles, provider, nil, viper.GetInt("parallelism"))
+	processedFiles, err := common.ProcessFilesConcurrently(ctx, videoFiles, provider, h.subtitles, h.plugins, viper.GetInt("parallelism"), onProgress)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err != nil {
 		log.Error("failed to process files concurrently", zap.Error(err))
+		h.notifier.Notify(notifications.Error(fmt.Sprintf("Lookup failed in %s: %s", directory.Path, err)))
 	}
 
 	// Create the plan
-	plan, err := plans.NewPlan(processedFiles, processedSubtitles, h.formatterService)
+	processedFiles = h.plugins.BeforeFormat(ctx, processedFiles)
+	plan, err := plans.NewPlan(processedFiles, h.formatterService)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create plan: %w", err)
 	}
+	plan.AddScanErrors(scanErrors)
+	if err := h.plugins.OnPlan(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	plan, err = h.savePlan(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	h.notifier.Notify(notifications.PlanReady(plan))
+	if event, ok := notifications.LowConfidence(plan, h.notifier.LowConfidenceThreshold()); ok {
+		h.notifier.Notify(event)
+	}
+
+	return plan, nil
+}
+
+// savePlan stores the plan so that it can be reviewed and applied by ID
+func (h *PlanHandler) savePlan(plan *plans.Plan) (*plans.Plan, error) {
+	if err := h.store.Save(plan); err != nil {
+		return nil, fmt.Errorf("failed to store plan: %w", err)
+	}
 
 	return plan, nil
 }
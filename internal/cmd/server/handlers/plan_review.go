@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// PlanListResponse represents the response listing the stored plans
+type PlanListResponse struct {
+	Plans []*plans.Plan `json:"plans"`
+}
+
+// UpdateChangeRequest represents the request body to review a change.
+// Every field is optional, the match is applied before the target.
+type UpdateChangeRequest struct {
+	Decision *plans.Decision `json:"decision,omitempty"`
+	Target   string          `json:"target,omitempty"`
+	Match    *MatchRequest   `json:"match,omitempty"`
+}
+
+// MatchRequest selects the metadata a change must be matched against
+type MatchRequest struct {
+	MediaType string `json:"media_type"` // "movie", "tv"
+	ID        string `json:"id"`
+	Season    int    `json:"season,omitempty"`
+	Episode   int    `json:"episode,omitempty"`
+}
+
+// List handles GET /api/plans
+func (h *PlanHandler) List(w http.ResponseWriter, r *http.Request) {
+	storedPlans, err := h.store.List()
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if storedPlans == nil {
+		storedPlans = []*plans.Plan{}
+	}
+
+	writeJSON(w, PlanListResponse{Plans: storedPlans})
+}
+
+// Get handles GET /api/plans/{id}
+func (h *PlanHandler) Get(w http.ResponseWriter, r *http.Request) {
+	plan, err := h.store.Get(mux.Vars(r)["id"])
+	if errors.Is(err, plans.ErrPlanNotFound) {
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, plan)
+}
+
+// UpdateChange handles PATCH /api/plans/{id}/changes/{cid}
+func (h *PlanHandler) UpdateChange(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req UpdateChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.store.Get(vars["id"])
+	if errors.Is(err, plans.ErrPlanNotFound) {
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if plan.AppliedAt != nil {
+		writeError(w, plans.ErrPlanAlreadyApplied.Error(), http.StatusConflict)
+		return
+	}
+
+	change, err := plan.GetChange(vars["cid"])
+	if err != nil {
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// The match is looked up before the plan is locked, the provider being slow
+	var videoFile *models.VideoFile
+	if req.Match != nil {
+		if videoFile, err = h.match(r.Context(), change.Before.Path, *req.Match); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The review is made on the plan as stored, in the transaction saving it
+	status := http.StatusInternalServerError
+	plan, err = h.store.Update(vars["id"], func(plan *plans.Plan) error {
+		change, err := plan.GetChange(vars["cid"])
+		if err != nil {
+			status = http.StatusNotFound
+			return err
+		}
+
+		status = http.StatusBadRequest
+		if videoFile != nil {
+			if err := plan.Rematch(change.ID, videoFile, h.formatterService); err != nil {
+				return err
+			}
+		}
+
+		if req.Target != "" {
+			target := req.Target
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(change.Before.Path), target)
+			}
+
+			if err := plan.SetTarget(change.ID, target); err != nil {
+				return err
+			}
+		}
+
+		// Overridden and re-matched targets must stay inside the library roots
+		if _, err := h.roots.Resolve(change.After.Path); err != nil {
+			status = http.StatusForbidden
+			return err
+		}
+
+		if req.Decision != nil {
+			if err := plan.SetDecision(change.ID, *req.Decision); err != nil {
+				return err
+			}
+		}
+
+		status = http.StatusInternalServerError
+		return nil
+	})
+	switch {
+	case errors.Is(err, plans.ErrPlanNotFound):
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, plans.ErrPlanAlreadyApplied):
+		writeError(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		writeError(w, err.Error(), status)
+		return
+	}
+
+	writeJSON(w, plan)
+}
+
+// ApplyByID handles POST /api/plans/{id}/apply
+func (h *PlanHandler) ApplyByID(w http.ResponseWriter, r *http.Request) {
+	h.apply(w, r, mux.Vars(r)["id"])
+}
+
+// match looks up the metadata selected by the reviewer for the given file
+func (h *PlanHandler) match(ctx context.Context, path string, req MatchRequest) (*models.VideoFile, error) {
+	if req.ID == "" {
+		return nil, fmt.Errorf("match id is required")
+	}
+
+	videoFile := models.NewVideoFile(path, models.DefaultConflictStrategy)
+	videoFile.FileType = videoFile.GetFileType()
+
+	switch req.MediaType {
+	case "movie":
+		movie, err := h.provider.GetMovieByID(ctx, req.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get movie: %w", err)
+		}
+
+		videoFile.MediaType = models.MediaTypeMovie
+		videoFile.Metadata = movie
+
+	case "tv":
+		if req.Season <= 0 || req.Episode <= 0 {
+			return nil, fmt.Errorf("season and episode are required for TV shows")
+		}
+
+		showID, err := strconv.Atoi(req.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TV show id: %s", req.ID)
+		}
+
+		show, err := h.provider.GetTVShowByID(ctx, req.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get TV show: %w", err)
+		}
+
+		episode, err := h.provider.GetEpisode(ctx, showID, req.Season, req.Episode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get episode: %w", err)
+		}
+		episode.TVShow = *show
+
+		videoFile.MediaType = models.MediaTypeTVShow
+		videoFile.Metadata = episode
+
+	default:
+		return nil, fmt.Errorf("unsupported media type: %q", req.MediaType)
+	}
+
+	// The reviewer chose the match
+	videoFile.Confidence = &models.Confidence{Score: 1}
+
+	return videoFile, nil
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"goru/internal/models"
+	"goru/internal/services/files"
+	"goru/internal/services/providers"
+	"goru/internal/services/reports"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+type ReportHandler struct {
+	fileService *files.FileService
+	provider    providers.Provider
+	directories []models.Directory
+}
+
+// NewReportHandler creates a new report handler on the library directories
+func NewReportHandler(fileService *files.FileService, provider providers.Provider, directories []models.Directory) ReportHandler {
+	return ReportHandler{
+		fileService: fileService,
+		provider:    provider,
+		directories: directories,
+	}
+}
+
+// Missing handles GET /api/reports/missing
+func (h *ReportHandler) Missing(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	directories, err := reports.SelectDirectories(h.directories, query.Get("directory"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	videoFiles, scanErrors, err := reports.Scan(h.fileService, directories)
+	if err != nil {
+		log.Error("failed to scan the library", zap.Error(err))
+		writeError(w, "failed to scan the library", http.StatusInternalServerError)
+		return
+	}
+
+	report, err := reports.Missing(r.Context(), h.provider, videoFiles, reports.MissingOptions{Aired: query.Get("aired") == "true"})
+	if err != nil {
+		log.Error("failed to create the missing episodes report", zap.Error(err))
+		writeError(w, "failed to create the report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report.AddScanErrors(scanErrors)
+
+	if query.Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="missing.csv"`)
+		if err := reports.WriteMissingCSV(w, report); err != nil {
+			log.Error("failed to write the CSV report", zap.Error(err))
+		}
+		return
+	}
+
+	writeJSON(w, report)
+}
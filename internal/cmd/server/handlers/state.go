@@ -2,21 +2,27 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"os"
+	"sort"
 	"strconv"
 
+	"goru/internal/cmd/backend"
+	"goru/internal/models"
+	"goru/internal/plugins"
 	"goru/internal/services/files"
+	"goru/internal/services/notifications"
 	"goru/internal/services/states"
 	"goru/pkg/log"
 
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
 type StateHandler struct {
 	stateService *states.StateService
 	fileService  *files.FileService
+	notifier     *notifications.Notifier
+	plugins      *plugins.PluginManager
 }
 
 func NewStateHandler() (*StateHandler, error) {
@@ -25,7 +31,7 @@ func NewStateHandler() (*StateHandler, error) {
 		return nil, err
 	}
 
-	fileService := files.NewFileService("", "", viper.GetStringSlice("filters"))
+	fileService := files.NewFileService("", "", models.Filters{})
 
 	return &StateHandler{
 		stateService: stateService,
@@ -33,6 +39,16 @@ func NewStateHandler() (*StateHandler, error) {
 	}, nil
 }
 
+// SetNotifier sets the notifier of the revert events
+func (h *StateHandler) SetNotifier(notifier *notifications.Notifier) {
+	h.notifier = notifier
+}
+
+// SetPlugins sets the plugins whose revert hooks are called
+func (h *StateHandler) SetPlugins(plugins *plugins.PluginManager) {
+	h.plugins = plugins
+}
+
 // StateResponse represents the response for the state endpoint
 type StateResponse struct {
 	Version     string              `json:"version"`
@@ -98,6 +114,11 @@ func (h *StateHandler) State(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Sort entries by timestamp (newest first)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+
 	// Apply limit if specified
 	if limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit < len(entries) {
@@ -123,152 +144,52 @@ func (h *StateHandler) Revert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate that only one option is specified
-	optionCount := 0
-	if req.ID != "" {
-		optionCount++
-	}
-	if req.Last {
-		optionCount++
-	}
-	if req.All {
-		optionCount++
-	}
-
-	if optionCount == 0 {
-		writeError(w, "Must specify one of: id, last, or all", http.StatusBadRequest)
+	selection := backend.RevertSelection{ID: req.ID, Last: req.Last, All: req.All}
+	if err := selection.Validate(); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if optionCount > 1 {
-		writeError(w, "Can only specify one of: id, last, or all", http.StatusBadRequest)
+	result, err := backend.NewReverter(h.stateService, h.fileService, h.plugins).Revert(r.Context(), selection)
+	switch {
+	case errors.Is(err, states.ErrEntryNotFound), errors.Is(err, states.ErrNoActiveEntry):
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, backend.ErrAlreadyReverted), errors.Is(err, backend.ErrNothingToRevert):
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil && result == nil:
+		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	var entriesToRevert []states.StateEntry
-
-	if req.ID != "" {
-		// Revert specific entry by ID
-		entry, err := h.stateService.GetEntryByID(req.ID)
-		if err != nil {
-			writeError(w, err.Error(), http.StatusNotFound)
-			return
-		}
-
-		if entry.Reverted {
-			writeError(w, "Entry is already reverted", http.StatusBadRequest)
-			return
-		}
-
-		entriesToRevert = []states.StateEntry{*entry}
-	} else if req.Last {
-		// Revert last active entry
-		entry, err := h.stateService.GetLastActiveEntry()
-		if err != nil {
-			writeError(w, err.Error(), http.StatusNotFound)
-			return
-		}
-
-		entriesToRevert = []states.StateEntry{*entry}
-	} else if req.All {
-		// Revert all active entries
-		entries, err := h.stateService.GetActiveEntries()
-		if err != nil {
-			writeError(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		if len(entries) == 0 {
-			writeError(w, "No active entries to revert", http.StatusBadRequest)
-			return
-		}
-
-		entriesToRevert = entries
-	}
-
-	// Perform the reverts
-	var revertedIDs []string
-	var failures []RevertFailure
-	successCount := 0
-
-	for _, entry := range entriesToRevert {
-		log.Debug("Attempting to revert entry",
-			zap.String("id", entry.ID),
-			zap.String("from", entry.NewName),
-			zap.String("to", entry.OriginalName))
-
-		failure := h.revertEntry(entry)
-		if failure != nil {
-			failures = append(failures, *failure)
-			log.Warn("Failed to revert entry",
-				zap.String("id", entry.ID),
-				zap.String("reason", failure.Reason))
-		} else {
-			revertedIDs = append(revertedIDs, entry.ID)
-			successCount++
-			log.Info("Successfully reverted entry",
-				zap.String("id", entry.ID),
-				zap.String("original_name", entry.OriginalName))
-		}
+	failures := make([]RevertFailure, 0, len(result.Failed))
+	failedIDs := make([]string, 0, len(result.Failed))
+	for _, failure := range result.Failed {
+		failures = append(failures, RevertFailure{ID: failure.ID, Reason: failure.Reason})
+		failedIDs = append(failedIDs, failure.ID)
 	}
+	h.notifier.Notify(notifications.Reverted(result.RevertedIDs, failedIDs))
 
+	successCount := len(result.RevertedIDs)
 	response := RevertResponse{
 		Success:      successCount > 0,
-		Message:      h.buildRevertMessage(successCount, len(entriesToRevert)),
-		RevertedIDs:  revertedIDs,
+		Message:      h.buildRevertMessage(successCount, result.Total),
+		RevertedIDs:  result.RevertedIDs,
 		Failed:       failures,
 		SuccessCount: successCount,
-		TotalCount:   len(entriesToRevert),
+		TotalCount:   result.Total,
 	}
 
 	// Use appropriate HTTP status code
 	statusCode := http.StatusOK
 	if successCount == 0 {
 		statusCode = http.StatusBadRequest
-	} else if successCount < len(entriesToRevert) {
+	} else if successCount < result.Total {
 		statusCode = http.StatusPartialContent
 	}
 
-	w.WriteHeader(statusCode)
-	writeJSON(w, response)
-}
-
-// revertEntry attempts to revert a single entry and returns failure info if unsuccessful
-func (h *StateHandler) revertEntry(entry states.StateEntry) *RevertFailure {
-	// Check if the new file still exists
-	if _, err := os.Stat(entry.NewPath); os.IsNotExist(err) {
-		return &RevertFailure{
-			ID:     entry.ID,
-			Reason: "File not found: " + entry.NewPath,
-		}
-	}
-
-	// Check if original path would conflict
-	originalPath := entry.OriginalPath
-	if _, err := os.Stat(originalPath); err == nil {
-		return &RevertFailure{
-			ID:     entry.ID,
-			Reason: "Original file already exists: " + originalPath,
-		}
-	}
-
-	// Perform the revert
-	if err := h.fileService.RenameFile(entry.NewPath, originalPath); err != nil {
-		return &RevertFailure{
-			ID:     entry.ID,
-			Reason: "Failed to rename file: " + err.Error(),
-		}
-	}
-
-	// Mark as reverted in state
-	if err := h.stateService.MarkAsReverted(entry.ID); err != nil {
-		// File was reverted but state wasn't updated - this is a warning, not a failure
-		log.Warn("File reverted but failed to update state",
-			zap.String("id", entry.ID),
-			zap.Error(err))
-	}
-
-	return nil
+	writeJSONWithStatus(w, response, statusCode)
 }
 
 // buildRevertMessage creates an appropriate message based on the revert results
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStateHandler_Revert(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	handler, err := NewStateHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A broken link moved by the audit is reverted as well
+	dir := t.TempDir()
+	original := filepath.Join(dir, "link.mkv")
+	moved := filepath.Join(dir, "quarantine", "link.mkv")
+	if err := os.MkdirAll(filepath.Dir(moved), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing.mkv"), moved); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := handler.stateService.AddRenameOperation(original, moved, "link.mkv", "link.mkv", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "no selection", body: `{}`, status: http.StatusBadRequest},
+		{name: "several selections", body: `{"last":true,"all":true}`, status: http.StatusBadRequest},
+		{name: "unknown entry", body: `{"id":"unknown"}`, status: http.StatusNotFound},
+		{name: "broken link", body: `{"id":"` + entry.ID + `"}`, status: http.StatusOK},
+		{name: "already reverted", body: `{"id":"` + entry.ID + `"}`, status: http.StatusBadRequest},
+		{name: "nothing to revert", body: `{"all":true}`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.Revert(w, httptest.NewRequest("POST", "/api/state/revert", strings.NewReader(tt.body)))
+		if w.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+	}
+
+	if _, err := os.Lstat(original); err != nil {
+		t.Errorf("the link was not reverted: %v", err)
+	}
+}
@@ -39,7 +39,7 @@ func (h *TVShowHandler) ListEpisodes(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	episodes, err := h.provider.ListEpisodes(tvShowID, season)
+	episodes, err := h.provider.ListEpisodes(r.Context(), tvShowID, season)
 	if err != nil {
 		log.Error("TV show search failed", zap.Error(err))
 		writeError(w, fmt.Sprintf("search failed: %v", err), http.StatusInternalServerError)
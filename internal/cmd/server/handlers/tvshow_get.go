@@ -20,7 +20,7 @@ func (h *TVShowHandler) Get(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	tvshow, err := h.provider.GetTVShowByID(id)
+	tvshow, err := h.provider.GetTVShowByID(r.Context(), id)
 	if err != nil {
 		log.Error("TV show get failed", zap.Error(err), zap.String("id", id))
 		writeError(w, fmt.Sprintf("get failed: %v", err), http.StatusInternalServerError)
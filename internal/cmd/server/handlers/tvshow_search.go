@@ -35,7 +35,7 @@ func (h *TVShowHandler) Search(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	tvshows, err := h.provider.SearchTVShows(query, year)
+	tvshows, err := h.provider.SearchTVShows(r.Context(), query, year)
 	if err != nil {
 		log.Error("TV show search failed", zap.Error(err), zap.String("query", query))
 		writeError(w, fmt.Sprintf("search failed: %v", err), http.StatusInternalServerError)
@@ -1,22 +1,39 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"time"
 
+	"goru/internal/cmd/common"
 	"goru/internal/cmd/server/handlers"
+	"goru/internal/cmd/server/openapi"
 	"goru/internal/models"
+	"goru/internal/plugins"
+	"goru/internal/services/auth"
 	"goru/internal/services/files"
 	"goru/internal/services/formatters"
+	"goru/internal/services/integrations"
+	"goru/internal/services/jobs"
+	"goru/internal/services/metrics"
+	"goru/internal/services/notifications"
+	"goru/internal/services/plans"
 	"goru/internal/services/providers"
 	"goru/internal/services/providers/tmdb"
+	"goru/internal/services/sidecars"
+	"goru/internal/services/states"
 	"goru/internal/services/watcher"
+	"goru/internal/ui"
 	"goru/pkg/log"
 
 	ghandlers "github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/tidwall/buntdb"
 	"go.uber.org/zap"
 )
 
@@ -33,8 +50,13 @@ func Run(cmd *cobra.Command, args []string) {
 	}
 
 	// Create services
-	fileService := files.NewFileService("", "", viper.GetStringSlice("filters"))
+	fileService := files.NewFileService("", "", config.Filters)
 	formatterService := formatters.NewFormatterService("", "")
+	sanitizer, err := common.NewSanitizer(config)
+	if err != nil {
+		log.Fatal("failed to create the filename sanitizer", zap.Error(err))
+	}
+	formatterService.SetSanitizer(sanitizer)
 
 	// Create provider
 	tmdbProvider, err := tmdb.New(viper.GetString("providers.tmdb.api_key"))
@@ -42,16 +64,79 @@ func Run(cmd *cobra.Command, args []string) {
 		log.Fatal("failed to create TMDB provider", zap.Error(err))
 	}
 
+	// Open the database shared by the watcher and the plan store
+	db, err := buntdb.Open(viper.GetString("db_file"))
+	if err != nil {
+		log.Fatal("failed to open database", zap.Error(err))
+	}
+	defer db.Close()
+
+	planStore, err := plans.NewStore(db)
+	if err != nil {
+		log.Fatal("failed to create plan store", zap.Error(err))
+	}
+
+	// Only the library directories can be browsed and renamed. The default directory
+	// is a root when explicitly given, or when no library is configured.
+	var rootPaths []string
+	for _, directory := range config.Directories {
+		rootPaths = append(rootPaths, directory.Path)
+	}
+	rootPaths = append(rootPaths, config.Roots...)
+	if cmd.Flags().Changed("directory") || len(rootPaths) == 0 {
+		rootPaths = append(rootPaths, viper.GetString("directory"))
+	}
+
+	roots, err := files.NewRoots(rootPaths...)
+	if err != nil {
+		log.Fatal("invalid library roots", zap.Error(err))
+	}
+	log.Info("library roots", zap.Strings("roots", roots.Paths()))
+
+	// Create notifier
+	notifier, err := notifications.New(config.Notifications)
+	if err != nil {
+		log.Fatal("failed to create notifier", zap.Error(err))
+	}
+
+	subtitleService, err := common.NewSubtitles(config)
+	if err != nil {
+		log.Fatal("failed to create the subtitle service", zap.Error(err))
+	}
+
+	// Enable the plugins
+	pluginManager, err := plugins.NewPluginManager(config.Plugins)
+	if err != nil {
+		log.Fatal("failed to enable the plugins", zap.Error(err))
+	}
+
 	// Create watcher
-	watcher, err := watcher.New(viper.GetString("db_file"), []providers.Provider{tmdbProvider})
+	watcher, err := watcher.New(db, []providers.Provider{tmdbProvider})
 	if err != nil {
 		log.Fatal("failed to create watcher", zap.Error(err))
 	}
+	watcher.SetNotifier(notifier)
 	go watcher.Start()
 
+	// Create authentication
+	authService, err := auth.NewService(config.Server.Auth, db)
+	if err != nil {
+		log.Fatal("failed to create authentication service", zap.Error(err))
+	}
+	if !authService.Enabled() {
+		log.Warn("authentication is disabled, every client of the API is an admin")
+	}
+
 	// Create handlers
-	planHandler := handlers.NewPlanHandler(fileService, formatterService)
-	healthHandler := handlers.NewHealthHandler()
+	authHandler := handlers.NewAuthHandler(authService)
+	planHandler := handlers.NewPlanHandler(fileService, formatterService, tmdbProvider, planStore, roots)
+	planHandler.SetNotifier(notifier)
+	planHandler.SetIntegrations(integrations.New(config.Integrations))
+	planHandler.SetSidecars(sidecars.New(config.Sidecars, formatterService.Preset()))
+	planHandler.SetSubtitles(subtitleService)
+	planHandler.SetPlugins(pluginManager)
+	jobManager := jobs.NewManager(jobs.DefaultRetention)
+	jobHandler := handlers.NewJobHandler(jobManager, &planHandler)
 	movieHandler := handlers.NewMovieHandler(tmdbProvider)
 	tvShowHandler := handlers.NewTVShowHandler(tmdbProvider)
 
@@ -59,45 +144,149 @@ func Run(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatal("failed to create state handler", zap.Error(err))
 	}
+	stateHandler.SetNotifier(notifier)
+	stateHandler.SetPlugins(pluginManager)
 
-	// Setup routes
-	router := mux.NewRouter()
+	stateService, err := states.NewStateService()
+	if err != nil {
+		log.Fatal("failed to initialize state service", zap.Error(err))
+	}
+	directoryHandler := handlers.NewDirectoryHandler(roots, viper.GetString("directory"), planStore, stateService)
+	healthHandler := handlers.NewHealthHandler(stateService, db, []providers.Provider{tmdbProvider}, roots)
+
+	// The reports cover the library, or the default directory when there is none
+	reportDirectories := config.Directories
+	if len(reportDirectories) == 0 {
+		reportDirectories = []models.Directory{{Name: "default", Path: viper.GetString("directory"), Type: "auto", Recursive: true}}
+	}
+	reportHandler := handlers.NewReportHandler(fileService, tmdbProvider, reportDirectories)
+
+	// Load the API specification
+	spec, err := openapi.Load()
+	if err != nil {
+		log.Fatal("failed to load the API specification", zap.Error(err))
+	}
+	validate := spec.Validate(handlers.InvalidRequest)
+
+	// mountAPI registers the API routes on the given prefix
+	mountAPI := func(api *mux.Router) {
+		// Public routes
+		public := api.NewRoute().Subrouter()
+		public.Use(validate)
+		public.Handle("/health", authHandler.Identify(http.HandlerFunc(healthHandler.Health))).Methods("GET")
+		public.Handle("/openapi.json", spec).Methods("GET")
+		public.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
+		public.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST")
+
+		// Authenticated routes, admin is required to rename and revert files
+		protected := api.NewRoute().Subrouter()
+		protected.Use(authHandler.Authenticate, validate)
+		protected.HandleFunc("/auth/me", authHandler.Me).Methods("GET")
+		protected.HandleFunc("/directory", directoryHandler.Directory).Methods("GET")
+		protected.HandleFunc("/directory/default", directoryHandler.DefaultDirectory).Methods("GET")
+		protected.HandleFunc("/directory/roots", directoryHandler.Roots).Methods("GET")
+
+		// Plan routes
+		protected.HandleFunc("/plan/create", planHandler.Create).Methods("GET")
+		protected.HandleFunc("/plan/apply", authHandler.RequireAdmin(planHandler.Apply)).Methods("POST")
+		protected.HandleFunc("/plans", planHandler.List).Methods("GET")
+		protected.HandleFunc("/plans/{id}", planHandler.Get).Methods("GET")
+		protected.HandleFunc("/plans/{id}/changes/{cid}", authHandler.RequireAdmin(planHandler.UpdateChange)).Methods("PATCH")
+		protected.HandleFunc("/plans/{id}/apply", authHandler.RequireAdmin(planHandler.ApplyByID)).Methods("POST")
+
+		// Job routes, apply jobs are authorized by the handler
+		protected.HandleFunc("/jobs", jobHandler.Create).Methods("POST")
+		protected.HandleFunc("/jobs", jobHandler.List).Methods("GET")
+		protected.HandleFunc("/jobs/{id}", jobHandler.Get).Methods("GET")
+		protected.HandleFunc("/jobs/{id}", authHandler.RequireAdmin(jobHandler.Cancel)).Methods("DELETE")
+		protected.HandleFunc("/jobs/{id}/events", jobHandler.Events).Methods("GET")
 
-	// API routes
-	api := router.PathPrefix("/api").Subrouter()
-	api.HandleFunc("/directory", handlers.Directory).Methods("GET")
-	api.HandleFunc("/directory/default", handlers.DefaultDirectory).Methods("GET")
+		// Movie routes
+		protected.HandleFunc("/movies", movieHandler.Search).Methods("GET")
+		protected.HandleFunc("/movies/{id}", movieHandler.Get).Methods("GET")
 
-	// Plan routes
-	api.HandleFunc("/plan/create", planHandler.Create).Methods("GET")
-	api.HandleFunc("/plan/apply", planHandler.Apply).Methods("POST")
+		// Episode routes
+		protected.HandleFunc("/tvshows", tvShowHandler.Search).Methods("GET")
+		protected.HandleFunc("/tvshows/{id}", tvShowHandler.Get).Methods("GET")
+		protected.HandleFunc("/tvshows/{id}/episodes", tvShowHandler.ListEpisodes).Methods("GET")
 
-	// Movie routes
-	api.HandleFunc("/movies", movieHandler.Search).Methods("GET")
-	api.HandleFunc("/movies/{id}", movieHandler.Get).Methods("GET")
+		// State routes
+		protected.HandleFunc("/state", stateHandler.State).Methods("GET")
+		protected.HandleFunc("/state/revert", authHandler.RequireAdmin(stateHandler.Revert)).Methods("POST")
 
-	// Episode routes
-	api.HandleFunc("/tvshows", tvShowHandler.Search).Methods("GET")
-	api.HandleFunc("/tvshows/{id}", tvShowHandler.Get).Methods("GET")
-	api.HandleFunc("/tvshows/{id}/episodes", tvShowHandler.ListEpisodes).Methods("GET")
+		// Report routes
+		protected.HandleFunc("/reports/missing", reportHandler.Missing).Methods("GET")
 
-	// State routes
-	api.HandleFunc("/state", stateHandler.State).Methods("GET")
-	api.HandleFunc("/state/revert", stateHandler.Revert).Methods("POST")
+		// Unknown API routes must not fall back to the web UI
+		api.PathPrefix("/").HandlerFunc(handlers.NotFound)
+	}
+
+	// Setup routes. The unversioned prefix is an alias of the latest version, kept for
+	// the existing clients.
+	router := mux.NewRouter()
+	mountAPI(router.PathPrefix("/api/v1").Subrouter())
+	mountAPI(router.PathPrefix("/api").Subrouter())
 
-	api.HandleFunc("/health", healthHandler.Health).Methods("GET")
+	// Prometheus metrics, without authentication so that they can be scraped. They only
+	// hold counters labelled by kind, never paths nor names.
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
 
-	// Allowed origins (your frontend)
-	allowedOrigins := ghandlers.AllowedOrigins([]string{"http://localhost:3000"})
-	allowedMethods := ghandlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
-	allowedHeaders := ghandlers.AllowedHeaders([]string{"Content-Type", "Authorization"})
+	// Web UI
+	bundle, err := ui.Bundle(config.Server.UIDir)
+	switch {
+	case errors.Is(err, ui.ErrNoBundle):
+		log.Warn("the web UI is not available, build it with `make ui` or use --ui-dir", zap.String("ui_dir", config.Server.UIDir))
+	case err != nil:
+		log.Fatal("failed to load the web UI", zap.Error(err))
+	default:
+		router.PathPrefix("/").Handler(ui.Handler(bundle))
+	}
+
+	// Allowed origins (the frontend in development). Without any, no cross-origin
+	// request is allowed: the credentials are never sent to any origin.
+	allowedOrigins := ghandlers.AllowedOriginValidator(func(origin string) bool {
+		return slices.Contains(config.Server.AllowedOrigins, origin)
+	})
+	allowedMethods := ghandlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
+	allowedHeaders := ghandlers.AllowedHeaders([]string{"Content-Type", "Authorization", auth.APIKeyHeader})
 
 	// Start server
 	address := fmt.Sprintf("%s:%s", viper.GetString("host"), viper.GetString("port"))
 	log.Info("Server starting", zap.String("address", address))
 	log.Info("Open your browser to", zap.String("url", fmt.Sprintf("http://%s", address)))
 
-	if err := http.ListenAndServe(address, ghandlers.CORS(allowedOrigins, allowedMethods, allowedHeaders)(router)); err != nil {
+	server := &http.Server{
+		Addr:    address,
+		Handler: ghandlers.CORS(allowedOrigins, allowedMethods, allowedHeaders, ghandlers.AllowCredentials())(router),
+	}
+
+	// Stop gracefully on interrupt
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go func() {
+		<-ctx.Done()
+		log.Info("Server stopping")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error("failed to stop server gracefully", zap.Error(err))
+		}
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal("Server failed to start", zap.Error(err))
 	}
+
+	jobManager.Stop()
+
+	// Shut the external plugins down
+	pluginManager.Close()
+
+	// Deliver the pending notifications
+	notifyCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	notifier.Close(notifyCtx)
 }
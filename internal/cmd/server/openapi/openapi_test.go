@@ -0,0 +1,55 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSpec_Validate(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+
+	var invalid int
+	handler := spec.Validate(func(w http.ResponseWriter, err error) {
+		invalid++
+		w.WriteHeader(http.StatusBadRequest)
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{method: http.MethodPost, target: "/api/v1/jobs", body: `{"kind":"plan","lookup":{"directory":"/media"}}`, want: http.StatusOK},
+		{method: http.MethodPost, target: "/api/v1/jobs", body: `{"kind":"delete"}`, want: http.StatusBadRequest},
+		{method: http.MethodPost, target: "/api/jobs", body: `{}`, want: http.StatusBadRequest},
+		{method: http.MethodGet, target: "/api/v1/state?limit=abc", want: http.StatusBadRequest},
+		{method: http.MethodGet, target: "/api/v1/state?limit=10", want: http.StatusOK},
+		{method: http.MethodGet, target: "/api/v1/unknown", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s %s returned %d, want %d", tt.method, tt.target, rec.Code, tt.want)
+		}
+	}
+
+	if invalid != 3 {
+		t.Errorf("the error function was called %d times, want 3", invalid)
+	}
+}
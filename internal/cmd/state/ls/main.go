@@ -2,54 +2,62 @@ package ls
 
 import (
 	"fmt"
-	"sort"
+	"os"
 
+	"goru/internal/cmd/backend"
 	"goru/internal/cmd/common"
-	"goru/internal/services/states"
+	"goru/internal/models"
 	"goru/pkg/log"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
 func Run(cmd *cobra.Command, args []string) {
 	log.Debug("goru state ls is starting", zap.String("command", "state ls"))
 
-	stateService, err := states.NewStateService()
+	// Parse the output format
+	outputFlag, _ := cmd.Flags().GetString("output")
+	output, err := common.ParseOutputFormat(outputFlag)
 	if err != nil {
-		log.Fatal("failed to initialize state service", zap.Error(err))
+		log.Fatal("invalid output format", zap.Error(err))
+	}
+	if output == common.OutputPlain {
+		color.NoColor = true
 	}
 
-	state, err := stateService.LoadState()
+	// Unmarshal configuration
+	var config models.Config
+	if err := viper.Unmarshal(&config); err != nil {
+		log.Fatal("failed to unmarshal config", zap.Error(err))
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatal("invalid config", zap.Error(err))
+	}
+
+	// List locally or on the remote server
+	b, err := backend.New(config)
 	if err != nil {
-		log.Fatal("failed to load state", zap.Error(err))
+		log.Fatal("failed to create the backend", zap.Error(err))
 	}
 
-	// Filter entries based on flags
-	entries := state.Entries
 	activeOnly, _ := cmd.Flags().GetBool("active")
-	if activeOnly {
-		var filteredEntries []states.StateEntry
-		for _, entry := range entries {
-			if !entry.Reverted {
-				filteredEntries = append(filteredEntries, entry)
-			}
-		}
-		entries = filteredEntries
+	limit, _ := cmd.Flags().GetInt("limit")
+	state, err := b.State(cmd.Context(), backend.StateQuery{Active: activeOnly, Limit: limit})
+	if err != nil {
+		log.Fatal("failed to load state", zap.Error(err))
 	}
 
-	// Sort entries by timestamp (newest first)
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Timestamp.After(entries[j].Timestamp)
-	})
-
-	// Apply limit if specified
-	limit, _ := cmd.Flags().GetInt("limit")
-	if limit > 0 && len(entries) > limit {
-		entries = entries[:limit]
+	if output.IsMachineReadable() {
+		if err := common.WriteState(os.Stdout, output, *state); err != nil {
+			log.Fatal("failed to write state", zap.Error(err))
+		}
+		return
 	}
 
+	entries := state.Entries
 	if len(entries) == 0 {
 		color.Yellow("No rename operations found.")
 		return
@@ -100,17 +108,11 @@ func Run(cmd *cobra.Command, args []string) {
 	if activeOnly {
 		fmt.Printf("Showing %d active operations", len(entries))
 	} else {
-		activeCount := 0
-		for _, entry := range state.Entries {
-			if !entry.Reverted {
-				activeCount++
-			}
-		}
 		fmt.Printf("Showing %d operations (%d active, %d reverted)",
-			len(entries), activeCount, len(state.Entries)-activeCount)
+			len(entries), state.ActiveCount, state.TotalCount-state.ActiveCount)
 	}
 
-	if limit > 0 && len(state.Entries) > limit {
+	if limit > 0 && state.TotalCount > limit {
 		fmt.Printf(" (limited to %d)", limit)
 	}
 	fmt.Println()
@@ -1,11 +1,11 @@
 package revert
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 
-	"goru/internal/services/files"
+	"goru/internal/cmd/backend"
+	"goru/internal/models"
 	"goru/internal/services/states"
 	"goru/pkg/log"
 
@@ -18,38 +18,31 @@ import (
 func Run(cmd *cobra.Command, args []string) {
 	log.Debug("goru state revert is starting", zap.String("command", "state revert"))
 
-	stateService, err := states.NewStateService()
-	if err != nil {
-		log.Fatal("failed to initialize state service", zap.Error(err))
-	}
-
-	fileService := files.NewFileService("", "", viper.GetStringSlice("filters"))
-
 	// Get flags
 	id, _ := cmd.Flags().GetString("id")
 	last, _ := cmd.Flags().GetBool("last")
 	all, _ := cmd.Flags().GetBool("all")
 
 	// Validate flags
-	flagCount := 0
-	if id != "" {
-		flagCount++
-	}
-	if last {
-		flagCount++
-	}
-	if all {
-		flagCount++
+	selection := backend.RevertSelection{ID: id, Last: last, All: all}
+	if err := selection.Validate(); err != nil {
+		color.Red("Error: %v", err)
+		return
 	}
 
-	if flagCount == 0 {
-		color.Red("Error: You must specify one of --id, --last, or --all")
-		return
+	// Unmarshal configuration
+	var config models.Config
+	if err := viper.Unmarshal(&config); err != nil {
+		log.Fatal("failed to unmarshal config", zap.Error(err))
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatal("invalid config", zap.Error(err))
 	}
 
-	if flagCount > 1 {
-		color.Red("Error: You can only specify one of --id, --last, or --all")
-		return
+	// Revert locally or on the remote server
+	b, err := backend.New(config)
+	if err != nil {
+		log.Fatal("failed to create the backend", zap.Error(err))
 	}
 
 	// Color setup
@@ -57,48 +50,26 @@ func Run(cmd *cobra.Command, args []string) {
 	red := color.New(color.FgRed, color.Bold)
 	yellow := color.New(color.FgYellow, color.Bold)
 
-	var entriesToRevert []states.StateEntry
-
-	if id != "" {
-		// Revert specific entry by ID
-		entry, err := stateService.GetEntryByID(id)
-		if err != nil {
-			red.Printf("Error: %v\n", err)
-			return
-		}
-
-		if entry.Reverted {
-			yellow.Printf("Entry %s is already reverted\n", id)
-			return
-		}
-
-		entriesToRevert = []states.StateEntry{*entry}
-	} else if last {
-		// Revert last active entry
-		entry, err := stateService.GetLastActiveEntry()
-		if err != nil {
-			red.Printf("Error: %v\n", err)
-			return
-		}
+	// Load the operations to describe them
+	state, err := b.State(cmd.Context(), backend.StateQuery{})
+	if err != nil {
+		red.Printf("Error: %v\n", err)
+		return
+	}
 
-		entriesToRevert = []states.StateEntry{*entry}
-	} else if all {
-		// Revert all active entries
-		entries, err := stateService.GetActiveEntries()
-		if err != nil {
-			red.Printf("Error: %v\n", err)
-			return
-		}
+	entries := make(map[string]states.StateEntry, len(state.Entries))
+	for _, entry := range state.Entries {
+		entries[entry.ID] = entry
+	}
 
-		if len(entries) == 0 {
+	if all {
+		if state.ActiveCount == 0 {
 			yellow.Println("No active entries to revert")
 			return
 		}
 
-		entriesToRevert = entries
-
 		// Confirmation for reverting all
-		fmt.Printf("This will revert %d operations. Are you sure? (y/N): ", len(entries))
+		fmt.Printf("This will revert %d operations. Are you sure? (y/N): ", state.ActiveCount)
 		var response string
 		fmt.Scanln(&response)
 		if response != "y" && response != "Y" {
@@ -108,45 +79,46 @@ func Run(cmd *cobra.Command, args []string) {
 	}
 
 	// Perform the reverts
-	successCount := 0
-	for _, entry := range entriesToRevert {
-		fmt.Printf("Reverting: %s -> %s\n", entry.NewName, entry.OriginalName)
-
-		// Check if the new file still exists
-		if _, err := os.Stat(entry.NewPath); os.IsNotExist(err) {
-			red.Printf("  ✗ File not found: %s\n", entry.NewPath)
-			continue
-		}
-
-		// Check if original path would conflict
-		originalDir := filepath.Dir(entry.OriginalPath)
-		originalPath := filepath.Join(originalDir, entry.OriginalName)
-
-		if _, err := os.Stat(originalPath); err == nil {
-			red.Printf("  ✗ Original file already exists: %s\n", originalPath)
-			continue
-		}
-
-		// Perform the revert
-		err := fileService.RenameFile(entry.NewPath, originalPath)
-		if err != nil {
-			red.Printf("  ✗ Failed to revert: %v\n", err)
-			continue
-		}
+	result, err := b.Revert(cmd.Context(), selection)
+	if errors.Is(err, backend.ErrAlreadyReverted) {
+		yellow.Printf("Entry %s is already reverted\n", id)
+		return
+	}
+	if errors.Is(err, backend.ErrNothingToRevert) {
+		yellow.Println("No active entries to revert")
+		return
+	}
+	if err != nil && result == nil {
+		red.Printf("Error: %v\n", err)
+		return
+	}
 
-		// Mark as reverted in state
-		if err := stateService.MarkAsReverted(entry.ID); err != nil {
-			yellow.Printf("  ! File reverted but failed to update state: %v\n", err)
-		} else {
-			green.Printf("  ✓ Successfully reverted\n")
-			successCount++
-		}
+	for _, revertedID := range result.RevertedIDs {
+		describe(entries, revertedID)
+		green.Printf("  ✓ Successfully reverted\n")
+	}
+	for _, failure := range result.Failed {
+		describe(entries, failure.ID)
+		red.Printf("  ✗ %s\n", failure.Reason)
 	}
 
 	// Summary
-	fmt.Printf("\nReverted %d out of %d operations\n", successCount, len(entriesToRevert))
+	fmt.Printf("\nReverted %d out of %d operations\n", len(result.RevertedIDs), result.Total)
 
-	if successCount < len(entriesToRevert) {
+	if len(result.RevertedIDs) < result.Total {
 		yellow.Println("Some operations could not be reverted. Check the output above for details.")
 	}
+	if err != nil {
+		red.Printf("Error: %v\n", err)
+	}
+}
+
+// describe prints the rename operation being reverted
+func describe(entries map[string]states.StateEntry, id string) {
+	if entry, ok := entries[id]; ok {
+		fmt.Printf("Reverting: %s -> %s\n", entry.NewName, entry.OriginalName)
+		return
+	}
+
+	fmt.Printf("Reverting: %s\n", id)
 }
@@ -0,0 +1,163 @@
+package convert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"goru/internal/cmd/common"
+	converter "goru/internal/services/subtitles/convert"
+	"goru/pkg/log"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// languageCode matches a language in a filename, such as the fr of Movie.fr.forced.srt
+var languageCode = regexp.MustCompile(`^[a-z]{2}(-[a-z]{2})?$`)
+
+func Run(cmd *cobra.Command, args []string) {
+	log.Debug("goru subtitles convert is starting", zap.String("command", "subtitles convert"))
+
+	to, _ := cmd.Flags().GetString("to")
+	if to != "" && !converter.Writable(to) {
+		log.Fatal("invalid format", zap.String("to", to))
+	}
+
+	options, err := parseOptions(cmd)
+	if err != nil {
+		log.Fatal("invalid options", zap.Error(err))
+	}
+	force, _ := cmd.Flags().GetBool("force")
+
+	failed := 0
+	for _, path := range args {
+		target, encoding, err := convertFile(path, to, options, force)
+		if err != nil {
+			common.Red.Print("✗ ")
+			fmt.Printf("%s: %s\n", path, err)
+			failed++
+			continue
+		}
+		common.Green.Print("✓ ")
+		fmt.Printf("%s → %s ", path, target)
+		common.Gray.Printf("(%s)\n", encoding)
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func parseOptions(cmd *cobra.Command) (converter.Options, error) {
+	var options converter.Options
+	options.Encoding, _ = cmd.Flags().GetString("encoding")
+	options.Language, _ = cmd.Flags().GetString("language")
+	options.Offset, _ = cmd.Flags().GetDuration("shift")
+	options.FPS, _ = cmd.Flags().GetFloat64("input-fps")
+
+	if fps, _ := cmd.Flags().GetString("fps"); fps != "" {
+		from, to, ok := strings.Cut(fps, ":")
+		if !ok {
+			return options, fmt.Errorf("--fps must be given as from:to, e.g. 25:23.976")
+		}
+		var err error
+		if options.FromFPS, err = strconv.ParseFloat(from, 64); err != nil || options.FromFPS <= 0 {
+			return options, fmt.Errorf("invalid frame rate %q", from)
+		}
+		if options.ToFPS, err = strconv.ParseFloat(to, 64); err != nil || options.ToFPS <= 0 {
+			return options, fmt.Errorf("invalid frame rate %q", to)
+		}
+	}
+
+	return options, nil
+}
+
+// convertFile converts a subtitle next to it and returns the path written and the
+// encoding read
+func convertFile(path, to string, options converter.Options, force bool) (string, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	if options.Language == "" {
+		options.Language = filenameLanguage(path)
+	}
+	text, encoding, err := converter.ToUTF8(data, options.Encoding, options.Language)
+	if err != nil {
+		return "", "", err
+	}
+
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if format != converter.SRT && format != converter.VTT && format != converter.ASS &&
+		format != converter.SSA && format != converter.MicroDVD {
+		format = converter.Detect(text)
+	}
+
+	sub, err := converter.Parse(text, format, options.FPS)
+	if err != nil {
+		return "", "", err
+	}
+	sub.Resample(options.FromFPS, options.ToFPS)
+	sub.Shift(options.Offset)
+
+	to = converter.Target(format, to)
+	output, err := sub.Format(to)
+	if err != nil {
+		return "", "", err
+	}
+
+	target := strings.TrimSuffix(path, filepath.Ext(path)) + "." + to
+	if target != path && !force {
+		if _, err := os.Lstat(target); err == nil {
+			return "", "", fmt.Errorf("%s already exists, use --force to replace it", target)
+		}
+	}
+
+	if err := writeFile(target, output); err != nil {
+		return "", "", err
+	}
+	return target, encoding, nil
+}
+
+// writeFile replaces a file at once, through a temporary file of its directory
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".goru-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// filenameLanguage returns the language of a subtitle named after its video, such as
+// Movie (1999).fr.forced.srt, empty when there is none
+func filenameLanguage(path string) string {
+	parts := strings.Split(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		part := strings.ToLower(parts[i])
+		if languageCode.MatchString(part) {
+			return part
+		}
+		if part != "forced" && part != "sdh" && part != "cc" && part != "hi" {
+			break
+		}
+	}
+	return ""
+}
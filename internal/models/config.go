@@ -1,6 +1,13 @@
 package models
 
 import (
+	"errors"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"time"
+
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
 
@@ -8,10 +15,280 @@ type Config struct {
 	Providers     map[string]Provider `yaml:"providers" mapstructure:"providers"`
 	Directories   []Directory         `yaml:"directories" mapstructure:"directories"`
 	MaxConcurrent int                 `yaml:"max_concurrent" mapstructure:"max_concurrent"`
+	Sanitize      Sanitize            `yaml:"sanitize" mapstructure:"sanitize"`
+
+	// Filters select the files scanned in every directory, on top of the filters of
+	// each directory
+	Filters Filters `yaml:"filters" mapstructure:"filters"`
+
+	// Roots are additional library directories the server is allowed to rename files in,
+	// on top of the configured directories
+	Roots []string `yaml:"roots" mapstructure:"roots"`
+
+	Server Server `yaml:"server" mapstructure:"server"`
+
+	// Remote is the server driven by the CLI instead of the local filesystem
+	Remote Remote `yaml:"remote" mapstructure:"remote"`
+
+	Notifications Notifications `yaml:"notifications" mapstructure:"notifications"`
+
+	// Integrations are the media servers refreshed after the renames
+	Integrations Integrations `yaml:"integrations" mapstructure:"integrations"`
+
+	// Plugins are the plugins enabled, their hooks are called in this order
+	Plugins []Plugin `yaml:"plugins" mapstructure:"plugins"`
+
+	// Sidecars are the .nfo and artwork files written next to the renamed files
+	Sidecars Sidecars `yaml:"sidecars" mapstructure:"sidecars"`
+
+	// Subtitles are downloaded next to the renamed files
+	Subtitles Subtitles `yaml:"subtitles" mapstructure:"subtitles"`
+}
+
+// The preferences of the hearing impaired and the forced subtitles
+const (
+	// SubtitleInclude accepts the subtitles, the regular ones ranking higher
+	SubtitleInclude = "include"
+	SubtitlePrefer  = "prefer"
+	SubtitleExclude = "exclude"
+	SubtitleOnly    = "only"
+)
+
+// Subtitles configures the subtitles searched for the video files
+type Subtitles struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+
+	// Providers are the names of the subtitle sources searched, in this order. They
+	// are configured in the providers, by name.
+	Providers []string `yaml:"providers" mapstructure:"providers"`
+
+	// Languages are ISO 639-1 codes, by priority
+	Languages []string `yaml:"languages" mapstructure:"languages"`
+
+	// MaxLanguages is the number of languages downloaded for a file, the first ones
+	// found in the order of the languages. 0 downloads all of them.
+	MaxLanguages int `yaml:"max_languages" mapstructure:"max_languages"`
+
+	// HearingImpaired and Forced are include (the default), prefer, exclude or only
+	HearingImpaired string `yaml:"hearing_impaired" mapstructure:"hearing_impaired"`
+	Forced          string `yaml:"forced" mapstructure:"forced"`
+
+	// MinScore is the score under which the subtitles are not downloaded, between 0 and 1
+	MinScore float64 `yaml:"min_score" mapstructure:"min_score"`
+
+	// Convert post-processes the downloaded subtitles
+	Convert SubtitleConversion `yaml:"convert" mapstructure:"convert"`
+}
+
+// SubtitleConversion re-encodes the downloaded subtitles to UTF-8, and converts them
+// to another format
+type SubtitleConversion struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+
+	// Format is srt, vtt or ass. The format downloaded is kept when empty, MicroDVD
+	// being converted to SRT.
+	Format string `yaml:"format" mapstructure:"format"`
+}
+
+// Sidecars configures the files written next to the renamed files after an apply.
+// The existing files are kept.
+type Sidecars struct {
+	// NFO writes the Kodi .nfo files of the movies, the shows and the episodes
+	NFO bool `yaml:"nfo" mapstructure:"nfo"`
+
+	// Artwork downloads the posters, the fanarts and the episode stills
+	Artwork bool `yaml:"artwork" mapstructure:"artwork"`
+
+	// Naming is the convention of the filenames, one of kodi, plex, emby or jellyfin.
+	// It defaults to the format preset.
+	Naming string `yaml:"naming" mapstructure:"naming"`
+
+	// ImageURL is the base URL of the artwork paths
+	ImageURL string `yaml:"image_url" mapstructure:"image_url"`
+
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// Plugin enables a built-in plugin, or an external one when the command is set
+type Plugin struct {
+	Name string `yaml:"name" mapstructure:"name"`
+
+	// Enabled defaults to true, to keep a plugin configured but disabled
+	Enabled *bool `yaml:"enabled" mapstructure:"enabled"`
+
+	Options map[string]interface{} `yaml:"options" mapstructure:"options"`
+
+	// Command and Args launch an external plugin, speaking JSON-RPC on its standard
+	// input and output
+	Command string   `yaml:"command" mapstructure:"command"`
+	Args    []string `yaml:"args" mapstructure:"args"`
+
+	// Timeout bounds each call to an external plugin
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// IsEnabled returns true unless the plugin is explicitly disabled
+func (p Plugin) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
+
+// Integrations configures the media servers whose libraries are refreshed after an
+// apply. A media server is enabled when its URL is set.
+type Integrations struct {
+	Plex     MediaServer `yaml:"plex" mapstructure:"plex"`
+	Jellyfin MediaServer `yaml:"jellyfin" mapstructure:"jellyfin"`
+	Emby     MediaServer `yaml:"emby" mapstructure:"emby"`
+}
+
+// MediaServer is a media server refreshing the folders of the renamed files
+type MediaServer struct {
+	URL string `yaml:"url" mapstructure:"url"`
+
+	// Token is the X-Plex-Token of Plex, or the API key of Jellyfin and Emby
+	Token string `yaml:"token" mapstructure:"token"`
+
+	// PathMappings translate the paths of goru into the paths seen by the media
+	// server, when they do not share the same mounts
+	PathMappings []PathMapping `yaml:"path_mappings" mapstructure:"path_mappings"`
+
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// PathMapping replaces the From prefix of a path with To
+type PathMapping struct {
+	From string `yaml:"from" mapstructure:"from"`
+	To   string `yaml:"to" mapstructure:"to"`
+}
+
+// Notifications configures the notifications sent by the server.
+type Notifications struct {
+	// Events are the events to notify, every event when empty
+	Events []string `yaml:"events" mapstructure:"events"`
+
+	// LowConfidenceThreshold is the score under which a match is notified as low-confidence
+	LowConfidenceThreshold float64 `yaml:"low_confidence_threshold" mapstructure:"low_confidence_threshold"`
+
+	Retry     NotificationRetry               `yaml:"retry" mapstructure:"retry"`
+	Templates map[string]NotificationTemplate `yaml:"templates" mapstructure:"templates"`
+	Sinks     []NotificationSink              `yaml:"sinks" mapstructure:"sinks"`
+}
+
+// NotificationRetry configures the retries of the failed deliveries, with an
+// exponential backoff
+type NotificationRetry struct {
+	Attempts       int           `yaml:"attempts" mapstructure:"attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" mapstructure:"max_backoff"`
+}
+
+// NotificationTemplate overrides the Go templates of the title and the body of the
+// messages of an event
+type NotificationTemplate struct {
+	Title string `yaml:"title" mapstructure:"title"`
+	Body  string `yaml:"body" mapstructure:"body"`
+}
+
+// NotificationSink is a destination of the notifications
+type NotificationSink struct {
+	// Type is one of webhook, discord, slack, ntfy or smtp
+	Type string `yaml:"type" mapstructure:"type"`
+	Name string `yaml:"name" mapstructure:"name"`
+
+	// Events restricts the events sent to the sink
+	Events []string `yaml:"events" mapstructure:"events"`
+
+	// URL of the webhook, or of the ntfy topic
+	URL string `yaml:"url" mapstructure:"url"`
+
+	// Secret signs the webhook payloads with HMAC-SHA256
+	Secret string `yaml:"secret" mapstructure:"secret"`
+
+	// Token is the access token of ntfy
+	Token string `yaml:"token" mapstructure:"token"`
+
+	// SMTP server and recipients
+	Host     string   `yaml:"host" mapstructure:"host"`
+	Port     int      `yaml:"port" mapstructure:"port"`
+	Username string   `yaml:"username" mapstructure:"username"`
+	Password string   `yaml:"password" mapstructure:"password"`
+	From     string   `yaml:"from" mapstructure:"from"`
+	To       []string `yaml:"to" mapstructure:"to"`
+}
+
+// Remote is a goru server driven by the CLI. It is accessed with an API key, or with
+// the username and password of a local user.
+type Remote struct {
+	URL      string `yaml:"url" mapstructure:"url"`
+	APIKey   string `yaml:"api_key" mapstructure:"api_key"`
+	Username string `yaml:"username" mapstructure:"username"`
+	Password string `yaml:"password" mapstructure:"password"`
+}
+
+// Server configures the HTTP API.
+type Server struct {
+	// AllowedOrigins are the origins allowed to call the API from a browser, none
+	// when empty
+	AllowedOrigins []string `yaml:"allowed_origins" mapstructure:"allowed_origins"`
+	Auth           Auth     `yaml:"auth" mapstructure:"auth"`
+
+	// UIDir overrides the embedded web UI bundle, for development
+	UIDir string `yaml:"ui_dir" mapstructure:"ui_dir"`
+}
+
+// Auth configures how the clients of the HTTP API are authenticated.
+// Authentication is disabled when no API key, user nor proxy is configured.
+type Auth struct {
+	APIKeys    []APIKey      `yaml:"api_keys" mapstructure:"api_keys"`
+	Users      []User        `yaml:"users" mapstructure:"users"`
+	SessionTTL time.Duration `yaml:"session_ttl" mapstructure:"session_ttl"`
+	Proxy      ProxyAuth     `yaml:"proxy" mapstructure:"proxy"`
+}
+
+// APIKey is a static key sent in the X-API-Key header
+type APIKey struct {
+	Name string `yaml:"name" mapstructure:"name"`
+	Key  string `yaml:"key" mapstructure:"key"`
+	Role string `yaml:"role" mapstructure:"role"`
+}
+
+// User is a local user, logging in with a bcrypt hashed password
+type User struct {
+	Username     string `yaml:"username" mapstructure:"username"`
+	PasswordHash string `yaml:"password_hash" mapstructure:"password_hash"`
+	Role         string `yaml:"role" mapstructure:"role"`
+}
+
+// ProxyAuth trusts the user set in a header by a reverse proxy
+type ProxyAuth struct {
+	Enabled        bool     `yaml:"enabled" mapstructure:"enabled"`
+	UserHeader     string   `yaml:"user_header" mapstructure:"user_header"`
+	RoleHeader     string   `yaml:"role_header" mapstructure:"role_header"`
+	DefaultRole    string   `yaml:"default_role" mapstructure:"default_role"`
+	TrustedProxies []string `yaml:"trusted_proxies" mapstructure:"trusted_proxies"`
+}
+
+// Sanitize configures how filenames are made safe for the target filesystem.
+type Sanitize struct {
+	Profile       string `yaml:"profile" mapstructure:"profile"`
+	Normalization string `yaml:"normalization" mapstructure:"normalization"`
+	Transliterate bool   `yaml:"transliterate" mapstructure:"transliterate"`
+	MaxBytes      int    `yaml:"max_bytes" mapstructure:"max_bytes"`
 }
 
 type Provider struct {
 	APIKey string `yaml:"api_key" mapstructure:"api_key"`
+
+	// Username and Password log in to the providers raising the quotas of their users,
+	// such as OpenSubtitles
+	Username string `yaml:"username" mapstructure:"username"`
+	Password string `yaml:"password" mapstructure:"password"`
+
+	// URL overrides the address of the provider, such as a mirror
+	URL string `yaml:"url" mapstructure:"url"`
+
+	// Path is the directory of the providers reading local files, such as the
+	// archive of the local subtitle provider
+	Path string `yaml:"path" mapstructure:"path"`
 }
 
 type Directory struct {
@@ -22,6 +299,63 @@ type Directory struct {
 	Recursive        bool             `yaml:"recursive" mapstructure:"recursive"`
 	ConflictStrategy ConflictStrategy `yaml:"conflict_strategy" mapstructure:"conflict_strategy"`
 	Format           string           `yaml:"format" mapstructure:"format"`
+
+	// MaxDepth is the number of levels of folders walked below the directory when
+	// recursive, 0 for no limit
+	MaxDepth int `yaml:"max_depth" mapstructure:"max_depth"`
+
+	// FollowSymlinks walks the folders the symbolic links point to. The links to
+	// files are always scanned.
+	FollowSymlinks bool `yaml:"follow_symlinks" mapstructure:"follow_symlinks"`
+
+	Filters Filters `yaml:"filters" mapstructure:"filters"`
+
+	// Extras sorts the samples, the trailers and the extras out of the videos
+	Extras Extras `yaml:"extras" mapstructure:"extras"`
+}
+
+// Filters select the files of a directory by their path relative to it, with forward
+// slashes. The globs without a slash match the names of the files and the folders,
+// such as *.mkv, and ** matches any number of folders.
+type Filters struct {
+	// Include keeps only the files matching one of the globs or the regular
+	// expressions, every file when both are empty
+	Include      []string `yaml:"include" mapstructure:"include"`
+	IncludeRegex []string `yaml:"include_regex" mapstructure:"include_regex"`
+
+	// Exclude leaves out the files and the folders matching one of the globs or the
+	// regular expressions
+	Exclude      []string `yaml:"exclude" mapstructure:"exclude"`
+	ExcludeRegex []string `yaml:"exclude_regex" mapstructure:"exclude_regex"`
+}
+
+// Extras are the rules telling the samples, the trailers, the extras and the junk from
+// the movies and the episodes
+type Extras struct {
+	// Action is skip (the default), route or keep. The samples and the junk are always
+	// skipped, unless kept.
+	Action string `yaml:"action" mapstructure:"action"`
+
+	// MinSize, in MB, and MinDuration tell the samples that no name gives away, 0
+	// disables them
+	MinSize     int64         `yaml:"min_size" mapstructure:"min_size"`
+	MinDuration time.Duration `yaml:"min_duration" mapstructure:"min_duration"`
+
+	// Rules are tried before the default ones
+	Rules []ExtraRule `yaml:"rules" mapstructure:"rules"`
+}
+
+// ExtraRule is the kind of the videos whose name matches a pattern, or that are in a
+// folder
+type ExtraRule struct {
+	// Pattern is a regular expression matched against the filename, without its
+	// extension
+	Pattern string `yaml:"pattern" mapstructure:"pattern"`
+
+	// Folder is the name of a folder of the path, case insensitive
+	Folder string `yaml:"folder" mapstructure:"folder"`
+
+	Kind string `yaml:"kind" mapstructure:"kind"`
 }
 
 func (c Config) Validate() error {
@@ -33,6 +367,210 @@ func (c Config) Validate() error {
 			}
 			return nil
 		}))),
+		validation.Field(&c.Sanitize),
+		validation.Field(&c.Filters),
+		validation.Field(&c.Server),
+		validation.Field(&c.Remote),
+		validation.Field(&c.Notifications),
+		validation.Field(&c.Integrations),
+		validation.Field(&c.Plugins),
+		validation.Field(&c.Sidecars),
+		validation.Field(&c.Subtitles),
+	)
+}
+
+func (p Provider) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.URL, validation.When(p.URL != "", httpURLRule)),
+	)
+}
+
+func (s Subtitles) Validate() error {
+	preference := validation.In(SubtitleInclude, SubtitlePrefer, SubtitleExclude, SubtitleOnly).
+		Error("must be one of include, prefer, exclude or only")
+
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Providers, validation.Each(validation.Required)),
+		validation.Field(&s.Languages, validation.Each(validation.Match(languageCode).Error("must be an ISO 639-1 code"))),
+		validation.Field(&s.MaxLanguages, validation.Min(0)),
+		validation.Field(&s.HearingImpaired, preference),
+		validation.Field(&s.Forced, preference),
+		validation.Field(&s.MinScore, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&s.Convert),
+	)
+}
+
+func (c SubtitleConversion) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Format, validation.In("srt", "vtt", "ass").Error("must be one of srt, vtt or ass")),
+	)
+}
+
+func (s Sidecars) Validate() error {
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Naming, validation.In("kodi", "plex", "emby", "jellyfin").
+			Error("must be one of kodi, plex, emby or jellyfin")),
+		validation.Field(&s.ImageURL, validation.When(s.ImageURL != "", httpURLRule)),
+		validation.Field(&s.Timeout, validation.Min(time.Duration(0))),
+	)
+}
+
+func (p Plugin) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.Required),
+		validation.Field(&p.Timeout, validation.Min(time.Duration(0))),
+	)
+}
+
+func (i Integrations) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Plex),
+		validation.Field(&i.Jellyfin),
+		validation.Field(&i.Emby),
+	)
+}
+
+func (m MediaServer) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.URL, validation.When(m.URL != "", httpURLRule)),
+		validation.Field(&m.Token, validation.When(m.URL != "", validation.Required)),
+		validation.Field(&m.PathMappings),
+		validation.Field(&m.Timeout, validation.Min(time.Duration(0))),
+	)
+}
+
+func (p PathMapping) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.From, validation.Required),
+		validation.Field(&p.To, validation.Required),
+	)
+}
+
+var notificationEventRule = validation.Each(validation.In("plan_ready", "apply_finished", "error", "low_confidence", "revert").
+	Error("must be one of plan_ready, apply_finished, error, low_confidence or revert"))
+
+func (n Notifications) Validate() error {
+	return validation.ValidateStruct(&n,
+		validation.Field(&n.Events, notificationEventRule),
+		validation.Field(&n.LowConfidenceThreshold, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&n.Retry),
+		validation.Field(&n.Templates, validation.By(func(value interface{}) error {
+			for event := range value.(map[string]NotificationTemplate) {
+				if err := notificationEventRule.Validate([]string{event}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})),
+		validation.Field(&n.Sinks),
+	)
+}
+
+func (r NotificationRetry) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Attempts, validation.Min(0)),
+		validation.Field(&r.InitialBackoff, validation.Min(time.Duration(0))),
+		validation.Field(&r.MaxBackoff, validation.Min(time.Duration(0))),
+	)
+}
+
+func (s NotificationSink) Validate() error {
+	isHTTP := s.Type != "smtp"
+
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Type, validation.Required, validation.In("webhook", "discord", "slack", "ntfy", "smtp").
+			Error("must be one of webhook, discord, slack, ntfy or smtp")),
+		validation.Field(&s.Events, notificationEventRule),
+		validation.Field(&s.URL, validation.When(isHTTP, validation.Required)),
+		validation.Field(&s.Host, validation.When(!isHTTP, validation.Required)),
+		validation.Field(&s.Port, validation.Min(0), validation.Max(65535)),
+		validation.Field(&s.From, validation.When(!isHTTP, validation.Required)),
+		validation.Field(&s.To, validation.When(!isHTTP, validation.Required)),
+	)
+}
+
+// httpURLRule checks that a value is an http or https URL
+var httpURLRule = validation.By(func(value interface{}) error {
+	u, err := url.Parse(value.(string))
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("must be an http or https URL")
+	}
+	return nil
+})
+
+var regexpRule = validation.By(func(value interface{}) error {
+	_, err := regexp.Compile(value.(string))
+	return err
+})
+
+var globRule = validation.By(func(value interface{}) error {
+	_, err := path.Match(value.(string), "")
+	return err
+})
+
+func (r Remote) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.URL, validation.When(r.URL != "", httpURLRule)),
+		validation.Field(&r.Password, validation.When(r.Username != "", validation.Required)),
+	)
+}
+
+func (s Server) Validate() error {
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Auth),
+	)
+}
+
+// languageCode matches the ISO 639-1 codes, with an optional region such as pt-br
+var languageCode = regexp.MustCompile(`^[a-z]{2}(-[a-z]{2})?$`)
+
+var roleRule = validation.In("read-only", "admin").Error("must be either 'read-only' or 'admin'")
+
+func (a Auth) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.APIKeys),
+		validation.Field(&a.Users),
+		validation.Field(&a.SessionTTL, validation.Min(time.Duration(0))),
+		validation.Field(&a.Proxy),
+	)
+}
+
+func (k APIKey) Validate() error {
+	return validation.ValidateStruct(&k,
+		validation.Field(&k.Key, validation.Required, validation.Length(16, 0)),
+		validation.Field(&k.Role, validation.Required, roleRule),
+	)
+}
+
+func (u User) Validate() error {
+	return validation.ValidateStruct(&u,
+		validation.Field(&u.Username, validation.Required),
+		validation.Field(&u.PasswordHash, validation.Required),
+		validation.Field(&u.Role, validation.Required, roleRule),
+	)
+}
+
+func (p ProxyAuth) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UserHeader, validation.When(p.Enabled, validation.Required)),
+		validation.Field(&p.DefaultRole, roleRule),
+		validation.Field(&p.TrustedProxies, validation.When(p.Enabled, validation.Required), validation.Each(validation.By(func(value interface{}) error {
+			if _, _, err := net.ParseCIDR(value.(string)); err != nil {
+				return err
+			}
+			return nil
+		}))),
+	)
+}
+
+func (s Sanitize) Validate() error {
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Profile, validation.In("posix", "windows-safe", "smb", "ascii").Error("must be one of 'posix', 'windows-safe', 'smb' or 'ascii'")),
+		validation.Field(&s.Normalization, validation.In("none", "nfc", "nfd").Error("must be one of 'none', 'nfc' or 'nfd'")),
+		validation.Field(&s.MaxBytes, validation.Min(0)),
 	)
 }
 
@@ -46,7 +584,44 @@ func (d Directory) Validate() error {
 			ConflictStrategyAppendTimestamp,
 			ConflictStrategyOverwrite,
 			ConflictStrategyPromptUser,
-		).Error("must be one of 'skip', 'append_number', 'append_timestamp', 'overwrite', or 'prompt_user'")),
+			ConflictStrategyKeepBest,
+		).Error("must be one of 'skip', 'append_number', 'append_timestamp', 'overwrite', 'prompt_user' or 'keep_best'")),
+		validation.Field(&d.MaxDepth, validation.Min(0)),
+		validation.Field(&d.Filters),
+		validation.Field(&d.Extras),
+	)
+}
+
+func (f Filters) Validate() error {
+	return validation.ValidateStruct(&f,
+		validation.Field(&f.Include, validation.Each(validation.Required, globRule)),
+		validation.Field(&f.IncludeRegex, validation.Each(validation.Required, regexpRule)),
+		validation.Field(&f.Exclude, validation.Each(validation.Required, globRule)),
+		validation.Field(&f.ExcludeRegex, validation.Each(validation.Required, regexpRule)),
+	)
+}
+
+func (e Extras) Validate() error {
+	return validation.ValidateStruct(&e,
+		validation.Field(&e.Action, validation.In(ExtrasSkip, ExtrasRoute, ExtrasKeep).Error("must be one of 'skip', 'route' or 'keep'")),
+		validation.Field(&e.MinSize, validation.Min(int64(0))),
+		validation.Field(&e.MinDuration, validation.Min(time.Duration(0))),
+		validation.Field(&e.Rules),
+	)
+}
+
+func (r ExtraRule) Validate() error {
+	kinds := make([]interface{}, len(ExtraKinds))
+	for i, kind := range ExtraKinds {
+		kinds[i] = kind
+	}
+
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Pattern,
+			validation.Required.When(r.Folder == "").Error("a pattern or a folder is required"),
+			regexpRule,
+		),
+		validation.Field(&r.Kind, validation.Required, validation.In(kinds...)),
 	)
 }
 
@@ -1,5 +1,7 @@
 package models
 
+import "fmt"
+
 // ConflictStrategy defines how to handle naming conflicts
 type ConflictStrategy string
 
@@ -9,6 +11,29 @@ const (
 	ConflictStrategyAppendTimestamp ConflictStrategy = "append_timestamp"
 	ConflictStrategyPromptUser      ConflictStrategy = "prompt_user"
 	ConflictStrategyOverwrite       ConflictStrategy = "overwrite"
+	ConflictStrategyKeepBest        ConflictStrategy = "keep_best"
 )
 
 const DefaultConflictStrategy = ConflictStrategyAppendNumber
+
+// conflictStrategyAliases are the short names of the strategies, used by the --conflict flag
+var conflictStrategyAliases = map[string]ConflictStrategy{
+	"append":    ConflictStrategyAppendNumber,
+	"timestamp": ConflictStrategyAppendTimestamp,
+	"prompt":    ConflictStrategyPromptUser,
+	"best":      ConflictStrategyKeepBest,
+}
+
+// ParseConflictStrategy returns the strategy given by its name or its short name
+func ParseConflictStrategy(name string) (ConflictStrategy, error) {
+	if strategy, ok := conflictStrategyAliases[name]; ok {
+		return strategy, nil
+	}
+
+	switch strategy := ConflictStrategy(name); strategy {
+	case ConflictStrategySkip, ConflictStrategyAppendNumber, ConflictStrategyAppendTimestamp,
+		ConflictStrategyPromptUser, ConflictStrategyOverwrite, ConflictStrategyKeepBest:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown conflict strategy: %s", name)
+}
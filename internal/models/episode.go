@@ -12,5 +12,8 @@ type Episode struct {
 	Thumbnail string    `json:"still_path"`
 	TVShow    TVShow    `json:"tv_show,omitempty"`
 
+	// Runtime is in minutes, 0 when unknown
+	Runtime int `json:"runtime,omitempty"`
+
 	ExternalIDs ExternalIDs `json:"external_ids,omitempty"`
 }
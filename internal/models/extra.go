@@ -0,0 +1,82 @@
+package models
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// The kinds of the videos that are not a movie or an episode. The samples and the junk
+// are always left out of the plans, the other kinds are the extras of Plex.
+const (
+	ExtraSample         = "sample"
+	ExtraJunk           = "junk"
+	ExtraTrailer        = "trailer"
+	ExtraBehindTheScene = "behind_the_scenes"
+	ExtraDeletedScene   = "deleted_scene"
+	ExtraFeaturette     = "featurette"
+	ExtraInterview      = "interview"
+	ExtraScene          = "scene"
+	ExtraShort          = "short"
+	ExtraOther          = "other"
+)
+
+// The actions on the extras of a directory
+const (
+	// ExtrasSkip leaves the extras out of the plans
+	ExtrasSkip = "skip"
+
+	// ExtrasRoute moves the extras to the folders of Plex, next to their video
+	ExtrasRoute = "route"
+
+	// ExtrasKeep matches the extras like the other videos
+	ExtrasKeep = "keep"
+)
+
+// ExtraFolders are the folders of Plex holding each kind of extra
+var ExtraFolders = map[string]string{
+	ExtraTrailer:        "Trailers",
+	ExtraBehindTheScene: "Behind The Scenes",
+	ExtraDeletedScene:   "Deleted Scenes",
+	ExtraFeaturette:     "Featurettes",
+	ExtraInterview:      "Interviews",
+	ExtraScene:          "Scenes",
+	ExtraShort:          "Shorts",
+	ExtraOther:          "Other",
+}
+
+// ExtraKinds are the kinds of the rules of the config file
+var ExtraKinds = []string{
+	ExtraSample, ExtraJunk, ExtraTrailer, ExtraBehindTheScene, ExtraDeletedScene,
+	ExtraFeaturette, ExtraInterview, ExtraScene, ExtraShort, ExtraOther,
+}
+
+// IsRoutable returns true when an extra is moved to a folder of Plex rather than
+// skipped
+func IsRoutable(kind string) bool {
+	_, ok := ExtraFolders[kind]
+	return ok
+}
+
+// ExtraBase returns the folder of the video of an extra: its own folder, or the parent
+// of the extras folder it is in, such as Movie/ for Movie/Featurettes/Making Of.mkv
+func ExtraBase(path string) string {
+	dir := filepath.Dir(path)
+	if IsExtraFolder(filepath.Base(dir)) {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+// IsExtraFolder returns true for the extras folders of Plex, and the Extras folders of
+// the releases
+func IsExtraFolder(name string) bool {
+	if strings.EqualFold(name, "extras") {
+		return true
+	}
+	for _, folder := range ExtraFolders {
+		if strings.EqualFold(name, folder) {
+			return true
+		}
+	}
+	return false
+}
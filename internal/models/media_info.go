@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// MediaInfo is the technical metadata read from the container of a video file
+type MediaInfo struct {
+	// Container is matroska, webm or mp4
+	Container string        `json:"container"`
+	Duration  time.Duration `json:"duration"`
+
+	// Title is the title tag embedded in the container
+	Title string `json:"title,omitempty"`
+
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	VideoCodec string `json:"video_codec"`
+
+	// HDR is HDR10, HLG or Dolby Vision, empty for SDR
+	HDR string `json:"hdr,omitempty"`
+
+	// Bitrate is the average bitrate of the file, in bits per second
+	Bitrate int64 `json:"bitrate,omitempty"`
+
+	AudioTracks    []AudioTrack    `json:"audio_tracks,omitempty"`
+	SubtitleTracks []SubtitleTrack `json:"subtitle_tracks,omitempty"`
+
+	// Truncated is set when the file ends before the end declared by its container
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// AudioTrack is an audio track of a video file
+type AudioTrack struct {
+	Codec    string `json:"codec"`
+	Language string `json:"language,omitempty"`
+	Channels int    `json:"channels,omitempty"`
+	Default  bool   `json:"default,omitempty"`
+}
+
+// SubtitleTrack is a subtitle track of a video file
+type SubtitleTrack struct {
+	Codec    string `json:"codec"`
+	Language string `json:"language,omitempty"`
+	Forced   bool   `json:"forced,omitempty"`
+	Default  bool   `json:"default,omitempty"`
+}
+
+// Resolution returns the usual name of the resolution, such as 1080p. The width
+// is also checked, the movies cropped to a wide aspect ratio being less high.
+func (m *MediaInfo) Resolution() string {
+	switch {
+	case m == nil || (m.Width == 0 && m.Height == 0):
+		return ""
+	case m.Width >= 3200 || m.Height >= 1800:
+		return "2160p"
+	case m.Width >= 1800 || m.Height >= 1000:
+		return "1080p"
+	case m.Width >= 1200 || m.Height >= 700:
+		return "720p"
+	case m.Height >= 560:
+		return "576p"
+	case m.Height >= 400:
+		return "480p"
+	default:
+		return "SD"
+	}
+}
+
+// MainAudioTrack returns the default audio track, or the first one
+func (m *MediaInfo) MainAudioTrack() *AudioTrack {
+	if m == nil || len(m.AudioTracks) == 0 {
+		return nil
+	}
+	for i := range m.AudioTracks {
+		if m.AudioTracks[i].Default {
+			return &m.AudioTracks[i]
+		}
+	}
+	return &m.AudioTracks[0]
+}
+
+// ChannelLayout returns the usual name of the channels of an audio track, such as 5.1
+func (t *AudioTrack) ChannelLayout() string {
+	switch {
+	case t == nil || t.Channels == 0:
+		return ""
+	case t.Channels >= 6:
+		return fmt.Sprintf("%d.1", t.Channels-1)
+	default:
+		return fmt.Sprintf("%d.0", t.Channels)
+	}
+}
@@ -10,8 +10,17 @@ type Movie struct {
 	Genre         Genre       `json:"genre"`
 	Director      string      `json:"director"`
 	ExternalIDs   ExternalIDs `json:"external_ids"`
+
+	Overview     string   `json:"overview,omitempty"`
+	Genres       []string `json:"genres,omitempty"`
+	PosterPath   string   `json:"poster_path,omitempty"`
+	BackdropPath string   `json:"backdrop_path,omitempty"`
+
+	// Runtime is in minutes, 0 when unknown
+	Runtime int `json:"runtime,omitempty"`
 }
 
+// Confidence scores a match between 0 and 1
 type Confidence struct {
 	Score float64 `json:"score"`
 }
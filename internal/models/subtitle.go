@@ -0,0 +1,62 @@
+package models
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Subtitle is a subtitle found by a provider for a video file
+type Subtitle struct {
+	Provider string `json:"provider"`
+
+	// ID identifies the file of the subtitle at its provider
+	ID string `json:"id"`
+
+	// Language is an ISO 639-1 code, such as en or pt-br
+	Language string `json:"language"`
+	Release  string `json:"release,omitempty"`
+
+	// Format is the extension of the file, such as srt
+	Format string `json:"format"`
+
+	// Convert is the format the subtitle is converted to once downloaded, in UTF-8.
+	// It is empty when the subtitle is written as downloaded.
+	Convert string `json:"convert,omitempty"`
+
+	HearingImpaired bool `json:"hearing_impaired,omitempty"`
+	Forced          bool `json:"forced,omitempty"`
+
+	// HashMatch is set when the subtitle has been found by the hash of the video file,
+	// so it has been made for this very release
+	HashMatch bool `json:"hash_match,omitempty"`
+	Downloads int  `json:"downloads,omitempty"`
+
+	// Sync is the confidence between 0 and 1 that the timings of the subtitle match
+	// the video, 1 for a hash match and 0 when unknown
+	Sync float64 `json:"sync,omitempty"`
+
+	// Score ranks the subtitles between 0 and 1
+	Score float64 `json:"score"`
+}
+
+// Path returns the path of the subtitle next to a video, named after it as the
+// media servers expect: Movie (1999).en.srt, Movie (1999).en.sdh.srt or
+// Movie (1999).en.forced.srt
+func (s *Subtitle) Path(video string) string {
+	parts := []string{strings.TrimSuffix(video, filepath.Ext(video)), s.Language}
+	if s.HearingImpaired {
+		parts = append(parts, "sdh")
+	}
+	if s.Forced {
+		parts = append(parts, "forced")
+	}
+
+	format := s.Format
+	if s.Convert != "" {
+		format = s.Convert
+	}
+	if format == "" {
+		format = "srt"
+	}
+	return strings.Join(append(parts, format), ".")
+}
@@ -12,4 +12,9 @@ type TVShow struct {
 	Seasons      int         `json:"seasons"`
 	Episodes     int         `json:"episodes"`
 	ExternalIDs  ExternalIDs `json:"external_ids"`
+
+	Overview     string   `json:"overview,omitempty"`
+	Genres       []string `json:"genres,omitempty"`
+	PosterPath   string   `json:"poster_path,omitempty"`
+	BackdropPath string   `json:"backdrop_path,omitempty"`
 }
@@ -10,6 +10,7 @@ type ExternalIDs struct {
 	TMDBID  string `json:"tmdb_id"`
 	TVDBID  string `json:"tvdb_id"`
 	AniDBID string `json:"anidb_id"`
+	IMDBID  string `json:"imdb_id"`
 }
 
 // VideoFile represents a video file.
@@ -28,6 +29,31 @@ type VideoFile struct {
 	ConflictStrategy ConflictStrategy `json:"conflict_strategy"`
 
 	ExternalIDs ExternalIDs `json:"external_ids"`
+
+	// Confidence is how well the metadata matches the filename, nil before the lookup
+	Confidence *Confidence `json:"confidence,omitempty"`
+
+	// Query overrides what the providers parse from the filename, nil by default
+	Query *Query `json:"query,omitempty"`
+
+	// MediaInfo is read from the container, nil when it cannot be parsed
+	MediaInfo *MediaInfo `json:"media_info,omitempty"`
+
+	// Extra is the kind of extra of a video routed to the folders of Plex, such as
+	// trailer, empty for the movies and the episodes
+	Extra string `json:"extra,omitempty"`
+
+	// Subtitles are the subtitles chosen for the file, downloaded once it is renamed
+	Subtitles []Subtitle `json:"subtitles,omitempty"`
+}
+
+// Query is the title, year, season and episode looked up for a file. The zero
+// fields are parsed from the filename.
+type Query struct {
+	Title   string `json:"title,omitempty"`
+	Year    int    `json:"year,omitempty"`
+	Season  int    `json:"season,omitempty"`
+	Episode int    `json:"episode,omitempty"`
 }
 
 func NewVideoFile(path string, cs ConflictStrategy) *VideoFile {
@@ -0,0 +1,104 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"goru/internal/models"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// The built-in plugins, enabled through the configuration
+func init() {
+	Register("ignore", NewIgnore)
+	Register("min_confidence", NewMinConfidence)
+}
+
+// decodeOptions decodes the options of a plugin into v, rejecting the unknown ones
+func decodeOptions(options map[string]interface{}, v interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+		Result:           v,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(options)
+}
+
+// Ignore leaves out of the plans the files whose path matches a pattern
+type Ignore struct {
+	patterns []*regexp.Regexp
+}
+
+// NewIgnore creates the ignore plugin. The patterns option lists the regular
+// expressions matched against the paths.
+func NewIgnore(options map[string]interface{}) (Plugin, error) {
+	var config struct {
+		Patterns []string `mapstructure:"patterns"`
+	}
+	if err := decodeOptions(options, &config); err != nil {
+		return nil, err
+	}
+	if len(config.Patterns) == 0 {
+		return nil, fmt.Errorf("patterns is required")
+	}
+
+	ignore := &Ignore{}
+	for _, pattern := range config.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		ignore.patterns = append(ignore.patterns, re)
+	}
+
+	return ignore, nil
+}
+
+func (i *Ignore) Name() string    { return "ignore" }
+func (i *Ignore) Version() string { return "1.0.0" }
+
+func (i *Ignore) OnScan(ctx context.Context, file *models.VideoFile) error {
+	for _, re := range i.patterns {
+		if re.MatchString(file.Path) {
+			return Veto("path matches %q", re.String())
+		}
+	}
+	return nil
+}
+
+// MinConfidence leaves out of the plans the files matched with a low confidence, for
+// them to be renamed by hand
+type MinConfidence struct {
+	threshold float64
+}
+
+// NewMinConfidence creates the min_confidence plugin. The threshold option is the
+// minimum score, between 0 and 1.
+func NewMinConfidence(options map[string]interface{}) (Plugin, error) {
+	var config struct {
+		Threshold float64 `mapstructure:"threshold"`
+	}
+	if err := decodeOptions(options, &config); err != nil {
+		return nil, err
+	}
+	if config.Threshold <= 0 || config.Threshold > 1 {
+		return nil, fmt.Errorf("threshold must be between 0 and 1")
+	}
+
+	return &MinConfidence{threshold: config.Threshold}, nil
+}
+
+func (m *MinConfidence) Name() string    { return "min_confidence" }
+func (m *MinConfidence) Version() string { return "1.0.0" }
+
+func (m *MinConfidence) AfterMatch(ctx context.Context, file *models.VideoFile) error {
+	if file.Confidence != nil && file.Confidence.Score < m.threshold {
+		return Veto("confidence %.2f is under %.2f", file.Confidence.Score, m.threshold)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/internal/services/states"
+)
+
+// ConformanceCheck is a check of the conformance harness, passed when Error is empty
+type ConformanceCheck struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// Passed returns true when every check passed
+func Passed(checks []ConformanceCheck) bool {
+	for _, check := range checks {
+		if check.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckConformance launches an external plugin and checks that it follows the
+// protocol: the handshake, the errors, the declared capabilities and hooks, the
+// concurrent requests and the shutdown.
+func CheckConformance(ctx context.Context, config models.Plugin) []ConformanceCheck {
+	if config.Name == "" {
+		config.Name = config.Command
+	}
+
+	process := newProcess(config.Name, config.Command, config.Args, config.Timeout, InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Options:         config.Options,
+	})
+
+	var checks []ConformanceCheck
+	check := func(name string, err error) bool {
+		result := ConformanceCheck{Name: name}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		checks = append(checks, result)
+		return err == nil
+	}
+
+	// The handshake is required by the other checks
+	_, err := process.running(ctx)
+	if !check("handshake", err) {
+		process.close()
+		return checks
+	}
+	info := process.info
+
+	check("unknown method", checkMethodNotFound(process.call(ctx, "goru/conformance", nil, nil)))
+
+	if contains(info.Capabilities, CapabilityParser) {
+		var parsed ParseResult
+		err := process.call(ctx, MethodParse, ParseParams{Path: "/media/The.Matrix.1999.1080p.mkv", Filename: "The.Matrix.1999.1080p.mkv"}, &parsed)
+		if err == nil && parsed.MediaType != "" {
+			if _, ok := mediaTypes[parsed.MediaType]; !ok {
+				err = fmt.Errorf("unknown media type %q", parsed.MediaType)
+			}
+		}
+		check("parser: parse", err)
+	}
+
+	if contains(info.Capabilities, CapabilityProvider) {
+		check("provider: provide", checkProvide(ctx, process))
+	}
+
+	for _, hook := range info.Hooks {
+		check("hooks: "+hook, checkHook(ctx, process, hook))
+	}
+
+	check("concurrent requests", checkConcurrency(ctx, process))
+
+	if n := process.invalidMessages.Load(); n > 0 {
+		check("standard output", fmt.Errorf("%d line(s) are not JSON-RPC responses, log to the standard error", n))
+	} else {
+		check("standard output", nil)
+	}
+
+	check("shutdown", process.close())
+
+	return checks
+}
+
+// checkMethodNotFound checks that an unknown method is answered with the JSON-RPC error
+func checkMethodNotFound(err error) error {
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		if err == nil {
+			return errors.New("an unknown method must return an error")
+		}
+		return err
+	}
+	if rpcErr.Code != CodeMethodNotFound {
+		return fmt.Errorf("got code %d, want %d (method not found)", rpcErr.Code, CodeMethodNotFound)
+	}
+	return nil
+}
+
+// checkProvide checks that a file is answered with metadata, or with a JSON-RPC error
+func checkProvide(ctx context.Context, process *process) error {
+	file := models.NewVideoFile("/media/The.Matrix.1999.1080p.mkv", models.DefaultConflictStrategy)
+
+	var result ProvideResult
+	err := process.call(ctx, MethodProvide, FileParams{File: file}, &result)
+
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if result.Movie == nil && result.Episode == nil {
+		return errors.New("the result must contain a movie or an episode")
+	}
+	return nil
+}
+
+// checkHook calls a hook with a sample subject and checks its result
+func checkHook(ctx context.Context, process *process, hook string) error {
+	file := models.NewVideoFile("/media/The.Matrix.1999.1080p.mkv", models.DefaultConflictStrategy)
+	plan := plans.NewEmptyPlan()
+	change := plans.Change{ID: "conformance", Action: plans.ActionRename, Before: *file, After: models.VideoFile{Path: "/media/The Matrix (1999).mkv", Filename: "The Matrix (1999).mkv"}}
+
+	params := HookParams{}
+	switch hook {
+	case HookOnScan, HookBeforeMatch, HookAfterMatch, HookBeforeFormat:
+		params.File = file
+	case HookOnPlan:
+		plan.Changes = append(plan.Changes, change)
+		params.Plan = plan
+	case HookBeforeApply:
+		params.Change = &change
+	case HookAfterApply:
+		params.Plan = plan
+		params.Result = &plans.ApplyResult{PlanID: plan.ID, Changes: []plans.ChangeResult{}}
+	case HookOnRevert:
+		params.Entry = &states.StateEntry{ID: "conformance", OriginalPath: file.Path, NewPath: change.After.Path, OriginalName: file.Filename, NewName: change.After.Filename, Timestamp: time.Now()}
+	}
+
+	var result HookResult
+	if err := process.call(ctx, hookMethodPrefix+hook, params, &result); err != nil {
+		return err
+	}
+	if len(result.File) > 0 {
+		var decoded models.VideoFile
+		if err := decodeFile(result.File, &decoded); err != nil {
+			return fmt.Errorf("invalid file: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkConcurrency sends concurrent requests and checks that each gets its own response
+func checkConcurrency(ctx context.Context, process *process) error {
+	const n = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := checkMethodNotFound(process.call(ctx, fmt.Sprintf("goru/conformance_%d", i), nil, nil))
+			if err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
@@ -0,0 +1,253 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/internal/services/providers"
+	"goru/internal/services/states"
+)
+
+// ErrNotSupported is returned by the provider methods a plugin does not implement
+var ErrNotSupported = errors.New("not supported by the plugin")
+
+// External is a plugin running as an executable, see the protocol
+type External struct {
+	name    string
+	process *process
+}
+
+// NewExternal starts the executable of the plugin and performs the handshake
+func NewExternal(config models.Plugin) (*External, error) {
+	process := newProcess(config.Name, config.Command, config.Args, config.Timeout, InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Options:         config.Options,
+	})
+
+	if _, err := process.running(context.Background()); err != nil {
+		process.close()
+		return nil, fmt.Errorf("failed to start plugin %q: %w", config.Name, err)
+	}
+
+	return &External{name: config.Name, process: process}, nil
+}
+
+func (e *External) Name() string {
+	return e.name
+}
+
+func (e *External) Version() string {
+	return e.Info().Version
+}
+
+// Info returns the handshake of the plugin
+func (e *External) Info() InitializeResult {
+	e.process.mu.Lock()
+	defer e.process.mu.Unlock()
+
+	return e.process.info
+}
+
+// Close shuts the plugin down
+func (e *External) Close() error {
+	return e.process.close()
+}
+
+func (e *External) hasCapability(capability string) bool {
+	return contains(e.Info().Capabilities, capability)
+}
+
+func (e *External) hasHook(hook string) bool {
+	return contains(e.Info().Hooks, hook)
+}
+
+// hook calls a hook declared by the plugin and returns its result, nil when the hook
+// is not declared
+func (e *External) hook(ctx context.Context, hook string, params HookParams) (*HookResult, error) {
+	if !e.hasHook(hook) {
+		return nil, nil
+	}
+
+	var result HookResult
+	if err := e.process.call(ctx, hookMethodPrefix+hook, params, &result); err != nil {
+		return nil, err
+	}
+	if result.Veto != "" {
+		return nil, Veto("%s", result.Veto)
+	}
+
+	return &result, nil
+}
+
+// fileHook calls a hook on a file, replaced by the returned one
+func (e *External) fileHook(ctx context.Context, hook string, file *models.VideoFile) error {
+	result, err := e.hook(ctx, hook, HookParams{File: file})
+	if err != nil || result == nil || len(result.File) == 0 {
+		return err
+	}
+
+	if err := decodeFile(result.File, file); err != nil {
+		return fmt.Errorf("invalid file returned by %s: %w", hook, err)
+	}
+	return nil
+}
+
+func (e *External) OnScan(ctx context.Context, file *models.VideoFile) error {
+	return e.fileHook(ctx, HookOnScan, file)
+}
+
+// BeforeMatch parses the filename with a parser plugin, then calls the hook
+func (e *External) BeforeMatch(ctx context.Context, file *models.VideoFile) error {
+	if e.hasCapability(CapabilityParser) {
+		var parsed ParseResult
+		if err := e.process.call(ctx, MethodParse, ParseParams{Path: file.Path, Filename: file.Filename}, &parsed); err != nil {
+			return err
+		}
+
+		if parsed.MediaType != "" {
+			mediaType, ok := mediaTypes[parsed.MediaType]
+			if !ok {
+				return fmt.Errorf("unknown media type %q", parsed.MediaType)
+			}
+			file.MediaType = mediaType
+		}
+		if parsed.Query != (models.Query{}) {
+			query := parsed.Query
+			file.Query = &query
+		}
+	}
+
+	return e.fileHook(ctx, HookBeforeMatch, file)
+}
+
+func (e *External) AfterMatch(ctx context.Context, file *models.VideoFile) error {
+	return e.fileHook(ctx, HookAfterMatch, file)
+}
+
+func (e *External) BeforeFormat(ctx context.Context, file *models.VideoFile) error {
+	return e.fileHook(ctx, HookBeforeFormat, file)
+}
+
+func (e *External) OnPlan(ctx context.Context, plan *plans.Plan) error {
+	result, err := e.hook(ctx, HookOnPlan, HookParams{Plan: plan})
+	if err == nil && result != nil && result.Plan != nil {
+		*plan = *result.Plan
+	}
+	return err
+}
+
+func (e *External) BeforeApply(ctx context.Context, change *plans.Change) error {
+	result, err := e.hook(ctx, HookBeforeApply, HookParams{Change: change})
+	if err == nil && result != nil && result.Change != nil {
+		*change = *result.Change
+	}
+	return err
+}
+
+func (e *External) AfterApply(ctx context.Context, plan *plans.Plan, applyResult *plans.ApplyResult) error {
+	result, err := e.hook(ctx, HookAfterApply, HookParams{Plan: plan, Result: applyResult})
+	if err == nil && result != nil && result.Result != nil {
+		*applyResult = *result.Result
+	}
+	return err
+}
+
+func (e *External) OnRevert(ctx context.Context, entry *states.StateEntry) error {
+	result, err := e.hook(ctx, HookOnRevert, HookParams{Entry: entry})
+	if err == nil && result != nil && result.Entry != nil {
+		*entry = *result.Entry
+	}
+	return err
+}
+
+// Provider returns the provider of the plugin, nil without the provider capability
+func (e *External) Provider() providers.Provider {
+	if !e.hasCapability(CapabilityProvider) {
+		return nil
+	}
+	return &externalProvider{plugin: e}
+}
+
+// externalProvider looks the files up with a provider plugin
+type externalProvider struct {
+	plugin *External
+}
+
+func (p *externalProvider) Name() string {
+	return p.plugin.Name()
+}
+
+// Provide sets the metadata found by the plugin
+func (p *externalProvider) Provide(ctx context.Context, file *models.VideoFile) error {
+	var result ProvideResult
+	if err := p.plugin.process.call(ctx, MethodProvide, FileParams{File: file}, &result); err != nil {
+		return err
+	}
+
+	switch {
+	case result.Movie != nil:
+		file.Metadata = result.Movie
+	case result.Episode != nil:
+		file.Metadata = result.Episode
+	default:
+		return fmt.Errorf("plugin %s returned no metadata", p.plugin.Name())
+	}
+
+	file.ExternalIDs = result.ExternalIDs
+	file.Confidence = result.Confidence
+	return nil
+}
+
+// call calls an optional provider method
+func (p *externalProvider) call(ctx context.Context, method string, params SearchParams, result interface{}) error {
+	err := p.plugin.process.call(ctx, method, params, result)
+
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == CodeMethodNotFound {
+		return fmt.Errorf("%s: %w", method, ErrNotSupported)
+	}
+	return err
+}
+
+func (p *externalProvider) GetMovie(ctx context.Context, title string, year int) (*models.Movie, error) {
+	var movie models.Movie
+	return &movie, p.call(ctx, MethodGetMovie, SearchParams{Title: title, Year: year}, &movie)
+}
+
+func (p *externalProvider) GetMovieByID(ctx context.Context, id string) (*models.Movie, error) {
+	var movie models.Movie
+	return &movie, p.call(ctx, MethodGetMovieByID, SearchParams{ID: id}, &movie)
+}
+
+func (p *externalProvider) SearchMovies(ctx context.Context, title string, year int) ([]*models.Movie, error) {
+	var movies []*models.Movie
+	return movies, p.call(ctx, MethodSearchMovies, SearchParams{Title: title, Year: year}, &movies)
+}
+
+func (p *externalProvider) GetTVShow(ctx context.Context, title string, year int) (*models.TVShow, error) {
+	var show models.TVShow
+	return &show, p.call(ctx, MethodGetTVShow, SearchParams{Title: title, Year: year}, &show)
+}
+
+func (p *externalProvider) GetTVShowByID(ctx context.Context, id string) (*models.TVShow, error) {
+	var show models.TVShow
+	return &show, p.call(ctx, MethodGetTVShowByID, SearchParams{ID: id}, &show)
+}
+
+func (p *externalProvider) SearchTVShows(ctx context.Context, title string, year int) ([]*models.TVShow, error) {
+	var shows []*models.TVShow
+	return shows, p.call(ctx, MethodSearchTVShows, SearchParams{Title: title, Year: year}, &shows)
+}
+
+func (p *externalProvider) GetEpisode(ctx context.Context, showID, season, episode int) (*models.Episode, error) {
+	var result models.Episode
+	return &result, p.call(ctx, MethodGetEpisode, SearchParams{ShowID: showID, Season: season, Episode: episode}, &result)
+}
+
+func (p *externalProvider) ListEpisodes(ctx context.Context, showID, season int) ([]*models.Episode, error) {
+	var episodes []*models.Episode
+	return episodes, p.call(ctx, MethodListEpisodes, SearchParams{ShowID: showID, Season: season}, &episodes)
+}
@@ -0,0 +1,307 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+)
+
+// pluginModeEnv makes the test binary run as an external plugin
+const pluginModeEnv = "GORU_TEST_PLUGIN_MODE"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(pluginModeEnv); mode != "" {
+		runTestPlugin(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runTestPlugin is a plugin whose behavior depends on the mode:
+// - ok follows the protocol
+// - crash exits when a scanned path contains "crash"
+// - hang never answers the scan of a path containing "hang"
+// - version speaks another version of the protocol
+// - noisy writes garbage to its standard output
+func runTestPlugin(mode string) {
+	out := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+
+	for scanner.Scan() {
+		var req struct {
+			ID     *int64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		json.Unmarshal(scanner.Bytes(), &req)
+
+		var params HookParams
+		json.Unmarshal(req.Params, &params)
+
+		var result interface{}
+		var rpcErr *RPCError
+
+		switch req.Method {
+		case MethodInitialize:
+			version := ProtocolVersion
+			if mode == "version" {
+				version = ProtocolVersion + 1
+			}
+			if mode == "noisy" {
+				fmt.Println("starting the plugin")
+			}
+			result = InitializeResult{
+				Name:            "test",
+				Version:         "0.1.0",
+				ProtocolVersion: version,
+				Capabilities:    []string{CapabilityParser, CapabilityProvider, CapabilityHooks},
+				Hooks:           []string{HookOnScan, HookAfterMatch, HookBeforeApply},
+			}
+		case MethodParse:
+			result = ParseResult{MediaType: "tvshow", Query: models.Query{Title: "Dark", Season: 1, Episode: 2}}
+		case MethodProvide:
+			result = ProvideResult{
+				Movie:       &models.Movie{ID: "603", Title: "The Matrix"},
+				ExternalIDs: models.ExternalIDs{TMDBID: "603"},
+				Confidence:  &models.Confidence{Score: 0.9},
+			}
+		case hookMethodPrefix + HookOnScan:
+			switch {
+			case mode == "crash" && strings.Contains(params.File.Path, "crash"):
+				os.Exit(3)
+			case mode == "hang" && strings.Contains(params.File.Path, "hang"):
+				continue
+			case strings.Contains(params.File.Path, "sample"):
+				result = HookResult{Veto: "sample"}
+			default:
+				result = HookResult{}
+			}
+		case hookMethodPrefix + HookAfterMatch:
+			file := params.File
+			if movie, ok := file.Metadata.(map[string]interface{}); ok {
+				movie["title"] = strings.ToUpper(movie["title"].(string))
+			}
+			data, _ := json.Marshal(file)
+			result = HookResult{File: data}
+		case hookMethodPrefix + HookBeforeApply:
+			change := params.Change
+			change.After.Filename = "renamed.mkv"
+			result = HookResult{Change: change}
+		case MethodShutdown:
+			result = struct{}{}
+		default:
+			rpcErr = &RPCError{Code: CodeMethodNotFound, Message: "method not found"}
+		}
+
+		if req.ID == nil {
+			continue
+		}
+		if rpcErr != nil {
+			out.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": rpcErr})
+		} else {
+			out.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		}
+	}
+}
+
+// testPlugin returns the configuration of the test binary running as a plugin
+func testPlugin(t *testing.T, mode string) models.Plugin {
+	t.Setenv(pluginModeEnv, mode)
+
+	return models.Plugin{
+		Name:    "test",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^$"},
+		Timeout: 2 * time.Second,
+	}
+}
+
+func newTestExternal(t *testing.T, config models.Plugin) *External {
+	external, err := NewExternal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { external.Close() })
+
+	return external
+}
+
+func TestExternal_Hooks(t *testing.T) {
+	external := newTestExternal(t, testPlugin(t, "ok"))
+	ctx := context.Background()
+
+	if external.Version() != "0.1.0" {
+		t.Errorf("got version %q", external.Version())
+	}
+
+	if err := external.OnScan(ctx, &models.VideoFile{Path: "/media/Dark.sample.mkv"}); !errors.Is(err, ErrVetoed) || err.Error() != "vetoed: sample" {
+		t.Errorf("got error %v, want a veto", err)
+	}
+
+	// The parser sets the query before the lookup
+	file := models.NewVideoFile("/media/[Grp] Dark - 01x02.mkv", models.DefaultConflictStrategy)
+	if err := external.BeforeMatch(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+	if file.MediaType != models.MediaTypeTVShow || *file.Query != (models.Query{Title: "Dark", Season: 1, Episode: 2}) {
+		t.Errorf("got media type %d and query %+v", file.MediaType, file.Query)
+	}
+
+	// The provider sets the metadata, that the hooks can change
+	file = models.NewVideoFile("/media/The.Matrix.1999.mkv", models.DefaultConflictStrategy)
+	if err := external.Provider().Provide(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+	if err := external.AfterMatch(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+	movie, ok := file.Metadata.(*models.Movie)
+	if !ok || movie.Title != "THE MATRIX" || file.ExternalIDs.TMDBID != "603" || file.Confidence.Score != 0.9 {
+		t.Errorf("got metadata %#v, external IDs %+v", file.Metadata, file.ExternalIDs)
+	}
+
+	change := &plans.Change{ID: "1", Action: plans.ActionRename, After: models.VideoFile{Filename: "a.mkv"}}
+	if err := external.BeforeApply(ctx, change); err != nil || change.After.Filename != "renamed.mkv" || change.ID != "1" {
+		t.Errorf("got change %+v and error %v", change, err)
+	}
+
+	// The hooks that are not declared are not called
+	if err := external.OnPlan(ctx, &plans.Plan{ID: "p1"}); err != nil {
+		t.Error(err)
+	}
+
+	// The optional provider methods may not be implemented
+	if _, err := external.Provider().SearchMovies(ctx, "The Matrix", 1999); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("got error %v, want %v", err, ErrNotSupported)
+	}
+}
+
+func TestExternal_Crash(t *testing.T) {
+	external := newTestExternal(t, testPlugin(t, "crash"))
+	ctx := context.Background()
+
+	// The crashed plugin is restarted by the next call
+	for i := 0; i < maxRestarts; i++ {
+		if err := external.OnScan(ctx, &models.VideoFile{Path: "/media/crash.mkv"}); !errors.Is(err, ErrPluginExited) {
+			t.Fatalf("got error %v, want %v", err, ErrPluginExited)
+		}
+		if err := external.OnScan(ctx, &models.VideoFile{Path: "/media/ok.mkv"}); err != nil {
+			t.Fatalf("the plugin was not restarted: %v", err)
+		}
+	}
+
+	// Until it crashed too many times
+	external.OnScan(ctx, &models.VideoFile{Path: "/media/crash.mkv"})
+	if err := external.OnScan(ctx, &models.VideoFile{Path: "/media/ok.mkv"}); !errors.Is(err, ErrPluginUnavailable) {
+		t.Errorf("got error %v, want %v", err, ErrPluginUnavailable)
+	}
+}
+
+func TestExternal_Timeout(t *testing.T) {
+	config := testPlugin(t, "hang")
+	config.Timeout = 100 * time.Millisecond
+	external := newTestExternal(t, config)
+	ctx := context.Background()
+
+	err := external.OnScan(ctx, &models.VideoFile{Path: "/media/hang.mkv"})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Fatalf("got error %v, want a timeout", err)
+	}
+
+	// The hung plugin was killed, and is restarted
+	if err := external.OnScan(ctx, &models.VideoFile{Path: "/media/ok.mkv"}); err != nil {
+		t.Errorf("the plugin was not restarted: %v", err)
+	}
+
+	// A cancelled call does not kill the plugin
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := external.OnScan(cancelled, &models.VideoFile{Path: "/media/ok.mkv"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNewExternal_Errors(t *testing.T) {
+	if _, err := NewExternal(testPlugin(t, "version")); err == nil || !strings.Contains(err.Error(), "unsupported protocol version 2") {
+		t.Errorf("got error %v, want a version mismatch", err)
+	}
+
+	if _, err := NewExternal(models.Plugin{Name: "missing", Command: "/nonexistent/plugin"}); err == nil {
+		t.Error("expected an error for a missing executable")
+	}
+}
+
+func TestPluginManager_External(t *testing.T) {
+	m, err := NewPluginManager([]models.Plugin{testPlugin(t, "ok")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if _, ok := m.Provider("test"); !ok {
+		t.Error("the provider of the plugin is not found")
+	}
+	if _, ok := m.Provider("tmdb"); ok {
+		t.Error("a plugin provider must be found by its name")
+	}
+
+	files := m.OnScan(context.Background(), []*models.VideoFile{{Path: "/media/a.mkv"}, {Path: "/media/a.sample.mkv"}})
+	if len(files) != 1 {
+		t.Errorf("got %d files, want the sample to be vetoed", len(files))
+	}
+}
+
+func TestCheckConformance(t *testing.T) {
+	tests := []struct {
+		mode   string
+		failed []string
+	}{
+		{mode: "ok"},
+		{mode: "noisy", failed: []string{"standard output"}},
+		{mode: "version", failed: []string{"handshake"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			checks := CheckConformance(context.Background(), testPlugin(t, tt.mode))
+
+			var failed []string
+			for _, check := range checks {
+				if check.Error != "" {
+					failed = append(failed, check.Name)
+				}
+			}
+			if strings.Join(failed, ",") != strings.Join(tt.failed, ",") {
+				t.Errorf("got failed checks %v, want %v: %+v", failed, tt.failed, checks)
+			}
+		})
+	}
+}
+
+// TestCheckConformance_Reference checks the reference plugin of the documentation
+func TestCheckConformance_Reference(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 is not installed")
+	}
+
+	checks := CheckConformance(context.Background(), models.Plugin{
+		Name:    "reference",
+		Command: python,
+		Args:    []string{filepath.Join("..", "..", "docs", "plugins", "reference.py")},
+	})
+	if !Passed(checks) {
+		t.Errorf("the reference plugin does not follow the protocol: %+v", checks)
+	}
+}
@@ -0,0 +1,251 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/internal/services/providers"
+	"goru/internal/services/states"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// PluginManager calls the hooks of the enabled plugins, in the configured order. A nil
+// manager has no plugin.
+type PluginManager struct {
+	plugins []Plugin
+}
+
+// NewPluginManager creates the registered plugins enabled in the configuration
+func NewPluginManager(configs []models.Plugin) (*PluginManager, error) {
+	m := &PluginManager{}
+
+	for _, config := range configs {
+		if !config.IsEnabled() {
+			log.Debug("plugin disabled", zap.String("plugin", config.Name))
+			continue
+		}
+
+		var plugin Plugin
+		var err error
+		if config.Command != "" {
+			plugin, err = NewExternal(config)
+		} else {
+			plugin, err = create(config.Name, config.Options)
+		}
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		m.Add(plugin)
+	}
+
+	return m, nil
+}
+
+// Close shuts the external plugins down
+func (m *PluginManager) Close() {
+	for _, plugin := range m.Plugins() {
+		if closer, ok := plugin.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// ProviderPlugin is implemented by the plugins able to act as a provider, the
+// provider is nil when the plugin is not one
+type ProviderPlugin interface {
+	Provider() providers.Provider
+}
+
+// Provider returns the provider of the enabled plugin with the given name
+func (m *PluginManager) Provider(name string) (providers.Provider, bool) {
+	for _, plugin := range m.Plugins() {
+		if plugin.Name() != name {
+			continue
+		}
+		if providerPlugin, ok := plugin.(ProviderPlugin); ok {
+			if provider := providerPlugin.Provider(); provider != nil {
+				return provider, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Add appends a plugin, its hooks are called after the ones of the previous plugins
+func (m *PluginManager) Add(plugin Plugin) {
+	log.Debug("plugin enabled", zap.String("plugin", plugin.Name()), zap.String("version", plugin.Version()))
+	m.plugins = append(m.plugins, plugin)
+}
+
+// Plugins returns the enabled plugins, in order
+func (m *PluginManager) Plugins() []Plugin {
+	if m == nil {
+		return nil
+	}
+	return m.plugins
+}
+
+// OnScan returns the scanned files that no plugin vetoed
+func (m *PluginManager) OnScan(ctx context.Context, files []*models.VideoFile) []*models.VideoFile {
+	return m.filterFiles("OnScan", files, func(plugin Plugin, file *models.VideoFile) (bool, error) {
+		hook, ok := plugin.(ScanHook)
+		if !ok {
+			return false, nil
+		}
+		return true, hook.OnScan(ctx, file)
+	})
+}
+
+// BeforeMatch calls the hooks before the lookup of a file, an error is a veto
+func (m *PluginManager) BeforeMatch(ctx context.Context, file *models.VideoFile) error {
+	return m.call("BeforeMatch", file.Path, func(plugin Plugin) (bool, error) {
+		hook, ok := plugin.(BeforeMatchHook)
+		if !ok {
+			return false, nil
+		}
+		return true, hook.BeforeMatch(ctx, file)
+	})
+}
+
+// AfterMatch calls the hooks after the lookup of a file, an error is a veto
+func (m *PluginManager) AfterMatch(ctx context.Context, file *models.VideoFile) error {
+	return m.call("AfterMatch", file.Path, func(plugin Plugin) (bool, error) {
+		hook, ok := plugin.(AfterMatchHook)
+		if !ok {
+			return false, nil
+		}
+		return true, hook.AfterMatch(ctx, file)
+	})
+}
+
+// BeforeFormat returns the files to format that no plugin vetoed
+func (m *PluginManager) BeforeFormat(ctx context.Context, files []*models.VideoFile) []*models.VideoFile {
+	return m.filterFiles("BeforeFormat", files, func(plugin Plugin, file *models.VideoFile) (bool, error) {
+		hook, ok := plugin.(BeforeFormatHook)
+		if !ok {
+			return false, nil
+		}
+		return true, hook.BeforeFormat(ctx, file)
+	})
+}
+
+// OnPlan calls the hooks on the created plan, an error fails the plan
+func (m *PluginManager) OnPlan(ctx context.Context, plan *plans.Plan) error {
+	return m.call("OnPlan", plan.ID, func(plugin Plugin) (bool, error) {
+		hook, ok := plugin.(PlanHook)
+		if !ok {
+			return false, nil
+		}
+		return true, hook.OnPlan(ctx, plan)
+	})
+}
+
+// BeforeApply calls the hooks on each applicable change of the plan, all of them before
+// the first rename, the vetoed changes are rejected. The hooks may rewrite the changes: check, if not nil, is run on
+// them afterwards, and the changes it fails are rejected as well.
+func (m *PluginManager) BeforeApply(ctx context.Context, plan *plans.Plan, check func(change *plans.Change) error) {
+	if len(m.Plugins()) == 0 {
+		return
+	}
+
+	for i := range plan.Changes {
+		change := &plan.Changes[i]
+		if !change.IsApplicable() {
+			continue
+		}
+
+		err := m.call("BeforeApply", change.Before.Path, func(plugin Plugin) (bool, error) {
+			hook, ok := plugin.(BeforeApplyHook)
+			if !ok {
+				return false, nil
+			}
+			return true, hook.BeforeApply(ctx, change)
+		})
+		if err != nil {
+			change.Decision = plans.DecisionRejected
+			continue
+		}
+
+		if check != nil {
+			if err := check(change); err != nil {
+				log.Warn("change rewritten by a plugin rejected", zap.String("change_id", change.ID), zap.Error(err))
+				change.Decision = plans.DecisionRejected
+			}
+		}
+	}
+
+	plan.RefreshConflicts()
+}
+
+// AfterApply calls the hooks once the plan is applied, the errors are logged
+func (m *PluginManager) AfterApply(ctx context.Context, plan *plans.Plan, result *plans.ApplyResult) {
+	m.call("AfterApply", plan.ID, func(plugin Plugin) (bool, error) {
+		hook, ok := plugin.(AfterApplyHook)
+		if !ok {
+			return false, nil
+		}
+		return true, hook.AfterApply(ctx, plan, result)
+	})
+}
+
+// OnRevert calls the hooks before a rename is reverted, an error is a veto
+func (m *PluginManager) OnRevert(ctx context.Context, entry *states.StateEntry) error {
+	return m.call("OnRevert", entry.ID, func(plugin Plugin) (bool, error) {
+		hook, ok := plugin.(RevertHook)
+		if !ok {
+			return false, nil
+		}
+		return true, hook.OnRevert(ctx, entry)
+	})
+}
+
+// filterFiles returns the files for which the hook did not fail
+func (m *PluginManager) filterFiles(hook string, files []*models.VideoFile, fn func(Plugin, *models.VideoFile) (bool, error)) []*models.VideoFile {
+	if len(m.Plugins()) == 0 {
+		return files
+	}
+
+	kept := make([]*models.VideoFile, 0, len(files))
+	for _, file := range files {
+		err := m.call(hook, file.Path, func(plugin Plugin) (bool, error) {
+			return fn(plugin, file)
+		})
+		if err == nil {
+			kept = append(kept, file)
+		}
+	}
+
+	return kept
+}
+
+// call runs a hook of each plugin implementing it, and stops at the first error. The
+// function returns false when the plugin does not implement the hook.
+func (m *PluginManager) call(hook, subject string, fn func(Plugin) (bool, error)) error {
+	for _, plugin := range m.Plugins() {
+		implemented, err := fn(plugin)
+		if !implemented || err == nil {
+			continue
+		}
+
+		var veto *VetoError
+		if errors.As(err, &veto) {
+			if veto.Plugin == "" {
+				veto.Plugin = plugin.Name()
+			}
+			log.Debug("plugin vetoed", zap.String("hook", hook), zap.String("plugin", plugin.Name()), zap.String("subject", subject), zap.String("reason", veto.Reason))
+			return err
+		}
+
+		log.Error("plugin hook failed", zap.String("hook", hook), zap.String("plugin", plugin.Name()), zap.String("subject", subject), zap.Error(err))
+		return fmt.Errorf("plugin %s: %w", plugin.Name(), err)
+	}
+
+	return nil
+}
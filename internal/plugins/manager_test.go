@@ -0,0 +1,232 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/internal/services/states"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+// recorder records its hook calls, and vetoes the subjects containing its veto string
+type recorder struct {
+	name  string
+	veto  string
+	calls *[]string
+}
+
+func (r *recorder) Name() string    { return r.name }
+func (r *recorder) Version() string { return "0.0.1" }
+
+func (r *recorder) record(hook, subject string) error {
+	*r.calls = append(*r.calls, r.name+"."+hook)
+	if r.veto != "" && strings.Contains(subject, r.veto) {
+		return Veto("%s is not welcome", subject)
+	}
+	return nil
+}
+
+func (r *recorder) OnScan(ctx context.Context, file *models.VideoFile) error {
+	return r.record("OnScan", file.Path)
+}
+
+func (r *recorder) AfterMatch(ctx context.Context, file *models.VideoFile) error {
+	file.Filename = r.name + "-" + file.Filename
+	return r.record("AfterMatch", file.Path)
+}
+
+func (r *recorder) BeforeApply(ctx context.Context, change *plans.Change) error {
+	return r.record("BeforeApply", change.Before.Path)
+}
+
+func (r *recorder) OnRevert(ctx context.Context, entry *states.StateEntry) error {
+	return r.record("OnRevert", entry.NewPath)
+}
+
+func TestPluginManager_Order(t *testing.T) {
+	var calls []string
+	m := &PluginManager{}
+	m.Add(&recorder{name: "first", calls: &calls})
+	m.Add(&recorder{name: "second", calls: &calls})
+
+	file := &models.VideoFile{Path: "/media/alien.mkv", Filename: "alien.mkv"}
+	if err := m.AfterMatch(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"first.AfterMatch", "second.AfterMatch"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+	if file.Filename != "second-first-alien.mkv" {
+		t.Errorf("got filename %q, the hooks must mutate the file in order", file.Filename)
+	}
+}
+
+func TestPluginManager_Veto(t *testing.T) {
+	var calls []string
+	m := &PluginManager{}
+	m.Add(&recorder{name: "first", veto: "sample", calls: &calls})
+	m.Add(&recorder{name: "second", calls: &calls})
+
+	files := []*models.VideoFile{{Path: "/media/alien.mkv"}, {Path: "/media/alien-sample.mkv"}}
+	kept := m.OnScan(context.Background(), files)
+	if len(kept) != 1 || kept[0].Path != "/media/alien.mkv" {
+		t.Errorf("got files %v, want the sample to be left out", kept)
+	}
+
+	// The next plugins are not called once vetoed
+	if want := []string{"first.OnScan", "second.OnScan", "first.OnScan"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+
+	err := m.OnRevert(context.Background(), &states.StateEntry{NewPath: "/media/sample.mkv"})
+	var veto *VetoError
+	if !errors.Is(err, ErrVetoed) || !errors.As(err, &veto) || veto.Plugin != "first" {
+		t.Errorf("got error %v, want a veto of the first plugin", err)
+	}
+	if err.Error() != "vetoed by first: /media/sample.mkv is not welcome" {
+		t.Errorf("got message %q", err.Error())
+	}
+}
+
+func TestPluginManager_BeforeApply(t *testing.T) {
+	var calls []string
+	m := &PluginManager{}
+	m.Add(&recorder{name: "guard", veto: "keep", calls: &calls})
+
+	plan := &plans.Plan{Changes: []plans.Change{
+		{ID: "1", Action: plans.ActionRename, Before: models.VideoFile{Path: "/media/a.mkv"}},
+		{ID: "2", Action: plans.ActionRename, Before: models.VideoFile{Path: "/media/keep.mkv"}},
+		{ID: "3", Action: plans.ActionNoop, Before: models.VideoFile{Path: "/media/keep-noop.mkv"}},
+	}}
+	m.BeforeApply(context.Background(), plan, nil)
+
+	if len(calls) != 2 {
+		t.Errorf("got calls %v, only the applicable changes must be checked", calls)
+	}
+	if plan.Changes[0].Decision != plans.DecisionPending || plan.Changes[1].Decision != plans.DecisionRejected {
+		t.Errorf("got decisions %q and %q, want the vetoed change to be rejected", plan.Changes[0].Decision, plan.Changes[1].Decision)
+	}
+}
+
+// redirect moves the targets of the changes to another folder
+type redirect struct{ folder string }
+
+func (redirect) Name() string    { return "redirect" }
+func (redirect) Version() string { return "0.0.1" }
+func (r redirect) BeforeApply(ctx context.Context, change *plans.Change) error {
+	change.After.Path = r.folder + "/" + change.After.Filename
+	return nil
+}
+
+func TestPluginManager_BeforeApply_Check(t *testing.T) {
+	m := &PluginManager{}
+	m.Add(redirect{folder: "/etc"})
+
+	plan := &plans.Plan{Changes: []plans.Change{
+		{ID: "1", Action: plans.ActionRename, Before: models.VideoFile{Path: "/media/a.mkv"}, After: models.VideoFile{Path: "/media/A.mkv", Filename: "A.mkv"}},
+	}}
+	m.BeforeApply(context.Background(), plan, func(change *plans.Change) error {
+		if !strings.HasPrefix(change.After.Path, "/media/") {
+			return errors.New("outside of the library")
+		}
+		return nil
+	})
+
+	if change := plan.Changes[0]; change.Decision != plans.DecisionRejected || change.After.Path != "/etc/A.mkv" {
+		t.Errorf("got %+v, want the rewritten change to be rejected", change)
+	}
+}
+
+// failing fails its hook with an error that is not a veto
+type failing struct{}
+
+func (failing) Name() string    { return "failing" }
+func (failing) Version() string { return "0.0.1" }
+func (failing) OnPlan(ctx context.Context, plan *plans.Plan) error {
+	return errors.New("catalog unreachable")
+}
+
+func TestPluginManager_Error(t *testing.T) {
+	m := &PluginManager{}
+	m.Add(failing{})
+
+	err := m.OnPlan(context.Background(), &plans.Plan{ID: "p1"})
+	if err == nil || err.Error() != "plugin failing: catalog unreachable" || errors.Is(err, ErrVetoed) {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestPluginManager_Nil(t *testing.T) {
+	var m *PluginManager
+
+	files := []*models.VideoFile{{Path: "/media/a.mkv"}}
+	if kept := m.OnScan(context.Background(), files); len(kept) != 1 {
+		t.Errorf("got %d files, a nil manager must keep every file", len(kept))
+	}
+	if err := m.BeforeMatch(context.Background(), files[0]); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewPluginManager(t *testing.T) {
+	disabled := false
+
+	m, err := NewPluginManager([]models.Plugin{
+		{Name: "min_confidence", Options: map[string]interface{}{"threshold": "0.5"}},
+		{Name: "ignore", Enabled: &disabled},
+		{Name: "ignore", Options: map[string]interface{}{"patterns": []interface{}{`(?i)sample`}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, plugin := range m.Plugins() {
+		names = append(names, plugin.Name())
+	}
+	if want := []string{"min_confidence", "ignore"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got plugins %v, want %v", names, want)
+	}
+
+	files := m.OnScan(context.Background(), []*models.VideoFile{{Path: "/media/Alien.SAMPLE.mkv"}, {Path: "/media/Alien.mkv"}})
+	if len(files) != 1 {
+		t.Errorf("got %d files, want the sample to be ignored", len(files))
+	}
+
+	file := &models.VideoFile{Path: "/media/Alien.mkv", Confidence: &models.Confidence{Score: 0.4}}
+	if err := m.AfterMatch(context.Background(), file); !errors.Is(err, ErrVetoed) {
+		t.Errorf("got error %v, want the low-confidence match to be vetoed", err)
+	}
+}
+
+func TestNewPluginManager_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config models.Plugin
+		want   string
+	}{
+		{name: "unknown plugin", config: models.Plugin{Name: "cleaner"}, want: `unknown plugin "cleaner"`},
+		{name: "missing option", config: models.Plugin{Name: "ignore"}, want: "patterns is required"},
+		{name: "unknown option", config: models.Plugin{Name: "min_confidence", Options: map[string]interface{}{"threshold": 0.5, "treshold": 0.5}}, want: "treshold"},
+		{name: "invalid pattern", config: models.Plugin{Name: "ignore", Options: map[string]interface{}{"patterns": []string{"("}}}, want: "invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPluginManager([]models.Plugin{tt.config})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
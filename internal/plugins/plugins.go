@@ -1,12 +1,90 @@
 package plugins
 
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/internal/services/states"
+)
+
+// Plugin extends goru by implementing any of the hook interfaces below. The hooks
+// receive pointers and may mutate them. A hook returning an error vetoes the
+// operation, Veto gives the reason without logging it as a failure.
 type Plugin interface {
 	Name() string
 	Version() string
-	//PreProcess(file *models.VideoFile) error
-	//PostProcess(result *models.RenameResult) error
 }
 
-type PluginManager struct {
-	plugins []Plugin
+// ScanHook is called for each file found by a scan. A veto leaves the file out of the plan.
+type ScanHook interface {
+	OnScan(ctx context.Context, file *models.VideoFile) error
+}
+
+// BeforeMatchHook is called before the metadata lookup of a file. A veto leaves the
+// file out of the plan.
+type BeforeMatchHook interface {
+	BeforeMatch(ctx context.Context, file *models.VideoFile) error
+}
+
+// AfterMatchHook is called after the metadata lookup of a file, even a failed one.
+// It may fix the metadata, a veto leaves the file out of the plan.
+type AfterMatchHook interface {
+	AfterMatch(ctx context.Context, file *models.VideoFile) error
+}
+
+// BeforeFormatHook is called before the target name of a file is formatted. A veto
+// leaves the file out of the plan.
+type BeforeFormatHook interface {
+	BeforeFormat(ctx context.Context, file *models.VideoFile) error
+}
+
+// PlanHook is called once the plan is created. It may edit the changes, a veto
+// fails the plan.
+type PlanHook interface {
+	OnPlan(ctx context.Context, plan *plans.Plan) error
+}
+
+// BeforeApplyHook is called on each change to apply, once for the whole plan before any
+// rename is made. It may change the target, a veto rejects the change.
+type BeforeApplyHook interface {
+	BeforeApply(ctx context.Context, change *plans.Change) error
+}
+
+// AfterApplyHook is called once the plan is applied. The renames are done, an error
+// is only logged.
+type AfterApplyHook interface {
+	AfterApply(ctx context.Context, plan *plans.Plan, result *plans.ApplyResult) error
+}
+
+// RevertHook is called before a rename is reverted. A veto keeps the rename.
+type RevertHook interface {
+	OnRevert(ctx context.Context, entry *states.StateEntry) error
+}
+
+// ErrVetoed is matched by the vetoes
+var ErrVetoed = errors.New("vetoed")
+
+// VetoError is the veto of an operation by a plugin
+type VetoError struct {
+	Plugin string
+	Reason string
+}
+
+func (e *VetoError) Error() string {
+	if e.Plugin == "" {
+		return "vetoed: " + e.Reason
+	}
+	return fmt.Sprintf("vetoed by %s: %s", e.Plugin, e.Reason)
+}
+
+func (e *VetoError) Is(target error) bool {
+	return target == ErrVetoed
+}
+
+// Veto returns the error vetoing an operation for the given reason
+func Veto(format string, args ...interface{}) error {
+	return &VetoError{Reason: fmt.Sprintf(format, args...)}
 }
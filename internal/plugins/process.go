@@ -0,0 +1,348 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultTimeout bounds the calls to an external plugin
+	DefaultTimeout = 10 * time.Second
+
+	// maxRestarts is the number of times a crashed plugin is restarted
+	maxRestarts = 3
+
+	// shutdownTimeout bounds the shutdown of a plugin before it is killed
+	shutdownTimeout = 2 * time.Second
+
+	// maxMessageSize bounds a message sent by a plugin
+	maxMessageSize = 16 << 20
+)
+
+var (
+	// ErrPluginExited is returned by the calls pending when a plugin exits
+	ErrPluginExited = errors.New("plugin exited")
+
+	// ErrPluginUnavailable is returned once a plugin crashed too many times
+	ErrPluginUnavailable = errors.New("plugin crashed too many times, it is disabled")
+)
+
+// process is an external plugin process, restarted when it crashes. The calls are
+// multiplexed on its standard input and output.
+type process struct {
+	name    string
+	command string
+	args    []string
+	timeout time.Duration
+
+	// initialize is the handshake of each start
+	initialize InitializeParams
+	info       InitializeResult
+
+	// startMux serializes the starts and their handshake
+	startMux sync.Mutex
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	done     chan struct{}
+	pending  map[int64]chan rpcResponse
+	nextID   int64
+	starts   int
+	closed   bool
+	writeMux sync.Mutex
+
+	// invalidMessages counts the lines of the standard output that are not responses
+	invalidMessages atomic.Int64
+}
+
+func newProcess(name, command string, args []string, timeout time.Duration, initialize InitializeParams) *process {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &process{
+		name:       name,
+		command:    command,
+		args:       args,
+		timeout:    timeout,
+		initialize: initialize,
+	}
+}
+
+// running returns the channel closed when the process exits, starting it when needed
+func (p *process) running(ctx context.Context) (chan struct{}, error) {
+	p.startMux.Lock()
+	defer p.startMux.Unlock()
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPluginExited
+	}
+	if p.done != nil {
+		select {
+		case <-p.done:
+		default:
+			done := p.done
+			p.mu.Unlock()
+			return done, nil
+		}
+	}
+	if p.starts > maxRestarts {
+		p.mu.Unlock()
+		return nil, ErrPluginUnavailable
+	}
+	p.starts++
+	restart := p.starts > 1
+
+	done, err := p.start()
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if restart {
+		log.Warn("restarting plugin", zap.String("plugin", p.name), zap.Int("restart", p.starts-1))
+	}
+
+	var info InitializeResult
+	if err := p.send(ctx, done, MethodInitialize, p.initialize, &info); err != nil {
+		p.kill()
+		return nil, fmt.Errorf("handshake failed: %w", err)
+	}
+	if err := info.validate(); err != nil {
+		p.kill()
+		return nil, fmt.Errorf("invalid handshake: %w", err)
+	}
+
+	p.mu.Lock()
+	p.info = info
+	p.mu.Unlock()
+
+	return done, nil
+}
+
+// start launches the process, p.mu is held
+func (p *process) start() (chan struct{}, error) {
+	cmd := exec.Command(p.command, p.args...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", p.command, err)
+	}
+
+	done := make(chan struct{})
+	p.cmd = cmd
+	p.stdin = stdin
+	p.done = done
+	p.pending = make(map[int64]chan rpcResponse)
+
+	go p.logStderr(stderr)
+	go p.readResponses(cmd, stdout, done)
+
+	return done, nil
+}
+
+// readResponses dispatches the responses until the process exits
+func (p *process) readResponses(cmd *exec.Cmd, stdout io.Reader, done chan struct{}) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	for scanner.Scan() {
+		var resp rpcResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil || resp.ID == nil {
+			p.invalidMessages.Add(1)
+			log.Warn("invalid message from plugin", zap.String("plugin", p.name), zap.ByteString("message", scanner.Bytes()))
+			continue
+		}
+
+		p.mu.Lock()
+		ch, ok := p.pending[*resp.ID]
+		delete(p.pending, *resp.ID)
+		p.mu.Unlock()
+
+		if !ok {
+			log.Warn("unexpected response from plugin", zap.String("plugin", p.name), zap.Int64("id", *resp.ID))
+			continue
+		}
+		ch <- resp
+	}
+
+	err := cmd.Wait()
+
+	p.mu.Lock()
+	p.pending = nil
+	closed := p.closed
+	p.mu.Unlock()
+	close(done)
+
+	if !closed {
+		log.Error("plugin exited", zap.String("plugin", p.name), zap.Error(err))
+	}
+}
+
+// logStderr logs the standard error of the process
+func (p *process) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Info("plugin output", zap.String("plugin", p.name), zap.String("line", scanner.Text()))
+	}
+}
+
+// call calls a method of the plugin, starting it when needed
+func (p *process) call(ctx context.Context, method string, params, result interface{}) error {
+	done, err := p.running(ctx)
+	if err != nil {
+		return err
+	}
+	return p.send(ctx, done, method, params, result)
+}
+
+// send sends a request and waits for its response. A plugin not answering in time is
+// killed, and restarted by the next call.
+func (p *process) send(ctx context.Context, done chan struct{}, method string, params, result interface{}) error {
+	callCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	ch := make(chan rpcResponse, 1)
+
+	p.mu.Lock()
+	if p.pending == nil {
+		p.mu.Unlock()
+		return ErrPluginExited
+	}
+	p.nextID++
+	id := p.nextID
+	p.pending[id] = ch
+	stdin := p.stdin
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		if p.pending != nil {
+			delete(p.pending, id)
+		}
+		p.mu.Unlock()
+	}()
+
+	message, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	p.writeMux.Lock()
+	_, err = stdin.Write(append(message, '\n'))
+	p.writeMux.Unlock()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPluginExited, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil {
+			if len(resp.Result) == 0 {
+				return fmt.Errorf("the response to %s has neither a result nor an error", method)
+			}
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("invalid result of %s: %w", method, err)
+			}
+		}
+		return nil
+
+	case <-done:
+		return ErrPluginExited
+
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Error("plugin timed out, killing it", zap.String("plugin", p.name), zap.String("method", method), zap.Duration("timeout", p.timeout))
+		p.kill()
+		<-done
+		return fmt.Errorf("%s timed out after %s", method, p.timeout)
+	}
+}
+
+// kill kills the running process
+func (p *process) kill() {
+	p.mu.Lock()
+	cmd := p.cmd
+	p.mu.Unlock()
+
+	if cmd != nil && cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+}
+
+// close asks the plugin to shut down, closes its input and kills it if it does not
+// exit. An error is returned when the plugin had to be killed.
+func (p *process) close() error {
+	p.mu.Lock()
+	done := p.done
+	stdin := p.stdin
+	p.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	var err error
+
+	select {
+	case <-done:
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		p.send(ctx, done, MethodShutdown, nil, nil)
+
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		stdin.Close()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			log.Warn("plugin did not exit, killing it", zap.String("plugin", p.name))
+			err = fmt.Errorf("plugin did not exit within %s of the shutdown", shutdownTimeout)
+			p.kill()
+			<-done
+		}
+	}
+
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	return err
+}
@@ -0,0 +1,244 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/internal/services/states"
+)
+
+// The external plugins are executables speaking JSON-RPC 2.0 on their standard input
+// and output, one message per line. Their standard error is logged.
+
+// ProtocolVersion is the version of the protocol spoken with the external plugins
+const ProtocolVersion = 1
+
+// Methods called on the external plugins
+const (
+	MethodInitialize = "initialize"
+	MethodShutdown   = "shutdown"
+	MethodParse      = "parse"
+
+	MethodProvide       = "provider/provide"
+	MethodGetMovie      = "provider/get_movie"
+	MethodGetMovieByID  = "provider/get_movie_by_id"
+	MethodSearchMovies  = "provider/search_movies"
+	MethodGetTVShow     = "provider/get_tvshow"
+	MethodGetTVShowByID = "provider/get_tvshow_by_id"
+	MethodSearchTVShows = "provider/search_tvshows"
+	MethodGetEpisode    = "provider/get_episode"
+	MethodListEpisodes  = "provider/list_episodes"
+	hookMethodPrefix    = "hooks/"
+)
+
+// Capabilities declared by the external plugins in the handshake
+const (
+	CapabilityProvider = "provider"
+	CapabilityParser   = "parser"
+	CapabilityHooks    = "hooks"
+)
+
+// Hooks declared by the external plugins, called with the hooks/<hook> methods
+const (
+	HookOnScan       = "on_scan"
+	HookBeforeMatch  = "before_match"
+	HookAfterMatch   = "after_match"
+	HookBeforeFormat = "before_format"
+	HookOnPlan       = "on_plan"
+	HookBeforeApply  = "before_apply"
+	HookAfterApply   = "after_apply"
+	HookOnRevert     = "on_revert"
+)
+
+var (
+	capabilities = []string{CapabilityProvider, CapabilityParser, CapabilityHooks}
+	hooks        = []string{HookOnScan, HookBeforeMatch, HookAfterMatch, HookBeforeFormat, HookOnPlan, HookBeforeApply, HookAfterApply, HookOnRevert}
+)
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// rpcRequest is a JSON-RPC request, or a notification without ID
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC response
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is an error returned by an external plugin
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// InitializeParams are sent by goru in the handshake
+type InitializeParams struct {
+	ProtocolVersion int                    `json:"protocol_version"`
+	Options         map[string]interface{} `json:"options"`
+}
+
+// InitializeResult is the answer of the plugin to the handshake
+type InitializeResult struct {
+	Name            string   `json:"name"`
+	Version         string   `json:"version"`
+	ProtocolVersion int      `json:"protocol_version"`
+	Capabilities    []string `json:"capabilities"`
+
+	// Hooks are the hooks handled by the plugin, with the hooks capability
+	Hooks []string `json:"hooks,omitempty"`
+}
+
+// validate checks the handshake of a plugin
+func (r InitializeResult) validate() error {
+	if r.ProtocolVersion != ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %d, goru speaks version %d", r.ProtocolVersion, ProtocolVersion)
+	}
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	for _, capability := range r.Capabilities {
+		if !contains(capabilities, capability) {
+			return fmt.Errorf("unknown capability %q", capability)
+		}
+	}
+	for _, hook := range r.Hooks {
+		if !contains(hooks, hook) {
+			return fmt.Errorf("unknown hook %q", hook)
+		}
+	}
+	if len(r.Hooks) > 0 && !contains(r.Capabilities, CapabilityHooks) {
+		return fmt.Errorf("hooks are declared without the %q capability", CapabilityHooks)
+	}
+	return nil
+}
+
+// ParseParams ask a parser to parse a filename
+type ParseParams struct {
+	Path     string `json:"path"`
+	Filename string `json:"filename"`
+}
+
+// ParseResult is a parsed filename. The media type is movie, tvshow or anime, empty
+// to keep the guess of goru.
+type ParseResult struct {
+	MediaType string `json:"media_type,omitempty"`
+	models.Query
+}
+
+// mediaTypes are the media types of the parse results
+var mediaTypes = map[string]models.MediaType{
+	"movie":  models.MediaTypeMovie,
+	"tvshow": models.MediaTypeTVShow,
+	"anime":  models.MediaTypeAnime,
+}
+
+// FileParams carry a file to a provider or a file hook
+type FileParams struct {
+	File *models.VideoFile `json:"file"`
+}
+
+// ProvideResult is the metadata found by a provider. Movie is set for the movies,
+// Episode for the episodes.
+type ProvideResult struct {
+	Movie       *models.Movie      `json:"movie,omitempty"`
+	Episode     *models.Episode    `json:"episode,omitempty"`
+	ExternalIDs models.ExternalIDs `json:"external_ids"`
+	Confidence  *models.Confidence `json:"confidence,omitempty"`
+}
+
+// SearchParams are the parameters of the provider/get_* and provider/search_* methods
+type SearchParams struct {
+	Title   string `json:"title,omitempty"`
+	Year    int    `json:"year,omitempty"`
+	ID      string `json:"id,omitempty"`
+	ShowID  int    `json:"show_id,omitempty"`
+	Season  int    `json:"season,omitempty"`
+	Episode int    `json:"episode,omitempty"`
+}
+
+// HookParams carry the subject of a hook. Only the fields of the hook are set.
+type HookParams struct {
+	File   *models.VideoFile  `json:"file,omitempty"`
+	Plan   *plans.Plan        `json:"plan,omitempty"`
+	Change *plans.Change      `json:"change,omitempty"`
+	Result *plans.ApplyResult `json:"result,omitempty"`
+	Entry  *states.StateEntry `json:"entry,omitempty"`
+}
+
+// HookResult is the answer of a hook. A non-empty veto vetoes the operation, the
+// returned subjects replace the sent ones.
+type HookResult struct {
+	Veto   string             `json:"veto,omitempty"`
+	File   json.RawMessage    `json:"file,omitempty"`
+	Plan   *plans.Plan        `json:"plan,omitempty"`
+	Change *plans.Change      `json:"change,omitempty"`
+	Result *plans.ApplyResult `json:"result,omitempty"`
+	Entry  *states.StateEntry `json:"entry,omitempty"`
+}
+
+// decodeFile decodes a file returned by a plugin into the file. The metadata is
+// decoded as a movie or an episode, depending on the media type.
+func decodeFile(data json.RawMessage, file *models.VideoFile) error {
+	var decoded struct {
+		models.VideoFile
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	updated := decoded.VideoFile
+	updated.Metadata = nil
+	if len(decoded.Metadata) > 0 && string(decoded.Metadata) != "null" {
+		switch updated.MediaType {
+		case models.MediaTypeMovie:
+			var movie models.Movie
+			if err := json.Unmarshal(decoded.Metadata, &movie); err != nil {
+				return fmt.Errorf("invalid movie: %w", err)
+			}
+			updated.Metadata = &movie
+		case models.MediaTypeTVShow, models.MediaTypeAnime:
+			var episode models.Episode
+			if err := json.Unmarshal(decoded.Metadata, &episode); err != nil {
+				return fmt.Errorf("invalid episode: %w", err)
+			}
+			updated.Metadata = &episode
+		default:
+			updated.Metadata = file.Metadata
+		}
+	}
+
+	*file = updated
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package plugins
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory creates a plugin from its options in the configuration
+type Factory func(options map[string]interface{}) (Plugin, error)
+
+var (
+	registryMux sync.RWMutex
+	registry    = make(map[string]Factory)
+)
+
+// Register makes a built-in plugin available to the configuration. It panics when
+// the name is already registered, it is meant to be called from init.
+func Register(name string, factory Factory) {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+
+	if factory == nil {
+		panic("plugins: Register factory is nil")
+	}
+	if _, ok := registry[name]; ok {
+		panic("plugins: Register called twice for plugin " + name)
+	}
+	registry[name] = factory
+}
+
+// Registered returns the names of the registered plugins, sorted
+func Registered() []string {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// create creates a registered plugin
+func create(name string, options map[string]interface{}) (Plugin, error) {
+	registryMux.RLock()
+	factory, ok := registry[name]
+	registryMux.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown plugin %q, registered plugins are %v", name, Registered())
+	}
+
+	plugin, err := factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plugin %q: %w", name, err)
+	}
+
+	return plugin, nil
+}
@@ -0,0 +1,229 @@
+// Package audit checks the library for problems without renaming anything. The fix
+// suggested for each finding is a change of a plan, applied like the renames.
+package audit
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"goru/internal/services/plans"
+)
+
+// Kind is the kind of a problem
+type Kind string
+
+const (
+	// KindUnmatched is a video that no provider matched
+	KindUnmatched Kind = "unmatched"
+
+	// KindMisnamed is a video whose name does not follow the format
+	KindMisnamed Kind = "misnamed"
+
+	// KindBrokenSymlink is a link to a file that does not exist anymore
+	KindBrokenSymlink Kind = "broken_symlink"
+
+	// KindEmpty is a video of 0 bytes
+	KindEmpty Kind = "empty"
+
+	// KindTruncated is a video cut before the end declared by its container
+	KindTruncated Kind = "truncated"
+
+	// KindSample is a sample left by a release, the trailers and the extras being left
+	// out of the audit
+	KindSample Kind = "sample"
+
+	// KindOrphanSubtitle is a subtitle named after no video of its folder
+	KindOrphanSubtitle Kind = "orphan_subtitle"
+
+	// KindJunk is a file that is not media, such as a .txt, an .exe or an orphan .nfo
+	KindJunk Kind = "junk"
+
+	// KindUnreadable is a folder or a file that cannot be read, left out of the audit
+	KindUnreadable Kind = "unreadable"
+)
+
+// Kinds are the kinds of problems, in the order of the report
+var Kinds = []Kind{
+	KindUnmatched,
+	KindMisnamed,
+	KindBrokenSymlink,
+	KindEmpty,
+	KindTruncated,
+	KindSample,
+	KindOrphanSubtitle,
+	KindJunk,
+	KindUnreadable,
+}
+
+// ParseKind parses the kind of a problem
+func ParseKind(s string) (Kind, error) {
+	for _, kind := range Kinds {
+		if string(kind) == s {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unknown kind of problem %q", s)
+}
+
+// Finding is a problem found in the library
+type Finding struct {
+	Kind    Kind   `json:"kind"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+
+	// Fix describes the suggested fix
+	Fix string `json:"fix"`
+
+	// ChangeID is the change of the plan fixing the problem, empty when it has to be
+	// fixed by hand
+	ChangeID string `json:"change_id,omitempty"`
+
+	// target is the path the file is moved to, by the fix found on disk
+	target string
+}
+
+// Report lists the problems of the library and the plan fixing them
+type Report struct {
+	Findings []Finding `json:"findings"`
+
+	// Plan holds a change per fix, the renames of the matched videos following the
+	// format being noop changes
+	Plan *plans.Plan `json:"plan"`
+}
+
+// Fixes returns the number of findings fixed by the plan
+func (r *Report) Fixes() int {
+	count := 0
+	for _, finding := range r.Findings {
+		if finding.ChangeID != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// Select rejects the changes fixing the findings for which keep returns false, the
+// other ones are accepted
+func (r *Report) Select(keep func(Finding) bool) error {
+	for _, finding := range r.Findings {
+		if finding.ChangeID == "" {
+			continue
+		}
+
+		decision := plans.DecisionAccepted
+		if !keep(finding) {
+			decision = plans.DecisionRejected
+		}
+		if err := r.Plan.SetDecision(finding.ChangeID, decision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewReport lists the problems found on disk and in a plan of the matched videos of
+// the scans. The plan is given the changes fixing the problems.
+func NewReport(plan *plans.Plan, scans ...*Scan) *Report {
+	report := &Report{Findings: []Finding{}, Plan: plan}
+
+	// The paths of the videos of each folder, as they will be once the plan is applied
+	videos := make(map[string][]string)
+
+	for _, planError := range plan.Errors {
+		report.Findings = append(report.Findings, Finding{
+			Kind:    KindUnmatched,
+			Path:    planError.File,
+			Message: planError.Message,
+			Fix:     "check its name, or add a .nfo file with its IDs",
+		})
+
+		dir := filepath.Dir(planError.File)
+		videos[dir] = append(videos[dir], planError.File)
+	}
+	for _, change := range plan.Changes {
+		switch change.Action {
+		case plans.ActionRename:
+			report.Findings = append(report.Findings, Finding{
+				Kind:     KindMisnamed,
+				Path:     change.Before.Path,
+				Message:  "the name does not follow the format",
+				Fix:      "rename it after the format",
+				ChangeID: change.ID,
+			})
+			fallthrough
+		case plans.ActionNoop, plans.ActionSkip:
+			dir := filepath.Dir(change.Before.Path)
+			videos[dir] = append(videos[dir], change.After.Path)
+		}
+	}
+
+	for _, scan := range scans {
+		for _, finding := range scan.Findings {
+			if finding.target != "" {
+				finding.ChangeID = plan.AddRename(finding.Path, finding.target)
+			}
+			report.Findings = append(report.Findings, finding)
+		}
+
+		for _, path := range scan.subtitles {
+			dir := filepath.Dir(path)
+			if namedAfter(path, scan.videos[dir]) {
+				continue
+			}
+
+			finding := Finding{
+				Kind:    KindOrphanSubtitle,
+				Path:    path,
+				Message: "no video of the folder has its name",
+			}
+			// The subtitle of the only video of its folder is named after it, and follows
+			// it when it is moved
+			if paths := videos[dir]; len(paths) == 1 {
+				target := strings.TrimSuffix(paths[0], filepath.Ext(paths[0])) + subtitleSuffix(path)
+				finding.Fix = "rename it after the video"
+				finding.ChangeID = plan.AddRename(path, target)
+			} else {
+				finding.Fix = "move it to the quarantine"
+				finding.ChangeID = plan.AddRename(path, scan.quarantine(path))
+			}
+			report.Findings = append(report.Findings, finding)
+		}
+	}
+
+	order := make(map[Kind]int, len(Kinds))
+	for i, kind := range Kinds {
+		order[kind] = i
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Kind != b.Kind {
+			return order[a.Kind] < order[b.Kind]
+		}
+		return a.Path < b.Path
+	})
+
+	plan.RefreshConflicts()
+	return report
+}
+
+// suffixPattern matches the language, the flags and the extension of a subtitle named
+// after its video, such as the .fr.forced.srt of Movie (1999).fr.forced.srt
+var suffixPattern = regexp.MustCompile(`(?:\.(?:[a-z]{2,3}(?:-[A-Za-z]{2})?|(?i:forced|sdh|cc|hi)))*\.[A-Za-z0-9]+$`)
+
+func subtitleSuffix(path string) string {
+	return suffixPattern.FindString(filepath.Base(path))
+}
+
+// namedAfter returns true when a file is named after one of the videos, given by name
+func namedAfter(path string, videos []string) bool {
+	name := filepath.Base(path)
+	for _, video := range videos {
+		if strings.HasPrefix(name, strings.TrimSuffix(video, filepath.Ext(video))+".") {
+			return true
+		}
+	}
+	return false
+}
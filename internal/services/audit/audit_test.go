@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "Inception", "inception.2010.1080p.mkv"), 64)
+	writeFile(t, filepath.Join(root, "Inception", "inception.2010.1080p.en.srt"), 8)
+	writeFile(t, filepath.Join(root, "Inception", "Old.Release.fr.forced.srt"), 8)
+	writeFile(t, filepath.Join(root, "Inception", "RARBG.txt"), 8)
+	writeFile(t, filepath.Join(root, "Inception", "RARBG.com.mp4"), 64)
+	writeFile(t, filepath.Join(root, "Inception", "inception.2010.1080p.nfo"), 8)
+	writeFile(t, filepath.Join(root, "Inception", "Sample", "inception.sample.mkv"), 64)
+	writeFile(t, filepath.Join(root, "Inception", "Inception (2010)-trailer.mkv"), 64)
+	writeFile(t, filepath.Join(root, "Matrix", "The.Matrix.1999.mkv"), 0)
+	writeFile(t, filepath.Join(root, "Matrix", "tvshow.nfo"), 8)
+	writeFile(t, filepath.Join(root, "Matrix", "old.nfo"), 8)
+	writeFile(t, filepath.Join(root, DefaultQuarantine, "junk.exe"), 8)
+	if err := os.Symlink(filepath.Join(root, "nowhere.mkv"), filepath.Join(root, "link.mkv")); err != nil {
+		t.Fatal(err)
+	}
+
+	scan, err := ScanDirectory(models.Directory{Path: root, Recursive: true}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The trailer is an extra, left out of the audit
+	if len(scan.Videos) != 1 {
+		t.Fatalf("got videos %+v", scan.Videos)
+	}
+
+	plan := plans.NewEmptyPlan()
+	for _, video := range scan.Videos {
+		plan.AddRename(video.Path, filepath.Join(filepath.Dir(video.Path), "Inception (2010).mkv"))
+	}
+
+	report := NewReport(plan, scan)
+
+	quarantine := filepath.Join(root, DefaultQuarantine)
+	want := []struct {
+		kind   Kind
+		path   string
+		target string
+	}{
+		{KindMisnamed, "Inception/inception.2010.1080p.mkv", filepath.Join(root, "Inception", "Inception (2010).mkv")},
+		{KindBrokenSymlink, "link.mkv", filepath.Join(quarantine, "link.mkv")},
+		{KindEmpty, "Matrix/The.Matrix.1999.mkv", filepath.Join(quarantine, "Matrix", "The.Matrix.1999.mkv")},
+		{KindSample, "Inception/Sample/inception.sample.mkv", filepath.Join(quarantine, "Inception", "Sample", "inception.sample.mkv")},
+		{KindOrphanSubtitle, "Inception/Old.Release.fr.forced.srt", filepath.Join(root, "Inception", "Inception (2010).fr.forced.srt")},
+		{KindJunk, "Inception/RARBG.com.mp4", filepath.Join(quarantine, "Inception", "RARBG.com.mp4")},
+		{KindJunk, "Inception/RARBG.txt", filepath.Join(quarantine, "Inception", "RARBG.txt")},
+		{KindJunk, "Matrix/old.nfo", filepath.Join(quarantine, "Matrix", "old.nfo")},
+	}
+	if len(report.Findings) != len(want) {
+		t.Fatalf("got findings %+v", report.Findings)
+	}
+	for i, w := range want {
+		finding := report.Findings[i]
+		if finding.Kind != w.kind || finding.Path != filepath.Join(root, w.path) {
+			t.Errorf("finding %d: got %s %s, want %s %s", i, finding.Kind, finding.Path, w.kind, w.path)
+			continue
+		}
+
+		change, err := plan.GetChange(finding.ChangeID)
+		switch {
+		case w.target == "" && err == nil:
+			t.Errorf("finding %d: got a fix for %s", i, finding.Path)
+		case w.target != "" && (err != nil || change.After.Path != w.target):
+			t.Errorf("finding %d: got fix %+v, want %s", i, change, w.target)
+		}
+	}
+
+	// Only the chosen fixes are applied
+	err = report.Select(func(finding Finding) bool { return finding.Kind == KindJunk })
+	if err != nil {
+		t.Fatal(err)
+	}
+	applicable := 0
+	for _, change := range plan.Changes {
+		if change.IsApplicable() {
+			applicable++
+		}
+	}
+	if applicable != 3 {
+		t.Errorf("got %d applicable fixes, want 3", applicable)
+	}
+}
+
+func TestNewReport_OrphanSubtitle(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{
+			name:   "renamed",
+			target: "Show - S01E01 - Pilot.mkv",
+			want:   "Show - S01E01 - Pilot.en.srt",
+		},
+		{
+			name:   "moved",
+			target: filepath.Join("Show", "Season 01", "Show - S01E01 - Pilot.mkv"),
+			want:   filepath.Join("Show", "Season 01", "Show - S01E01 - Pilot.en.srt"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, "Show.S01E01.720p.mkv"), 64)
+			writeFile(t, filepath.Join(root, "Show.S01E01.HDTV.en.srt"), 8)
+
+			scan, err := ScanDirectory(models.Directory{Path: root}, Options{Quarantine: "/quarantine"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The subtitle is named after the only video of its folder, once renamed
+			plan := plans.NewEmptyPlan()
+			plan.AddRename(scan.Videos[0].Path, filepath.Join(root, tt.target))
+			report := NewReport(plan, scan)
+
+			if len(report.Findings) != 2 || report.Findings[1].Kind != KindOrphanSubtitle {
+				t.Fatalf("got findings %+v", report.Findings)
+			}
+			change, err := plan.GetChange(report.Findings[1].ChangeID)
+			if err != nil || change.After.Path != filepath.Join(root, tt.want) {
+				t.Errorf("got fix %+v, %v", change, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/files"
+	"goru/internal/services/mediainfo"
+)
+
+// DefaultQuarantine is the folder of each directory the problematic files are moved to
+const DefaultQuarantine = ".goru-quarantine"
+
+// minBitrate is the bitrate under which a video is too small for its duration, in bits
+// per second
+const minBitrate = 100_000
+
+var (
+	// sidecarNFO matches the .nfo files describing a folder rather than a video
+	sidecarNFO = regexp.MustCompile(`(?i)^(?:tvshow|season|movie)\.nfo$`)
+)
+
+// subtitleExtensions are the extensions of the subtitle files
+var subtitleExtensions = map[string]bool{
+	".srt": true, ".ass": true, ".ssa": true, ".vtt": true, ".sub": true, ".idx": true, ".sup": true,
+}
+
+// junkExtensions are the extensions of the files left by the downloads
+var junkExtensions = map[string]bool{
+	".txt": true, ".exe": true, ".com": true, ".bat": true, ".cmd": true, ".scr": true,
+	".lnk": true, ".url": true, ".sfv": true, ".md5": true, ".torrent": true, ".nzb": true,
+	".par2": true, ".part": true, ".crdownload": true, ".!qb": true,
+}
+
+// junkFiles are the metadata files of the file managers
+var junkFiles = map[string]bool{
+	".ds_store": true, "thumbs.db": true, "desktop.ini": true,
+}
+
+// Options are the options of the scans
+type Options struct {
+	// Quarantine is the folder the problematic files are moved to, the files of each
+	// directory in a folder named after it. By default, they are moved to the
+	// DefaultQuarantine folder of their directory.
+	Quarantine string
+
+	// Filters select the files of every directory, on top of the filters of each
+	// directory. The excluded files are not audited, the videos not included are not
+	// matched.
+	Filters models.Filters
+}
+
+// Scan is a directory of the library, sorted out
+type Scan struct {
+	// Videos are the videos to match
+	Videos []*models.VideoFile
+
+	// Findings are the problems found on disk
+	Findings []Finding
+
+	root           string
+	quarantineRoot string
+	classifier     *files.Classifier
+	filter         *files.Filter
+
+	// videos are the names of the videos of each folder, and subtitles the subtitle
+	// files, checked once the videos are matched
+	videos    map[string][]string
+	subtitles []string
+}
+
+// ScanDirectory walks a directory, lists its videos to match and the problems found
+// on disk. The quarantine is not walked.
+func ScanDirectory(directory models.Directory, options Options) (*Scan, error) {
+	classifier, err := files.NewClassifier(directory.Extras)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := files.NewFilter(options.Filters, directory.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Clean(directory.Path)
+	scan := &Scan{
+		root:           root,
+		quarantineRoot: filepath.Join(root, DefaultQuarantine),
+		classifier:     classifier,
+		filter:         filter,
+		videos:         make(map[string][]string),
+	}
+	if options.Quarantine != "" {
+		scan.quarantineRoot = filepath.Join(options.Quarantine, filepath.Base(root))
+	}
+
+	var nfos []string
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		switch {
+		case err != nil && path == root:
+			return err
+		case err != nil:
+			// The unreadable folders are left out, with what they hold
+			scan.Findings = append(scan.Findings, Finding{Kind: KindUnreadable, Path: path, Message: err.Error(), Fix: "check its permissions"})
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel := scan.rel(path)
+		if entry.IsDir() {
+			switch {
+			case path == root:
+				return nil
+			case !directory.Recursive, path == scan.quarantineRoot, strings.HasPrefix(entry.Name(), "."), strings.HasPrefix(entry.Name(), "@"):
+				return filepath.SkipDir
+			case directory.MaxDepth > 0 && strings.Count(rel, "/") >= directory.MaxDepth, filter.Excluded(rel):
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filter.Excluded(rel) {
+			return nil
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if entry.Type()&fs.ModeSymlink == 0 {
+				return err
+			}
+			target, _ := os.Readlink(path)
+			scan.add(KindBrokenSymlink, path, fmt.Sprintf("the link to %s is broken", target))
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		name := entry.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		switch {
+		case junkFiles[strings.ToLower(name)], strings.HasPrefix(name, "._"):
+			scan.add(KindJunk, path, "metadata file of a file manager")
+		case junkExtensions[ext]:
+			scan.add(KindJunk, path, fmt.Sprintf("%s file", ext))
+		case ext == ".nfo":
+			nfos = append(nfos, path)
+		case subtitleExtensions[ext]:
+			scan.subtitles = append(scan.subtitles, path)
+		case models.IsSupportedExtension(ext) && filter.Match(rel):
+			scan.addVideo(directory, path, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The .nfo files describe a video, or the folder of a show or a movie
+	for _, path := range nfos {
+		dir := filepath.Dir(path)
+		if sidecarNFO.MatchString(filepath.Base(path)) || namedAfter(path, scan.videos[dir]) {
+			continue
+		}
+		scan.add(KindJunk, path, "no video of the folder has its name")
+	}
+
+	return scan, nil
+}
+
+// addVideo sorts a video out. The extras are not matched.
+func (s *Scan) addVideo(directory models.Directory, path string, info fs.FileInfo) {
+	if info.Size() == 0 {
+		s.add(KindEmpty, path, "the file is empty")
+		return
+	}
+
+	var kind string
+	if directory.Extras.Action != models.ExtrasKeep {
+		kind = s.classifier.Classify(s.root, path)
+	}
+	switch kind {
+	case models.ExtraSample:
+		s.add(KindSample, path, "the file is named as a sample, or is in a sample folder")
+		return
+	case models.ExtraJunk:
+		s.add(KindJunk, path, "advertisement of a release")
+		return
+	}
+
+	dir := filepath.Dir(path)
+	s.videos[dir] = append(s.videos[dir], info.Name())
+
+	media, err := mediainfo.Probe(path)
+	switch {
+	case errors.Is(err, mediainfo.ErrTruncated), errors.Is(err, io.ErrUnexpectedEOF):
+		s.add(KindTruncated, path, "the file is cut before its metadata")
+		return
+	case err != nil:
+		// The other containers cannot be checked
+	case media.Truncated:
+		s.add(KindTruncated, path, fmt.Sprintf("the file ends before the end of its %s container", media.Container))
+		return
+	case media.Duration > 0 && media.Bitrate < minBitrate:
+		s.add(KindTruncated, path, fmt.Sprintf("%d MB is too small for a duration of %s", info.Size()>>20, media.Duration.Round(time.Second)))
+		return
+	}
+
+	var duration time.Duration
+	if media != nil {
+		duration = media.Duration
+	}
+	switch {
+	case kind != "":
+		return
+	case directory.Extras.Action != models.ExtrasKeep && s.classifier.IsSample(info, duration):
+		s.add(KindSample, path, "the file is smaller or shorter than the thresholds of the samples")
+		return
+	}
+
+	video := models.NewVideoFile(path, directory.ConflictStrategy)
+	video.MediaInfo = media
+	switch directory.Type {
+	case "movie":
+		video.MediaType = models.MediaTypeMovie
+	case "tv":
+		video.MediaType = models.MediaTypeTVShow
+	}
+	if video.ConflictStrategy == "" {
+		video.ConflictStrategy = models.DefaultConflictStrategy
+	}
+	s.Videos = append(s.Videos, video)
+}
+
+// add adds a problem fixed by moving the file to the quarantine
+func (s *Scan) add(kind Kind, path, message string) {
+	s.Findings = append(s.Findings, Finding{
+		Kind:    kind,
+		Path:    path,
+		Message: message,
+		Fix:     "move it to the quarantine",
+		target:  s.quarantine(path),
+	})
+}
+
+// rel returns the path of a file relative to the directory, with forward slashes
+func (s *Scan) rel(path string) string {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// quarantine returns the path of a file of the directory once moved to the quarantine
+func (s *Scan) quarantine(path string) string {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return filepath.Join(s.quarantineRoot, rel)
+}
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"goru/internal/models"
+
+	"github.com/tidwall/buntdb"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// APIKeyHeader is the header carrying the API keys
+	APIKeyHeader = "X-API-Key"
+
+	// SessionCookie is the name of the cookie carrying the session token
+	SessionCookie = "goru_session"
+
+	// DefaultSessionTTL is how long a session lasts without login
+	DefaultSessionTTL = 24 * time.Hour
+)
+
+var (
+	ErrUnauthenticated    = errors.New("authentication required")
+	ErrForbidden          = errors.New("insufficient permissions")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// Role grants permissions to an identity
+type Role string
+
+const (
+	// RoleReadOnly can browse and create plans
+	RoleReadOnly Role = "read-only"
+
+	// RoleAdmin can also rename and revert files
+	RoleAdmin Role = "admin"
+)
+
+// Allows returns true if the role grants the permissions of the required role
+func (r Role) Allows(required Role) bool {
+	switch r {
+	case RoleAdmin:
+		return true
+	case RoleReadOnly:
+		return required == RoleReadOnly
+	default:
+		return false
+	}
+}
+
+// Method is the way an identity has been authenticated
+type Method string
+
+const (
+	MethodNone    Method = "none"
+	MethodAPIKey  Method = "api_key"
+	MethodSession Method = "session"
+	MethodProxy   Method = "proxy"
+)
+
+// Identity is an authenticated client of the API
+type Identity struct {
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+	Method Method `json:"method"`
+}
+
+// anonymous is the identity of every client when authentication is disabled
+var anonymous = &Identity{Name: "anonymous", Role: RoleAdmin, Method: MethodNone}
+
+type contextKey struct{}
+
+// WithIdentity returns a copy of the context holding the identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext returns the identity held by the context, if any
+func FromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(*Identity)
+	return identity, ok
+}
+
+// Service authenticates the requests with the configured methods: API keys, local
+// users with session cookies, and headers set by a trusted reverse proxy.
+type Service struct {
+	apiKeys  []models.APIKey
+	users    map[string]models.User
+	proxy    models.ProxyAuth
+	trusted  []*net.IPNet
+	sessions *SessionStore
+}
+
+// NewService creates the authentication service. The sessions are stored in the database.
+func NewService(config models.Auth, db *buntdb.DB) (*Service, error) {
+	s := &Service{
+		apiKeys: config.APIKeys,
+		users:   make(map[string]models.User, len(config.Users)),
+		proxy:   config.Proxy,
+	}
+
+	for _, user := range config.Users {
+		if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+			return nil, fmt.Errorf("invalid password hash for user %s: %w", user.Username, err)
+		}
+		s.users[user.Username] = user
+	}
+
+	if s.proxy.Enabled {
+		for _, cidr := range s.proxy.TrustedProxies {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %s: %w", cidr, err)
+			}
+			s.trusted = append(s.trusted, network)
+		}
+	}
+
+	ttl := config.SessionTTL
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	s.sessions = NewSessionStore(db, ttl)
+
+	return s, nil
+}
+
+// Enabled returns true if at least one authentication method is configured
+func (s *Service) Enabled() bool {
+	return len(s.apiKeys) > 0 || len(s.users) > 0 || s.proxy.Enabled
+}
+
+// Authenticate returns the identity of the client of the request.
+// When authentication is disabled, every client is an anonymous admin.
+func (s *Service) Authenticate(r *http.Request) (*Identity, error) {
+	if !s.Enabled() {
+		return anonymous, nil
+	}
+
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		for _, apiKey := range s.apiKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey.Key)) == 1 {
+				return &Identity{Name: apiKey.Name, Role: Role(apiKey.Role), Method: MethodAPIKey}, nil
+			}
+		}
+		return nil, ErrUnauthenticated
+	}
+
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		// The user is checked against the configuration, so that a user removed or
+		// demoted since the login loses the access of the session
+		session, err := s.sessions.Get(cookie.Value)
+		if err == nil {
+			if user, ok := s.users[session.Username]; ok {
+				return &Identity{Name: user.Username, Role: Role(user.Role), Method: MethodSession}, nil
+			}
+		}
+	}
+
+	if identity := s.authenticateProxy(r); identity != nil {
+		return identity, nil
+	}
+
+	return nil, ErrUnauthenticated
+}
+
+// authenticateProxy trusts the user header if the request comes from a trusted proxy
+func (s *Service) authenticateProxy(r *http.Request) *Identity {
+	if !s.proxy.Enabled {
+		return nil
+	}
+
+	username := strings.TrimSpace(r.Header.Get(s.proxy.UserHeader))
+	if username == "" || !s.isTrustedProxy(r.RemoteAddr) {
+		return nil
+	}
+
+	role := Role(s.proxy.DefaultRole)
+	if s.proxy.RoleHeader != "" {
+		if value := Role(strings.TrimSpace(r.Header.Get(s.proxy.RoleHeader))); value == RoleAdmin || value == RoleReadOnly {
+			role = value
+		}
+	}
+	if role == "" {
+		role = RoleReadOnly
+	}
+
+	return &Identity{Name: username, Role: role, Method: MethodProxy}
+}
+
+// isTrustedProxy returns true if the remote address belongs to a trusted proxy
+func (s *Service) isTrustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range s.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Login checks the credentials of a local user and opens a session
+func (s *Service) Login(username, password string) (*Session, error) {
+	user, ok := s.users[username]
+	if !ok {
+		// Compare anyway so that unknown users cannot be told apart by timing
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.sessions.Create(user.Username, Role(user.Role))
+}
+
+// Logout closes the session
+func (s *Service) Logout(token string) error {
+	return s.sessions.Delete(token)
+}
+
+// HashPassword hashes a password for the configuration of a local user
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// dummyHash is compared against when the user does not exist
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("goru"), bcrypt.DefaultCost)
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"goru/internal/models"
+
+	"github.com/tidwall/buntdb"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	db, err := buntdb.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := NewService(models.Auth{
+		APIKeys: []models.APIKey{{Name: "ci", Key: "0123456789abcdef", Role: "read-only"}},
+		Users:   []models.User{{Username: "alice", PasswordHash: hash, Role: "admin"}},
+		Proxy: models.ProxyAuth{
+			Enabled:        true,
+			UserHeader:     "X-Forwarded-User",
+			RoleHeader:     "X-Forwarded-Role",
+			TrustedProxies: []string{"10.0.0.0/8"},
+		},
+	}, db)
+	if err != nil {
+		t.Fatalf("NewService() returned an error: %v", err)
+	}
+
+	return service
+}
+
+func TestService_Authenticate(t *testing.T) {
+	service := newTestService(t)
+
+	session, err := service.Login("alice", "secret")
+	if err != nil {
+		t.Fatalf("Login() returned an error: %v", err)
+	}
+	if _, err := service.Login("alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with a wrong password = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := service.Login("bob", "secret"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with an unknown user = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		cookie     string
+		want       *Identity
+	}{
+		{
+			name: "no credentials",
+		},
+		{
+			name:    "api key",
+			headers: map[string]string{APIKeyHeader: "0123456789abcdef"},
+			want:    &Identity{Name: "ci", Role: RoleReadOnly, Method: MethodAPIKey},
+		},
+		{
+			name:    "wrong api key",
+			headers: map[string]string{APIKeyHeader: "wrong"},
+		},
+		{
+			name:   "session",
+			cookie: session.Token,
+			want:   &Identity{Name: "alice", Role: RoleAdmin, Method: MethodSession},
+		},
+		{
+			name:   "unknown session",
+			cookie: "unknown",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.1.2.3:4567",
+			headers:    map[string]string{"X-Forwarded-User": "carol", "X-Forwarded-Role": "admin"},
+			want:       &Identity{Name: "carol", Role: RoleAdmin, Method: MethodProxy},
+		},
+		{
+			name:       "trusted proxy without role",
+			remoteAddr: "10.1.2.3:4567",
+			headers:    map[string]string{"X-Forwarded-User": "carol"},
+			want:       &Identity{Name: "carol", Role: RoleReadOnly, Method: MethodProxy},
+		},
+		{
+			name:       "untrusted proxy",
+			remoteAddr: "192.168.1.2:4567",
+			headers:    map[string]string{"X-Forwarded-User": "carol", "X-Forwarded-Role": "admin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/plans", nil)
+			if tt.remoteAddr != "" {
+				r.RemoteAddr = tt.remoteAddr
+			}
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			if tt.cookie != "" {
+				r.Header.Set("Cookie", SessionCookie+"="+tt.cookie)
+			}
+
+			identity, err := service.Authenticate(r)
+			if tt.want == nil {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Errorf("Authenticate() = %+v, %v, want %v", identity, err, ErrUnauthenticated)
+				}
+				return
+			}
+
+			if err != nil || *identity != *tt.want {
+				t.Errorf("Authenticate() = %+v, %v, want %+v", identity, err, tt.want)
+			}
+		})
+	}
+
+	if err := service.Logout(session.Token); err != nil {
+		t.Fatalf("Logout() returned an error: %v", err)
+	}
+	if _, err := service.sessions.Get(session.Token); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("session still exists after logout: %v", err)
+	}
+}
+
+func TestService_Authenticate_ConfigChanged(t *testing.T) {
+	service := newTestService(t)
+
+	session, err := service.Login("alice", "secret")
+	if err != nil {
+		t.Fatalf("Login() returned an error: %v", err)
+	}
+	r := httptest.NewRequest("GET", "/api/plans", nil)
+	r.Header.Set("Cookie", SessionCookie+"="+session.Token)
+
+	// Demoted since the login
+	user := service.users["alice"]
+	user.Role = string(RoleReadOnly)
+	service.users["alice"] = user
+	if identity, err := service.Authenticate(r); err != nil || identity.Role != RoleReadOnly {
+		t.Errorf("Authenticate() for a demoted user = %+v, %v", identity, err)
+	}
+
+	// Removed since the login
+	delete(service.users, "alice")
+	if identity, err := service.Authenticate(r); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Authenticate() for a removed user = %+v, %v, want %v", identity, err, ErrUnauthenticated)
+	}
+}
+
+func TestRole_Allows(t *testing.T) {
+	if !RoleAdmin.Allows(RoleReadOnly) || !RoleAdmin.Allows(RoleAdmin) {
+		t.Error("admin must be allowed everything")
+	}
+	if !RoleReadOnly.Allows(RoleReadOnly) || RoleReadOnly.Allows(RoleAdmin) {
+		t.Error("read-only must only be allowed read-only")
+	}
+	if Role("").Allows(RoleReadOnly) {
+		t.Error("unknown roles must not be allowed anything")
+	}
+}
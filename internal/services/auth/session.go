@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tidwall/buntdb"
+)
+
+// sessionKeyPrefix prefixes the keys of the sessions, the database being shared
+const sessionKeyPrefix = "session:"
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session of a local user, identified by a random token
+type Session struct {
+	Token     string    `json:"-"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionStore keeps the sessions in the database, which expires them
+type SessionStore struct {
+	db  *buntdb.DB
+	ttl time.Duration
+}
+
+// NewSessionStore creates a new session store
+func NewSessionStore(db *buntdb.DB, ttl time.Duration) *SessionStore {
+	return &SessionStore{
+		db:  db,
+		ttl: ttl,
+	}
+}
+
+// Create opens a new session for the user
+func (s *SessionStore) Create(username string, role Role) (*Session, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+
+	session := &Session{
+		Token:     hex.EncodeToString(buf),
+		Username:  username,
+		Role:      role,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Update(func(tx *buntdb.Tx) error {
+		_, _, err := tx.Set(sessionKeyPrefix+session.Token, string(data), &buntdb.SetOptions{Expires: true, TTL: s.ttl})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store session: %w", err)
+	}
+
+	return session, nil
+}
+
+// Get returns the session with the given token, if it has not expired
+func (s *SessionStore) Get(token string) (*Session, error) {
+	if token == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	var session Session
+	err := s.db.View(func(tx *buntdb.Tx) error {
+		value, err := tx.Get(sessionKeyPrefix + token)
+		if err != nil {
+			return err
+		}
+
+		return json.Unmarshal([]byte(value), &session)
+	})
+	if errors.Is(err, buntdb.ErrNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	session.Token = token
+	return &session, nil
+}
+
+// Delete closes the session with the given token
+func (s *SessionStore) Delete(token string) error {
+	err := s.db.Update(func(tx *buntdb.Tx) error {
+		_, err := tx.Delete(sessionKeyPrefix + token)
+		return err
+	})
+	if errors.Is(err, buntdb.ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
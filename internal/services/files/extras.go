@@ -0,0 +1,119 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"goru/internal/models"
+)
+
+// defaultExtraRules tell the samples, the trailers and the extras named after the
+// releases and the conventions of Plex
+var defaultExtraRules = []models.ExtraRule{
+	{Folder: "sample", Kind: models.ExtraSample},
+	{Folder: "samples", Kind: models.ExtraSample},
+	{Pattern: `(?i)(?:^|[ ._\-\[(])sample(?:$|[ ._\-\])])`, Kind: models.ExtraSample},
+	{Pattern: `(?i)^(?:rarbg(?:[ ._]com)?|etrg|yify|yts(?:[ ._][a-z]{2,3})?|www\..+)$`, Kind: models.ExtraJunk},
+
+	{Folder: "trailers", Kind: models.ExtraTrailer},
+	{Folder: "behind the scenes", Kind: models.ExtraBehindTheScene},
+	{Folder: "deleted scenes", Kind: models.ExtraDeletedScene},
+	{Folder: "featurettes", Kind: models.ExtraFeaturette},
+	{Folder: "interviews", Kind: models.ExtraInterview},
+	{Folder: "scenes", Kind: models.ExtraScene},
+	{Folder: "shorts", Kind: models.ExtraShort},
+	{Folder: "other", Kind: models.ExtraOther},
+	{Folder: "extras", Kind: models.ExtraOther},
+
+	{Pattern: `(?i)(?:^|[ ._\-\[(])trailer(?:[ ._-]?\d{1,2})?(?:[ ._-]\d{3,4}p)?[\])]?$`, Kind: models.ExtraTrailer},
+	{Pattern: `(?i)-(?:behindthescenes|making[ ._-]?of)$`, Kind: models.ExtraBehindTheScene},
+	{Pattern: `(?i)-deleted(?:[ ._-]?scenes?)?$`, Kind: models.ExtraDeletedScene},
+	{Pattern: `(?i)-featurette$`, Kind: models.ExtraFeaturette},
+	{Pattern: `(?i)-interview$`, Kind: models.ExtraInterview},
+	{Pattern: `(?i)-scene$`, Kind: models.ExtraScene},
+	{Pattern: `(?i)-short$`, Kind: models.ExtraShort},
+	{Pattern: `(?i)-other$`, Kind: models.ExtraOther},
+}
+
+// Classifier tells the samples, the trailers, the extras and the junk from the movies
+// and the episodes of a directory
+type Classifier struct {
+	rules       []extraRule
+	minSize     int64
+	minDuration time.Duration
+}
+
+type extraRule struct {
+	pattern *regexp.Regexp
+	folder  string
+	kind    string
+}
+
+// NewClassifier compiles the rules of a directory, tried before the default ones
+func NewClassifier(extras models.Extras) (*Classifier, error) {
+	classifier := &Classifier{
+		minSize:     extras.MinSize << 20,
+		minDuration: extras.MinDuration,
+	}
+
+	for _, rule := range append(append([]models.ExtraRule{}, extras.Rules...), defaultExtraRules...) {
+		compiled := extraRule{folder: strings.ToLower(rule.Folder), kind: rule.Kind}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", rule.Pattern, err)
+			}
+			compiled.pattern = pattern
+		}
+		classifier.rules = append(classifier.rules, compiled)
+	}
+	return classifier, nil
+}
+
+// Classify returns the kind of a video of the directory root, empty for the movies and
+// the episodes. Only the folders below the root are matched.
+func (c *Classifier) Classify(root, path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	var folders []string
+	if rel, err := filepath.Rel(root, filepath.Dir(path)); err == nil && rel != "." {
+		folders = strings.Split(strings.ToLower(rel), string(filepath.Separator))
+	}
+
+	for _, rule := range c.rules {
+		if rule.folder != "" && !contains(folders, rule.folder) {
+			continue
+		}
+		if rule.pattern != nil && !rule.pattern.MatchString(name) {
+			continue
+		}
+		return rule.kind
+	}
+	return ""
+}
+
+// IsSample returns true when a video is smaller or shorter than the thresholds. A
+// duration of 0 is unknown.
+func (c *Classifier) IsSample(info os.FileInfo, duration time.Duration) bool {
+	return (c.minSize > 0 && info.Size() < c.minSize) ||
+		(c.minDuration > 0 && duration > 0 && duration < c.minDuration)
+}
+
+// ProbesDuration returns true when the duration of the videos is needed
+func (c *Classifier) ProbesDuration() bool {
+	return c.minDuration > 0
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"goru/internal/models"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+func TestFileService_ScanDirectory_Extras(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]int{
+		"Show/Show.S01E01.mkv":               2 << 20,
+		"Show/Show.S01E01.sample.mkv":        2 << 20,
+		"Show/Sample/Show.S01E02.mkv":        2 << 20,
+		"Trailer Park Boys/TPB.S01E01.mkv":   2 << 20,
+		"Movie/Movie.2010.mkv":               2 << 20,
+		"Movie/Movie.2010.Trailer.1080p.mkv": 2 << 20,
+		"Movie/RARBG.com.mp4":                2 << 20,
+		"Movie/Featurettes/Making Of.mkv":    2 << 20,
+		"Movie/Extras/Interview.mkv":         2 << 20,
+		"Movie/Movie.2010.Promo.mkv":         2 << 20,
+		"Movie/tiny.mkv":                     1 << 10,
+	}
+	for name, size := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan := func(extras models.Extras) []string {
+		t.Helper()
+		videoFiles, _, err := NewFileService("", "", models.Filters{}).ScanDirectory(models.Directory{Path: root, Type: "auto", Recursive: true, Extras: extras})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, videoFile := range videoFiles {
+			rel, _ := filepath.Rel(root, videoFile.Path)
+			if videoFile.Extra != "" {
+				rel += " (" + videoFile.Extra + ")"
+			}
+			got = append(got, filepath.ToSlash(rel))
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	tests := []struct {
+		name   string
+		extras models.Extras
+		want   []string
+	}{
+		{
+			name:   "skip",
+			extras: models.Extras{MinSize: 1},
+			want: []string{
+				"Movie/Movie.2010.Promo.mkv",
+				"Movie/Movie.2010.mkv",
+				"Show/Show.S01E01.mkv",
+				"Trailer Park Boys/TPB.S01E01.mkv",
+			},
+		},
+		{
+			name: "route",
+			extras: models.Extras{
+				Action: models.ExtrasRoute,
+				Rules:  []models.ExtraRule{{Pattern: `(?i)\.promo$`, Kind: models.ExtraTrailer}},
+			},
+			want: []string{
+				"Movie/Extras/Interview.mkv (other)",
+				"Movie/Featurettes/Making Of.mkv (featurette)",
+				"Movie/Movie.2010.Promo.mkv (trailer)",
+				"Movie/Movie.2010.Trailer.1080p.mkv (trailer)",
+				"Movie/Movie.2010.mkv",
+				"Movie/tiny.mkv",
+				"Show/Show.S01E01.mkv",
+				"Trailer Park Boys/TPB.S01E01.mkv",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scan(tt.extras)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	if got := scan(models.Extras{Action: models.ExtrasKeep}); len(got) != len(files) {
+		t.Errorf("got %v, want every video when the extras are kept", got)
+	}
+}
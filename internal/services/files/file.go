@@ -4,12 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"goru/internal/models"
-	"goru/pkg/log"
-
-	"go.uber.org/zap"
 )
 
 var SupportedExtensions = []models.FileType{
@@ -30,11 +26,13 @@ var SupportedExtensions = []models.FileType{
 // FileService handles file operations and scanning
 type FileService struct {
 	supportedExtensions []models.FileType
-	filters             []string
+
+	// filters select the files of every directory
+	filters models.Filters
 }
 
 // NewFileService creates a new file service instance
-func NewFileService(tvTemplate, movieTemplate string, filters []string) *FileService {
+func NewFileService(tvTemplate, movieTemplate string, filters models.Filters) *FileService {
 	fileService := &FileService{
 		supportedExtensions: SupportedExtensions,
 		filters:             filters,
@@ -43,53 +41,6 @@ func NewFileService(tvTemplate, movieTemplate string, filters []string) *FileSer
 	return fileService
 }
 
-// ScanDirectory scans a directory for video files
-func (fs *FileService) ScanDirectory(dirPath string, recursive bool, mediaTypeOverride string) ([]*models.VideoFile, error) {
-	var videoFiles []*models.VideoFile
-
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip directories
-		if info.IsDir() {
-			// If not recursive and this is a subdirectory, skip it
-			if !recursive && path != dirPath {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		// Check if file has a supported video extension
-		ext := strings.ToLower(filepath.Ext(path))
-		if !models.IsSupportedExtension(ext) {
-			log.Error("unsupported video file type", zap.String("file", path))
-			return nil
-		}
-
-		videoFile := &models.VideoFile{
-			Path:     path,
-			Filename: info.Name(),
-		}
-
-		// Try to determine media type from filename
-		switch mediaTypeOverride {
-		case "movie":
-			videoFile.MediaType = models.MediaTypeMovie
-		case "tv":
-			videoFile.MediaType = models.MediaTypeTVShow
-		case "auto":
-			videoFile.MediaType = models.GuessMediaType(info.Name())
-		}
-
-		videoFiles = append(videoFiles, videoFile)
-		return nil
-	})
-
-	return videoFiles, err
-}
-
 // RenameFile renames a file from old path to new path with conflict resolution
 func (fs *FileService) RenameFile(oldPath, newPath string) error {
 	// Create directory if it doesn't exist
@@ -0,0 +1,141 @@
+package files
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"goru/internal/models"
+)
+
+// Filter selects the files and the folders of a directory by their path relative to
+// it, with forward slashes
+type Filter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// NewFilter compiles the globs and the regular expressions of the filters, merged
+func NewFilter(filters ...models.Filters) (*Filter, error) {
+	filter := &Filter{}
+	for _, f := range filters {
+		for _, glob := range f.Include {
+			pattern, err := compileGlob(glob)
+			if err != nil {
+				return nil, err
+			}
+			filter.include = append(filter.include, pattern)
+		}
+		for _, glob := range f.Exclude {
+			pattern, err := compileGlob(glob)
+			if err != nil {
+				return nil, err
+			}
+			filter.exclude = append(filter.exclude, pattern)
+		}
+
+		include, err := compileRegexps(f.IncludeRegex)
+		if err != nil {
+			return nil, err
+		}
+		filter.include = append(filter.include, include...)
+
+		exclude, err := compileRegexps(f.ExcludeRegex)
+		if err != nil {
+			return nil, err
+		}
+		filter.exclude = append(filter.exclude, exclude...)
+	}
+	return filter, nil
+}
+
+// Match returns true when a file is included and not excluded
+func (f *Filter) Match(rel string) bool {
+	if f.Excluded(rel) {
+		return false
+	}
+	return len(f.include) == 0 || matchAny(f.include, rel)
+}
+
+// Excluded returns true when a file or a folder is excluded, a folder with everything
+// below it
+func (f *Filter) Excluded(rel string) bool {
+	return matchAny(f.exclude, rel)
+}
+
+func matchAny(patterns []*regexp.Regexp, rel string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileRegexps(expressions []string) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(expressions))
+	for _, expression := range expressions {
+		pattern, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", expression, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// compileGlob translates a glob into a regular expression. The globs without a slash
+// match a name at any level, the other ones the path from the directory: a leading
+// slash is ignored, ** matches any number of folders and a trailing /** everything
+// below a folder.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	if !strings.Contains(glob, "/") {
+		b.WriteString("(?:.*/)?")
+	}
+	glob = strings.TrimPrefix(glob, "/")
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			switch {
+			case strings.HasPrefix(glob[i:], "**/"):
+				b.WriteString("(?:.*/)?")
+				i += 2
+			case glob[i:] == "**":
+				b.WriteString(".*")
+				i++
+			default:
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid glob %q: unclosed [", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	pattern, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+	}
+	return pattern, nil
+}
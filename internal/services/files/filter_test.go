@@ -0,0 +1,43 @@
+package files
+
+import (
+	"testing"
+
+	"goru/internal/models"
+)
+
+func TestFilter(t *testing.T) {
+	filter, err := NewFilter(
+		models.Filters{Exclude: []string{"**/incomplete/**", "*.part.mkv"}},
+		models.Filters{
+			Include:      []string{"*.mkv", "/Movies/*/*.mp4"},
+			ExcludeRegex: []string{`(?i)\bcam\b`},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{rel: "Show/Season 1/Show.S01E01.mkv", want: true},
+		{rel: "Show.S01E01.mkv", want: true},
+		{rel: "Movies/Heat (1995)/Heat.mp4", want: true},
+		{rel: "Movies/Heat.mp4", want: false},
+		{rel: "Show/Show.S01E01.avi", want: false},
+		{rel: "downloads/incomplete/Show.S01E02.mkv", want: false},
+		{rel: "Show.S01E03.part.mkv", want: false},
+		{rel: "Movie.2024.CAM.mkv", want: false},
+	}
+	for _, tt := range tests {
+		if got := filter.Match(tt.rel); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+
+	if _, err := NewFilter(models.Filters{Include: []string{"[a-"}}); err == nil {
+		t.Error("expected an error for an invalid glob")
+	}
+}
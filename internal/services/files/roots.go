@@ -0,0 +1,107 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrOutsideRoots = errors.New("path is outside of the library roots")
+
+// Roots are the library directories goru is allowed to read and write.
+type Roots struct {
+	paths []string
+}
+
+// NewRoots creates the library roots from the given directories.
+// Relative paths and symlinks are resolved, empty paths are ignored.
+func NewRoots(paths ...string) (*Roots, error) {
+	roots := &Roots{}
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		resolved, err := resolvePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid library root %s: %w", path, err)
+		}
+
+		roots.paths = append(roots.paths, resolved)
+	}
+
+	return roots, nil
+}
+
+// Paths returns the resolved paths of the roots
+func (r *Roots) Paths() []string {
+	return r.paths
+}
+
+// Resolve returns the absolute path, with symlinks resolved, if it is inside one of
+// the roots. The path does not need to exist, so that rename targets can be checked.
+func (r *Roots) Resolve(path string) (string, error) {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	for _, root := range r.paths {
+		if isWithin(root, resolved) {
+			return resolved, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrOutsideRoots, path)
+}
+
+// Contains returns true if the path is inside one of the roots
+func (r *Roots) Contains(path string) bool {
+	_, err := r.Resolve(path)
+	return err == nil
+}
+
+// resolvePath makes the path absolute and resolves the symlinks of its longest
+// existing ancestor.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	existing := abs
+	var missing []string
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = parent
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(append([]string{resolved}, missing...)...), nil
+}
+
+// isWithin returns true if path is root or one of its descendants
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
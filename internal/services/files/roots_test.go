@@ -0,0 +1,62 @@
+package files
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRoots_Resolve(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	library := filepath.Join(base, "library")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(library, "movies"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(library, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(library, "movies"), filepath.Join(base, "shortcut")); err != nil {
+		t.Fatal(err)
+	}
+
+	roots, err := NewRoots(library, "")
+	if err != nil {
+		t.Fatalf("NewRoots() returned an error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: library, want: library},
+		{path: filepath.Join(library, "movies"), want: filepath.Join(library, "movies")},
+		{path: filepath.Join(library, "movies", "new", "Movie (2020).mkv"), want: filepath.Join(library, "movies", "new", "Movie (2020).mkv")},
+		{path: filepath.Join(base, "shortcut", "a.mkv"), want: filepath.Join(library, "movies", "a.mkv")},
+		{path: filepath.Join(library, "..", "outside")},
+		{path: filepath.Join(library, "escape", "a.mkv")},
+		{path: library + "-other"},
+		{path: "/"},
+	}
+
+	for _, tt := range tests {
+		got, err := roots.Resolve(tt.path)
+		if tt.want == "" {
+			if !errors.Is(err, ErrOutsideRoots) {
+				t.Errorf("Resolve(%q) = %q, %v, want %v", tt.path, got, err, ErrOutsideRoots)
+			}
+			continue
+		}
+
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
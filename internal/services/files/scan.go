@@ -0,0 +1,215 @@
+package files
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/mediainfo"
+	"goru/internal/services/metrics"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// ScanError is a path of a directory that could not be read, the rest of the
+// directory being scanned
+type ScanError struct {
+	Path string
+	Err  error
+}
+
+func (e ScanError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+// ScanDirectory scans a directory for video files. The files are selected by the
+// filters of the service and of the directory. The samples and the junk are left out,
+// and the extras unless they are routed to the folders of Plex or kept. The paths that
+// cannot be read are returned with the files, only an unreadable directory fails.
+func (fs *FileService) ScanDirectory(directory models.Directory) ([]*models.VideoFile, []ScanError, error) {
+	classifier, err := NewClassifier(directory.Extras)
+	if err != nil {
+		return nil, nil, err
+	}
+	filter, err := NewFilter(fs.filters, directory.Filters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := filepath.Clean(directory.Path)
+	if _, err := os.Stat(root); err != nil {
+		return nil, nil, err
+	}
+
+	w := &walker{
+		directory:  directory,
+		root:       root,
+		filter:     filter,
+		classifier: classifier,
+		visited:    make(map[string]bool),
+	}
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		w.visited[real] = true
+	}
+	if err := w.walk(root, 0); err != nil {
+		return nil, nil, err
+	}
+
+	return w.files, w.errors, nil
+}
+
+// walker lists the video files of a directory
+type walker struct {
+	directory  models.Directory
+	root       string
+	filter     *Filter
+	classifier *Classifier
+
+	// visited are the real paths of the folders walked, against the loops of links
+	visited map[string]bool
+
+	files  []*models.VideoFile
+	errors []ScanError
+}
+
+// walk lists the files of a folder, depth levels below the directory. The errors of
+// the folders below the directory are recorded.
+func (w *walker) walk(dir string, depth int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if dir == w.root {
+			return err
+		}
+		// The entries read before the error are still scanned
+		w.errors = append(w.errors, ScanError{Path: dir, Err: err})
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		rel := w.rel(path)
+
+		info, err := entry.Info()
+		if err != nil {
+			w.errors = append(w.errors, ScanError{Path: path, Err: err})
+			continue
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			target, err := os.Stat(path)
+			if err != nil {
+				w.errors = append(w.errors, ScanError{Path: path, Err: fmt.Errorf("broken link: %w", err)})
+				continue
+			}
+			if target.IsDir() && !w.directory.FollowSymlinks {
+				continue
+			}
+			info = target
+		}
+
+		if info.IsDir() {
+			if !w.descend(depth) || w.filter.Excluded(rel) {
+				continue
+			}
+			if real, err := filepath.EvalSymlinks(path); err == nil {
+				if w.visited[real] {
+					continue
+				}
+				w.visited[real] = true
+			}
+			if err := w.walk(path, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		if !w.filter.Match(rel) {
+			log.Debug("file filtered out", zap.String("file", path))
+			continue
+		}
+		w.add(path, info)
+	}
+	return nil
+}
+
+// descend returns true when the folders depth levels below the directory are walked
+func (w *walker) descend(depth int) bool {
+	return w.directory.Recursive && (w.directory.MaxDepth == 0 || depth < w.directory.MaxDepth)
+}
+
+// rel returns the path of a file relative to the directory, with forward slashes
+func (w *walker) rel(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// add adds a video file, unless it is an extra left out
+func (w *walker) add(path string, info os.FileInfo) {
+	// Check if file has a supported video extension
+	ext := strings.ToLower(filepath.Ext(path))
+	if !models.IsSupportedExtension(ext) {
+		log.Error("unsupported video file type", zap.String("file", path))
+		return
+	}
+
+	videoFile := &models.VideoFile{
+		Path:     path,
+		Filename: info.Name(),
+		FileType: models.GetFileTypeFromExtension(ext),
+	}
+
+	// Try to determine media type from filename
+	switch w.directory.Type {
+	case "movie":
+		videoFile.MediaType = models.MediaTypeMovie
+	case "tv":
+		videoFile.MediaType = models.MediaTypeTVShow
+	case "auto":
+		videoFile.MediaType = models.GuessMediaType(info.Name())
+	}
+
+	if w.directory.Extras.Action != models.ExtrasKeep {
+		kind := classify(w.classifier, w.root, videoFile, info)
+		switch {
+		case kind == "":
+		case w.directory.Extras.Action == models.ExtrasRoute && models.IsRoutable(kind):
+			videoFile.Extra = kind
+		default:
+			log.Debug("skipping the extra", zap.String("file", path), zap.String("kind", kind))
+			return
+		}
+	}
+
+	w.files = append(w.files, videoFile)
+	metrics.FilesScanned.WithLabelValues(metrics.SourcePlan).Inc()
+}
+
+// classify returns the kind of extra of a video, empty for the movies and the
+// episodes. The media info read for the duration is kept.
+func classify(classifier *Classifier, root string, videoFile *models.VideoFile, info os.FileInfo) string {
+	if kind := classifier.Classify(root, videoFile.Path); kind != "" {
+		return kind
+	}
+
+	var duration time.Duration
+	if classifier.ProbesDuration() {
+		media, err := mediainfo.Probe(videoFile.Path)
+		if err == nil {
+			videoFile.MediaInfo = media
+			duration = media.Duration
+		}
+	}
+	if classifier.IsSample(info, duration) {
+		return models.ExtraSample
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"goru/internal/models"
+)
+
+func TestFileService_ScanDirectory(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "library")
+	outside := filepath.Join(base, "outside")
+	for _, name := range []string{
+		"library/Movie.2010.mp4",
+		"library/Show/Season 1/Show.S01E01.mkv",
+		"library/Show/Season 1/Deep/Show.S01E02.mkv",
+		"library/incomplete/Show.S01E03.mkv",
+		"outside/Other.2020.mkv",
+	} {
+		path := filepath.Join(base, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, 64), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		filepath.Join(root, "linked"):     outside,
+		filepath.Join(root, "loop"):       root,
+		filepath.Join(root, "broken.mkv"): filepath.Join(base, "nowhere.mkv"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := NewFileService("", "", models.Filters{Exclude: []string{"incomplete"}})
+	scan := func(directory models.Directory) ([]string, []ScanError) {
+		t.Helper()
+		directory.Path = root
+		videoFiles, scanErrors, err := service.ScanDirectory(directory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, videoFile := range videoFiles {
+			rel, _ := filepath.Rel(root, videoFile.Path)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		sort.Strings(got)
+		return got, scanErrors
+	}
+
+	tests := []struct {
+		name      string
+		directory models.Directory
+		want      []string
+	}{
+		{
+			name:      "not recursive",
+			directory: models.Directory{},
+			want:      []string{"Movie.2010.mp4"},
+		},
+		{
+			name:      "max depth",
+			directory: models.Directory{Recursive: true, MaxDepth: 2},
+			want:      []string{"Movie.2010.mp4", "Show/Season 1/Show.S01E01.mkv"},
+		},
+		{
+			name:      "symlinks",
+			directory: models.Directory{Recursive: true, FollowSymlinks: true},
+			want:      []string{"Movie.2010.mp4", "Show/Season 1/Deep/Show.S01E02.mkv", "Show/Season 1/Show.S01E01.mkv", "linked/Other.2020.mkv"},
+		},
+		{
+			name:      "filters",
+			directory: models.Directory{Recursive: true, Filters: models.Filters{IncludeRegex: []string{`S01E0[13]`}}},
+			want:      []string{"Show/Season 1/Show.S01E01.mkv"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, scanErrors := scan(tt.directory)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+
+			// The broken link does not stop the scan
+			if len(scanErrors) != 1 || scanErrors[0].Path != filepath.Join(root, "broken.mkv") {
+				t.Errorf("got scan errors %v", scanErrors)
+			}
+		})
+	}
+
+	videoFiles, _, err := service.ScanDirectory(models.Directory{Path: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if videoFiles[0].FileType != models.FileTypeMP4 {
+		t.Errorf("got file type %v, want mp4", videoFiles[0].FileType)
+	}
+
+	if _, _, err := service.ScanDirectory(models.Directory{Path: filepath.Join(base, "missing")}); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestFileService_ScanDirectory_Unreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root reads every folder")
+	}
+
+	root := t.TempDir()
+	locked := filepath.Join(root, "locked")
+	for _, path := range []string{filepath.Join(root, "Movie.2010.mkv"), filepath.Join(locked, "Other.2020.mkv")} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, 64), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0o755)
+
+	videoFiles, scanErrors, err := NewFileService("", "", models.Filters{}).ScanDirectory(models.Directory{Path: root, Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(videoFiles) != 1 || len(scanErrors) != 1 || scanErrors[0].Path != locked {
+		t.Errorf("got files %v and scan errors %v", videoFiles, scanErrors)
+	}
+}
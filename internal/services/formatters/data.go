@@ -1,5 +1,7 @@
 package formatters
 
+import "goru/internal/models"
+
 // MovieTemplateData represents the data available for movie filename templates
 type MovieTemplateData struct {
 	Name     string
@@ -7,6 +9,8 @@ type MovieTemplateData struct {
 	Year     int
 	Director string
 	Genre    string
+
+	MediaTemplateData
 }
 
 type TVShowTemplateData struct {
@@ -15,4 +19,33 @@ type TVShowTemplateData struct {
 	Year    int    // First air date year
 	Season  int    // Season number
 	Episode int    // Episode number
+
+	MediaTemplateData
+}
+
+// MediaTemplateData represents the technical metadata of the file, empty when it
+// cannot be read from its container
+type MediaTemplateData struct {
+	Resolution    string // 1080p, 2160p...
+	VideoCodec    string // h264, hevc, av1...
+	HDR           string // HDR10, HLG or Dolby Vision
+	AudioCodec    string // Codec of the main audio track
+	AudioChannels string // Channels of the main audio track: 2.0, 5.1...
+}
+
+func mediaTemplateData(info *models.MediaInfo) MediaTemplateData {
+	if info == nil {
+		return MediaTemplateData{}
+	}
+
+	data := MediaTemplateData{
+		Resolution: info.Resolution(),
+		VideoCodec: info.VideoCodec,
+		HDR:        info.HDR,
+	}
+	if track := info.MainAudioTrack(); track != nil {
+		data.AudioCodec = track.Codec
+		data.AudioChannels = track.ChannelLayout()
+	}
+	return data
 }
@@ -12,21 +12,53 @@ import (
 type FormatterService struct {
 	tvShowTemplate string
 	movieTemplate  string
+	preset         string
+	sanitizer      *Sanitizer
 }
 
+// NewFormatterService creates a formatter with the templates of the TV shows and the
+// movies. A template is either a Go template or the name of a preset, the default
+// preset being used when it is empty.
 func NewFormatterService(tvTemplate, movieTemplate string) *FormatterService {
-	fs := &FormatterService{}
+	fs := &FormatterService{
+		tvShowTemplate: tvTemplate,
+		movieTemplate:  movieTemplate,
+		sanitizer:      defaultSanitizer,
+	}
 
 	if tvTemplate == "" {
-		fs.tvShowTemplate = TVShowTemplateDefault
+		tvTemplate = DefaultPreset
+	}
+	if name, preset, ok := lookupPreset(tvTemplate); ok {
+		fs.tvShowTemplate = preset.TVShow
+		fs.preset = name
 	}
+
 	if movieTemplate == "" {
-		fs.movieTemplate = MovieTemplateDefault
+		movieTemplate = DefaultPreset
+	}
+	if name, preset, ok := lookupPreset(movieTemplate); ok {
+		fs.movieTemplate = preset.Movie
+		if fs.preset == "" {
+			fs.preset = name
+		}
 	}
 
 	return fs
 }
 
+// Preset returns the name of the preset of the templates, empty for custom templates
+func (fs *FormatterService) Preset() string {
+	return fs.preset
+}
+
+// SetSanitizer sets the sanitizer used to make filenames safe for the target filesystem.
+func (fs *FormatterService) SetSanitizer(sanitizer *Sanitizer) {
+	if sanitizer != nil {
+		fs.sanitizer = sanitizer
+	}
+}
+
 func (fs *FormatterService) FormatFilename(videoFile *models.VideoFile) (string, error) {
 	var templateStr string
 	var data any
@@ -45,6 +77,8 @@ func (fs *FormatterService) FormatFilename(videoFile *models.VideoFile) (string,
 			Year:     movie.ReleaseDate.Year(),
 			Director: movie.Director,
 			Genre:    string(movie.Genre),
+
+			MediaTemplateData: mediaTemplateData(videoFile.MediaInfo),
 		}
 
 	case models.MediaTypeTVShow:
@@ -64,6 +98,8 @@ func (fs *FormatterService) FormatFilename(videoFile *models.VideoFile) (string,
 			Year:    episode.AirDate.Year(),
 			Season:  episode.Season,
 			Episode: episode.Episode,
+
+			MediaTemplateData: mediaTemplateData(videoFile.MediaInfo),
 		}
 	}
 
@@ -78,11 +114,8 @@ func (fs *FormatterService) FormatFilename(videoFile *models.VideoFile) (string,
 		return "", err
 	}
 
-	// Append the extension after template processing
-	filename := sanitizeFilename(buf.String())
-
-	// Append the extension
-	filename += models.SupportedExtensions[videoFile.FileType]
+	// Sanitize and append the extension after template processing
+	filename := fs.sanitizer.SanitizeFilename(buf.String(), models.SupportedExtensions[videoFile.FileType])
 
 	return filename, nil
 }
@@ -0,0 +1,96 @@
+package formatters
+
+import (
+	"testing"
+	"time"
+
+	"goru/internal/models"
+)
+
+func TestFormatFilename_Presets(t *testing.T) {
+	movie := &models.VideoFile{
+		Filename:  "matrix.mkv",
+		MediaType: models.MediaTypeMovie,
+		Metadata:  &models.Movie{Title: "The Matrix", ReleaseDate: time.Date(1999, 3, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	episode := &models.VideoFile{
+		Filename:  "bb.s01e02.mkv",
+		MediaType: models.MediaTypeTVShow,
+		Metadata: &models.Episode{
+			Title:   "Pilot",
+			Season:  1,
+			Episode: 2,
+			AirDate: time.Date(2008, 1, 27, 0, 0, 0, 0, time.UTC),
+			TVShow:  models.TVShow{Name: "Breaking Bad"},
+		},
+	}
+
+	tests := []struct {
+		format  string
+		preset  string
+		movie   string
+		episode string
+	}{
+		{"", "plex", "The Matrix (1999).mkv", "Breaking Bad - S01E02 - Pilot.mkv"},
+		{"plex", "plex", "The Matrix (1999).mkv", "Breaking Bad - S01E02 - Pilot.mkv"},
+		{"Kodi", "kodi", "The Matrix (1999).mkv", "Breaking Bad (2008) - 1x02 - Pilot.mkv"},
+		{"emby", "emby", "The Matrix (1999).mkv", "Breaking Bad (2008) - S01E02 - Pilot.mkv"},
+		{"{{.Name}}", "", "The Matrix.mkv", "Breaking Bad.mkv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			fs := NewFormatterService(tt.format, tt.format)
+			if fs.Preset() != tt.preset {
+				t.Errorf("got preset %q, want %q", fs.Preset(), tt.preset)
+			}
+
+			got, err := fs.FormatFilename(movie)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.movie {
+				t.Errorf("got movie %q, want %q", got, tt.movie)
+			}
+
+			got, err = fs.FormatFilename(episode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.episode {
+				t.Errorf("got episode %q, want %q", got, tt.episode)
+			}
+		})
+	}
+}
+
+func TestFormatFilename_MediaInfo(t *testing.T) {
+	movie := &models.VideoFile{
+		Filename:  "matrix.mkv",
+		MediaType: models.MediaTypeMovie,
+		Metadata:  &models.Movie{Title: "The Matrix", ReleaseDate: time.Date(1999, 3, 31, 0, 0, 0, 0, time.UTC)},
+		MediaInfo: &models.MediaInfo{
+			Width:       3840,
+			Height:      1600,
+			VideoCodec:  "hevc",
+			HDR:         "HDR10",
+			AudioTracks: []models.AudioTrack{{Codec: "truehd", Channels: 8}},
+		},
+	}
+
+	fs := NewFormatterService("", "{{.Name}} ({{.Year}}) [{{.Resolution}} {{.VideoCodec}} {{.HDR}} {{.AudioCodec}} {{.AudioChannels}}]")
+	got, err := fs.FormatFilename(movie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "The Matrix (1999) [2160p hevc HDR10 truehd 7.1].mkv"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Unknown media info leaves the fields empty
+	movie.MediaInfo = nil
+	fs = NewFormatterService("", "{{.Name}}{{with .Resolution}} [{{.}}]{{end}}")
+	if got, _ := fs.FormatFilename(movie); got != "The Matrix.mkv" {
+		t.Errorf("got %q without media info", got)
+	}
+}
@@ -1,27 +1,9 @@
 package formatters
 
-import "strings"
+// defaultSanitizer is used when the formatter is not given a sanitizer.
+var defaultSanitizer, _ = NewSanitizer(SanitizerOptions{})
 
 // sanitizeFilename removes or replaces characters that are not allowed in filenames
 func sanitizeFilename(filename string) string {
-	// Replace invalid characters with safe alternatives
-	replacements := map[string]string{
-		":":  " -",
-		"*":  "",
-		"?":  "",
-		"\"": "'",
-		"<":  "",
-		">":  "",
-		"|":  "",
-		"/":  "",
-		"\\": "",
-	}
-
-	for old, new := range replacements {
-		filename = strings.ReplaceAll(filename, old, new)
-	}
-
-	// Remove multiple spaces and trim
-	filename = strings.Join(strings.Fields(filename), " ")
-	return strings.TrimSpace(filename)
+	return defaultSanitizer.Sanitize(filename)
 }
@@ -0,0 +1,292 @@
+package formatters
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mozillazg/go-unidecode"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Profile is a sanitization profile targeting a given filesystem.
+type Profile string
+
+const (
+	// ProfilePOSIX only removes what POSIX filesystems forbid: slashes, NUL and control characters.
+	ProfilePOSIX Profile = "posix"
+
+	// ProfileWindowsSafe removes the characters forbidden by NTFS/FAT, trailing dots and spaces,
+	// and escapes reserved device names such as CON or NUL.
+	ProfileWindowsSafe Profile = "windows-safe"
+
+	// ProfileSMB applies the Windows rules and normalizes to NFC, as expected by most SMB servers.
+	ProfileSMB Profile = "smb"
+
+	// ProfileASCII applies the Windows rules and transliterates everything to plain ASCII.
+	ProfileASCII Profile = "ascii"
+)
+
+// DefaultProfile is the profile used when none is configured.
+const DefaultProfile = ProfileWindowsSafe
+
+// Normalization is the Unicode normalization form applied to filenames.
+type Normalization string
+
+const (
+	NormalizationNone Normalization = "none"
+	NormalizationNFC  Normalization = "nfc"
+	NormalizationNFD  Normalization = "nfd"
+)
+
+// DefaultMaxBytes is the maximum filename length on most filesystems (ext4, NTFS, Btrfs).
+// eCryptfs users should set it to 143.
+const DefaultMaxBytes = 255
+
+// reservedNames are the device names that cannot be used as filenames on Windows,
+// with or without extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Each replacer makes a single pass over the name: at each position the first listed
+// string that matches is replaced, and the replacements are never replaced again.
+var (
+	posixReplacer = strings.NewReplacer(
+		"/", "",
+		"\x00", "",
+	)
+	windowsReplacer = strings.NewReplacer(
+		":", " -",
+		"*", "",
+		"?", "",
+		"\"", "'",
+		"<", "",
+		">", "",
+		"|", "",
+		"/", "",
+		"\\", "",
+		"\x00", "",
+	)
+)
+
+// seasonEpisodeRegex matches the SxxExx part of a filename that must survive truncation.
+var seasonEpisodeRegex = regexp.MustCompile(`(?i)\bS\d{1,3}E\d{1,4}(?:-?E\d{1,4})*\b`)
+
+// SanitizerOptions configures a Sanitizer.
+type SanitizerOptions struct {
+	Profile       Profile
+	Normalization Normalization
+	Transliterate bool
+	MaxBytes      int
+}
+
+// Sanitizer makes filenames safe for a target filesystem.
+type Sanitizer struct {
+	profile       Profile
+	normalization Normalization
+	transliterate bool
+	maxBytes      int
+	replacer      *strings.Replacer
+}
+
+// NewSanitizer creates a new sanitizer from the given options.
+// Empty options fall back to the profile defaults.
+func NewSanitizer(opts SanitizerOptions) (*Sanitizer, error) {
+	s := &Sanitizer{
+		profile:       opts.Profile,
+		normalization: opts.Normalization,
+		transliterate: opts.Transliterate,
+		maxBytes:      opts.MaxBytes,
+	}
+
+	if s.profile == "" {
+		s.profile = DefaultProfile
+	}
+
+	switch s.profile {
+	case ProfilePOSIX:
+		s.replacer = posixReplacer
+	case ProfileWindowsSafe, ProfileSMB:
+		s.replacer = windowsReplacer
+	case ProfileASCII:
+		s.replacer = windowsReplacer
+		s.transliterate = true
+	default:
+		return nil, fmt.Errorf("unsupported sanitization profile: %s", s.profile)
+	}
+
+	if s.normalization == "" {
+		s.normalization = NormalizationNFC
+	}
+	switch s.normalization {
+	case NormalizationNone, NormalizationNFC, NormalizationNFD:
+	default:
+		return nil, fmt.Errorf("unsupported unicode normalization: %s", s.normalization)
+	}
+	if s.profile == ProfileSMB {
+		s.normalization = NormalizationNFC
+	}
+
+	if s.maxBytes < 0 {
+		return nil, fmt.Errorf("max bytes must be positive, got %d", s.maxBytes)
+	}
+	if s.maxBytes == 0 {
+		s.maxBytes = DefaultMaxBytes
+	}
+
+	return s, nil
+}
+
+// Profile returns the profile of the sanitizer.
+func (s *Sanitizer) Profile() Profile {
+	return s.profile
+}
+
+// Sanitize cleans a filename without its extension. It does not truncate.
+func (s *Sanitizer) Sanitize(name string) string {
+	if !utf8.ValidString(name) {
+		name = strings.ToValidUTF8(name, "")
+	}
+
+	if s.transliterate {
+		name = unidecode.Unidecode(norm.NFC.String(name))
+	}
+
+	name = s.replacer.Replace(name)
+
+	// Control characters are turned into spaces so that tabs and newlines act as separators
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		if s.profile == ProfileASCII && r > unicode.MaxASCII {
+			return -1
+		}
+		return r
+	}, name)
+
+	// Remove multiple spaces and trim
+	name = strings.Join(strings.Fields(name), " ")
+
+	if s.profile != ProfilePOSIX {
+		name = strings.TrimRight(name, ". ")
+		name = escapeReservedName(name)
+	}
+
+	return s.normalize(name)
+}
+
+// SanitizeFilename cleans a filename and appends the given extension, truncating
+// the name so that the result fits in the configured byte limit. The extension and
+// the SxxExx part are preserved, unless the extension alone does not fit.
+func (s *Sanitizer) SanitizeFilename(name, ext string) string {
+	name = s.Sanitize(name)
+	ext = s.normalize(ext)
+
+	budget := s.maxBytes - len(ext)
+	if budget <= 0 {
+		// The extension is cut with the name
+		name, ext, budget = name+ext, "", s.maxBytes
+	}
+	if len(name) > budget {
+		name = truncateName(name, budget)
+	}
+
+	// A truncation can bring a reserved name back, such as CON_ cut to CON
+	if s.profile != ProfilePOSIX {
+		escaped := escapeReservedName(strings.TrimRight(name, ". "))
+		if len(escaped) > budget {
+			escaped = escapeReservedName(strings.TrimRight(truncateName(name, budget-1), ". "))
+		}
+		name = escaped
+	}
+
+	return name + ext
+}
+
+// normalize applies the configured Unicode normalization form.
+func (s *Sanitizer) normalize(name string) string {
+	switch s.normalization {
+	case NormalizationNFC:
+		return norm.NFC.String(name)
+	case NormalizationNFD:
+		return norm.NFD.String(name)
+	}
+	return name
+}
+
+// escapeReservedName suffixes Windows reserved device names with an underscore.
+func escapeReservedName(name string) string {
+	stem, rest, _ := strings.Cut(name, ".")
+	if reservedNames[strings.ToUpper(strings.TrimSpace(stem))] {
+		if rest == "" {
+			return stem + "_"
+		}
+		return stem + "_." + rest
+	}
+	return name
+}
+
+// truncateName shortens name to at most budget bytes. When the name holds a SxxExx
+// part, the text after it (usually the episode title) is shortened first, then the
+// text before it (the show name).
+func truncateName(name string, budget int) string {
+	loc := seasonEpisodeRegex.FindStringIndex(name)
+	if loc == nil {
+		return trimSeparators(truncateBytes(name, budget))
+	}
+
+	prefix, token, suffix := name[:loc[0]], name[loc[0]:loc[1]], name[loc[1]:]
+
+	if len(token) >= budget {
+		return truncateBytes(token, budget)
+	}
+
+	if len(prefix)+len(token) <= budget {
+		suffix = trimSeparators(truncateBytes(suffix, budget-len(prefix)-len(token)))
+		return prefix + token + suffix
+	}
+
+	prefix = truncateBytes(prefix, budget-len(token)-1)
+	prefix = strings.TrimRight(prefix, " -._")
+	if prefix == "" {
+		return token
+	}
+	return prefix + " " + token
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a rune.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	// Do not leave a base character without its combining marks
+	for n > 0 {
+		r, _ := utf8.DecodeRuneInString(s[n:])
+		if !unicode.Is(unicode.Mn, r) {
+			break
+		}
+		_, size := utf8.DecodeLastRuneInString(s[:n])
+		n -= size
+	}
+
+	return s[:n]
+}
+
+// trimSeparators removes the dangling separators left by a truncation.
+func trimSeparators(s string) string {
+	return strings.TrimRight(s, " -._")
+}
@@ -0,0 +1,309 @@
+package formatters
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// alphabet holds the characters that are the most likely to break a filesystem
+var alphabet = []rune{
+	'a', 'Z', '0', '9', ' ', '\u00a0', '.', '-', '_', '(', ')', '\'',
+	':', '*', '?', '"', '<', '>', '|', '/', '\\', '\x00', '\t', '\n', '\x7f', '\u0085', ' ',
+	'é', 'ñ', 'ø', 'ß', 'e', '\u0301', '\u0308', '日', '本', '語', '한', 'ア', '🎉', '\u200b',
+}
+
+// filenameInput is a random filename made of troublesome characters
+type filenameInput string
+
+func (filenameInput) Generate(r *rand.Rand, size int) reflect.Value {
+	var b strings.Builder
+
+	n := r.Intn(size*4 + 1)
+	for i := 0; i < n; i++ {
+		b.WriteRune(alphabet[r.Intn(len(alphabet))])
+	}
+
+	// Sometimes make it look like an episode, sometimes like a reserved name
+	switch r.Intn(4) {
+	case 0:
+		return reflect.ValueOf(filenameInput(b.String() + " - S01E02 - " + b.String()))
+	case 1:
+		return reflect.ValueOf(filenameInput([]string{"CON", "nul", "Com1", "LPT9", "aux.info"}[r.Intn(5)] + b.String()))
+	}
+
+	return reflect.ValueOf(filenameInput(b.String()))
+}
+
+var profiles = []Profile{ProfilePOSIX, ProfileWindowsSafe, ProfileSMB, ProfileASCII}
+
+func newTestSanitizer(t *testing.T, opts SanitizerOptions) *Sanitizer {
+	t.Helper()
+
+	s, err := NewSanitizer(opts)
+	if err != nil {
+		t.Fatalf("NewSanitizer(%+v) returned an error: %v", opts, err)
+	}
+	return s
+}
+
+func TestSanitizer_Properties(t *testing.T) {
+	for _, profile := range profiles {
+		for _, maxBytes := range []int{255, 143, 40} {
+			s := newTestSanitizer(t, SanitizerOptions{Profile: profile, MaxBytes: maxBytes})
+
+			t.Run(string(profile), func(t *testing.T) {
+				property := func(in filenameInput) bool {
+					name := s.Sanitize(string(in))
+					filename := s.SanitizeFilename(string(in), ".mkv")
+
+					if !utf8.ValidString(name) || !utf8.ValidString(filename) {
+						t.Logf("invalid UTF-8 for %q", in)
+						return false
+					}
+					if s.Sanitize(name) != name {
+						t.Logf("not idempotent for %q: %q then %q", in, name, s.Sanitize(name))
+						return false
+					}
+					if len(filename) > maxBytes || !strings.HasSuffix(filename, ".mkv") {
+						t.Logf("wrong length or extension for %q: %q", in, filename)
+						return false
+					}
+					if strings.Contains(string(in), "S01E02") && !strings.Contains(filename, "S01E02") {
+						t.Logf("season and episode lost for %q: %q", in, filename)
+						return false
+					}
+					if strings.ContainsAny(name, "/\x00\t\n") || name != strings.TrimSpace(name) {
+						t.Logf("forbidden characters for %q: %q", in, name)
+						return false
+					}
+
+					return checkProfile(t, profile, string(in), name)
+				}
+
+				if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+func TestSanitizer_Properties_Truncation(t *testing.T) {
+	for _, profile := range profiles {
+		for _, maxBytes := range []int{1, 3, 4, 5, 8, 12} {
+			s := newTestSanitizer(t, SanitizerOptions{Profile: profile, MaxBytes: maxBytes})
+
+			t.Run(string(profile), func(t *testing.T) {
+				property := func(in filenameInput) bool {
+					for _, input := range []string{string(in), "CON_" + string(in), "com1" + string(in)} {
+						filename := s.SanitizeFilename(input, ".mkv")
+						if len(filename) > maxBytes || !utf8.ValidString(filename) {
+							t.Logf("too long for %q: %q", input, filename)
+							return false
+						}
+						if maxBytes > len(".mkv") && !strings.HasSuffix(filename, ".mkv") {
+							t.Logf("extension lost for %q: %q", input, filename)
+							return false
+						}
+						if !checkProfile(t, profile, input, strings.TrimSuffix(filename, ".mkv")) {
+							return false
+						}
+					}
+					return true
+				}
+
+				if err := quick.Check(property, &quick.Config{MaxCount: 300}); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+// checkProfile checks the properties specific to a profile
+func checkProfile(t *testing.T, profile Profile, in, name string) bool {
+	if profile == ProfilePOSIX {
+		return true
+	}
+
+	if strings.ContainsAny(name, `:*?"<>|\`) || strings.HasSuffix(name, ".") {
+		t.Logf("%s: forbidden characters for %q: %q", profile, in, name)
+		return false
+	}
+
+	stem, _, _ := strings.Cut(name, ".")
+	if reservedNames[strings.ToUpper(strings.TrimSpace(stem))] {
+		t.Logf("%s: reserved name for %q: %q", profile, in, name)
+		return false
+	}
+
+	switch profile {
+	case ProfileSMB:
+		if !norm.NFC.IsNormalString(name) {
+			t.Logf("%s: not NFC for %q: %q", profile, in, name)
+			return false
+		}
+	case ProfileASCII:
+		for i := 0; i < len(name); i++ {
+			if name[i] >= utf8.RuneSelf {
+				t.Logf("%s: not ASCII for %q: %q", profile, in, name)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func TestSanitizer_Normalization(t *testing.T) {
+	decomposed := "Poke\u0301mon"
+	composed := "Pokémon"
+
+	tests := []struct {
+		normalization Normalization
+		expected      string
+	}{
+		{NormalizationNFC, composed},
+		{NormalizationNFD, decomposed},
+		{NormalizationNone, decomposed},
+	}
+
+	for _, tt := range tests {
+		s := newTestSanitizer(t, SanitizerOptions{Profile: ProfilePOSIX, Normalization: tt.normalization})
+		if result := s.Sanitize(decomposed); result != tt.expected {
+			t.Errorf("Sanitize(%q) with %s = %q, want %q", decomposed, tt.normalization, result, tt.expected)
+		}
+	}
+}
+
+func TestSanitizer_Transliterate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Amélie", "Amelie"},
+		{"Le Fabuleux Destin d'Amélie Poulain", "Le Fabuleux Destin d'Amelie Poulain"},
+		{"千と千尋の神隠し", "Qian toQian Xun noShen Yin shi"},
+		{"Smörgåsbord: 🎉", "Smorgasbord -"},
+	}
+
+	s := newTestSanitizer(t, SanitizerOptions{Profile: ProfileWindowsSafe, Transliterate: true})
+	for _, tt := range tests {
+		if result := s.Sanitize(tt.input); result != tt.expected {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestSanitizer_WindowsRules(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"CON", "CON_"},
+		{"nul.backup", "nul_.backup"},
+		{"Console", "Console"},
+		{"Mr. Robot...", "Mr. Robot"},
+		{"Trailing space . ", "Trailing space"},
+		{"bell\x07char", "bell char"},
+	}
+
+	s := newTestSanitizer(t, SanitizerOptions{Profile: ProfileWindowsSafe})
+	for _, tt := range tests {
+		if result := s.Sanitize(tt.input); result != tt.expected {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+
+	posix := newTestSanitizer(t, SanitizerOptions{Profile: ProfilePOSIX})
+	if result := posix.Sanitize("What?: CON..."); result != "What?: CON..." {
+		t.Errorf("posix Sanitize() = %q, want it untouched", result)
+	}
+}
+
+func TestSanitizer_Truncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int
+		input    string
+		expected string
+	}{
+		{
+			name:     "fits",
+			maxBytes: 255,
+			input:    "Show - S01E01 - Pilot",
+			expected: "Show - S01E01 - Pilot.mkv",
+		},
+		{
+			name:     "episode title is shortened first",
+			maxBytes: 24,
+			input:    "Show - S01E01 - A Very Long Episode Title",
+			expected: "Show - S01E01 - A Ve.mkv",
+		},
+		{
+			name:     "show name is shortened when the title is not enough",
+			maxBytes: 20,
+			input:    "A Very Long Show Name - S01E01 - Pilot",
+			expected: "A Very Lo S01E01.mkv",
+		},
+		{
+			name:     "multi-episode token is kept",
+			maxBytes: 24,
+			input:    "Show Name - S01E01-E02 - Title",
+			expected: "Show Name S01E01-E02.mkv",
+		},
+		{
+			name:     "runes are not split",
+			maxBytes: 10,
+			input:    "ééééé",
+			expected: "ééé.mkv",
+		},
+		{
+			name:     "reserved name brought back by the truncation",
+			maxBytes: 7,
+			input:    "CON_ Air",
+			expected: "CO.mkv",
+		},
+		{
+			name:     "extension that does not fit",
+			maxBytes: 3,
+			input:    "Movie",
+			expected: "Mov",
+		},
+		{
+			name:     "movie without token",
+			maxBytes: 16,
+			input:    "The Shawshank Redemption (1994)",
+			expected: "The Shawshan.mkv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSanitizer(t, SanitizerOptions{Profile: ProfileWindowsSafe, MaxBytes: tt.maxBytes})
+			if result := s.SanitizeFilename(tt.input, ".mkv"); result != tt.expected {
+				t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewSanitizer_Invalid(t *testing.T) {
+	invalid := []SanitizerOptions{
+		{Profile: "fat12"},
+		{Normalization: "nfkc"},
+		{MaxBytes: -1},
+	}
+
+	for _, opts := range invalid {
+		if _, err := NewSanitizer(opts); err == nil {
+			t.Errorf("NewSanitizer(%+v) should have returned an error", opts)
+		}
+	}
+}
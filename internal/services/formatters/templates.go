@@ -1,5 +1,7 @@
 package formatters
 
+import "strings"
+
 // Common template formats for different naming conventions
 const (
 	// TV Show templates
@@ -15,8 +17,38 @@ const (
 	PlexFormatMovie = "{{.Name}} ({{.Year}})"
 
 	// KodiFormatTVShow is : ShowName (2001) - 1x01 - First Episode
-	KodiFormatTVShow = "{{.Name}} ({{.Year}}) - {{.Title}} {{.Season}}x{{.Episode}})"
+	KodiFormatTVShow = "{{.Name}} ({{.Year}}) - {{.Season}}x{{printf \"%02d\" .Episode}} - {{.Title}}"
+
+	// KodiFormatMovie is : MovieName (2001)
+	KodiFormatMovie = "{{.Name}} ({{.Year}})"
 
 	// EmbyFormatTVShow is : ShowName (2001) - S01E01 - First Episode
 	EmbyFormatTVShow = "{{.Name}} ({{.Year}}) - S{{printf \"%02d\" .Season}}E{{printf \"%02d\" .Episode}} - {{.Title}}"
+
+	// EmbyFormatMovie is : MovieName (2001)
+	EmbyFormatMovie = "{{.Name}} ({{.Year}})"
 )
+
+// Preset is the naming convention of a media server
+type Preset struct {
+	TVShow string
+	Movie  string
+}
+
+// Presets are the naming conventions selected by name instead of a template
+var Presets = map[string]Preset{
+	"plex":     {TVShow: PlexFormatTVShow, Movie: PlexFormatMovie},
+	"kodi":     {TVShow: KodiFormatTVShow, Movie: KodiFormatMovie},
+	"emby":     {TVShow: EmbyFormatTVShow, Movie: EmbyFormatMovie},
+	"jellyfin": {TVShow: EmbyFormatTVShow, Movie: EmbyFormatMovie},
+}
+
+// DefaultPreset is the preset used when no template is given
+const DefaultPreset = "plex"
+
+// lookupPreset returns the preset named by a format, if any
+func lookupPreset(format string) (string, Preset, bool) {
+	name := strings.ToLower(strings.TrimSpace(format))
+	preset, ok := Presets[name]
+	return name, preset, ok
+}
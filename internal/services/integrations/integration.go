@@ -0,0 +1,138 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout bounds the refresh of a media server
+const DefaultTimeout = 30 * time.Second
+
+// Integration is a media server refreshing the folders of the renamed files
+type Integration interface {
+	Name() string
+
+	// Refresh rescans the folders, given as seen by the media server
+	Refresh(ctx context.Context, folders []string) error
+}
+
+// server is an enabled media server with its path mappings
+type server struct {
+	integration Integration
+	mappings    []models.PathMapping
+	timeout     time.Duration
+}
+
+// Integrations refreshes the configured media servers after an apply
+type Integrations struct {
+	servers []server
+}
+
+// New creates the integrations of the media servers that have a URL
+func New(config models.Integrations) *Integrations {
+	integrations := &Integrations{}
+
+	add := func(integration Integration, config models.MediaServer) {
+		timeout := config.Timeout
+		if timeout == 0 {
+			timeout = DefaultTimeout
+		}
+		integrations.servers = append(integrations.servers, server{
+			integration: integration,
+			mappings:    config.PathMappings,
+			timeout:     timeout,
+		})
+	}
+
+	if config.Plex.URL != "" {
+		add(NewPlex(config.Plex.URL, config.Plex.Token), config.Plex)
+	}
+	if config.Jellyfin.URL != "" {
+		add(NewJellyfin(config.Jellyfin.URL, config.Jellyfin.Token), config.Jellyfin)
+	}
+	if config.Emby.URL != "" {
+		add(NewEmby(config.Emby.URL, config.Emby.Token), config.Emby)
+	}
+
+	return integrations
+}
+
+// Refresh asks the media servers to rescan the folders of the applied changes, and
+// records the outcome in the result. A failing media server does not stop the others.
+func (i *Integrations) Refresh(ctx context.Context, result *plans.ApplyResult) {
+	if i == nil || len(i.servers) == 0 {
+		return
+	}
+
+	folders := result.Folders()
+	if len(folders) == 0 {
+		return
+	}
+
+	for _, server := range i.servers {
+		mapped := make([]string, 0, len(folders))
+		for _, folder := range folders {
+			mapped = append(mapped, MapPath(folder, server.mappings))
+		}
+
+		integrationResult := plans.IntegrationResult{
+			Name:    server.integration.Name(),
+			Folders: mapped,
+		}
+
+		refreshCtx, cancel := context.WithTimeout(ctx, server.timeout)
+		err := server.integration.Refresh(refreshCtx, mapped)
+		cancel()
+
+		if err != nil {
+			log.Warn("failed to refresh the media server", zap.String("integration", integrationResult.Name), zap.Error(err))
+			integrationResult.Error = err.Error()
+		} else {
+			log.Debug("media server refreshed", zap.String("integration", integrationResult.Name), zap.Strings("folders", mapped))
+		}
+
+		result.Integrations = append(result.Integrations, integrationResult)
+	}
+}
+
+// MapPath translates a path with the first mapping whose prefix matches it
+func MapPath(path string, mappings []models.PathMapping) string {
+	for _, mapping := range mappings {
+		from := filepath.Clean(mapping.From)
+		if path == from {
+			return mapping.To
+		}
+		if rel, ok := strings.CutPrefix(path, from+string(filepath.Separator)); ok {
+			return strings.TrimSuffix(mapping.To, "/") + "/" + filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// do sends the request and checks the status of the response
+func do(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,241 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+// mockServer records the requests of the media server under test
+type mockServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+}
+
+func newMockServer(t *testing.T, handler http.HandlerFunc) *mockServer {
+	m := &mockServer{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		m.mu.Lock()
+		m.requests = append(m.requests, r)
+		m.bodies = append(m.bodies, string(body))
+		m.mu.Unlock()
+
+		handler(w, r)
+	}))
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+// plexHandler serves two library sections and accepts the refreshes
+func plexHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Plex-Token") != "plex-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/library/sections":
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"MediaContainer":{"Directory":[
+			{"key":"1","title":"Movies","Location":[{"path":"/data/movies"}]},
+			{"key":"2","title":"Kids movies","Location":[{"path":"/data/movies/kids"}]},
+			{"key":"3","title":"TV Shows","Location":[{"path":"/data/tv"}]}
+		]}}`)
+	case "/library/sections/1/refresh", "/library/sections/2/refresh", "/library/sections/3/refresh":
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPlex_Refresh(t *testing.T) {
+	server := newMockServer(t, plexHandler)
+
+	err := NewPlex(server.URL+"/", "plex-token").Refresh(context.Background(), []string{
+		"/data/movies/Alien (1979)",
+		"/data/movies/kids/Up (2009)",
+		"/data/tv/Dark/Season 01",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, r := range server.requests[1:] {
+		got = append(got, r.URL.Path+" "+r.URL.Query().Get("path"))
+	}
+	want := []string{
+		"/library/sections/1/refresh /data/movies/Alien (1979)",
+		"/library/sections/2/refresh /data/movies/kids/Up (2009)",
+		"/library/sections/3/refresh /data/tv/Dark/Season 01",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got refreshes %q, want %q", got, want)
+	}
+}
+
+func TestPlex_Refresh_Errors(t *testing.T) {
+	server := newMockServer(t, plexHandler)
+
+	err := NewPlex(server.URL, "plex-token").Refresh(context.Background(), []string{"/data/movies/Alien (1979)", "/downloads/Alien (1979)"})
+	if err == nil || !strings.Contains(err.Error(), "/downloads/Alien (1979): not in a library section") {
+		t.Errorf("got error %v", err)
+	}
+	if len(server.requests) != 2 {
+		t.Errorf("got %d requests, the folders in a section must still be refreshed", len(server.requests))
+	}
+
+	err = NewPlex(server.URL, "wrong").Refresh(context.Background(), []string{"/data/movies"})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("got error %v, want an unauthorized error", err)
+	}
+}
+
+func TestMediaBrowser_Refresh(t *testing.T) {
+	tests := []struct {
+		name     string
+		create   func(url string) *MediaBrowser
+		wantPath string
+	}{
+		{name: "jellyfin", create: func(url string) *MediaBrowser { return NewJellyfin(url, "api-key") }, wantPath: "/Library/Media/Updated"},
+		{name: "emby", create: func(url string) *MediaBrowser { return NewEmby(url, "api-key") }, wantPath: "/emby/Library/Media/Updated"},
+		{name: "emby with prefix", create: func(url string) *MediaBrowser { return NewEmby(url+"/emby/", "api-key") }, wantPath: "/emby/Library/Media/Updated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			if err := tt.create(server.URL).Refresh(context.Background(), []string{"/data/movies/Alien (1979)"}); err != nil {
+				t.Fatal(err)
+			}
+
+			r := server.requests[0]
+			if r.Method != http.MethodPost || r.URL.Path != tt.wantPath || r.Header.Get("X-Emby-Token") != "api-key" {
+				t.Errorf("got %s %s with token %q", r.Method, r.URL.Path, r.Header.Get("X-Emby-Token"))
+			}
+
+			var body struct {
+				Updates []mediaUpdate
+			}
+			json.Unmarshal([]byte(server.bodies[0]), &body)
+			if want := []mediaUpdate{{Path: "/data/movies/Alien (1979)", UpdateType: "Modified"}}; !reflect.DeepEqual(body.Updates, want) {
+				t.Errorf("got updates %+v, want %+v", body.Updates, want)
+			}
+		})
+	}
+}
+
+func TestIntegrations_Refresh(t *testing.T) {
+	plex := newMockServer(t, plexHandler)
+	jellyfin := newMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	integrations := New(models.Integrations{
+		Plex: models.MediaServer{
+			URL:          plex.URL,
+			Token:        "plex-token",
+			PathMappings: []models.PathMapping{{From: "/media", To: "/data/"}},
+		},
+		Jellyfin: models.MediaServer{URL: jellyfin.URL, Token: "api-key"},
+	})
+
+	result := &plans.ApplyResult{Changes: []plans.ChangeResult{
+		{Before: "/media/movies/alien.mkv", After: "/media/movies/Alien (1979)/Alien (1979).mkv", Status: plans.ChangeStatusApplied},
+		{Before: "/media/movies/up.mkv", After: "/media/movies/Up (2009)/Up (2009).mkv", Status: plans.ChangeStatusFailed},
+		{Before: "/media/movies/heat.mkv", After: "/media/movies/Heat (1995).mkv", Status: plans.ChangeStatusApplied},
+	}}
+	integrations.Refresh(context.Background(), result)
+
+	if len(result.Integrations) != 2 {
+		t.Fatalf("got %d integration results, want 2", len(result.Integrations))
+	}
+
+	want := plans.IntegrationResult{Name: "plex", Folders: []string{"/data/movies", "/data/movies/Alien (1979)"}}
+	if !reflect.DeepEqual(result.Integrations[0], want) {
+		t.Errorf("got %+v, want %+v", result.Integrations[0], want)
+	}
+
+	if jellyfinResult := result.Integrations[1]; jellyfinResult.Name != "jellyfin" || !strings.Contains(jellyfinResult.Error, "500") {
+		t.Errorf("got %+v, want a failed jellyfin refresh", jellyfinResult)
+	}
+}
+
+func TestIntegrations_Refresh_Timeout(t *testing.T) {
+	server := newMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	integrations := New(models.Integrations{
+		Jellyfin: models.MediaServer{URL: server.URL, Token: "api-key", Timeout: 50 * time.Millisecond},
+	})
+
+	result := &plans.ApplyResult{Changes: []plans.ChangeResult{
+		{Before: "/media/a.mkv", After: "/media/A.mkv", Status: plans.ChangeStatusApplied},
+	}}
+	integrations.Refresh(context.Background(), result)
+
+	if len(result.Integrations) != 1 || !strings.Contains(result.Integrations[0].Error, "deadline exceeded") {
+		t.Errorf("got %+v, want a timeout", result.Integrations)
+	}
+}
+
+func TestIntegrations_Refresh_NothingApplied(t *testing.T) {
+	server := newMockServer(t, plexHandler)
+
+	result := &plans.ApplyResult{Changes: []plans.ChangeResult{
+		{Before: "/data/movies/a.mkv", After: "/data/movies/A.mkv", Status: plans.ChangeStatusFailed},
+	}}
+	New(models.Integrations{Plex: models.MediaServer{URL: server.URL, Token: "plex-token"}}).Refresh(context.Background(), result)
+
+	if len(result.Integrations) != 0 || len(server.requests) != 0 {
+		t.Errorf("got %+v and %d requests, want no refresh", result.Integrations, len(server.requests))
+	}
+}
+
+func TestMapPath(t *testing.T) {
+	mappings := []models.PathMapping{
+		{From: "/mnt/media/movies", To: "/movies"},
+		{From: "/mnt/media/", To: "/data/"},
+	}
+
+	tests := map[string]string{
+		"/mnt/media/movies/Alien (1979)": "/movies/Alien (1979)",
+		"/mnt/media/tv/Dark":             "/data/tv/Dark",
+		"/mnt/media":                     "/data/",
+		"/mnt/mediaserver/tv":            "/mnt/mediaserver/tv",
+	}
+
+	for path, want := range tests {
+		if got := MapPath(path, mappings); got != want {
+			t.Errorf("MapPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MediaBrowser refreshes the folders of Jellyfin or Emby, which share the API of
+// MediaBrowser to report the updated paths
+type MediaBrowser struct {
+	name   string
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewJellyfin creates the Jellyfin integration, authenticated with an API key
+func NewJellyfin(serverURL, apiKey string) *MediaBrowser {
+	return &MediaBrowser{
+		name:   "jellyfin",
+		url:    strings.TrimSuffix(serverURL, "/"),
+		token:  apiKey,
+		client: &http.Client{},
+	}
+}
+
+// NewEmby creates the Emby integration, authenticated with an API key. The API is
+// served under /emby.
+func NewEmby(serverURL, apiKey string) *MediaBrowser {
+	serverURL = strings.TrimSuffix(serverURL, "/")
+	if !strings.HasSuffix(serverURL, "/emby") {
+		serverURL += "/emby"
+	}
+
+	return &MediaBrowser{
+		name:   "emby",
+		url:    serverURL,
+		token:  apiKey,
+		client: &http.Client{},
+	}
+}
+
+func (m *MediaBrowser) Name() string {
+	return m.name
+}
+
+// mediaUpdate is a path reported to /Library/Media/Updated
+type mediaUpdate struct {
+	Path       string `json:"Path"`
+	UpdateType string `json:"UpdateType"`
+}
+
+// Refresh reports the folders as modified, the server rescans the libraries containing them
+func (m *MediaBrowser) Refresh(ctx context.Context, folders []string) error {
+	updates := make([]mediaUpdate, 0, len(folders))
+	for _, folder := range folders {
+		updates = append(updates, mediaUpdate{Path: folder, UpdateType: "Modified"})
+	}
+
+	body, err := json.Marshal(map[string][]mediaUpdate{"Updates": updates})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url+"/Library/Media/Updated", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Emby-Token", m.token)
+
+	resp, err := do(m.client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,115 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Plex refreshes the folders of the library sections of a Plex Media Server
+type Plex struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewPlex creates the Plex integration, authenticated with an X-Plex-Token
+func NewPlex(serverURL, token string) *Plex {
+	return &Plex{
+		url:    strings.TrimSuffix(serverURL, "/"),
+		token:  token,
+		client: &http.Client{},
+	}
+}
+
+func (p *Plex) Name() string {
+	return "plex"
+}
+
+// plexSections is the response of /library/sections
+type plexSections struct {
+	MediaContainer struct {
+		Directory []struct {
+			Key      string `json:"key"`
+			Title    string `json:"title"`
+			Location []struct {
+				Path string `json:"path"`
+			} `json:"Location"`
+		} `json:"Directory"`
+	} `json:"MediaContainer"`
+}
+
+// Refresh starts a partial scan of each folder, in the section whose location contains it
+func (p *Plex) Refresh(ctx context.Context, folders []string) error {
+	sections, err := p.sections(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the library sections: %w", err)
+	}
+
+	var failures []string
+	for _, folder := range folders {
+		key, ok := sectionOf(sections, folder)
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s: not in a library section", folder))
+			continue
+		}
+
+		query := url.Values{"path": {folder}}
+		if err := p.get(ctx, "/library/sections/"+url.PathEscape(key)+"/refresh?"+query.Encode(), nil); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", folder, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to refresh %d folder(s): %s", len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (p *Plex) sections(ctx context.Context) (*plexSections, error) {
+	var sections plexSections
+	if err := p.get(ctx, "/library/sections", &sections); err != nil {
+		return nil, err
+	}
+	return &sections, nil
+}
+
+// sectionOf returns the key of the section with the longest location containing the folder
+func sectionOf(sections *plexSections, folder string) (string, bool) {
+	key, longest := "", -1
+	for _, section := range sections.MediaContainer.Directory {
+		for _, location := range section.Location {
+			root := strings.TrimSuffix(location.Path, "/")
+			if (folder == root || strings.HasPrefix(folder, root+"/")) && len(root) > longest {
+				key, longest = section.Key, len(root)
+			}
+		}
+	}
+	return key, longest >= 0
+}
+
+// get sends a request to the server, decoding the JSON response into v when not nil
+func (p *Plex) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Plex-Token", p.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := do(p.client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if v == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,279 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"goru/internal/services/metrics"
+	"goru/internal/services/plans"
+)
+
+// Kind is the kind of work performed by a job
+type Kind string
+
+const (
+	KindPlan  Kind = "plan"
+	KindApply Kind = "apply"
+)
+
+// Status is the status of a job
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// IsFinal returns true if the job will not change anymore
+func (s Status) IsFinal() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+// EventType is the type of an event emitted by a job
+type EventType string
+
+const (
+	// EventStatus is emitted when the status of the job changes
+	EventStatus EventType = "status"
+
+	// EventProgress is emitted each time a file has been processed
+	EventProgress EventType = "progress"
+
+	// EventError is emitted when a file could not be processed
+	EventError EventType = "error"
+
+	// EventPlan is emitted when a plan job produced its plan
+	EventPlan EventType = "plan"
+
+	// EventResult is emitted when an apply job applied its plan
+	EventResult EventType = "result"
+)
+
+// Event is emitted by a job while it runs
+type Event struct {
+	Type      EventType          `json:"type"`
+	Timestamp time.Time          `json:"timestamp"`
+	Status    Status             `json:"status,omitempty"`
+	File      string             `json:"file,omitempty"`
+	Message   string             `json:"message,omitempty"`
+	Current   int                `json:"current,omitempty"`
+	Total     int                `json:"total,omitempty"`
+	Plan      *plans.Plan        `json:"plan,omitempty"`
+	Result    *plans.ApplyResult `json:"result,omitempty"`
+}
+
+// Progress of a job
+type Progress struct {
+	Current int `json:"current"`
+	Total   int `json:"total"`
+}
+
+// Snapshot is the state of a job at a given time
+type Snapshot struct {
+	ID         string             `json:"id"`
+	Kind       Kind               `json:"kind"`
+	Status     Status             `json:"status"`
+	Progress   Progress           `json:"progress"`
+	Error      string             `json:"error,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	StartedAt  *time.Time         `json:"started_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	Plan       *plans.Plan        `json:"plan,omitempty"`
+	Result     *plans.ApplyResult `json:"result,omitempty"`
+}
+
+// RunFunc performs the work of a job. It must return when the context is cancelled.
+type RunFunc func(ctx context.Context, job *Job) error
+
+// Job is a plan or an apply running in the background
+type Job struct {
+	mu sync.RWMutex
+
+	id         string
+	kind       Kind
+	status     Status
+	progress   Progress
+	err        string
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	plan       *plans.Plan
+	result     *plans.ApplyResult
+
+	cancel      context.CancelFunc
+	events      []Event
+	subscribers map[chan Event]struct{}
+}
+
+// ID returns the ID of the job
+func (j *Job) ID() string {
+	return j.id
+}
+
+// Status returns the current status of the job
+func (j *Job) Status() Status {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.status
+}
+
+// Snapshot returns the current state of the job
+func (j *Job) Snapshot() Snapshot {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	snapshot := Snapshot{
+		ID:        j.id,
+		Kind:      j.kind,
+		Status:    j.status,
+		Progress:  j.progress,
+		Error:     j.err,
+		CreatedAt: j.createdAt,
+		Plan:      j.plan,
+		Result:    j.result,
+	}
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		snapshot.StartedAt = &startedAt
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		snapshot.FinishedAt = &finishedAt
+	}
+
+	return snapshot
+}
+
+// ReportProgress records that a file has been processed
+func (j *Job) ReportProgress(file string, current, total int, err error) {
+	event := Event{Type: EventProgress, File: file, Current: current, Total: total}
+	if err != nil {
+		event.Type = EventError
+		event.Message = err.Error()
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.progress = Progress{Current: current, Total: total}
+	j.emit(event)
+}
+
+// SetPlan records the plan produced by the job
+func (j *Job) SetPlan(plan *plans.Plan) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.plan = plan
+	j.emit(Event{Type: EventPlan, Plan: plan})
+}
+
+// SetResult records the result of the plan applied by the job
+func (j *Job) SetResult(result *plans.ApplyResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.result = result
+	j.emit(Event{Type: EventResult, Result: result})
+}
+
+// Subscribe returns a channel receiving the past events of the job, then the new ones.
+// The channel is closed once the job is finished. The returned function must be called
+// to unsubscribe when the caller is not interested anymore.
+func (j *Job) Subscribe() (<-chan Event, func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// The buffer holds the history so that replaying it never blocks, and keeps room
+	// for the events ending the job
+	ch := make(chan Event, len(j.events)+subscriberBuffer+finalEvents)
+	for _, event := range j.events {
+		ch <- event
+	}
+
+	if j.status.IsFinal() {
+		close(ch)
+		return ch, func() {}
+	}
+
+	j.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			j.mu.Lock()
+			defer j.mu.Unlock()
+
+			if _, ok := j.subscribers[ch]; ok {
+				delete(j.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+}
+
+// setStatus changes the status of the job and notifies the subscribers. The subscribers
+// are closed in the same critical section once the job is finished, so none of them
+// misses the final status.
+func (j *Job) setStatus(status Status, err error) {
+	event := Event{Type: EventStatus, Status: status}
+	if err != nil {
+		event.Message = err.Error()
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.status = status
+	switch {
+	case status == StatusRunning:
+		j.startedAt = time.Now()
+	case status.IsFinal():
+		j.finishedAt = time.Now()
+		if !j.startedAt.IsZero() {
+			metrics.JobDuration.WithLabelValues(string(j.kind), string(status)).Observe(j.finishedAt.Sub(j.startedAt).Seconds())
+		}
+	}
+	if err != nil {
+		j.err = err.Error()
+	}
+	j.emit(event)
+
+	if status.IsFinal() {
+		for ch := range j.subscribers {
+			close(ch)
+			delete(j.subscribers, ch)
+		}
+	}
+}
+
+// emit records the event and sends it to the subscribers. It must be called with the
+// lock held. Slow subscribers miss progress events rather than blocking the job, but
+// the room kept for the status, the plan and the result is never used by them. A
+// subscriber that cannot take one of those anymore is closed, and reads the outcome
+// from the snapshot of the job.
+func (j *Job) emit(event Event) {
+	event.Timestamp = time.Now()
+	j.events = append(j.events, event)
+
+	for ch := range j.subscribers {
+		if event.Type == EventProgress || event.Type == EventError {
+			if len(ch) < cap(ch)-finalEvents {
+				ch <- event
+			}
+			continue
+		}
+
+		select {
+		case ch <- event:
+		default:
+			close(ch)
+			delete(j.subscribers, ch)
+		}
+	}
+}
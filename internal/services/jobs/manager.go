@@ -0,0 +1,164 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"goru/pkg/log"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// subscriberBuffer is the number of live events a subscriber can lag behind
+const subscriberBuffer = 256
+
+// finalEvents is the room kept in the buffer of a subscriber for the events that are
+// never dropped: the running status, the plan or the result, and the final status
+const finalEvents = 3
+
+// DefaultRetention is how long finished jobs are kept in memory
+const DefaultRetention = 1 * time.Hour
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobNotRunning  = errors.New("job is not running")
+	ErrManagerStopped = errors.New("job manager is stopped")
+)
+
+// Manager runs jobs in the background and keeps track of them
+type Manager struct {
+	mu        sync.RWMutex
+	jobs      map[string]*Job
+	retention time.Duration
+	ctx       context.Context
+	stop      context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewManager creates a new job manager. Finished jobs are forgotten after the retention.
+func NewManager(retention time.Duration) *Manager {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &Manager{
+		jobs:      make(map[string]*Job),
+		retention: retention,
+		ctx:       ctx,
+		stop:      stop,
+	}
+}
+
+// Start creates a job and runs it in the background
+func (m *Manager) Start(kind Kind, run RunFunc) (*Job, error) {
+	if m.ctx.Err() != nil {
+		return nil, ErrManagerStopped
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+
+	job := &Job{
+		id:          uuid.New().String(),
+		kind:        kind,
+		status:      StatusPending,
+		createdAt:   time.Now(),
+		cancel:      cancel,
+		subscribers: make(map[chan Event]struct{}),
+	}
+
+	m.mu.Lock()
+	m.prune()
+	m.jobs[job.id] = job
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+
+		log.Debug("job started", zap.String("id", job.id), zap.String("kind", string(kind)))
+		job.setStatus(StatusRunning, nil)
+
+		err := run(ctx, job)
+		switch {
+		case ctx.Err() != nil:
+			job.setStatus(StatusCancelled, ctx.Err())
+		case err != nil:
+			log.Error("job failed", zap.String("id", job.id), zap.Error(err))
+			job.setStatus(StatusFailed, err)
+		default:
+			job.setStatus(StatusSucceeded, nil)
+		}
+
+		log.Debug("job finished", zap.String("id", job.id), zap.String("status", string(job.Status())))
+	}()
+
+	return job, nil
+}
+
+// Get returns the job with the given ID
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	return job, nil
+}
+
+// List returns the snapshots of all known jobs, newest first
+func (m *Manager) List() []Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshots := make([]Snapshot, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		snapshots = append(snapshots, job.Snapshot())
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+
+	return snapshots
+}
+
+// Cancel cancels a running job
+func (m *Manager) Cancel(id string) error {
+	job, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if job.Status().IsFinal() {
+		return ErrJobNotRunning
+	}
+
+	job.cancel()
+	return nil
+}
+
+// Stop cancels all running jobs and waits for them to return
+func (m *Manager) Stop() {
+	m.stop()
+	m.wg.Wait()
+}
+
+// prune forgets the jobs finished for longer than the retention. The lock must be held.
+func (m *Manager) prune() {
+	for id, job := range m.jobs {
+		snapshot := job.Snapshot()
+		if snapshot.FinishedAt != nil && time.Since(*snapshot.FinishedAt) > m.retention {
+			delete(m.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goru/internal/services/plans"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+func waitFinal(t *testing.T, job *Job) Snapshot {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if snapshot := job.Snapshot(); snapshot.Status.IsFinal() {
+			return snapshot
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("job %s did not finish", job.ID())
+	return Snapshot{}
+}
+
+func TestManager_Events(t *testing.T) {
+	manager := NewManager(time.Minute)
+	defer manager.Stop()
+
+	release := make(chan struct{})
+	job, err := manager.Start(KindPlan, func(ctx context.Context, job *Job) error {
+		<-release
+		job.ReportProgress("a.mkv", 1, 2, nil)
+		job.ReportProgress("b.mkv", 2, 2, errors.New("not found"))
+		job.SetPlan(plans.NewEmptyPlan())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Start() returned an error: %v", err)
+	}
+
+	events, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+	close(release)
+
+	var types []EventType
+	for event := range events {
+		types = append(types, event.Type)
+	}
+
+	expected := []EventType{EventStatus, EventProgress, EventError, EventPlan, EventStatus}
+	if len(types) != len(expected) {
+		t.Fatalf("got events %v, want %v", types, expected)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Fatalf("got events %v, want %v", types, expected)
+		}
+	}
+
+	snapshot := waitFinal(t, job)
+	if snapshot.Status != StatusSucceeded || snapshot.Plan == nil || snapshot.Progress.Current != 2 {
+		t.Errorf("unexpected snapshot: %+v", snapshot)
+	}
+
+	// Late subscribers get the whole history
+	replay, _ := job.Subscribe()
+	count := 0
+	for range replay {
+		count++
+	}
+	if count != len(expected) {
+		t.Errorf("replayed %d events, want %d", count, len(expected))
+	}
+}
+
+func TestManager_SlowSubscriber(t *testing.T) {
+	manager := NewManager(time.Minute)
+	defer manager.Stop()
+
+	release := make(chan struct{})
+	job, err := manager.Start(KindPlan, func(ctx context.Context, job *Job) error {
+		<-release
+		for i := 1; i <= 2*subscriberBuffer; i++ {
+			job.ReportProgress("a.mkv", i, 2*subscriberBuffer, nil)
+		}
+		job.SetPlan(plans.NewEmptyPlan())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Start() returned an error: %v", err)
+	}
+
+	// The subscriber does not read anything until the job is finished
+	events, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+	close(release)
+	waitFinal(t, job)
+
+	var last []EventType
+	count := 0
+	for event := range events {
+		count++
+		last = append(last, event.Type)
+		if len(last) > 2 {
+			last = last[1:]
+		}
+	}
+
+	if count >= 2*subscriberBuffer {
+		t.Errorf("got %d events, the progress should have been dropped", count)
+	}
+	if len(last) != 2 || last[0] != EventPlan || last[1] != EventStatus {
+		t.Errorf("got last events %v, want the plan and the final status", last)
+	}
+}
+
+func TestManager_Cancel(t *testing.T) {
+	manager := NewManager(time.Minute)
+	defer manager.Stop()
+
+	job, err := manager.Start(KindApply, func(ctx context.Context, job *Job) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Start() returned an error: %v", err)
+	}
+
+	if err := manager.Cancel(job.ID()); err != nil {
+		t.Fatalf("Cancel() returned an error: %v", err)
+	}
+
+	if snapshot := waitFinal(t, job); snapshot.Status != StatusCancelled {
+		t.Errorf("status = %s, want %s", snapshot.Status, StatusCancelled)
+	}
+
+	if err := manager.Cancel(job.ID()); !errors.Is(err, ErrJobNotRunning) {
+		t.Errorf("Cancel() on a finished job = %v, want %v", err, ErrJobNotRunning)
+	}
+	if err := manager.Cancel("unknown"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Cancel() on an unknown job = %v, want %v", err, ErrJobNotFound)
+	}
+}
@@ -0,0 +1,425 @@
+package mediainfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"goru/internal/models"
+)
+
+// The EBML IDs read in a Matroska file, with their marker bits
+const (
+	idEBML           = 0x1A45DFA3
+	idDocType        = 0x4282
+	idSegment        = 0x18538067
+	idCluster        = 0x1F43B675
+	idInfo           = 0x1549A966
+	idTracks         = 0x1654AE6B
+	idTimestampScale = 0x2AD7B1
+	idDuration       = 0x4489
+	idTitle          = 0x7BA9
+
+	idTrackEntry      = 0xAE
+	idTrackType       = 0x83
+	idCodecID         = 0x86
+	idLanguage        = 0x22B59C
+	idLanguageIETF    = 0x22B59D
+	idFlagDefault     = 0x88
+	idFlagForced      = 0x55AA
+	idVideo           = 0xE0
+	idPixelWidth      = 0xB0
+	idPixelHeight     = 0xBA
+	idColour          = 0x55B0
+	idTransfer        = 0x55BA
+	idAudio           = 0xE1
+	idChannels        = 0x9F
+	idBlockAddMapping = 0x41E4
+	idBlockAddIDType  = 0x41E7
+	trackTypeVideo    = 1
+	trackTypeAudio    = 2
+	trackTypeSubtitle = 17
+	blockAddDvcC      = 0x64766343 // dvcC
+	blockAddDvvC      = 0x64767643 // dvvC
+)
+
+// maxElementSize bounds the elements read in memory, the clusters being skipped
+const maxElementSize = 16 << 20
+
+// errUnknownSize is returned for the elements whose size is not known, usually
+// the clusters of a live stream
+var errUnknownSize = errors.New("element of unknown size")
+
+// element is the header of an EBML element
+type element struct {
+	id   uint64
+	size int64 // -1 when unknown
+}
+
+// ebmlReader reads the EBML elements of a file
+type ebmlReader struct {
+	r   io.ReadSeeker
+	pos int64
+}
+
+// vint reads a variable size integer. The marker bit is kept for the IDs.
+func (e *ebmlReader) vint(keepMarker bool) (uint64, int, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(e.r, b[:1]); err != nil {
+		return 0, 0, err
+	}
+	e.pos++
+
+	length := 1
+	for mask := byte(0x80); length <= 8 && b[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 {
+		return 0, 0, fmt.Errorf("invalid EBML integer at %d", e.pos-1)
+	}
+
+	if length > 1 {
+		if _, err := io.ReadFull(e.r, b[1:length]); err != nil {
+			return 0, 0, err
+		}
+		e.pos += int64(length - 1)
+	}
+
+	value := uint64(b[0])
+	if !keepMarker {
+		value &= uint64(0xFF >> length)
+	}
+	for _, c := range b[1:length] {
+		value = value<<8 | uint64(c)
+	}
+	return value, length, nil
+}
+
+func (e *ebmlReader) next() (element, error) {
+	id, _, err := e.vint(true)
+	if err != nil {
+		return element{}, err
+	}
+	size, length, err := e.vint(false)
+	if err != nil {
+		return element{}, err
+	}
+	if size == 1<<(7*length)-1 {
+		return element{id: id, size: -1}, nil
+	}
+	if size > math.MaxInt64/2 {
+		return element{}, fmt.Errorf("invalid size of element %x", id)
+	}
+	return element{id: id, size: int64(size)}, nil
+}
+
+func (e *ebmlReader) skip(size int64) error {
+	if size < 0 {
+		return errUnknownSize
+	}
+	pos, err := e.r.Seek(size, io.SeekCurrent)
+	e.pos = pos
+	return err
+}
+
+func (e *ebmlReader) read(size int64) ([]byte, error) {
+	if size < 0 {
+		return nil, errUnknownSize
+	}
+	if size > maxElementSize {
+		return nil, fmt.Errorf("element of %d bytes too large", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(e.r, data); err != nil {
+		return nil, err
+	}
+	e.pos += size
+	return data, nil
+}
+
+// children walks the elements of a master element, given as bytes
+func children(data []byte, fn func(id uint64, data []byte) error) error {
+	e := &ebmlReader{r: bytes.NewReader(data)}
+	for e.pos < int64(len(data)) {
+		el, err := e.next()
+		if err != nil {
+			return err
+		}
+		if el.size < 0 || e.pos+el.size > int64(len(data)) {
+			return fmt.Errorf("truncated element %x", el.id)
+		}
+		if err := fn(el.id, data[e.pos:e.pos+el.size]); err != nil {
+			return err
+		}
+		if err := e.skip(el.size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMatroska reads the Info and Tracks elements of the segment, skipping the
+// clusters until both are found
+func readMatroska(r io.ReadSeeker, size int64) (*models.MediaInfo, error) {
+	e := &ebmlReader{r: r}
+
+	header, err := e.next()
+	if err != nil || header.id != idEBML {
+		return nil, ErrUnsupported
+	}
+	data, err := e.read(header.size)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &models.MediaInfo{Container: "matroska"}
+	children(data, func(id uint64, data []byte) error {
+		if id == idDocType {
+			info.Container = strings.TrimRight(string(data), "\x00")
+		}
+		return nil
+	})
+
+	segment, err := e.next()
+	for err == nil && segment.id != idSegment {
+		if err = e.skip(segment.size); err == nil {
+			segment, err = e.next()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no segment: %w", err)
+	}
+
+	end := size
+	if segment.size >= 0 && e.pos+segment.size < end {
+		end = e.pos + segment.size
+	}
+
+	// The segment of a cut file, such as an interrupted download, ends after the file
+	info.Truncated = segment.size >= 0 && e.pos+segment.size > size
+
+	var hasInfo, hasTracks bool
+walk:
+	for e.pos < end && !(hasInfo && hasTracks) {
+		el, err := e.next()
+		if err != nil {
+			break
+		}
+
+		switch el.id {
+		case idInfo:
+			data, err := e.read(el.size)
+			if err != nil {
+				return nil, err
+			}
+			if err := readInfo(info, data); err != nil {
+				return nil, err
+			}
+			hasInfo = true
+
+		case idTracks:
+			data, err := e.read(el.size)
+			if err != nil {
+				return nil, err
+			}
+			if err := readTracks(info, data); err != nil {
+				return nil, err
+			}
+			hasTracks = true
+
+		default:
+			if err := e.skip(el.size); err != nil {
+				// The clusters of a live stream have no size, the elements after them
+				// cannot be reached
+				if el.id == idCluster && errors.Is(err, errUnknownSize) {
+					break walk
+				}
+				return nil, err
+			}
+		}
+	}
+
+	if !hasInfo && !hasTracks {
+		return nil, fmt.Errorf("no info nor tracks in the segment")
+	}
+	return info, nil
+}
+
+func readInfo(info *models.MediaInfo, data []byte) error {
+	scale := uint64(1000000)
+	var duration float64
+
+	err := children(data, func(id uint64, data []byte) error {
+		switch id {
+		case idTimestampScale:
+			scale = readUint(data)
+		case idDuration:
+			duration = readFloat(data)
+		case idTitle:
+			info.Title = strings.TrimRight(string(data), "\x00")
+		}
+		return nil
+	})
+
+	info.Duration = time.Duration(duration * float64(scale))
+	return err
+}
+
+func readTracks(info *models.MediaInfo, data []byte) error {
+	return children(data, func(id uint64, data []byte) error {
+		if id != idTrackEntry {
+			return nil
+		}
+
+		// The flags and the language have defaults in Matroska
+		var (
+			trackType          uint64
+			codec, hdr         string
+			lang, langIETF     = "eng", ""
+			isDefault, forced  = true, false
+			width, height, chs int
+		)
+
+		err := children(data, func(id uint64, data []byte) error {
+			switch id {
+			case idTrackType:
+				trackType = readUint(data)
+			case idCodecID:
+				codec = strings.TrimRight(string(data), "\x00")
+			case idLanguage:
+				lang = strings.TrimRight(string(data), "\x00")
+			case idLanguageIETF:
+				langIETF = strings.TrimRight(string(data), "\x00")
+			case idFlagDefault:
+				isDefault = readUint(data) == 1
+			case idFlagForced:
+				forced = readUint(data) == 1
+			case idVideo:
+				return children(data, func(id uint64, data []byte) error {
+					switch id {
+					case idPixelWidth:
+						width = int(readUint(data))
+					case idPixelHeight:
+						height = int(readUint(data))
+					case idColour:
+						return children(data, func(id uint64, data []byte) error {
+							if id == idTransfer && hdr == "" {
+								hdr = hdrOf(readUint(data))
+							}
+							return nil
+						})
+					}
+					return nil
+				})
+			case idAudio:
+				return children(data, func(id uint64, data []byte) error {
+					if id == idChannels {
+						chs = int(readUint(data))
+					}
+					return nil
+				})
+			case idBlockAddMapping:
+				return children(data, func(id uint64, data []byte) error {
+					if id == idBlockAddIDType {
+						if t := readUint(data); t == blockAddDvcC || t == blockAddDvvC {
+							hdr = HDRDolbyVision
+						}
+					}
+					return nil
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if langIETF != "" {
+			lang = langIETF
+		}
+
+		switch trackType {
+		case trackTypeVideo:
+			// The first video track is the main one, the others are usually covers
+			if info.VideoCodec == "" {
+				info.VideoCodec = matroskaCodec(codec)
+				info.Width, info.Height = width, height
+				info.HDR = hdr
+			}
+		case trackTypeAudio:
+			info.AudioTracks = append(info.AudioTracks, models.AudioTrack{
+				Codec:    matroskaCodec(codec),
+				Language: language(lang),
+				Channels: chs,
+				Default:  isDefault,
+			})
+		case trackTypeSubtitle:
+			info.SubtitleTracks = append(info.SubtitleTracks, models.SubtitleTrack{
+				Codec:    matroskaCodec(codec),
+				Language: language(lang),
+				Forced:   forced,
+				Default:  isDefault,
+			})
+		}
+		return nil
+	})
+}
+
+// matroskaCodecs are the names of the Matroska codec IDs, by prefix
+var matroskaCodecs = []struct{ prefix, name string }{
+	{"V_MPEG4/ISO/AVC", "h264"},
+	{"V_MPEGH/ISO/HEVC", "hevc"},
+	{"V_AV1", "av1"},
+	{"V_VP9", "vp9"},
+	{"V_VP8", "vp8"},
+	{"V_MPEG4/ISO/", "mpeg4"},
+	{"V_MPEG2", "mpeg2"},
+	{"A_AAC", "aac"},
+	{"A_EAC3", "eac3"},
+	{"A_AC3", "ac3"},
+	{"A_DTS", "dts"},
+	{"A_TRUEHD", "truehd"},
+	{"A_OPUS", "opus"},
+	{"A_FLAC", "flac"},
+	{"A_VORBIS", "vorbis"},
+	{"A_MPEG/L3", "mp3"},
+	{"A_PCM", "pcm"},
+	{"S_TEXT/UTF8", "srt"},
+	{"S_TEXT/ASS", "ass"},
+	{"S_TEXT/SSA", "ass"},
+	{"S_TEXT/WEBVTT", "webvtt"},
+	{"S_HDMV/PGS", "pgs"},
+	{"S_VOBSUB", "vobsub"},
+}
+
+func matroskaCodec(id string) string {
+	for _, codec := range matroskaCodecs {
+		if strings.HasPrefix(id, codec.prefix) {
+			return codec.name
+		}
+	}
+	return strings.ToLower(id)
+}
+
+func readUint(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+func readFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+// Package mediainfo reads the technical metadata of the video files from their
+// container, without external tools. Matroska (and WebM) and MP4 are supported.
+package mediainfo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"goru/internal/models"
+)
+
+// ErrUnsupported is returned for the containers that cannot be parsed
+var ErrUnsupported = errors.New("unsupported container")
+
+// ErrTruncated is returned for the files cut before their metadata
+var ErrTruncated = errors.New("truncated file")
+
+// Probe reads the technical metadata of a video file
+func Probe(path string) (*models.MediaInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := Read(f, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Duration > 0 {
+		info.Bitrate = int64(float64(stat.Size()*8) / info.Duration.Seconds())
+	}
+	return info, nil
+}
+
+// Read reads the technical metadata of a video of the given size, detecting its
+// container from its first bytes
+func Read(r io.ReadSeeker, size int64) (*models.MediaInfo, error) {
+	header := make([]byte, 12)
+	n, err := io.ReadFull(r, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	header = header[:n]
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return readMatroska(r, size)
+	case len(header) >= 8 && string(header[4:8]) == "ftyp":
+		return readMP4(r, size)
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// Compare compares the quality of two videos: the resolution first, then HDR, the
+// audio channels and the bitrate. It returns a positive number when a is better
+// than b, negative when it is worse and 0 when they cannot be told apart. An
+// unknown video is worse than any known one.
+func Compare(a, b *models.MediaInfo) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	for _, diff := range []int64{
+		int64(resolutionRank(a) - resolutionRank(b)),
+		int64(hdrRank(a.HDR) - hdrRank(b.HDR)),
+		int64(channels(a) - channels(b)),
+		a.Bitrate - b.Bitrate,
+	} {
+		if diff > 0 {
+			return 1
+		}
+		if diff < 0 {
+			return -1
+		}
+	}
+	return 0
+}
+
+// resolutionRank ranks the resolutions by their name, a movie cropped to a wide aspect
+// ratio being as good as the full frame
+func resolutionRank(info *models.MediaInfo) int {
+	switch info.Resolution() {
+	case "2160p":
+		return 6
+	case "1080p":
+		return 5
+	case "720p":
+		return 4
+	case "576p":
+		return 3
+	case "480p":
+		return 2
+	case "SD":
+		return 1
+	}
+	return 0
+}
+
+func hdrRank(hdr string) int {
+	switch hdr {
+	case HDRDolbyVision:
+		return 3
+	case HDR10:
+		return 2
+	case HLG:
+		return 1
+	}
+	return 0
+}
+
+func channels(info *models.MediaInfo) int {
+	if track := info.MainAudioTrack(); track != nil {
+		return track.Channels
+	}
+	return 0
+}
+
+// The HDR formats
+const (
+	HDR10          = "HDR10"
+	HLG            = "HLG"
+	HDRDolbyVision = "Dolby Vision"
+)
+
+// hdrOf returns the HDR format of a video from its transfer characteristics, as
+// defined by ITU-T H.273
+func hdrOf(transfer uint64) string {
+	switch transfer {
+	case 16:
+		return HDR10
+	case 18:
+		return HLG
+	}
+	return ""
+}
+
+// language returns a language code, empty when undetermined
+func language(code string) string {
+	code = strings.TrimRight(code, "\x00")
+	if code == "und" {
+		return ""
+	}
+	return code
+}
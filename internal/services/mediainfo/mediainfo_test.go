@@ -0,0 +1,285 @@
+package mediainfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goru/internal/models"
+)
+
+// ebml encodes an element, its size on 8 bytes
+func ebml(id uint64, children ...[]byte) []byte {
+	var buf bytes.Buffer
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || buf.Len() > 0 {
+			buf.WriteByte(b)
+		}
+	}
+	body := bytes.Join(children, nil)
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(body)))
+	size[0] = 0x01
+	buf.Write(size)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func ebmlUint(id uint64, v uint64) []byte {
+	return ebml(id, binary.BigEndian.AppendUint64(nil, v))
+}
+
+func ebmlString(id uint64, s string) []byte {
+	return ebml(id, []byte(s))
+}
+
+// atom encodes an MP4 atom
+func atom(kind string, children ...[]byte) []byte {
+	body := bytes.Join(children, nil)
+	data := binary.BigEndian.AppendUint32(nil, uint32(len(body)+8))
+	return append(append(data, kind...), body...)
+}
+
+func matroska() []byte {
+	duration := binary.BigEndian.AppendUint64(nil, math.Float64bits(5400000))
+
+	return bytes.Join([][]byte{
+		ebml(idEBML, ebmlString(idDocType, "matroska")),
+		ebml(idSegment,
+			// A cluster before the info, skipped
+			ebml(idCluster, make([]byte, 64)),
+			ebml(idInfo,
+				ebmlUint(idTimestampScale, 1000000),
+				ebml(idDuration, duration),
+				ebmlString(idTitle, "The Matrix"),
+			),
+			ebml(idTracks,
+				ebml(idTrackEntry,
+					ebmlUint(idTrackType, trackTypeVideo),
+					ebmlString(idCodecID, "V_MPEGH/ISO/HEVC"),
+					ebml(idVideo,
+						ebmlUint(idPixelWidth, 3840),
+						ebmlUint(idPixelHeight, 1600),
+						ebml(idColour, ebmlUint(idTransfer, 16)),
+					),
+				),
+				ebml(idTrackEntry,
+					ebmlUint(idTrackType, trackTypeAudio),
+					ebmlString(idCodecID, "A_EAC3"),
+					ebmlString(idLanguage, "fre"),
+					ebmlUint(idFlagDefault, 0),
+					ebml(idAudio, ebmlUint(idChannels, 6)),
+				),
+				ebml(idTrackEntry,
+					ebmlUint(idTrackType, trackTypeAudio),
+					ebmlString(idCodecID, "A_TRUEHD"),
+					ebml(idAudio, ebmlUint(idChannels, 8)),
+				),
+				ebml(idTrackEntry,
+					ebmlUint(idTrackType, trackTypeSubtitle),
+					ebmlString(idCodecID, "S_TEXT/UTF8"),
+					ebmlString(idLanguage, "fre"),
+					ebmlUint(idFlagForced, 1),
+				),
+			),
+		),
+	}, nil)
+}
+
+func TestRead_Matroska(t *testing.T) {
+	data := matroska()
+	info, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Container != "matroska" || info.Title != "The Matrix" || info.Duration != 90*time.Minute {
+		t.Errorf("got container %q, title %q, duration %s", info.Container, info.Title, info.Duration)
+	}
+	if info.VideoCodec != "hevc" || info.Resolution() != "2160p" || info.HDR != HDR10 {
+		t.Errorf("got video %s %s %q", info.VideoCodec, info.Resolution(), info.HDR)
+	}
+
+	want := []models.AudioTrack{
+		{Codec: "eac3", Language: "fre", Channels: 6},
+		{Codec: "truehd", Language: "eng", Channels: 8, Default: true},
+	}
+	if len(info.AudioTracks) != 2 || info.AudioTracks[0] != want[0] || info.AudioTracks[1] != want[1] {
+		t.Errorf("got audio tracks %+v, want %+v", info.AudioTracks, want)
+	}
+	if main := info.MainAudioTrack(); main.ChannelLayout() != "7.1" {
+		t.Errorf("got main audio track %+v", main)
+	}
+
+	sub := models.SubtitleTrack{Codec: "srt", Language: "fre", Forced: true, Default: true}
+	if len(info.SubtitleTracks) != 1 || info.SubtitleTracks[0] != sub {
+		t.Errorf("got subtitle tracks %+v", info.SubtitleTracks)
+	}
+}
+
+func TestRead_MatroskaTruncated(t *testing.T) {
+	data := matroska()
+	data = data[:len(data)-20]
+	if _, err := Read(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("expected an error for a truncated file")
+	}
+}
+
+func mp4() []byte {
+	fullAtom := func(kind string, body ...[]byte) []byte {
+		return atom(kind, append([][]byte{make([]byte, 4)}, body...)...)
+	}
+	handler := func(kind string) []byte {
+		return fullAtom("hdlr", make([]byte, 4), []byte(kind), make([]byte, 13))
+	}
+	mdhd := func(lang string) []byte {
+		packed := uint16(lang[0]-0x60)<<10 | uint16(lang[1]-0x60)<<5 | uint16(lang[2]-0x60)
+		return fullAtom("mdhd", make([]byte, 16), binary.BigEndian.AppendUint16(nil, packed), make([]byte, 2))
+	}
+	stsd := func(entry []byte) []byte {
+		return atom("minf", atom("stbl", fullAtom("stsd", binary.BigEndian.AppendUint32(nil, 1), entry)))
+	}
+
+	visual := make([]byte, 78)
+	binary.BigEndian.PutUint16(visual[24:], 1920)
+	binary.BigEndian.PutUint16(visual[26:], 1080)
+	colr := append([]byte("nclx"), 0, 9, 0, 18, 0, 9, 0)
+
+	sound := make([]byte, 28)
+	binary.BigEndian.PutUint16(sound[16:], 2)
+
+	mvhd := make([]byte, 96)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], 2700000)
+
+	tkhd := make([]byte, 80)
+	tkhd[3] = 1
+
+	return bytes.Join([][]byte{
+		atom("ftyp", []byte("isom"), make([]byte, 4)),
+		atom("mdat", make([]byte, 128)),
+		atom("moov",
+			atom("mvhd", mvhd),
+			atom("trak",
+				atom("tkhd", tkhd),
+				atom("mdia", mdhd("und"), handler("vide"), stsd(atom("avc1", visual, atom("colr", colr)))),
+			),
+			atom("trak",
+				atom("tkhd", tkhd),
+				atom("mdia", mdhd("jpn"), handler("soun"), stsd(atom("mp4a", sound))),
+			),
+			atom("trak",
+				atom("tkhd", make([]byte, 80)),
+				atom("mdia", mdhd("eng"), handler("sbtl"), stsd(atom("tx3g", make([]byte, 30)))),
+			),
+			atom("udta", fullAtom("meta", atom("ilst", atom("\xa9nam", atom("data", make([]byte, 8), []byte("Spirited Away")))))),
+		),
+	}, nil)
+}
+
+func TestRead_MP4(t *testing.T) {
+	data := mp4()
+	info, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Container != "mp4" || info.Title != "Spirited Away" || info.Duration != 45*time.Minute {
+		t.Errorf("got container %q, title %q, duration %s", info.Container, info.Title, info.Duration)
+	}
+	if info.VideoCodec != "h264" || info.Resolution() != "1080p" || info.HDR != HLG {
+		t.Errorf("got video %s %s %q", info.VideoCodec, info.Resolution(), info.HDR)
+	}
+
+	audio := models.AudioTrack{Codec: "aac", Language: "jpn", Channels: 2, Default: true}
+	if len(info.AudioTracks) != 1 || info.AudioTracks[0] != audio {
+		t.Errorf("got audio tracks %+v", info.AudioTracks)
+	}
+	sub := models.SubtitleTrack{Codec: "mov_text", Language: "eng"}
+	if len(info.SubtitleTracks) != 1 || info.SubtitleTracks[0] != sub {
+		t.Errorf("got subtitle tracks %+v", info.SubtitleTracks)
+	}
+}
+
+func TestRead_Truncated(t *testing.T) {
+	// The clusters of a Matroska file are cut, its info and tracks are read
+	data := bytes.Join([][]byte{
+		ebml(idEBML, ebmlString(idDocType, "matroska")),
+		ebml(idSegment,
+			ebml(idInfo, ebmlUint(idTimestampScale, 1000000)),
+			ebml(idTracks, ebml(idTrackEntry, ebmlUint(idTrackType, trackTypeVideo))),
+			ebml(idCluster, make([]byte, 64)),
+		),
+	}, nil)
+	data = data[:len(data)-32]
+	info, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil || !info.Truncated {
+		t.Errorf("got %+v, %v, want a truncated file", info, err)
+	}
+
+	// The moov atom of an MP4 file comes after its media data
+	data = mp4()[:100]
+	if _, err := Read(bytes.NewReader(data), int64(len(data))); err != ErrTruncated {
+		t.Errorf("got error %v, want ErrTruncated", err)
+	}
+
+	data = mp4()
+	if info, err := Read(bytes.NewReader(data), int64(len(data))); err != nil || info.Truncated {
+		t.Errorf("got %+v, %v, want a complete file", info, err)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "movie.mp4")
+	data := mp4()
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := Probe(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(len(data)*8) / (45 * 60); info.Bitrate != want {
+		t.Errorf("got bitrate %d, want %d", info.Bitrate, want)
+	}
+
+	path = filepath.Join(dir, "movie.avi")
+	if err := os.WriteFile(path, []byte("RIFF....AVI LIST"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Probe(path); err != ErrUnsupported {
+		t.Errorf("got error %v, want ErrUnsupported", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	hd := &models.MediaInfo{Width: 1920, Height: 800, Bitrate: 8000000}
+	sd := &models.MediaInfo{Width: 720, Height: 576, Bitrate: 12000000}
+	hdr := &models.MediaInfo{Width: 1920, Height: 1080, HDR: HDR10, Bitrate: 6000000}
+	surround := &models.MediaInfo{Width: 1920, Height: 800, Bitrate: 4000000, AudioTracks: []models.AudioTrack{{Channels: 6}}}
+
+	tests := []struct {
+		name string
+		a, b *models.MediaInfo
+		want int
+	}{
+		{"cropped 1080p beats 576p", hd, sd, 1},
+		{"HDR beats SDR", hdr, hd, 1},
+		{"surround beats a higher bitrate", surround, hd, 1},
+		{"known beats unknown", nil, sd, -1},
+		{"equal", hd, hd, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compare(tt.a, tt.b); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package mediainfo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"goru/internal/models"
+)
+
+// maxMoovSize bounds the moov atom read in memory, the one of a long movie holding
+// the sample tables of all its tracks
+const maxMoovSize = 128 << 20
+
+// atoms walks the atoms of a container atom, given as bytes
+func atoms(data []byte, fn func(kind string, data []byte) error) error {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data))
+		kind := string(data[4:8])
+		header := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return fmt.Errorf("truncated atom %q", kind)
+			}
+			size, header = binary.BigEndian.Uint64(data[8:]), 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return fmt.Errorf("truncated atom %q", kind)
+		}
+
+		if err := fn(kind, data[header:size]); err != nil {
+			return err
+		}
+		data = data[size:]
+	}
+	return nil
+}
+
+// readMP4 reads the moov atom of the file, skipping the media data. The atoms after
+// it are walked to tell whether the file is cut.
+func readMP4(r io.ReadSeeker, size int64) (*models.MediaInfo, error) {
+	var (
+		pos    int64
+		info   *models.MediaInfo
+		header = make([]byte, 16)
+	)
+
+	for pos+8 <= size {
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			if info != nil {
+				break
+			}
+			return nil, err
+		}
+
+		atomSize := int64(binary.BigEndian.Uint32(header))
+		kind := string(header[4:8])
+		headerSize := int64(8)
+
+		switch atomSize {
+		case 0:
+			atomSize = size - pos
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return nil, err
+			}
+			atomSize, headerSize = int64(binary.BigEndian.Uint64(header[8:])), 16
+		}
+		if atomSize < headerSize {
+			if info != nil {
+				break
+			}
+			return nil, fmt.Errorf("invalid atom %q at %d", kind, pos)
+		}
+
+		if kind == "moov" && info == nil {
+			if atomSize > maxMoovSize {
+				return nil, fmt.Errorf("moov atom of %d bytes too large", atomSize)
+			}
+			data := make([]byte, atomSize-headerSize)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			var err error
+			if info, err = readMoov(data); err != nil {
+				return nil, err
+			}
+		}
+
+		pos += atomSize
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	if info == nil && pos > size {
+		// The moov atom written at the end is missing
+		return nil, ErrTruncated
+	}
+	if info == nil {
+		return nil, fmt.Errorf("no moov atom")
+	}
+	info.Truncated = pos > size
+	return info, nil
+}
+
+func readMoov(data []byte) (*models.MediaInfo, error) {
+	info := &models.MediaInfo{Container: "mp4"}
+
+	err := atoms(data, func(kind string, data []byte) error {
+		switch kind {
+		case "mvhd":
+			info.Duration = readMvhd(data)
+		case "trak":
+			return readTrak(info, data)
+		case "udta":
+			info.Title = readTitle(data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// readMvhd returns the duration of the movie header
+func readMvhd(data []byte) time.Duration {
+	var timescale, duration uint64
+	switch {
+	case len(data) >= 32 && data[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(data[20:]))
+		duration = binary.BigEndian.Uint64(data[24:])
+	case len(data) >= 20:
+		timescale = uint64(binary.BigEndian.Uint32(data[12:]))
+		duration = uint64(binary.BigEndian.Uint32(data[16:]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+}
+
+// track is what is read from a trak atom
+type track struct {
+	handler  string
+	language string
+	codec    string
+	width    int
+	height   int
+	hdr      string
+	channels int
+	enabled  bool
+}
+
+func readTrak(info *models.MediaInfo, data []byte) error {
+	var t track
+
+	err := atoms(data, func(kind string, data []byte) error {
+		switch kind {
+		case "tkhd":
+			t.enabled = len(data) >= 4 && data[3]&1 == 1
+		case "mdia":
+			return atoms(data, func(kind string, data []byte) error {
+				switch kind {
+				case "mdhd":
+					t.language = readMdhdLanguage(data)
+				case "hdlr":
+					if len(data) >= 12 {
+						t.handler = string(data[8:12])
+					}
+				case "minf":
+					return atoms(data, func(kind string, data []byte) error {
+						if kind != "stbl" {
+							return nil
+						}
+						return atoms(data, func(kind string, data []byte) error {
+							if kind == "stsd" {
+								return readStsd(&t, data)
+							}
+							return nil
+						})
+					})
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch t.handler {
+	case "vide":
+		// The first video track is the main one, the others are usually covers
+		if info.VideoCodec == "" {
+			info.VideoCodec = t.codec
+			info.Width, info.Height = t.width, t.height
+			info.HDR = t.hdr
+		}
+	case "soun":
+		info.AudioTracks = append(info.AudioTracks, models.AudioTrack{
+			Codec:    t.codec,
+			Language: language(t.language),
+			Channels: t.channels,
+			Default:  t.enabled,
+		})
+	case "subt", "sbtl", "text", "clcp":
+		info.SubtitleTracks = append(info.SubtitleTracks, models.SubtitleTrack{
+			Codec:    t.codec,
+			Language: language(t.language),
+			Default:  t.enabled,
+		})
+	}
+	return nil
+}
+
+// readMdhdLanguage returns the ISO 639-2 code packed in the media header
+func readMdhdLanguage(data []byte) string {
+	offset := 20
+	if len(data) > 0 && data[0] == 1 {
+		offset = 32
+	}
+	if len(data) < offset+2 {
+		return ""
+	}
+
+	packed := binary.BigEndian.Uint16(data[offset:])
+	if packed == 0 || packed == 0x7FFF {
+		return ""
+	}
+	return string([]byte{
+		byte(packed>>10&0x1F) + 0x60,
+		byte(packed>>5&0x1F) + 0x60,
+		byte(packed&0x1F) + 0x60,
+	})
+}
+
+// readStsd reads the first sample description of a track
+func readStsd(t *track, data []byte) error {
+	if len(data) < 8 {
+		return nil
+	}
+
+	first := true
+	return atoms(data[8:], func(kind string, data []byte) error {
+		if !first {
+			return nil
+		}
+		first = false
+
+		t.codec = mp4Codec(kind)
+		if kind == "dvh1" || kind == "dvhe" || kind == "dvav" || kind == "dva1" {
+			t.hdr = HDRDolbyVision
+		}
+
+		switch t.handler {
+		case "vide":
+			// The visual sample entry has 78 bytes of fields before its atoms
+			if len(data) < 78 {
+				return nil
+			}
+			t.width = int(binary.BigEndian.Uint16(data[24:]))
+			t.height = int(binary.BigEndian.Uint16(data[26:]))
+			return atoms(data[78:], func(kind string, data []byte) error {
+				switch kind {
+				case "colr":
+					if len(data) >= 8 && string(data[:4]) == "nclx" && t.hdr == "" {
+						t.hdr = hdrOf(uint64(binary.BigEndian.Uint16(data[6:])))
+					}
+				case "dvcC", "dvvC":
+					t.hdr = HDRDolbyVision
+				}
+				return nil
+			})
+		case "soun":
+			if len(data) >= 18 {
+				t.channels = int(binary.BigEndian.Uint16(data[16:]))
+			}
+		}
+		return nil
+	})
+}
+
+// readTitle returns the title in the iTunes metadata of the user data
+func readTitle(data []byte) string {
+	var title string
+	atoms(data, func(kind string, data []byte) error {
+		if kind != "meta" || len(data) < 4 {
+			return nil
+		}
+		// meta is a full atom, its version and flags come before its atoms
+		return atoms(data[4:], func(kind string, data []byte) error {
+			if kind != "ilst" {
+				return nil
+			}
+			return atoms(data, func(kind string, data []byte) error {
+				if kind != "\xa9nam" {
+					return nil
+				}
+				return atoms(data, func(kind string, data []byte) error {
+					// The value follows its type and locale
+					if kind == "data" && len(data) >= 8 {
+						title = strings.TrimRight(string(data[8:]), "\x00")
+					}
+					return nil
+				})
+			})
+		})
+	})
+	return title
+}
+
+// mp4Codecs are the names of the sample entries
+var mp4Codecs = map[string]string{
+	"avc1": "h264",
+	"avc3": "h264",
+	"dvav": "h264",
+	"dva1": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"dvh1": "hevc",
+	"dvhe": "hevc",
+	"av01": "av1",
+	"vp09": "vp9",
+	"mp4v": "mpeg4",
+	"mp4a": "aac",
+	"ac-3": "ac3",
+	"ec-3": "eac3",
+	"Opus": "opus",
+	"fLaC": "flac",
+	"alac": "alac",
+	"tx3g": "mov_text",
+	"wvtt": "webvtt",
+	"stpp": "ttml",
+	"c608": "eia_608",
+}
+
+func mp4Codec(kind string) string {
+	if codec, ok := mp4Codecs[kind]; ok {
+		return codec
+	}
+	return strings.TrimSpace(kind)
+}
@@ -0,0 +1,146 @@
+// Package metrics holds the Prometheus metrics of goru, served by the server on /metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "goru"
+
+// Sources of the scanned files
+const (
+	SourcePlan    = "plan"
+	SourceWatcher = "watcher"
+)
+
+// Results of the renames
+const (
+	RenameApplied      = "applied"
+	RenameFailed       = "failed"
+	RenameReverted     = "reverted"
+	RenameRevertFailed = "revert_failed"
+)
+
+// Results of the cache lookups
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+var (
+	// FilesScanned counts the video files found while scanning, by source
+	FilesScanned = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "files_scanned_total",
+		Help:      "Number of video files found while scanning the directories.",
+	}, []string{"source"})
+
+	// ProviderRequests counts the HTTP requests sent to the metadata providers, by status code
+	ProviderRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_requests_total",
+		Help:      "Number of HTTP requests sent to the metadata providers, the code is 0 for network errors.",
+	}, []string{"provider", "code"})
+
+	// ProviderRequestDuration observes the latency of the metadata providers
+	ProviderRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Latency of the HTTP requests sent to the metadata providers.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	// RateLimiterWait observes the time spent waiting for the rate limiters
+	RateLimiterWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rate_limiter_wait_seconds",
+		Help:      "Time spent waiting for a rate limiter before sending a request.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"limiter"})
+
+	// CacheRequests counts the lookups of the caches, by result
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Number of cache lookups, by result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	// Renames counts the renames, by result
+	Renames = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "renames_total",
+		Help:      "Number of renames, by result (applied, failed, reverted or revert_failed).",
+	}, []string{"result"})
+
+	// WatcherQueueDepth is the number of files found by the watcher waiting to be processed
+	WatcherQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "watcher_queue_depth",
+		Help:      "Number of files found by the watcher waiting to be processed.",
+	})
+
+	// JobDuration observes the duration of the background jobs, by kind and final status
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of the background jobs, by kind and final status.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+	}, []string{"kind", "status"})
+)
+
+// Registry holds the metrics of goru, and the ones of the Go runtime and the process
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		FilesScanned,
+		ProviderRequests,
+		ProviderRequestDuration,
+		RateLimiterWait,
+		CacheRequests,
+		Renames,
+		WatcherQueueDepth,
+		JobDuration,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// InstrumentTransport counts the requests sent through the transport and observes their
+// latency, for the given provider. The default transport is used when next is nil.
+func InstrumentTransport(provider string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		ProviderRequestDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+
+		code := 0
+		if err == nil {
+			code = resp.StatusCode
+		}
+		ProviderRequests.WithLabelValues(provider, strconv.Itoa(code)).Inc()
+
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: InstrumentTransport("test", nil)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	failing := &http.Client{Transport: InstrumentTransport("test", roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}))}
+	if _, err := failing.Get(server.URL); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if got := testutil.ToFloat64(ProviderRequests.WithLabelValues("test", "429")); got != 1 {
+		t.Errorf("got %v requests answered 429, want 1", got)
+	}
+	if got := testutil.ToFloat64(ProviderRequests.WithLabelValues("test", "0")); got != 1 {
+		t.Errorf("got %v failed requests, want 1", got)
+	}
+	if got := testutil.CollectAndCount(ProviderRequestDuration, "goru_provider_request_duration_seconds"); got < 1 {
+		t.Errorf("got %d latency series, want at least 1", got)
+	}
+}
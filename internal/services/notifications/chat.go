@@ -0,0 +1,115 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Colors of the Discord embeds
+const (
+	colorInfo    = 0x3498db
+	colorSuccess = 0x2ecc71
+	colorWarning = 0xf1c40f
+	colorError   = 0xe74c3c
+)
+
+// discordPayload is the body of a Discord webhook
+type discordPayload struct {
+	Username string         `json:"username"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Color       int    `json:"color"`
+	Timestamp   string `json:"timestamp"`
+}
+
+// slackPayload is the body of a Slack incoming webhook, also accepted by Mattermost
+// and the Slack-compatible endpoint of Discord
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// Chat posts the messages to a Discord or Slack incoming webhook
+type Chat struct {
+	name   string
+	url    string
+	encode func(Message) interface{}
+	client *http.Client
+}
+
+// NewDiscord creates a sink posting embeds to a Discord webhook
+func NewDiscord(name, url string) *Chat {
+	return &Chat{
+		name: name,
+		url:  url,
+		encode: func(message Message) interface{} {
+			return discordPayload{
+				Username: "goru",
+				Embeds: []discordEmbed{{
+					Title:       message.Title,
+					Description: truncate(message.Body, 4096),
+					Color:       color(message.Event),
+					Timestamp:   message.Event.Timestamp.Format(time.RFC3339),
+				}},
+			}
+		},
+		client: &http.Client{},
+	}
+}
+
+// NewSlack creates a sink posting to a Slack incoming webhook
+func NewSlack(name, url string) *Chat {
+	return &Chat{
+		name: name,
+		url:  url,
+		encode: func(message Message) interface{} {
+			return slackPayload{Text: "*" + message.Title + "*\n" + message.Body}
+		},
+		client: &http.Client{},
+	}
+}
+
+func (c *Chat) Name() string {
+	return c.name
+}
+
+// Send posts the message
+func (c *Chat) Send(ctx context.Context, message Message) error {
+	body, err := json.Marshal(c.encode(message))
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	return post(ctx, c.client, c.url, body, http.Header{"Content-Type": {"application/json"}})
+}
+
+// color returns the color of the embed of the event
+func color(event Event) int {
+	switch event.Type {
+	case EventError:
+		return colorError
+	case EventLowConfidence:
+		return colorWarning
+	case EventApplyFinished:
+		if event.Result != nil && event.Result.Failed > 0 {
+			return colorWarning
+		}
+		return colorSuccess
+	default:
+		return colorInfo
+	}
+}
+
+// truncate cuts the text to the given number of runes
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
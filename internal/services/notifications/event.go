@@ -0,0 +1,105 @@
+package notifications
+
+import (
+	"time"
+
+	"goru/internal/services/plans"
+)
+
+// EventType is the type of a notified event
+type EventType string
+
+const (
+	// EventPlanReady is sent when a plan is stored and ready to be reviewed
+	EventPlanReady EventType = "plan_ready"
+
+	// EventApplyFinished is sent when a plan has been applied
+	EventApplyFinished EventType = "apply_finished"
+
+	// EventError is sent when a job or a scan failed
+	EventError EventType = "error"
+
+	// EventLowConfidence is sent when a plan contains matches under the confidence threshold
+	EventLowConfidence EventType = "low_confidence"
+
+	// EventRevert is sent when renames have been reverted
+	EventRevert EventType = "revert"
+)
+
+// EventTypes lists every event type
+var EventTypes = []EventType{EventPlanReady, EventApplyFinished, EventError, EventLowConfidence, EventRevert}
+
+// Event is a notified event, also the data of the message templates
+type Event struct {
+	Type      EventType `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// PlanID is the plan the event is about, if any
+	PlanID string `json:"plan_id,omitempty"`
+
+	// Summary of the plan, for plan_ready and apply_finished
+	Summary *plans.PlanSummary `json:"summary,omitempty"`
+
+	// Result of the apply, for apply_finished
+	Result *plans.ApplyResult `json:"result,omitempty"`
+
+	// Matches under the confidence threshold, for low_confidence
+	Matches []Match `json:"matches,omitempty"`
+
+	// RevertedIDs and FailedIDs are the state entries of a revert
+	RevertedIDs []string `json:"reverted_ids,omitempty"`
+	FailedIDs   []string `json:"failed_ids,omitempty"`
+
+	// Message describes the event, for errors
+	Message string `json:"message,omitempty"`
+}
+
+// Match is a change of a plan with a low confidence
+type Match struct {
+	File   string  `json:"file"`
+	Target string  `json:"target"`
+	Score  float64 `json:"score"`
+}
+
+// PlanReady is the event of a stored plan
+func PlanReady(plan *plans.Plan) Event {
+	summary := plan.Summary()
+	return Event{Type: EventPlanReady, PlanID: plan.ID, Summary: &summary}
+}
+
+// ApplyFinished is the event of an applied plan
+func ApplyFinished(plan *plans.Plan, result *plans.ApplyResult) Event {
+	summary := plan.Summary()
+	return Event{Type: EventApplyFinished, PlanID: plan.ID, Summary: &summary, Result: result}
+}
+
+// Error is the event of a failure
+func Error(message string) Event {
+	return Event{Type: EventError, Message: message}
+}
+
+// Reverted is the event of reverted renames
+func Reverted(revertedIDs, failedIDs []string) Event {
+	return Event{Type: EventRevert, RevertedIDs: revertedIDs, FailedIDs: failedIDs}
+}
+
+// LowConfidence returns the event listing the changes of the plan matched with a score
+// under the threshold, and false when there is none
+func LowConfidence(plan *plans.Plan, threshold float64) (Event, bool) {
+	event := Event{Type: EventLowConfidence, PlanID: plan.ID}
+
+	for _, change := range plan.Changes {
+		confidence := change.After.Confidence
+		if confidence == nil || confidence.Score >= threshold {
+			continue
+		}
+
+		event.Matches = append(event.Matches, Match{
+			File:   change.Before.Path,
+			Target: change.After.Filename,
+			Score:  confidence.Score,
+		})
+	}
+
+	return event, len(event.Matches) > 0
+}
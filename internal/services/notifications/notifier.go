@@ -0,0 +1,262 @@
+// Package notifications sends the events of the server to webhooks, chat services, ntfy
+// and email.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"goru/internal/models"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// Defaults of the configuration
+const (
+	DefaultLowConfidenceThreshold = 0.6
+	DefaultRetryAttempts          = 3
+	DefaultInitialBackoff         = time.Second
+	DefaultMaxBackoff             = 30 * time.Second
+)
+
+// deliveryTimeout bounds a single delivery attempt
+const deliveryTimeout = 10 * time.Second
+
+// Message is the rendered notification of an event
+type Message struct {
+	Event Event
+	Title string
+	Body  string
+}
+
+// Sink delivers the messages to a destination
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, message Message) error
+}
+
+// permanentError is a delivery failure that retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// route is a sink and the events it receives
+type route struct {
+	sink   Sink
+	events map[EventType]bool
+}
+
+// Notifier renders the events and delivers them to the sinks in the background, retrying
+// the failed deliveries. A nil notifier discards the events.
+type Notifier struct {
+	routes    []route
+	templates map[EventType]messageTemplate
+	threshold float64
+	retry     models.NotificationRetry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates the notifier described by the configuration
+func New(config models.Notifications) (*Notifier, error) {
+	templates, err := parseTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	sinks := make([]Sink, 0, len(config.Sinks))
+	for i, sinkConfig := range config.Sinks {
+		sink, err := NewSink(sinkConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notification sink %d: %w", i, err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	n := NewWithSinks(sinks...)
+	n.templates = templates
+
+	if config.LowConfidenceThreshold > 0 {
+		n.threshold = config.LowConfidenceThreshold
+	}
+	if config.Retry.Attempts > 0 {
+		n.retry.Attempts = config.Retry.Attempts
+	}
+	if config.Retry.InitialBackoff > 0 {
+		n.retry.InitialBackoff = config.Retry.InitialBackoff
+	}
+	if config.Retry.MaxBackoff > 0 {
+		n.retry.MaxBackoff = config.Retry.MaxBackoff
+	}
+
+	// Restrict the events, globally then per sink
+	for i, sinkConfig := range config.Sinks {
+		n.routes[i].events = allowedEvents(config.Events, sinkConfig.Events)
+	}
+
+	return n, nil
+}
+
+// NewWithSinks creates a notifier sending every event to the given sinks, with the
+// default templates and retries
+func NewWithSinks(sinks ...Sink) *Notifier {
+	templates, _ := parseTemplates(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	n := &Notifier{
+		templates: templates,
+		threshold: DefaultLowConfidenceThreshold,
+		retry: models.NotificationRetry{
+			Attempts:       DefaultRetryAttempts,
+			InitialBackoff: DefaultInitialBackoff,
+			MaxBackoff:     DefaultMaxBackoff,
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, sink := range sinks {
+		n.routes = append(n.routes, route{sink: sink, events: allowedEvents(nil, nil)})
+	}
+
+	return n
+}
+
+// SetRetry replaces the retry policy
+func (n *Notifier) SetRetry(retry models.NotificationRetry) {
+	n.retry = retry
+}
+
+// LowConfidenceThreshold is the score under which a match is notified as low-confidence
+func (n *Notifier) LowConfidenceThreshold() float64 {
+	if n == nil {
+		return DefaultLowConfidenceThreshold
+	}
+	return n.threshold
+}
+
+// Notify renders the event and delivers it to the sinks in the background
+func (n *Notifier) Notify(event Event) {
+	if n == nil || len(n.routes) == 0 {
+		return
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	template, ok := n.templates[event.Type]
+	if !ok {
+		log.Error("unknown notification event", zap.String("event", string(event.Type)))
+		return
+	}
+
+	message, err := template.render(event)
+	if err != nil {
+		log.Error("failed to render the notification", zap.String("event", string(event.Type)), zap.Error(err))
+		return
+	}
+
+	for _, r := range n.routes {
+		if !r.events[event.Type] {
+			continue
+		}
+
+		n.wg.Add(1)
+		go func(sink Sink) {
+			defer n.wg.Done()
+
+			if err := n.deliver(sink, message); err != nil {
+				log.Error("failed to send the notification",
+					zap.String("sink", sink.Name()),
+					zap.String("event", string(event.Type)),
+					zap.Error(err))
+			}
+		}(r.sink)
+	}
+}
+
+// Close waits for the deliveries in progress until the context is done, then abandons them
+func (n *Notifier) Close(ctx context.Context) {
+	if n == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		n.cancel()
+		<-done
+	}
+}
+
+// deliver sends the message to the sink, retrying with an exponential backoff
+func (n *Notifier) deliver(sink Sink, message Message) error {
+	backoff := n.retry.InitialBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(n.ctx, deliveryTimeout)
+		err = sink.Send(ctx, message)
+		cancel()
+
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) || attempt >= n.retry.Attempts {
+			return err
+		}
+
+		log.Debug("retrying the notification",
+			zap.String("sink", sink.Name()),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		select {
+		case <-time.After(backoff):
+		case <-n.ctx.Done():
+			return err
+		}
+
+		backoff *= 2
+		if n.retry.MaxBackoff > 0 && backoff > n.retry.MaxBackoff {
+			backoff = n.retry.MaxBackoff
+		}
+	}
+}
+
+// allowedEvents returns the events enabled both globally and for the sink, every event
+// being enabled when a list is empty
+func allowedEvents(global, sink []string) map[EventType]bool {
+	events := make(map[EventType]bool, len(EventTypes))
+	for _, eventType := range EventTypes {
+		events[eventType] = contains(global, string(eventType)) && contains(sink, string(eventType))
+	}
+	return events
+}
+
+// contains returns true if the list is empty or holds the value
+func contains(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+// request is a request received by the stand-in server
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// standIn records the requests, answering with the given statuses then 204
+func standIn(t *testing.T, statuses ...int) (*httptest.Server, func() []request) {
+	var mu sync.Mutex
+	var requests []request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		requests = append(requests, request{header: r.Header.Clone(), body: body})
+		status := http.StatusNoContent
+		if len(requests) <= len(statuses) {
+			status = statuses[len(requests)-1]
+		}
+		mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]request(nil), requests...)
+	}
+}
+
+// notify sends the event and waits for the deliveries
+func notify(t *testing.T, n *Notifier, event Event) {
+	t.Helper()
+
+	n.Notify(event)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	n.Close(ctx)
+}
+
+func fastRetry(attempts int) models.NotificationRetry {
+	return models.NotificationRetry{Attempts: attempts, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+}
+
+func TestWebhook_Signature(t *testing.T) {
+	server, requests := standIn(t)
+
+	n := NewWithSinks(NewWebhook("hook", server.URL, "s3cret"))
+	notify(t, n, Error("scan failed"))
+
+	received := requests()
+	if len(received) != 1 {
+		t.Fatalf("got %d requests, want 1", len(received))
+	}
+
+	header := received[0].header
+	if header.Get(EventHeader) != string(EventError) {
+		t.Errorf("got event header %q", header.Get(EventHeader))
+	}
+	if want := Sign("s3cret", header.Get(TimestampHeader), received[0].body); header.Get(SignatureHeader) != want {
+		t.Errorf("got signature %q, want %q", header.Get(SignatureHeader), want)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(received[0].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event.Type != EventError || payload.Title != "Error" || payload.Body != "scan failed" {
+		t.Errorf("got payload %+v", payload)
+	}
+}
+
+func TestNotifier_Retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		want     int
+	}{
+		{name: "server errors are retried", statuses: []int{500, 503}, want: 3},
+		{name: "rate limits are retried", statuses: []int{429}, want: 2},
+		{name: "client errors are not retried", statuses: []int{400}, want: 1},
+		{name: "attempts are limited", statuses: []int{500, 500, 500, 500}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := standIn(t, tt.statuses...)
+
+			n := NewWithSinks(NewSlack("slack", server.URL))
+			n.SetRetry(fastRetry(3))
+			notify(t, n, Error("boom"))
+
+			if got := len(requests()); got != tt.want {
+				t.Errorf("got %d attempts, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_Payloads(t *testing.T) {
+	discord, discordRequests := standIn(t)
+	slack, slackRequests := standIn(t)
+
+	plan := &plans.Plan{ID: "p1"}
+	result := &plans.ApplyResult{PlanID: "p1", Applied: 1, Failed: 1, Changes: []plans.ChangeResult{
+		{Before: "/media/a.mkv", Status: plans.ChangeStatusApplied},
+		{Before: "/media/b.mkv", Status: plans.ChangeStatusFailed, Error: "permission denied"},
+	}}
+
+	n := NewWithSinks(NewDiscord("discord", discord.URL), NewSlack("slack", slack.URL))
+	notify(t, n, ApplyFinished(plan, result))
+
+	var discordPayload discordPayload
+	json.Unmarshal(discordRequests()[0].body, &discordPayload)
+	embed := discordPayload.Embeds[0]
+	if embed.Title != "Apply finished with errors" || embed.Color != colorWarning {
+		t.Errorf("got embed %+v", embed)
+	}
+	if !strings.Contains(embed.Description, "1 renamed, 1 failed") || !strings.Contains(embed.Description, "/media/b.mkv: permission denied") {
+		t.Errorf("got description %q", embed.Description)
+	}
+
+	var slackPayload slackPayload
+	json.Unmarshal(slackRequests()[0].body, &slackPayload)
+	if !strings.HasPrefix(slackPayload.Text, "*Apply finished with errors*\nPlan p1 applied") {
+		t.Errorf("got text %q", slackPayload.Text)
+	}
+}
+
+func TestNtfy(t *testing.T) {
+	server, requests := standIn(t)
+
+	n := NewWithSinks(NewNtfy("ntfy", server.URL+"/goru", "tk_123"))
+	notify(t, n, Reverted([]string{"a", "b"}, []string{"c"}))
+
+	received := requests()[0]
+	if received.header.Get("Title") != "Renames reverted" || received.header.Get("Authorization") != "Bearer tk_123" {
+		t.Errorf("got headers %v", received.header)
+	}
+	if string(received.body) != "2 rename(s) reverted, 1 failed." {
+		t.Errorf("got body %q", received.body)
+	}
+}
+
+func TestNew_EventsAndTemplates(t *testing.T) {
+	all, allRequests := standIn(t)
+	errorsOnly, errorsOnlyRequests := standIn(t)
+
+	n, err := New(models.Notifications{
+		Events: []string{"error", "revert"},
+		Templates: map[string]models.NotificationTemplate{
+			"error": {Title: "goru failed", Body: "Failure: {{.Message}}"},
+		},
+		Sinks: []models.NotificationSink{
+			{Type: "webhook", URL: all.URL},
+			{Type: "webhook", URL: errorsOnly.URL, Events: []string{"error"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n.Notify(PlanReady(&plans.Plan{ID: "p1"}))
+	n.Notify(Reverted([]string{"a"}, nil))
+	notify(t, n, Error("disk full"))
+
+	if got := len(allRequests()); got != 2 {
+		t.Errorf("got %d requests to the sink of every event, want 2", got)
+	}
+
+	received := errorsOnlyRequests()
+	if len(received) != 1 {
+		t.Fatalf("got %d requests to the sink of the errors, want 1", len(received))
+	}
+
+	var payload WebhookPayload
+	json.Unmarshal(received[0].body, &payload)
+	if payload.Title != "goru failed" || payload.Body != "Failure: disk full" {
+		t.Errorf("got payload %+v", payload)
+	}
+}
+
+func TestNew_InvalidTemplate(t *testing.T) {
+	_, err := New(models.Notifications{
+		Templates: map[string]models.NotificationTemplate{"error": {Body: "{{.Message"}},
+	})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	var sent []byte
+	var recipients []string
+
+	sink := NewSMTP("mail", "mail.local", 0, "goru", "secret", "goru@nas.local", []string{"me@home.local"})
+	sink.sendMail = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		if addr != "mail.local:587" || auth == nil {
+			t.Errorf("got address %q and auth %v", addr, auth)
+		}
+		sent, recipients = msg, to
+		return nil
+	}
+
+	n := NewWithSinks(sink)
+	notify(t, n, Error("scan failed"))
+
+	email := string(sent)
+	if len(recipients) != 1 || !strings.Contains(email, "Subject: [goru] Error\r\n") || !strings.HasSuffix(email, "\r\n\r\nscan failed\r\n") {
+		t.Errorf("got email %q to %v", email, recipients)
+	}
+}
+
+func TestLowConfidence(t *testing.T) {
+	plan := &plans.Plan{ID: "p1", Changes: []plans.Change{
+		{Before: models.VideoFile{Path: "/media/a.mkv"}, After: models.VideoFile{Filename: "A (2000).mkv", Confidence: &models.Confidence{Score: 0.4}}},
+		{Before: models.VideoFile{Path: "/media/b.mkv"}, After: models.VideoFile{Filename: "B (2001).mkv", Confidence: &models.Confidence{Score: 0.9}}},
+		{Before: models.VideoFile{Path: "/media/c.mkv"}},
+	}}
+
+	event, ok := LowConfidence(plan, 0.6)
+	if !ok || len(event.Matches) != 1 || event.Matches[0].File != "/media/a.mkv" {
+		t.Fatalf("LowConfidence() = %+v, %v", event, ok)
+	}
+
+	message, err := NewWithSinks().templates[EventLowConfidence].render(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(message.Body, "/media/a.mkv → A (2000).mkv (40%)") {
+		t.Errorf("got body %q", message.Body)
+	}
+
+	if _, ok := LowConfidence(plan, 0.3); ok {
+		t.Error("expected no low-confidence match under 0.3")
+	}
+}
@@ -0,0 +1,72 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+)
+
+// Ntfy publishes the messages to a ntfy topic
+type Ntfy struct {
+	name   string
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewNtfy creates a sink publishing to the topic at the URL, e.g. https://ntfy.sh/goru.
+// The token is optional.
+func NewNtfy(name, url, token string) *Ntfy {
+	return &Ntfy{
+		name:   name,
+		url:    url,
+		token:  token,
+		client: &http.Client{},
+	}
+}
+
+func (n *Ntfy) Name() string {
+	return n.name
+}
+
+// Send publishes the message
+func (n *Ntfy) Send(ctx context.Context, message Message) error {
+	headers := http.Header{
+		"Content-Type": {"text/plain; charset=utf-8"},
+		"Title":        {message.Title},
+		"Tags":         {tag(message.Event)},
+		"Priority":     {priority(message.Event)},
+	}
+	if n.token != "" {
+		headers.Set("Authorization", "Bearer "+n.token)
+	}
+
+	return post(ctx, n.client, n.url, []byte(message.Body), headers)
+}
+
+// tag returns the ntfy tag of the event, displayed as an emoji
+func tag(event Event) string {
+	switch event.Type {
+	case EventError:
+		return "rotating_light"
+	case EventLowConfidence:
+		return "warning"
+	case EventApplyFinished:
+		return "white_check_mark"
+	case EventRevert:
+		return "leftwards_arrow_with_hook"
+	default:
+		return "clipboard"
+	}
+}
+
+// priority returns the ntfy priority of the event
+func priority(event Event) string {
+	switch event.Type {
+	case EventError:
+		return "high"
+	case EventPlanReady, EventRevert:
+		return "low"
+	default:
+		return "default"
+	}
+}
@@ -0,0 +1,69 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"goru/internal/models"
+)
+
+// NewSink creates the sink described by the configuration
+func NewSink(config models.NotificationSink) (Sink, error) {
+	name := config.Name
+	if name == "" {
+		name = config.Type
+	}
+
+	switch config.Type {
+	case "webhook":
+		return NewWebhook(name, config.URL, config.Secret), nil
+	case "discord":
+		return NewDiscord(name, config.URL), nil
+	case "slack":
+		return NewSlack(name, config.URL), nil
+	case "ntfy":
+		return NewNtfy(name, config.URL, config.Token), nil
+	case "smtp":
+		return NewSMTP(name, config.Host, config.Port, config.Username, config.Password, config.From, config.To), nil
+	default:
+		return nil, fmt.Errorf("unsupported sink type: %q", config.Type)
+	}
+}
+
+// post sends the body to the URL and checks the status of the response. The client
+// errors, except 408 and 429, are not retried.
+func post(ctx context.Context, client *http.Client, url string, body []byte, headers http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err}
+	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(detail))
+
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return err
+	case resp.StatusCode < 500:
+		return &permanentError{err}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,92 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSMTPPort is the submission port, with STARTTLS
+const defaultSMTPPort = 587
+
+// SMTP sends the messages by email
+type SMTP struct {
+	name     string
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+
+	// sendMail is smtp.SendMail, replaced by the tests
+	sendMail func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTP creates a sink sending emails through the server, authenticated when a
+// username is set
+func NewSMTP(name, host string, port int, username, password, from string, to []string) *SMTP {
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+
+	return &SMTP{
+		name:     name,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+		to:       to,
+		sendMail: smtp.SendMail,
+	}
+}
+
+func (s *SMTP) Name() string {
+	return s.name
+}
+
+// Send sends the message. The SMTP client does not support contexts, the delivery is
+// abandoned but not interrupted when the context is done.
+func (s *SMTP) Send(ctx context.Context, message Message) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.sendMail(s.addr, auth, s.from, s.to, s.compose(message))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// compose builds the email of the message
+func (s *SMTP) compose(message Message) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "[goru] "+message.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", message.Event.Timestamp.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	return b.Bytes()
+}
@@ -0,0 +1,98 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"goru/internal/models"
+)
+
+// defaultTemplates are the titles and bodies of the messages, executed with the event
+var defaultTemplates = map[EventType]models.NotificationTemplate{
+	EventPlanReady: {
+		Title: "Plan ready for review",
+		Body: `Plan {{.PlanID}} is ready: {{.Summary.ReadyChanges}} to rename, {{.Summary.NoopChanges}} correct, ` +
+			`{{.Summary.ConflictedChanges}} conflicting, {{.Summary.ErrorChanges}} errors.`,
+	},
+	EventApplyFinished: {
+		Title: "{{if .Result.Failed}}Apply finished with errors{{else}}Apply finished{{end}}",
+		Body: `Plan {{.PlanID}} applied: {{.Result.Applied}} renamed, {{.Result.Failed}} failed.` +
+			`{{range .Result.Changes}}{{if .Error}}
+- {{.Before}}: {{.Error}}{{end}}{{end}}`,
+	},
+	EventError: {
+		Title: "Error",
+		Body:  "{{.Message}}",
+	},
+	EventLowConfidence: {
+		Title: "Low-confidence matches to review",
+		Body: `Plan {{.PlanID}} has {{len .Matches}} low-confidence match(es):` +
+			`{{range .Matches}}
+- {{.File}} → {{.Target}} ({{percent .Score}}){{end}}`,
+	},
+	EventRevert: {
+		Title: "Renames reverted",
+		Body:  `{{len .RevertedIDs}} rename(s) reverted{{if .FailedIDs}}, {{len .FailedIDs}} failed{{end}}.`,
+	},
+}
+
+var templateFuncs = template.FuncMap{
+	"percent": func(score float64) string {
+		return fmt.Sprintf("%.0f%%", score*100)
+	},
+	"join": strings.Join,
+}
+
+// messageTemplate renders the title and the body of the messages of an event type
+type messageTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// parseTemplates parses the default templates, replaced by the configured ones
+func parseTemplates(overrides map[string]models.NotificationTemplate) (map[EventType]messageTemplate, error) {
+	templates := make(map[EventType]messageTemplate, len(defaultTemplates))
+
+	for eventType, defaults := range defaultTemplates {
+		override := overrides[string(eventType)]
+		if override.Title == "" {
+			override.Title = defaults.Title
+		}
+		if override.Body == "" {
+			override.Body = defaults.Body
+		}
+
+		title, err := template.New(string(eventType) + ".title").Funcs(templateFuncs).Parse(override.Title)
+		if err != nil {
+			return nil, fmt.Errorf("invalid title template of %s: %w", eventType, err)
+		}
+		body, err := template.New(string(eventType) + ".body").Funcs(templateFuncs).Parse(override.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body template of %s: %w", eventType, err)
+		}
+
+		templates[eventType] = messageTemplate{title: title, body: body}
+	}
+
+	return templates, nil
+}
+
+// render renders the message of the event
+func (t messageTemplate) render(event Event) (Message, error) {
+	var title, body bytes.Buffer
+
+	if err := t.title.Execute(&title, event); err != nil {
+		return Message{}, fmt.Errorf("failed to render the title of %s: %w", event.Type, err)
+	}
+	if err := t.body.Execute(&body, event); err != nil {
+		return Message{}, fmt.Errorf("failed to render the body of %s: %w", event.Type, err)
+	}
+
+	return Message{
+		Event: event,
+		Title: strings.TrimSpace(title.String()),
+		Body:  strings.TrimSpace(body.String()),
+	}, nil
+}
@@ -0,0 +1,84 @@
+package notifications
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Headers of the generic webhook
+const (
+	EventHeader     = "X-Goru-Event"
+	TimestampHeader = "X-Goru-Timestamp"
+
+	// SignatureHeader holds "sha256=" followed by the hex HMAC-SHA256 of the timestamp,
+	// a dot and the body, keyed with the secret
+	SignatureHeader = "X-Goru-Signature"
+)
+
+// WebhookPayload is the body of the generic webhook
+type WebhookPayload struct {
+	Event Event  `json:"event"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Webhook posts the events as JSON, signed when a secret is set
+type Webhook struct {
+	name   string
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhook creates a generic JSON webhook
+func NewWebhook(name, url, secret string) *Webhook {
+	return &Webhook{
+		name:   name,
+		url:    url,
+		secret: secret,
+		client: &http.Client{},
+	}
+}
+
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+// Send posts the message
+func (w *Webhook) Send(ctx context.Context, message Message) error {
+	body, err := json.Marshal(WebhookPayload{
+		Event: message.Event,
+		Title: message.Title,
+		Body:  message.Body,
+	})
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	timestamp := strconv.FormatInt(message.Event.Timestamp.Unix(), 10)
+	headers := http.Header{
+		"Content-Type":  {"application/json"},
+		EventHeader:     {string(message.Event.Type)},
+		TimestampHeader: {timestamp},
+	}
+	if w.secret != "" {
+		headers.Set(SignatureHeader, Sign(w.secret, timestamp, body))
+	}
+
+	return post(ctx, w.client, w.url, body, headers)
+}
+
+// Sign computes the signature of a webhook payload, for the receivers to check it
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
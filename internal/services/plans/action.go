@@ -17,3 +17,19 @@ const (
 	// ActionCreate indicates a file should be created.
 	ActionCreate Action = '+'
 )
+
+// String returns the name of the action
+func (a Action) String() string {
+	switch a {
+	case ActionNoop:
+		return "noop"
+	case ActionRename:
+		return "rename"
+	case ActionSkip:
+		return "skip"
+	case ActionCreate:
+		return "create"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,172 @@
+package plans
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+
+	"goru/internal/services/files"
+	"goru/internal/services/metrics"
+	"goru/internal/services/states"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// ChangeStatus is the outcome of applying a single change
+type ChangeStatus string
+
+const (
+	ChangeStatusApplied ChangeStatus = "applied"
+	ChangeStatusFailed  ChangeStatus = "failed"
+)
+
+// ChangeResult is the result of applying a single change
+type ChangeResult struct {
+	ChangeID string `json:"change_id"`
+
+	// Before is empty for the files created, such as the subtitles
+	Before string       `json:"before"`
+	After  string       `json:"after"`
+	Status ChangeStatus `json:"status"`
+	Error  string       `json:"error,omitempty"`
+
+	// StateID is the ID of the state entry tracking the rename, used to revert it
+	StateID string `json:"state_id,omitempty"`
+
+	// Sidecars are the .nfo and artwork files written next to the renamed file
+	Sidecars []string `json:"sidecars,omitempty"`
+}
+
+// ApplyResult is the result of applying a plan
+type ApplyResult struct {
+	PlanID  string         `json:"plan_id"`
+	Applied int            `json:"applied"`
+	Failed  int            `json:"failed"`
+	Changes []ChangeResult `json:"changes"`
+
+	// Downloaded counts the subtitles downloaded next to the videos
+	Downloaded int `json:"downloaded,omitempty"`
+
+	// Integrations are the refreshes of the media servers that followed the renames
+	Integrations []IntegrationResult `json:"integrations,omitempty"`
+}
+
+// IntegrationResult is the refresh of a media server after the renames
+type IntegrationResult struct {
+	Name    string   `json:"name"`
+	Folders []string `json:"folders"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Folders returns the folders that lost or gained a file, sorted
+func (r *ApplyResult) Folders() []string {
+	seen := make(map[string]bool)
+	folders := make([]string, 0)
+
+	for _, change := range r.Changes {
+		if change.Status != ChangeStatusApplied {
+			continue
+		}
+
+		for _, path := range []string{change.Before, change.After} {
+			if path == "" {
+				continue
+			}
+			folder := filepath.Dir(path)
+			if !seen[folder] {
+				seen[folder] = true
+				folders = append(folders, folder)
+			}
+		}
+	}
+
+	sort.Strings(folders)
+	return folders
+}
+
+// ChangeFunc is called after each change has been applied. It may be nil.
+type ChangeFunc func(result ChangeResult, applied, total int)
+
+// Apply performs the renames of the plan that are ready to be applied, and tracks
+// them in the state. A failing rename does not stop the others, a cancelled context does.
+// The files to create are left to the services creating them, such as the subtitles.
+func (p *Plan) Apply(ctx context.Context, fileService *files.FileService, stateService *states.StateService, onChange ChangeFunc) *ApplyResult {
+	result := &ApplyResult{
+		PlanID:  p.ID,
+		Changes: make([]ChangeResult, 0),
+	}
+
+	total := 0
+	for _, change := range p.Changes {
+		if change.Action == ActionRename && change.IsApplicable() {
+			total++
+		}
+	}
+
+	for _, change := range p.Changes {
+		if change.Action != ActionRename || !change.IsApplicable() {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			log.Debug("apply cancelled", zap.String("plan_id", p.ID), zap.Int("applied", result.Applied))
+			break
+		}
+
+		changeResult := ChangeResult{
+			ChangeID: change.ID,
+			Before:   change.Before.Path,
+			After:    change.After.Path,
+			Status:   ChangeStatusApplied,
+		}
+
+		if err := fileService.RenameFile(change.Before.Path, change.After.Path); err != nil {
+			log.Error("failed to rename file", zap.Error(err), zap.String("from", change.Before.Path), zap.String("to", change.After.Path))
+
+			changeResult.Status = ChangeStatusFailed
+			changeResult.Error = err.Error()
+			result.Failed++
+			metrics.Renames.WithLabelValues(metrics.RenameFailed).Inc()
+			result.Changes = append(result.Changes, changeResult)
+			if onChange != nil {
+				onChange(changeResult, len(result.Changes), total)
+			}
+			continue
+		}
+
+		result.Applied++
+		metrics.Renames.WithLabelValues(metrics.RenameApplied).Inc()
+
+		// Track the rename in the state
+		entry, err := stateService.AddRenameOperation(
+			change.Before.Path,
+			change.After.Path,
+			change.Before.Filename,
+			change.After.Filename,
+			change.After.Metadata,
+		)
+		if err != nil {
+			log.Error("failed to add rename to state", zap.Error(err))
+		} else {
+			changeResult.StateID = entry.ID
+		}
+
+		result.Changes = append(result.Changes, changeResult)
+		if onChange != nil {
+			onChange(changeResult, len(result.Changes), total)
+		}
+	}
+
+	return result
+}
+
+// Pending returns true if the plan contains changes that would be applied
+func (p *Plan) Pending() bool {
+	for _, change := range p.Changes {
+		if change.IsApplicable() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package plans
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goru/internal/models"
+	"goru/internal/services/files"
+	"goru/internal/services/states"
+)
+
+func TestPlan_Pending(t *testing.T) {
+	rename := Change{ID: "rename", Action: ActionRename}
+
+	tests := []struct {
+		name   string
+		change Change
+		want   bool
+	}{
+		{name: "noop", change: Change{ID: "noop", Action: ActionNoop}, want: false},
+		{name: "skip", change: Change{ID: "skip", Action: ActionSkip}, want: false},
+		{name: "rename", change: rename, want: true},
+		{name: "create", change: Change{ID: "create", Action: ActionCreate}, want: true},
+		{name: "rejected", change: Change{ID: "rejected", Action: ActionRename, Decision: DecisionRejected}, want: false},
+		{name: "conflicting", change: Change{ID: "conflicting", Action: ActionRename, ConflictIDs: []string{"conflict"}}, want: false},
+	}
+
+	for _, tt := range tests {
+		plan := NewEmptyPlan()
+		plan.Changes = []Change{tt.change}
+		if got := plan.Pending(); got != tt.want {
+			t.Errorf("%s: Pending() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if NewEmptyPlan().Pending() {
+		t.Error("an empty plan should not be pending")
+	}
+}
+
+func TestPlan_Apply(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	stateService, err := states.NewStateService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.mkv", "c.mkv"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rename := func(id, from, to string) Change {
+		return Change{
+			ID:     id,
+			Action: ActionRename,
+			Before: models.VideoFile{Path: filepath.Join(dir, from), Filename: from},
+			After:  models.VideoFile{Path: filepath.Join(dir, to), Filename: to},
+		}
+	}
+
+	plan := NewEmptyPlan()
+	plan.Changes = []Change{
+		rename("ok", "a.mkv", "Movie (2020).mkv"),
+		// The source is missing
+		rename("missing", "b.mkv", "Other (2021).mkv"),
+		rename("rejected", "c.mkv", "Rejected (2022).mkv"),
+		{ID: "noop", Action: ActionNoop},
+	}
+	plan.Changes[2].Decision = DecisionRejected
+
+	var calls int
+	result := plan.Apply(context.Background(), files.NewFileService("", "", models.Filters{}), stateService, func(r ChangeResult, applied, total int) {
+		calls++
+		if total != 2 || applied != calls {
+			t.Errorf("got progress %d/%d for %s", applied, total, r.ChangeID)
+		}
+	})
+
+	if result.Applied != 1 || result.Failed != 1 || len(result.Changes) != 2 || calls != 2 {
+		t.Fatalf("got result %+v", result)
+	}
+	if ok := result.Changes[0]; ok.Status != ChangeStatusApplied || ok.StateID == "" {
+		t.Errorf("got %+v for the rename", ok)
+	}
+	if missing := result.Changes[1]; missing.Status != ChangeStatusFailed || missing.Error == "" {
+		t.Errorf("got %+v for the missing source", missing)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "Movie (2020).mkv")); err != nil {
+		t.Errorf("the file was not renamed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c.mkv")); err != nil {
+		t.Errorf("the rejected change was applied: %v", err)
+	}
+
+	// A cancelled context applies nothing
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	plan.Changes = []Change{rename("cancelled", "c.mkv", "Cancelled (2023).mkv")}
+	if result := plan.Apply(ctx, files.NewFileService("", "", models.Filters{}), stateService, nil); result.Applied != 0 || len(result.Changes) != 0 {
+		t.Errorf("got result %+v after the cancellation", result)
+	}
+}
@@ -20,6 +20,15 @@ type Change struct {
 	// ConflictIDs tracks which conflicts affect this change
 	ConflictIDs []string `json:"conflict_ids,omitempty"`
 
+	// Decision of the reviewer, rejected changes are not applied
+	Decision Decision `json:"decision,omitempty"`
+
+	// RuntimeMismatch is set when the file does not last as long as its match
+	RuntimeMismatch *RuntimeMismatch `json:"runtime_mismatch,omitempty"`
+
+	// Subtitle is the subtitle created next to the video of Before, for the create changes
+	Subtitle *models.Subtitle `json:"subtitle,omitempty"`
+
 	// Error stores any error that occurred during planning
 	//Error string `json:"error,omitempty"`
 }
@@ -28,3 +37,8 @@ type Change struct {
 func (c *Change) IsConflicting() bool {
 	return len(c.ConflictIDs) > 0
 }
+
+// IsApplicable returns true if this change would be performed when applying the plan
+func (c *Change) IsApplicable() bool {
+	return (c.Action == ActionRename || c.Action == ActionCreate) && !c.IsConflicting() && c.Decision != DecisionRejected
+}
@@ -0,0 +1,46 @@
+package plans
+
+import (
+	"testing"
+
+	"goru/internal/models"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+func TestPlan_ResolveConflicts_KeepBest(t *testing.T) {
+	plan := newTestPlan(t.TempDir())
+	plan.Changes[0].After.MediaInfo = &models.MediaInfo{Width: 1280, Height: 720}
+	plan.Changes[1].After.MediaInfo = &models.MediaInfo{Width: 1920, Height: 800}
+	plan.Changes[1].After.ConflictStrategy = models.ConflictStrategyKeepBest
+
+	if err := plan.ResolveConflicts(models.DefaultConflictStrategy); err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Changes[0].Action != ActionSkip || plan.Changes[1].Action != ActionRename {
+		t.Errorf("got actions %s and %s, want the 1080p file renamed", plan.Changes[0].Action, plan.Changes[1].Action)
+	}
+	if !plan.Conflicts[0].Resolved || plan.Conflicts[0].Resolution.Strategy != "keep_best" {
+		t.Errorf("got conflict %+v", plan.Conflicts[0])
+	}
+	if !plan.Changes[1].IsApplicable() {
+		t.Error("expected the kept change to be applicable")
+	}
+}
+
+func TestPlan_ResolveConflicts_PromptUser(t *testing.T) {
+	plan := newTestPlan(t.TempDir())
+	plan.Changes[0].After.ConflictStrategy = models.ConflictStrategyPromptUser
+
+	if err := plan.ResolveConflicts(models.DefaultConflictStrategy); err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Conflicts[0].Resolved || plan.Changes[0].IsApplicable() || plan.Changes[1].IsApplicable() {
+		t.Error("expected the conflict to be left to the user")
+	}
+}
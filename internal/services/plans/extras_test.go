@@ -0,0 +1,47 @@
+package plans
+
+import (
+	"testing"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/formatters"
+)
+
+func TestNewPlan_Extras(t *testing.T) {
+	movie := &models.VideoFile{
+		Path:      "/media/Lion King/lion.king.mkv",
+		Filename:  "lion.king.mkv",
+		MediaType: models.MediaTypeMovie,
+		Metadata:  &models.Movie{Title: "The Lion King", ReleaseDate: time.Date(1994, 6, 24, 0, 0, 0, 0, time.UTC)},
+	}
+	files := []*models.VideoFile{
+		{Path: "/media/Lion King/Extras/Making Of.mkv", Filename: "Making Of.mkv", Extra: models.ExtraBehindTheScene},
+		movie,
+		{Path: "/media/Lion King/lion.king.trailer.mkv", Filename: "lion.king.trailer.mkv", Extra: models.ExtraTrailer},
+		{Path: "/media/Other/Trailers/teaser.mkv", Filename: "teaser.mkv", Extra: models.ExtraTrailer},
+	}
+	plan, err := NewPlan(files, formatters.NewFormatterService("", "{{.Name}} ({{.Year}})"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 4 {
+		t.Fatalf("got changes %+v, errors %+v", plan.Changes, plan.Errors)
+	}
+
+	// The extras are moved next to the movie, unless already in their folder
+	want := map[string]string{
+		"/media/Lion King/lion.king.mkv":         "/media/Lion King/The Lion King (1994).mkv",
+		"/media/Lion King/Extras/Making Of.mkv":  "/media/Lion King/Behind The Scenes/Making Of.mkv",
+		"/media/Lion King/lion.king.trailer.mkv": "/media/Lion King/Trailers/lion.king.trailer.mkv",
+		"/media/Other/Trailers/teaser.mkv":       "/media/Other/Trailers/teaser.mkv",
+	}
+	for _, change := range plan.Changes {
+		if change.After.Path != want[change.Before.Path] {
+			t.Errorf("got %s → %s, want %s", change.Before.Path, change.After.Path, want[change.Before.Path])
+		}
+		if change.Before.Path == "/media/Other/Trailers/teaser.mkv" && change.Action != ActionNoop {
+			t.Errorf("got action %s for an extra already in its folder", change.Action)
+		}
+	}
+}
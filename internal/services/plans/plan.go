@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"goru/internal/models"
+	"goru/internal/services/files"
 	"goru/internal/services/formatters"
+	"goru/internal/services/mediainfo"
 	"goru/pkg/log"
 
 	"github.com/google/uuid"
@@ -41,10 +43,14 @@ type Plan struct {
 
 	// Conflicts between changes
 	Conflicts []Conflict `json:"conflicts"`
+
+	// AppliedAt is set once the plan has been applied by the server
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
 }
 
-// NewPlan creates a new rename plan for the given video files
-func NewPlan(videoFiles []*models.VideoFile, subtitleFiles []string, formatterService *formatters.FormatterService) (*Plan, error) {
+// NewPlan creates a new rename plan for the given video files. The subtitles of the
+// files are created next to their target.
+func NewPlan(videoFiles []*models.VideoFile, formatterService *formatters.FormatterService) (*Plan, error) {
 	plan := &Plan{
 		ID:        uuid.New().String(),
 		Timestamp: time.Now(),
@@ -52,8 +58,14 @@ func NewPlan(videoFiles []*models.VideoFile, subtitleFiles []string, formatterSe
 		Conflicts: make([]Conflict, 0),
 	}
 
-	// Create planned changes
+	// Create planned changes, the extras being moved to the folders of Plex
+	var extras []*models.VideoFile
 	for _, videoFile := range videoFiles {
+		if videoFile.Extra != "" {
+			extras = append(extras, videoFile)
+			continue
+		}
+
 		change, err := createChange(videoFile, formatterService)
 		if err != nil {
 			log.Debug("failed to create planned change", zap.Error(err), zap.String("file", videoFile.Path))
@@ -68,20 +80,11 @@ func NewPlan(videoFiles []*models.VideoFile, subtitleFiles []string, formatterSe
 		}
 
 		plan.Changes = append(plan.Changes, *change)
+		plan.Changes = append(plan.Changes, createSubtitleChanges(videoFile, change.After.Path)...)
 	}
 
-	// Create subtitle changes
-	for _, subtitleFile := range subtitleFiles {
-		change := Change{
-			ID:     uuid.New().String(),
-			Action: ActionCreate,
-			Before: models.VideoFile{
-				Path:     subtitleFile,
-				Filename: filepath.Base(subtitleFile),
-			},
-		}
-
-		plan.Changes = append(plan.Changes, change)
+	for _, videoFile := range extras {
+		plan.Changes = append(plan.Changes, createExtraChange(videoFile))
 	}
 
 	// Detect conflicts
@@ -94,6 +97,24 @@ func NewPlan(videoFiles []*models.VideoFile, subtitleFiles []string, formatterSe
 	return plan, nil
 }
 
+// NewEmptyPlan creates a plan without any change
+func NewEmptyPlan() *Plan {
+	return &Plan{
+		ID:        uuid.New().String(),
+		Timestamp: time.Now(),
+		Changes:   []Change{},
+		Errors:    []Error{},
+		Conflicts: []Conflict{},
+	}
+}
+
+// AddScanErrors records the paths that could not be read by the scan
+func (p *Plan) AddScanErrors(scanErrors []files.ScanError) {
+	for _, scanError := range scanErrors {
+		p.Errors = append(p.Errors, Error{Message: scanError.Err.Error(), File: scanError.Path})
+	}
+}
+
 // PlanSummary provides an overview of the plan
 type PlanSummary struct {
 	TotalChanges      int `json:"total_changes"`
@@ -102,8 +123,10 @@ type PlanSummary struct {
 	SkippedChanges    int `json:"skipped_changes"`
 	ErrorChanges      int `json:"error_changes"`
 	NoopChanges       int `json:"noop_changes"`
+	CreateChanges     int `json:"create_changes"`
 	TotalConflicts    int `json:"total_conflicts"`
 	ResolvedConflicts int `json:"resolved_conflicts"`
+	RuntimeMismatches int `json:"runtime_mismatches"`
 }
 
 // Summary provides a summary of the plan's changes and conflicts
@@ -115,6 +138,10 @@ func (p *Plan) Summary() PlanSummary {
 	}
 
 	for _, change := range p.Changes {
+		if change.RuntimeMismatch != nil {
+			summary.RuntimeMismatches++
+		}
+
 		switch change.Action {
 		case ActionRename:
 			if change.IsConflicting() {
@@ -126,6 +153,8 @@ func (p *Plan) Summary() PlanSummary {
 			summary.SkippedChanges++
 		case ActionNoop:
 			summary.NoopChanges++
+		case ActionCreate:
+			summary.CreateChanges++
 		}
 	}
 
@@ -149,6 +178,9 @@ func createChange(videoFile *models.VideoFile, formatterService *formatters.Form
 		},
 	}
 
+	// Check the match against the duration of the file, before its confidence is kept
+	change.RuntimeMismatch = verifyRuntime(videoFile)
+
 	// Format the target name
 	targetName, err := formatterService.FormatFilename(videoFile)
 	if err != nil {
@@ -157,10 +189,18 @@ func createChange(videoFile *models.VideoFile, formatterService *formatters.Form
 
 	targetPath := filepath.Join(filepath.Dir(videoFile.Path), targetName)
 
-	// Set the After info
+	// Set the After info, the metadata being kept for the state and the sidecar files
 	change.After = models.VideoFile{
-		Path:     targetPath,
-		Filename: targetName,
+		Path:        targetPath,
+		Filename:    targetName,
+		FileType:    videoFile.FileType,
+		MediaType:   videoFile.MediaType,
+		Metadata:    videoFile.Metadata,
+		ExternalIDs: videoFile.ExternalIDs,
+		Confidence:  videoFile.Confidence,
+		MediaInfo:   videoFile.MediaInfo,
+
+		ConflictStrategy: videoFile.ConflictStrategy,
 	}
 
 	// Determine action based on whether file needs to be renamed
@@ -173,6 +213,62 @@ func createChange(videoFile *models.VideoFile, formatterService *formatters.Form
 	return change, nil
 }
 
+// createExtraChange moves an extra to the folder of Plex of its kind, next to the
+// videos of its folder
+func createExtraChange(videoFile *models.VideoFile) Change {
+	targetPath := filepath.Join(models.ExtraBase(videoFile.Path), models.ExtraFolders[videoFile.Extra], videoFile.Filename)
+
+	change := Change{
+		ID:     uuid.New().String(),
+		Action: ActionNoop,
+		Before: models.VideoFile{
+			Path:     videoFile.Path,
+			Filename: videoFile.Filename,
+		},
+		After: models.VideoFile{
+			Path:             targetPath,
+			Filename:         videoFile.Filename,
+			FileType:         videoFile.FileType,
+			MediaType:        videoFile.MediaType,
+			MediaInfo:        videoFile.MediaInfo,
+			Extra:            videoFile.Extra,
+			ConflictStrategy: videoFile.ConflictStrategy,
+		},
+	}
+	if targetPath != videoFile.Path {
+		change.Action = ActionRename
+	}
+	return change
+}
+
+// createSubtitleChanges creates the subtitles of a file next to its target, unless
+// they already exist
+func createSubtitleChanges(videoFile *models.VideoFile, targetPath string) []Change {
+	var changes []Change
+	for i := range videoFile.Subtitles {
+		subtitle := videoFile.Subtitles[i]
+		path := subtitle.Path(targetPath)
+		if fileExists(path) {
+			continue
+		}
+
+		changes = append(changes, Change{
+			ID:     uuid.New().String(),
+			Action: ActionCreate,
+			Before: models.VideoFile{
+				Path:     videoFile.Path,
+				Filename: videoFile.Filename,
+			},
+			After: models.VideoFile{
+				Path:     path,
+				Filename: filepath.Base(path),
+			},
+			Subtitle: &subtitle,
+		})
+	}
+	return changes
+}
+
 // detectConflicts detects conflicts between planned changes
 func detectConflicts(changes []Change) []Conflict {
 	conflicts := make([]Conflict, 0)
@@ -256,22 +352,43 @@ type RenameResult struct {
 	ConflictAction string // Action taken during conflict resolution
 }
 
-// ResolveConflicts resolves all conflicts in the plan using the specified strategy
+// ResolveConflicts resolves all conflicts in the plan. The strategy of the files, set
+// from their directory, comes first, the given strategy being used for the files
+// without one. The conflicts left to the user are kept unresolved.
 func (p *Plan) ResolveConflicts(strategy models.ConflictStrategy) error {
 	for i := range p.Conflicts {
-		log.Debug("resolving conflict", zap.String("conflict_id", p.Conflicts[i].ID), zap.String("strategy", string(strategy)))
 		conflict := &p.Conflicts[i]
-		if !conflict.Resolved {
-			if err := p.resolveConflict(conflict, strategy); err != nil {
-				log.Error("failed to resolve conflict", zap.Error(err), zap.String("conflict_id", conflict.ID))
-				return fmt.Errorf("failed to resolve conflict %s: %w", conflict.ID, err)
-			}
+		if conflict.Resolved {
+			continue
+		}
+
+		conflictStrategy := p.conflictStrategy(conflict, strategy)
+		log.Debug("resolving conflict", zap.String("conflict_id", conflict.ID), zap.String("strategy", string(conflictStrategy)))
+		if conflictStrategy == models.ConflictStrategyPromptUser {
+			continue
+		}
+
+		if err := p.resolveConflict(conflict, conflictStrategy); err != nil {
+			log.Error("failed to resolve conflict", zap.Error(err), zap.String("conflict_id", conflict.ID))
+			return fmt.Errorf("failed to resolve conflict %s: %w", conflict.ID, err)
 		}
 	}
 
 	return nil
 }
 
+// conflictStrategy returns the strategy of the first change of a conflict having one
+func (p *Plan) conflictStrategy(conflict *Conflict, strategy models.ConflictStrategy) models.ConflictStrategy {
+	for _, changeID := range conflict.ChangeIDs {
+		for i := range p.Changes {
+			if p.Changes[i].ID == changeID && p.Changes[i].After.ConflictStrategy != "" {
+				return p.Changes[i].After.ConflictStrategy
+			}
+		}
+	}
+	return strategy
+}
+
 // resolveConflict resolves a single conflict
 func (p *Plan) resolveConflict(conflict *Conflict, strategy models.ConflictStrategy) error {
 	switch conflict.ConflictType {
@@ -371,6 +488,20 @@ func (p *Plan) resolveMultipleSourceConflict(conflict *Conflict, strategy models
 			}
 		}
 
+	case models.ConflictStrategyKeepBest:
+		// Keep the file of the best quality, the first one when they cannot be told apart
+		best := 0
+		for i, change := range conflictingChanges {
+			if mediainfo.Compare(change.After.MediaInfo, conflictingChanges[best].After.MediaInfo) > 0 {
+				best = i
+			}
+		}
+		for i, change := range conflictingChanges {
+			if i != best {
+				change.Action = ActionSkip
+			}
+		}
+
 	default:
 		return fmt.Errorf("unsupported conflict strategy: %v", strategy)
 	}
@@ -419,6 +550,16 @@ func (p *Plan) resolveTargetExistsConflict(conflict *Conflict, strategy models.C
 		// Keep the rename action - the actual file service will handle the overwrite
 		// No action needed here
 
+	case models.ConflictStrategyKeepBest:
+		// Replace the existing file only by a better one, both being known
+		existing, err := mediainfo.Probe(conflict.TargetPath)
+		if err != nil {
+			log.Debug("failed to read the media info of the existing file", zap.String("file", conflict.TargetPath), zap.Error(err))
+		}
+		if existing == nil || change.After.MediaInfo == nil || mediainfo.Compare(change.After.MediaInfo, existing) <= 0 {
+			change.Action = ActionSkip
+		}
+
 	default:
 		// For other strategies, skip for now - more sophisticated logic can be added later
 		change.Action = ActionSkip
@@ -451,6 +592,8 @@ func (p *Plan) getStrategyName(strategy models.ConflictStrategy) string {
 		return "prompt_user"
 	case models.ConflictStrategyOverwrite:
 		return "overwrite"
+	case models.ConflictStrategyKeepBest:
+		return "keep_best"
 	default:
 		return "unknown"
 	}
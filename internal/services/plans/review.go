@@ -0,0 +1,153 @@
+package plans
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"goru/internal/models"
+	"goru/internal/services/formatters"
+
+	"github.com/google/uuid"
+)
+
+// Decision is the decision of a reviewer on a change
+type Decision string
+
+const (
+	// DecisionPending is the default, the change is applied unless rejected
+	DecisionPending Decision = ""
+
+	DecisionAccepted Decision = "accepted"
+	DecisionRejected Decision = "rejected"
+)
+
+var (
+	ErrChangeNotFound     = errors.New("change not found")
+	ErrPlanAlreadyApplied = errors.New("plan has already been applied")
+)
+
+// GetChange returns the change with the given ID
+func (p *Plan) GetChange(id string) (*Change, error) {
+	for i := range p.Changes {
+		if p.Changes[i].ID == id {
+			return &p.Changes[i], nil
+		}
+	}
+
+	return nil, ErrChangeNotFound
+}
+
+// SetDecision accepts or rejects a change. Rejected changes are not applied.
+func (p *Plan) SetDecision(changeID string, decision Decision) error {
+	switch decision {
+	case DecisionPending, DecisionAccepted, DecisionRejected:
+	default:
+		return fmt.Errorf("unsupported decision: %q", decision)
+	}
+
+	change, err := p.GetChange(changeID)
+	if err != nil {
+		return err
+	}
+
+	change.Decision = decision
+	p.RefreshConflicts()
+
+	return nil
+}
+
+// SetTarget overrides the target path of a change. The target must be an absolute
+// path, already validated by the caller.
+func (p *Plan) SetTarget(changeID, targetPath string) error {
+	if !filepath.IsAbs(targetPath) {
+		return fmt.Errorf("target path must be absolute: %s", targetPath)
+	}
+
+	change, err := p.GetChange(changeID)
+	if err != nil {
+		return err
+	}
+
+	change.After.Path = filepath.Clean(targetPath)
+	change.After.Filename = filepath.Base(targetPath)
+
+	if change.After.Path == change.Before.Path {
+		change.Action = ActionNoop
+	} else {
+		change.Action = ActionRename
+	}
+
+	p.RefreshConflicts()
+
+	return nil
+}
+
+// AddRename adds a change moving a file to the given path, and returns its ID. The
+// conflicts are left to RefreshConflicts.
+func (p *Plan) AddRename(from, to string) string {
+	change := Change{
+		ID:     uuid.New().String(),
+		Action: ActionRename,
+		Before: models.VideoFile{Path: from, Filename: filepath.Base(from)},
+		After:  models.VideoFile{Path: to, Filename: filepath.Base(to)},
+	}
+	p.Changes = append(p.Changes, change)
+	return change.ID
+}
+
+// Rematch computes the target of a change again from the given video file, whose
+// metadata has been looked up with another match chosen by the reviewer. The runtime
+// mismatch of the former match is dropped.
+func (p *Plan) Rematch(changeID string, videoFile *models.VideoFile, formatterService *formatters.FormatterService) error {
+	change, err := p.GetChange(changeID)
+	if err != nil {
+		return err
+	}
+
+	targetName, err := formatterService.FormatFilename(videoFile)
+	if err != nil {
+		return fmt.Errorf("error while formatting filename: %w", err)
+	}
+
+	if err := p.SetTarget(change.ID, filepath.Join(filepath.Dir(change.Before.Path), targetName)); err != nil {
+		return err
+	}
+
+	change.RuntimeMismatch = nil
+	return nil
+}
+
+// RefreshConflicts detects the conflicts again after the changes have been edited.
+// Resolved conflicts are kept as they are, rejected changes never conflict.
+func (p *Plan) RefreshConflicts() {
+	resolved := make([]Conflict, 0, len(p.Conflicts))
+	for _, conflict := range p.Conflicts {
+		if conflict.Resolved {
+			resolved = append(resolved, conflict)
+		}
+	}
+
+	candidates := make([]Change, 0, len(p.Changes))
+	for i := range p.Changes {
+		p.Changes[i].ConflictIDs = nil
+		if p.Changes[i].Decision != DecisionRejected {
+			candidates = append(candidates, p.Changes[i])
+		}
+	}
+
+	p.Conflicts = append(resolved, detectConflicts(candidates)...)
+	updateChangeConflicts(p)
+
+	// Resolved conflicts do not prevent their changes from being applied
+	for _, conflict := range resolved {
+		for i := range p.Changes {
+			p.Changes[i].ConflictIDs = removeConflictID(p.Changes[i].ConflictIDs, conflict.ID)
+		}
+	}
+	for i := range p.Changes {
+		if len(p.Changes[i].ConflictIDs) == 0 {
+			p.Changes[i].ConflictIDs = nil
+		}
+	}
+}
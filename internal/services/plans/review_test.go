@@ -0,0 +1,145 @@
+package plans
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"goru/internal/models"
+
+	"github.com/tidwall/buntdb"
+)
+
+func newTestPlan(dir string) *Plan {
+	plan := NewEmptyPlan()
+	plan.Changes = []Change{
+		{
+			ID:     "a",
+			Action: ActionRename,
+			Before: models.VideoFile{Path: filepath.Join(dir, "a.mkv"), Filename: "a.mkv"},
+			After:  models.VideoFile{Path: filepath.Join(dir, "Movie (2020).mkv"), Filename: "Movie (2020).mkv"},
+		},
+		{
+			ID:     "b",
+			Action: ActionRename,
+			Before: models.VideoFile{Path: filepath.Join(dir, "b.mkv"), Filename: "b.mkv"},
+			After:  models.VideoFile{Path: filepath.Join(dir, "Movie (2020).mkv"), Filename: "Movie (2020).mkv"},
+		},
+	}
+	plan.RefreshConflicts()
+
+	return plan
+}
+
+func TestPlan_Review(t *testing.T) {
+	dir := t.TempDir()
+	plan := newTestPlan(dir)
+
+	if len(plan.Conflicts) != 1 || plan.Pending() {
+		t.Fatalf("expected a conflict between both changes, got %+v", plan.Conflicts)
+	}
+
+	// Rejecting one of the changes removes the conflict
+	if err := plan.SetDecision("b", DecisionRejected); err != nil {
+		t.Fatalf("SetDecision() returned an error: %v", err)
+	}
+	if len(plan.Conflicts) != 0 || !plan.Pending() {
+		t.Fatalf("expected no conflict after rejecting, got %+v", plan.Conflicts)
+	}
+
+	// Accepting it again with another target keeps the plan free of conflicts
+	if err := plan.SetDecision("b", DecisionAccepted); err != nil {
+		t.Fatalf("SetDecision() returned an error: %v", err)
+	}
+	if err := plan.SetTarget("b", filepath.Join(dir, "Other (2021).mkv")); err != nil {
+		t.Fatalf("SetTarget() returned an error: %v", err)
+	}
+	if len(plan.Conflicts) != 0 {
+		t.Fatalf("expected no conflict after overriding the target, got %+v", plan.Conflicts)
+	}
+
+	if err := plan.SetTarget("b", "relative.mkv"); err == nil {
+		t.Error("SetTarget() accepted a relative path")
+	}
+	if err := plan.SetDecision("b", "maybe"); err == nil {
+		t.Error("SetDecision() accepted an unsupported decision")
+	}
+	if err := plan.SetDecision("unknown", DecisionAccepted); !errors.Is(err, ErrChangeNotFound) {
+		t.Errorf("SetDecision() on an unknown change = %v, want %v", err, ErrChangeNotFound)
+	}
+}
+
+func TestStore(t *testing.T) {
+	db, err := buntdb.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("NewStore() returned an error: %v", err)
+	}
+
+	plan := newTestPlan(t.TempDir())
+	if err := plan.SetDecision("a", DecisionRejected); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(plan); err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+
+	stored, err := store.Get(plan.ID)
+	if err != nil {
+		t.Fatalf("Get() returned an error: %v", err)
+	}
+	if change, _ := stored.GetChange("a"); change.Decision != DecisionRejected {
+		t.Errorf("decision = %q, want %q", change.Decision, DecisionRejected)
+	}
+
+	list, err := store.List()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List() = %d plans, %v", len(list), err)
+	}
+
+	// A failing update leaves the plan as stored
+	errReview := errors.New("review")
+	if _, err := store.Update(plan.ID, func(plan *Plan) error {
+		plan.Changes = nil
+		return errReview
+	}); !errors.Is(err, errReview) {
+		t.Fatalf("Update() with a failing review = %v, want %v", err, errReview)
+	}
+	updated, err := store.Update(plan.ID, func(plan *Plan) error {
+		return plan.SetDecision("a", DecisionAccepted)
+	})
+	if err != nil || len(updated.Changes) != 2 {
+		t.Fatalf("Update() = %+v, %v", updated, err)
+	}
+	if change, _ := updated.GetChange("a"); change.Decision != DecisionAccepted {
+		t.Errorf("decision = %q, want %q", change.Decision, DecisionAccepted)
+	}
+
+	// A failing check leaves the plan applicable
+	errCheck := errors.New("check")
+	if _, err := store.MarkApplied(plan.ID, func(*Plan) error { return errCheck }); !errors.Is(err, errCheck) {
+		t.Fatalf("MarkApplied() with a failing check = %v, want %v", err, errCheck)
+	}
+
+	if _, err := store.MarkApplied(plan.ID, nil); err != nil {
+		t.Fatalf("MarkApplied() returned an error: %v", err)
+	}
+	if _, err := store.MarkApplied(plan.ID, nil); !errors.Is(err, ErrPlanAlreadyApplied) {
+		t.Errorf("second MarkApplied() = %v, want %v", err, ErrPlanAlreadyApplied)
+	}
+	if _, err := store.Update(plan.ID, func(*Plan) error { return nil }); !errors.Is(err, ErrPlanAlreadyApplied) {
+		t.Errorf("Update() of an applied plan = %v, want %v", err, ErrPlanAlreadyApplied)
+	}
+	if _, err := store.Update("unknown", func(*Plan) error { return nil }); !errors.Is(err, ErrPlanNotFound) {
+		t.Errorf("Update() of an unknown plan = %v, want %v", err, ErrPlanNotFound)
+	}
+
+	if _, err := store.Get("unknown"); !errors.Is(err, ErrPlanNotFound) {
+		t.Errorf("Get() on an unknown plan = %v, want %v", err, ErrPlanNotFound)
+	}
+}
@@ -0,0 +1,55 @@
+package plans
+
+import (
+	"fmt"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/providers"
+)
+
+// RuntimeMismatch flags a change whose file lasts more or less than the runtime of its
+// match, usually a wrong match such as a remake
+type RuntimeMismatch struct {
+	// Duration is the duration of the file
+	Duration time.Duration `json:"duration"`
+
+	// Runtime is the runtime of the match
+	Runtime time.Duration `json:"runtime"`
+}
+
+func (m *RuntimeMismatch) String() string {
+	return fmt.Sprintf("file lasts %d min, match %d min", int(m.Duration.Round(time.Minute).Minutes()), int(m.Runtime.Minutes()))
+}
+
+// verifyRuntime compares the duration of a file with the runtime of its match. On a
+// mismatch, the confidence of the match is lowered and the mismatch returned.
+func verifyRuntime(videoFile *models.VideoFile) *RuntimeMismatch {
+	if videoFile.MediaInfo == nil || videoFile.MediaInfo.Duration <= 0 {
+		return nil
+	}
+
+	var runtime int
+	switch metadata := videoFile.Metadata.(type) {
+	case *models.Movie:
+		runtime = metadata.Runtime
+	case *models.Episode:
+		runtime = metadata.Runtime
+	}
+
+	score := providers.RuntimeConfidence(videoFile.MediaInfo.Duration, runtime)
+	if score >= 1 {
+		return nil
+	}
+
+	confidence := models.Confidence{Score: score}
+	if videoFile.Confidence != nil {
+		confidence.Score *= videoFile.Confidence.Score
+	}
+	videoFile.Confidence = &confidence
+
+	return &RuntimeMismatch{
+		Duration: videoFile.MediaInfo.Duration,
+		Runtime:  time.Duration(runtime) * time.Minute,
+	}
+}
@@ -0,0 +1,46 @@
+package plans
+
+import (
+	"testing"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/formatters"
+)
+
+func TestNewPlan_RuntimeMismatch(t *testing.T) {
+	newFile := func(name string, duration time.Duration) *models.VideoFile {
+		return &models.VideoFile{
+			Path:       "/media/" + name,
+			Filename:   name,
+			MediaType:  models.MediaTypeMovie,
+			Metadata:   &models.Movie{Title: "The Lion King", ReleaseDate: time.Date(1994, 6, 24, 0, 0, 0, 0, time.UTC), Runtime: 88},
+			Confidence: &models.Confidence{Score: 0.9},
+			MediaInfo:  &models.MediaInfo{Duration: duration},
+		}
+	}
+
+	files := []*models.VideoFile{
+		newFile("lion.king.mkv", 89*time.Minute),
+		newFile("lion.king.2019.mkv", 118*time.Minute),
+	}
+	plan, err := NewPlan(files, formatters.NewFormatterService("", "{{.Name}} {{.Year}} {{.Resolution}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Changes[0].RuntimeMismatch != nil || plan.Changes[0].After.Confidence.Score != 0.9 {
+		t.Errorf("got mismatch %v and confidence %v for a matching runtime", plan.Changes[0].RuntimeMismatch, plan.Changes[0].After.Confidence)
+	}
+
+	mismatch := plan.Changes[1].RuntimeMismatch
+	if mismatch == nil || mismatch.String() != "file lasts 118 min, match 88 min" {
+		t.Fatalf("got mismatch %v", mismatch)
+	}
+	if score := plan.Changes[1].After.Confidence.Score; score >= 0.6 {
+		t.Errorf("got confidence %v, want it lowered under the notification threshold", score)
+	}
+	if plan.Summary().RuntimeMismatches != 1 {
+		t.Errorf("got %d runtime mismatches in the summary", plan.Summary().RuntimeMismatches)
+	}
+}
@@ -0,0 +1,165 @@
+package plans
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tidwall/buntdb"
+)
+
+// planKeyPrefix prefixes the keys of the plans, the database being shared with the watcher
+const planKeyPrefix = "plan:"
+
+var ErrPlanNotFound = errors.New("plan not found")
+
+// Store persists the plans created by the server so that they can be reviewed and
+// applied by ID, instead of trusting plans sent back by the clients.
+type Store struct {
+	db *buntdb.DB
+}
+
+// NewStore creates a new plan store
+func NewStore(db *buntdb.DB) (*Store, error) {
+	err := db.CreateIndex("plans", planKeyPrefix+"*", buntdb.IndexJSON("timestamp"))
+	if err != nil && !errors.Is(err, buntdb.ErrIndexExists) {
+		return nil, fmt.Errorf("failed to create plans index: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Save creates or updates a plan
+func (s *Store) Save(plan *Plan) error {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+
+	return s.db.Update(func(tx *buntdb.Tx) error {
+		_, _, err := tx.Set(planKeyPrefix+plan.ID, string(data), nil)
+		return err
+	})
+}
+
+// Get returns the plan with the given ID
+func (s *Store) Get(id string) (*Plan, error) {
+	var plan Plan
+
+	err := s.db.View(func(tx *buntdb.Tx) error {
+		value, err := tx.Get(planKeyPrefix + id)
+		if err != nil {
+			return err
+		}
+
+		return json.Unmarshal([]byte(value), &plan)
+	})
+	if errors.Is(err, buntdb.ErrNotFound) {
+		return nil, ErrPlanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plan: %w", err)
+	}
+
+	return &plan, nil
+}
+
+// List returns all the stored plans, newest first
+func (s *Store) List() ([]*Plan, error) {
+	var plans []*Plan
+
+	err := s.db.View(func(tx *buntdb.Tx) error {
+		return tx.Descend("plans", func(key, value string) bool {
+			var plan Plan
+			if err := json.Unmarshal([]byte(value), &plan); err == nil {
+				plans = append(plans, &plan)
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plans: %w", err)
+	}
+
+	sort.SliceStable(plans, func(i, j int) bool {
+		return plans[i].Timestamp.After(plans[j].Timestamp)
+	})
+
+	return plans, nil
+}
+
+// Update modifies the plan with the given ID in a single transaction and returns it as
+// stored, so that a review is never written back over a concurrent apply. It fails with
+// ErrPlanAlreadyApplied if the plan has already been applied. The plan is left untouched
+// when fn returns an error, which is returned as is.
+func (s *Store) Update(id string, fn func(plan *Plan) error) (*Plan, error) {
+	var plan Plan
+	var fnErr error
+
+	err := s.db.Update(func(tx *buntdb.Tx) error {
+		value, err := tx.Get(planKeyPrefix + id)
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal([]byte(value), &plan); err != nil {
+			return err
+		}
+		if plan.AppliedAt != nil {
+			return ErrPlanAlreadyApplied
+		}
+		if fnErr = fn(&plan); fnErr != nil {
+			return fnErr
+		}
+
+		data, err := json.Marshal(plan)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = tx.Set(planKeyPrefix+id, string(data), nil)
+		return err
+	})
+	switch {
+	case fnErr != nil, errors.Is(err, ErrPlanAlreadyApplied):
+		return nil, err
+	case errors.Is(err, buntdb.ErrNotFound):
+		return nil, ErrPlanNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to update plan: %w", err)
+	}
+
+	return &plan, nil
+}
+
+// MarkApplied records that the plan is being applied and returns it as stored. It fails
+// with ErrPlanAlreadyApplied if the plan has already been applied, so that a plan is never
+// applied twice. The plan is left untouched when check, if not nil, returns an error.
+func (s *Store) MarkApplied(id string, check func(plan *Plan) error) (*Plan, error) {
+	return s.Update(id, func(plan *Plan) error {
+		if check != nil {
+			if err := check(plan); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		plan.AppliedAt = &now
+		return nil
+	})
+}
+
+// Delete removes the plan with the given ID
+func (s *Store) Delete(id string) error {
+	err := s.db.Update(func(tx *buntdb.Tx) error {
+		_, err := tx.Delete(planKeyPrefix + id)
+		return err
+	})
+	if errors.Is(err, buntdb.ErrNotFound) {
+		return ErrPlanNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,88 @@
+package providers
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// RuntimeTolerance is the difference allowed between the duration of a file and the
+	// runtime of its title, relative to the runtime. The cuts and the frame rates differ.
+	RuntimeTolerance = 0.15
+
+	// MinRuntimeTolerance is the difference always allowed, the runtimes being rounded
+	// and the short episodes varying the most
+	MinRuntimeTolerance = 5 * time.Minute
+)
+
+// MatchConfidence scores between 0 and 1 how well a title found by a provider matches
+// the title parsed from a filename. The years are ignored when one of them is unknown.
+func MatchConfidence(query string, year int, title string, releaseYear int) float64 {
+	score := tokenSimilarity(tokenize(query), tokenize(title))
+
+	if year > 0 && releaseYear > 0 {
+		switch diff := year - releaseYear; {
+		case diff == 0:
+		case diff == 1 || diff == -1:
+			// Release dates differ between countries
+			score *= 0.9
+		default:
+			score *= 0.6
+		}
+	}
+
+	return score
+}
+
+// RuntimeConfidence scores between 0 and 1 how well the duration of a file matches the
+// runtime of a title, in minutes. It is 1 within the tolerance or when one of them is
+// unknown, and drops with the square of the excess beyond.
+func RuntimeConfidence(duration time.Duration, runtime int) float64 {
+	if duration <= 0 || runtime <= 0 {
+		return 1
+	}
+
+	expected := time.Duration(runtime) * time.Minute
+	tolerance := max(MinRuntimeTolerance, time.Duration(float64(expected)*RuntimeTolerance))
+
+	diff := duration - expected
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff <= tolerance {
+		return 1
+	}
+
+	return math.Pow(float64(tolerance)/float64(diff), 2)
+}
+
+// tokenize splits a title into lower case words, ignoring the punctuation
+func tokenize(title string) []string {
+	return strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// tokenSimilarity is the Dice coefficient of the words of two titles
+func tokenSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(a))
+	for _, token := range a {
+		counts[token]++
+	}
+
+	common := 0
+	for _, token := range b {
+		if counts[token] > 0 {
+			counts[token]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMatchConfidence(t *testing.T) {
+	tests := []struct {
+		query       string
+		year        int
+		title       string
+		releaseYear int
+		want        float64
+	}{
+		{query: "the matrix", year: 1999, title: "The Matrix", releaseYear: 1999, want: 1},
+		{query: "the matrix", title: "The Matrix", releaseYear: 1999, want: 1},
+		{query: "the matrix", year: 1999, title: "The Matrix", releaseYear: 2000, want: 0.9},
+		{query: "the matrix", year: 2003, title: "The Matrix Reloaded", releaseYear: 2003, want: 0.8},
+		{query: "spider man", year: 2002, title: "Spider-Man", releaseYear: 1977, want: 0.6},
+		{query: "alien", title: "Prometheus", want: 0},
+		{query: "", title: "Alien", want: 0},
+	}
+
+	for _, tt := range tests {
+		got := MatchConfidence(tt.query, tt.year, tt.title, tt.releaseYear)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("MatchConfidence(%q, %d, %q, %d) = %v, want %v", tt.query, tt.year, tt.title, tt.releaseYear, got, tt.want)
+		}
+	}
+}
+
+func TestRuntimeConfidence(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		runtime  int
+		want     float64
+	}{
+		{"unknown duration", 0, 120, 1},
+		{"unknown runtime", 2 * time.Hour, 0, 1},
+		{"within the tolerance", 130 * time.Minute, 120, 1},
+		{"short episode", 27 * time.Minute, 22, 1},
+		{"remake", 130 * time.Minute, 108, math.Pow(16.2/22, 2)},
+		{"sample", 2 * time.Minute, 108, math.Pow(16.2/106, 2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RuntimeConfidence(tt.duration, tt.runtime); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("RuntimeConfidence(%s, %d) = %v, want %v", tt.duration, tt.runtime, got, tt.want)
+			}
+		})
+	}
+}
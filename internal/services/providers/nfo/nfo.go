@@ -0,0 +1,307 @@
+package nfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"goru/internal/models"
+	"goru/internal/services/providers"
+	"goru/internal/utils"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// ErrNotSupported is returned by the lookups needing an online provider
+var ErrNotSupported = errors.New("not supported without a provider after nfo")
+
+// errNoID is returned when the .nfo files hold no ID known by the next provider
+var errNoID = errors.New("no usable ID in the .nfo files")
+
+type nfoProvider struct {
+	next providers.Provider
+}
+
+// New creates a provider reading the Kodi .nfo files written next to the video files.
+// The IDs they hold are looked up with the next provider for the canonical titles,
+// and the files without .nfo are handed to it. Without a next provider, the metadata
+// only comes from the .nfo files.
+func New(next providers.Provider) providers.Provider {
+	return &nfoProvider{next: next}
+}
+
+func (p *nfoProvider) Name() string {
+	if p.next != nil {
+		return "nfo+" + p.next.Name()
+	}
+	return "nfo"
+}
+
+// Ping checks the next provider
+func (p *nfoProvider) Ping(ctx context.Context) error {
+	if pinger, ok := p.next.(providers.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (p *nfoProvider) Provide(ctx context.Context, file *models.VideoFile) error {
+	s, err := readSidecar(file.Path, file.MediaType)
+	if err != nil {
+		// A broken .nfo file should not prevent the lookup
+		log.Warn("ignoring the .nfo files", zap.String("file", file.Path), zap.Error(err))
+	}
+	if s == nil {
+		if p.next == nil {
+			return fmt.Errorf("no .nfo file found for %s", file.Filename)
+		}
+		return p.next.Provide(ctx, file)
+	}
+
+	// The .nfo files tell the type of the files guessed as unknown
+	if file.MediaType == models.MediaTypeUnknown {
+		if s.movie != nil {
+			file.MediaType = models.MediaTypeMovie
+		} else {
+			file.MediaType = models.MediaTypeTVShow
+		}
+	}
+
+	switch file.MediaType {
+	case models.MediaTypeMovie:
+		if s.movie == nil {
+			return p.fallback(ctx, file, nil)
+		}
+		return p.provideMovie(ctx, file, s.movie)
+	default:
+		return p.provideEpisode(ctx, file, s)
+	}
+}
+
+func (p *nfoProvider) provideMovie(ctx context.Context, file *models.VideoFile, doc *document) error {
+	movie := doc.toMovie()
+
+	if p.next != nil {
+		found, err := p.lookupMovie(ctx, movie.ExternalIDs)
+		if errors.Is(err, errNoID) {
+			// Search the title of the .nfo file instead of the filename
+			return p.fallback(ctx, file, &models.Query{Title: movie.Title, Year: doc.year()})
+		}
+		if err != nil {
+			log.Warn("looking up the ID of the .nfo file failed", zap.String("file", file.Path), zap.String("provider", p.next.Name()), zap.Error(err))
+		} else {
+			mergeIDs(&found.ExternalIDs, movie.ExternalIDs)
+			movie = found
+		}
+	}
+
+	if movie.Title == "" {
+		return fmt.Errorf("no title in the .nfo file of %s", file.Filename)
+	}
+
+	log.Debug("provided metadata from the .nfo file", zap.String("file", file.Filename), zap.String("title", movie.Title))
+	file.Metadata = movie
+	file.Confidence = &models.Confidence{Score: 1}
+	return nil
+}
+
+func (p *nfoProvider) provideEpisode(ctx context.Context, file *models.VideoFile, s *sidecar) error {
+	season, episode := utils.ExtractSeasonEpisode(file.Filename)
+	if file.Query != nil && file.Query.Season != 0 && file.Query.Episode != 0 {
+		season, episode = file.Query.Season, file.Query.Episode
+	}
+
+	// The episode .nfo file has the last word on the numbers
+	var info *models.Episode
+	if doc := s.episode(season, episode); doc != nil {
+		info = doc.toEpisode()
+		if info.Season != 0 && info.Episode != 0 {
+			season, episode = info.Season, info.Episode
+		}
+	}
+	if season == 0 || episode == 0 {
+		return fmt.Errorf("could not extract season/episode from filename: %s", file.Filename)
+	}
+
+	show := &models.TVShow{}
+	if s.show != nil {
+		show = s.show.toTVShow()
+	}
+	if show.Name == "" {
+		if doc := s.episode(season, episode); doc != nil {
+			show.Name = doc.ShowTitle
+		}
+	}
+
+	if p.next != nil {
+		found, err := p.lookupTVShow(ctx, show.ExternalIDs)
+		if errors.Is(err, errNoID) {
+			var year int
+			if s.show != nil {
+				year = s.show.year()
+			}
+			return p.fallback(ctx, file, &models.Query{Title: show.Name, Year: year, Season: season, Episode: episode})
+		}
+		if err != nil {
+			log.Warn("looking up the ID of the .nfo file failed", zap.String("file", file.Path), zap.String("provider", p.next.Name()), zap.Error(err))
+		} else {
+			mergeIDs(&found.ExternalIDs, show.ExternalIDs)
+			show = found
+
+			if online, err := p.lookupEpisode(ctx, found, season, episode); err != nil {
+				log.Warn("looking up the episode failed", zap.String("file", file.Path), zap.String("provider", p.next.Name()), zap.Error(err))
+			} else {
+				if info != nil {
+					mergeIDs(&online.ExternalIDs, info.ExternalIDs)
+				}
+				info = online
+			}
+		}
+	}
+
+	if show.Name == "" {
+		return fmt.Errorf("no show title in the .nfo files of %s", file.Filename)
+	}
+	if info == nil {
+		info = &models.Episode{}
+	}
+	info.Season, info.Episode = season, episode
+	info.TVShow = *show
+
+	log.Debug("provided metadata from the .nfo files", zap.String("file", file.Filename), zap.String("show", show.Name), zap.Int("season", season), zap.Int("episode", episode))
+	file.Metadata = info
+	file.Confidence = &models.Confidence{Score: 1}
+	return nil
+}
+
+// fallback hands the file to the next provider, searching the title of the .nfo
+// files unless the query is already overridden
+func (p *nfoProvider) fallback(ctx context.Context, file *models.VideoFile, query *models.Query) error {
+	if p.next == nil {
+		return fmt.Errorf("no usable .nfo file found for %s", file.Filename)
+	}
+
+	if file.Query == nil && query != nil && query.Title != "" {
+		file.Query = query
+	}
+	return p.next.Provide(ctx, file)
+}
+
+// lookupMovie finds the movie with the ID of the next provider, or with the ID of
+// another database when it can
+func (p *nfoProvider) lookupMovie(ctx context.Context, ids models.ExternalIDs) (*models.Movie, error) {
+	if id := idOf(p.next.Name(), ids); id != "" {
+		return p.next.GetMovieByID(ctx, id)
+	}
+	if finder, ok := p.next.(providers.Finder); ok && ids != (models.ExternalIDs{}) {
+		return finder.FindMovie(ctx, ids)
+	}
+	return nil, errNoID
+}
+
+// lookupTVShow finds the show with the ID of the next provider, or with the ID of
+// another database when it can
+func (p *nfoProvider) lookupTVShow(ctx context.Context, ids models.ExternalIDs) (*models.TVShow, error) {
+	if id := idOf(p.next.Name(), ids); id != "" {
+		return p.next.GetTVShowByID(ctx, id)
+	}
+	if finder, ok := p.next.(providers.Finder); ok && ids != (models.ExternalIDs{}) {
+		return finder.FindTVShow(ctx, ids)
+	}
+	return nil, errNoID
+}
+
+func (p *nfoProvider) lookupEpisode(ctx context.Context, show *models.TVShow, season, episode int) (*models.Episode, error) {
+	showID, err := strconv.Atoi(show.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid show ID: %w", err)
+	}
+	return p.next.GetEpisode(ctx, showID, season, episode)
+}
+
+// idOf returns the ID of a title in the database of a provider
+func idOf(provider string, ids models.ExternalIDs) string {
+	switch provider {
+	case "tmdb":
+		return ids.TMDBID
+	case "tvdb":
+		return ids.TVDBID
+	case "anidb":
+		return ids.AniDBID
+	}
+	return ""
+}
+
+// mergeIDs fills the IDs unknown by a provider with the ones of the .nfo files
+func mergeIDs(dst *models.ExternalIDs, src models.ExternalIDs) {
+	if dst.TMDBID == "" {
+		dst.TMDBID = src.TMDBID
+	}
+	if dst.TVDBID == "" {
+		dst.TVDBID = src.TVDBID
+	}
+	if dst.AniDBID == "" {
+		dst.AniDBID = src.AniDBID
+	}
+	if dst.IMDBID == "" {
+		dst.IMDBID = src.IMDBID
+	}
+}
+
+func (p *nfoProvider) GetMovie(ctx context.Context, title string, year int) (*models.Movie, error) {
+	if p.next == nil {
+		return nil, ErrNotSupported
+	}
+	return p.next.GetMovie(ctx, title, year)
+}
+
+func (p *nfoProvider) GetMovieByID(ctx context.Context, id string) (*models.Movie, error) {
+	if p.next == nil {
+		return nil, ErrNotSupported
+	}
+	return p.next.GetMovieByID(ctx, id)
+}
+
+func (p *nfoProvider) SearchMovies(ctx context.Context, title string, year int) ([]*models.Movie, error) {
+	if p.next == nil {
+		return nil, ErrNotSupported
+	}
+	return p.next.SearchMovies(ctx, title, year)
+}
+
+func (p *nfoProvider) GetTVShow(ctx context.Context, title string, year int) (*models.TVShow, error) {
+	if p.next == nil {
+		return nil, ErrNotSupported
+	}
+	return p.next.GetTVShow(ctx, title, year)
+}
+
+func (p *nfoProvider) GetTVShowByID(ctx context.Context, id string) (*models.TVShow, error) {
+	if p.next == nil {
+		return nil, ErrNotSupported
+	}
+	return p.next.GetTVShowByID(ctx, id)
+}
+
+func (p *nfoProvider) SearchTVShows(ctx context.Context, title string, year int) ([]*models.TVShow, error) {
+	if p.next == nil {
+		return nil, ErrNotSupported
+	}
+	return p.next.SearchTVShows(ctx, title, year)
+}
+
+func (p *nfoProvider) GetEpisode(ctx context.Context, showID, season, episode int) (*models.Episode, error) {
+	if p.next == nil {
+		return nil, ErrNotSupported
+	}
+	return p.next.GetEpisode(ctx, showID, season, episode)
+}
+
+func (p *nfoProvider) ListEpisodes(ctx context.Context, showID, season int) ([]*models.Episode, error) {
+	if p.next == nil {
+		return nil, ErrNotSupported
+	}
+	return p.next.ListEpisodes(ctx, showID, season)
+}
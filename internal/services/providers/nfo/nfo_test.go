@@ -0,0 +1,257 @@
+package nfo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goru/internal/models"
+	"goru/internal/services/providers"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+// fakeProvider records the lookups handed by the nfo provider
+type fakeProvider struct {
+	providers.Provider
+	calls []string
+}
+
+func (f *fakeProvider) Name() string { return "tmdb" }
+
+func (f *fakeProvider) Provide(ctx context.Context, file *models.VideoFile) error {
+	title := file.Filename
+	if file.Query != nil {
+		title = file.Query.Title
+	}
+	f.calls = append(f.calls, "provide:"+title)
+	file.Metadata = &models.Movie{Title: title}
+	return nil
+}
+
+func (f *fakeProvider) GetMovieByID(ctx context.Context, id string) (*models.Movie, error) {
+	f.calls = append(f.calls, "movie:"+id)
+	return &models.Movie{ID: id, Title: "The Matrix", ExternalIDs: models.ExternalIDs{TMDBID: id}}, nil
+}
+
+func (f *fakeProvider) GetTVShowByID(ctx context.Context, id string) (*models.TVShow, error) {
+	f.calls = append(f.calls, "show:"+id)
+	return &models.TVShow{ID: id, Name: "Breaking Bad", ExternalIDs: models.ExternalIDs{TMDBID: id}}, nil
+}
+
+func (f *fakeProvider) GetEpisode(ctx context.Context, showID, season, episode int) (*models.Episode, error) {
+	f.calls = append(f.calls, "episode")
+	return &models.Episode{Title: "Pilot", Season: season, Episode: episode}, nil
+}
+
+func (f *fakeProvider) FindMovie(ctx context.Context, ids models.ExternalIDs) (*models.Movie, error) {
+	f.calls = append(f.calls, "find:"+ids.IMDBID)
+	return &models.Movie{ID: "603", Title: "The Matrix", ExternalIDs: models.ExternalIDs{TMDBID: "603"}}, nil
+}
+
+func (f *fakeProvider) FindTVShow(ctx context.Context, ids models.ExternalIDs) (*models.TVShow, error) {
+	f.calls = append(f.calls, "find:"+ids.TVDBID)
+	return &models.TVShow{ID: "1396", Name: "Breaking Bad", ExternalIDs: models.ExternalIDs{TMDBID: "1396"}}, nil
+}
+
+// writeFiles creates the files of a library, the video files being empty
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+const matrixNFO = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<movie>
+  <title>The Matrix</title>
+  <year>1999</year>
+  <genre>Action</genre>
+  <uniqueid type="imdb" default="true">tt0133093</uniqueid>
+  <uniqueid type="tmdb">603</uniqueid>
+</movie>`
+
+func TestProvide_MovieOffline(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"Matrix/movie.nfo":  matrixNFO,
+		"Matrix/matrix.mkv": "",
+	})
+
+	file := models.NewVideoFile(filepath.Join(root, "Matrix/matrix.mkv"), models.DefaultConflictStrategy)
+	if err := New(nil).Provide(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+
+	movie, ok := file.Metadata.(*models.Movie)
+	if !ok {
+		t.Fatalf("got metadata %T, want *models.Movie", file.Metadata)
+	}
+	if movie.Title != "The Matrix" || movie.ReleaseDate.Year() != 1999 || movie.Genre != models.GenreAction {
+		t.Errorf("got movie %+v", movie)
+	}
+	if movie.ExternalIDs.IMDBID != "tt0133093" || movie.ExternalIDs.TMDBID != "603" {
+		t.Errorf("got IDs %+v", movie.ExternalIDs)
+	}
+	if file.Confidence == nil || file.Confidence.Score != 1 {
+		t.Errorf("got confidence %v, want 1", file.Confidence)
+	}
+}
+
+func TestProvide_MovieHandsTheIDToTheNextProvider(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"matrix.mkv": "",
+		"matrix.nfo": matrixNFO,
+	})
+
+	next := &fakeProvider{}
+	file := models.NewVideoFile(filepath.Join(root, "matrix.mkv"), models.DefaultConflictStrategy)
+	if err := New(next).Provide(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(next.calls) != 1 || next.calls[0] != "movie:603" {
+		t.Errorf("got calls %v, want the lookup of the TMDB ID", next.calls)
+	}
+	movie := file.Metadata.(*models.Movie)
+	if movie.ExternalIDs.IMDBID != "tt0133093" {
+		t.Errorf("got IDs %+v, want the IMDb ID of the .nfo file kept", movie.ExternalIDs)
+	}
+}
+
+func TestProvide_URLOnly(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"matrix.mkv": "",
+		"matrix.nfo": "https://www.imdb.com/title/tt0133093/\n",
+	})
+
+	next := &fakeProvider{}
+	file := models.NewVideoFile(filepath.Join(root, "matrix.mkv"), models.DefaultConflictStrategy)
+	file.MediaType = models.MediaTypeMovie
+	if err := New(next).Provide(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(next.calls) != 1 || next.calls[0] != "find:tt0133093" {
+		t.Errorf("got calls %v, want the lookup of the IMDb ID", next.calls)
+	}
+}
+
+func TestProvide_TitleOnlyIsSearched(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"m.mkv": "",
+		"m.nfo": `<movie><title>The Matrix</title><year>1999</year></movie>`,
+	})
+
+	next := &fakeProvider{}
+	file := models.NewVideoFile(filepath.Join(root, "m.mkv"), models.DefaultConflictStrategy)
+	file.MediaType = models.MediaTypeMovie
+	if err := New(next).Provide(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(next.calls) != 1 || next.calls[0] != "provide:The Matrix" {
+		t.Errorf("got calls %v, want a search of the title of the .nfo file", next.calls)
+	}
+	if file.Query.Year != 1999 {
+		t.Errorf("got year %d, want 1999", file.Query.Year)
+	}
+}
+
+func TestProvide_WithoutNFO(t *testing.T) {
+	root := writeFiles(t, map[string]string{"The.Matrix.1999.mkv": ""})
+	file := models.NewVideoFile(filepath.Join(root, "The.Matrix.1999.mkv"), models.DefaultConflictStrategy)
+
+	if err := New(nil).Provide(context.Background(), file); err == nil {
+		t.Error("expected an error without .nfo file nor next provider")
+	}
+
+	next := &fakeProvider{}
+	if err := New(next).Provide(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+	if len(next.calls) != 1 || next.calls[0] != "provide:The.Matrix.1999.mkv" {
+		t.Errorf("got calls %v, want the file handed to the next provider", next.calls)
+	}
+}
+
+func TestProvide_EpisodeOffline(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"Breaking Bad/tvshow.nfo": `<tvshow>
+  <title>Breaking Bad</title>
+  <premiered>2008-01-20</premiered>
+  <id>81189</id>
+</tvshow>`,
+		// A multi-episode file
+		"Breaking Bad/Season 1/bb.s01e01e02.mkv": "",
+		"Breaking Bad/Season 1/bb.s01e01e02.nfo": `<episodedetails>
+  <title>Pilot</title><season>1</season><episode>1</episode><aired>2008-01-20</aired>
+</episodedetails>
+<episodedetails>
+  <title>Cat's in the Bag...</title><season>1</season><episode>2</episode>
+</episodedetails>`,
+	})
+
+	file := models.NewVideoFile(filepath.Join(root, "Breaking Bad/Season 1/bb.s01e01e02.mkv"), models.DefaultConflictStrategy)
+	file.MediaType = models.MediaTypeTVShow
+	if err := New(nil).Provide(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+
+	episode, ok := file.Metadata.(*models.Episode)
+	if !ok {
+		t.Fatalf("got metadata %T, want *models.Episode", file.Metadata)
+	}
+	if episode.Title != "Pilot" || episode.Season != 1 || episode.Episode != 1 {
+		t.Errorf("got episode %+v", episode)
+	}
+	if episode.TVShow.Name != "Breaking Bad" || episode.TVShow.ExternalIDs.TVDBID != "81189" || episode.TVShow.FirstAirDate.Year() != 2008 {
+		t.Errorf("got show %+v", episode.TVShow)
+	}
+}
+
+func TestProvide_EpisodeHandsTheIDToTheNextProvider(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"Show/tvshow.nfo":      `<tvshow><title>BB</title><uniqueid type="tvdb">81189</uniqueid></tvshow>`,
+		"Show/Show - 1x01.mkv": "",
+	})
+
+	next := &fakeProvider{}
+	file := models.NewVideoFile(filepath.Join(root, "Show/Show - 1x01.mkv"), models.DefaultConflictStrategy)
+	file.MediaType = models.MediaTypeTVShow
+	if err := New(next).Provide(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(next.calls) != 2 || next.calls[0] != "find:81189" || next.calls[1] != "episode" {
+		t.Errorf("got calls %v, want the lookup of the TVDB ID then of the episode", next.calls)
+	}
+	episode := file.Metadata.(*models.Episode)
+	if episode.Title != "Pilot" || episode.TVShow.Name != "Breaking Bad" || episode.TVShow.ExternalIDs.TVDBID != "81189" {
+		t.Errorf("got episode %+v", episode)
+	}
+}
+
+func TestParse_Latin1(t *testing.T) {
+	data := append([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?><movie><title>Am`), 0xe9, 'l', 'i', 'e')
+	data = append(data, []byte(`</title></movie>`)...)
+
+	docs, err := parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].Title != "Amélie" {
+		t.Errorf("got %+v, want Amélie", docs)
+	}
+}
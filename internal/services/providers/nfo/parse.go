@@ -0,0 +1,380 @@
+package nfo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"goru/internal/models"
+
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// Names of the Kodi .nfo files describing a whole folder
+const (
+	movieNFO  = "movie.nfo"
+	tvshowNFO = "tvshow.nfo"
+)
+
+// Root elements of the Kodi .nfo files
+const (
+	rootMovie   = "movie"
+	rootTVShow  = "tvshow"
+	rootEpisode = "episodedetails"
+)
+
+// document holds the elements of a .nfo file read by goru
+type document struct {
+	XMLName       xml.Name
+	Title         string     `xml:"title"`
+	OriginalTitle string     `xml:"originaltitle"`
+	ShowTitle     string     `xml:"showtitle"`
+	Year          string     `xml:"year"`
+	Premiered     string     `xml:"premiered"`
+	Aired         string     `xml:"aired"`
+	Plot          string     `xml:"plot"`
+	Genres        []string   `xml:"genre"`
+	Directors     []string   `xml:"director"`
+	Season        string     `xml:"season"`
+	Episode       string     `xml:"episode"`
+	UniqueIDs     []uniqueID `xml:"uniqueid"`
+
+	// Legacy elements written before the uniqueid ones
+	ID     string `xml:"id"`
+	IMDBID string `xml:"imdbid"`
+	TMDBID string `xml:"tmdbid"`
+	TVDBID string `xml:"tvdbid"`
+}
+
+type uniqueID struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Links to the databases, for the .nfo files holding only an URL
+var (
+	imdbURL = regexp.MustCompile(`imdb\.com/title/(tt\d+)`)
+	tmdbURL = regexp.MustCompile(`themoviedb\.org/(movie|tv)/(\d+)`)
+	tvdbURL = regexp.MustCompile(`thetvdb\.com/\S*?[?&]id=(\d+)`)
+)
+
+// sidecar gathers the .nfo files describing a video file, nil when missing
+type sidecar struct {
+	movie    *document
+	show     *document
+	episodes []*document
+}
+
+// readSidecar reads the .nfo files of a video file. It returns nil when the file has
+// none.
+func readSidecar(path string, mediaType models.MediaType) (*sidecar, error) {
+	dir := filepath.Dir(path)
+
+	var s sidecar
+	docs, err := readFile(strings.TrimSuffix(path, filepath.Ext(path)) + ".nfo")
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		switch doc.XMLName.Local {
+		case rootMovie:
+			s.movie = doc
+		case rootTVShow:
+			s.show = doc
+		case rootEpisode:
+			// Multi-episode files hold one element per episode
+			s.episodes = append(s.episodes, doc)
+		case "":
+			// The URL of a title does not tell whether it is a movie or a show
+			if mediaType == models.MediaTypeMovie {
+				s.movie = doc
+			} else {
+				s.show = doc
+			}
+		}
+	}
+
+	if s.movie == nil && len(s.episodes) == 0 {
+		docs, err := readFile(filepath.Join(dir, movieNFO))
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) > 0 {
+			s.movie = docs[0]
+		}
+	}
+
+	// The episodes are either in the folder of the show or in a season folder
+	for _, d := range []string{dir, filepath.Dir(dir)} {
+		if s.show != nil {
+			break
+		}
+		docs, err := readFile(filepath.Join(d, tvshowNFO))
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) > 0 {
+			s.show = docs[0]
+		}
+	}
+
+	if s.movie == nil && s.show == nil && len(s.episodes) == 0 {
+		return nil, nil
+	}
+
+	return &s, nil
+}
+
+// episode returns the element describing an episode, or the first one when the
+// season and episode numbers are unknown
+func (s *sidecar) episode(season, episode int) *document {
+	for _, doc := range s.episodes {
+		if atoi(doc.Season) == season && atoi(doc.Episode) == episode {
+			return doc
+		}
+	}
+	if len(s.episodes) > 0 {
+		return s.episodes[0]
+	}
+	return nil
+}
+
+// readFile parses a .nfo file, returning nothing when it does not exist
+func readFile(path string) ([]*document, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	docs, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return docs, nil
+}
+
+// parse decodes the root elements of a .nfo file. Kodi also accepts the files holding
+// only the URL of the title in a database, turned into a document with the ID.
+func parse(data []byte) ([]*document, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.CharsetReader = charsetReader
+
+	var docs []*document
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(docs) > 0 {
+				// Trailing garbage, such as an URL after the XML
+				break
+			}
+			return parseURL(data)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var doc document
+		if err := decoder.DecodeElement(&doc, &start); err != nil {
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+
+	if len(docs) == 0 {
+		return parseURL(data)
+	}
+
+	return docs, nil
+}
+
+// parseURL reads the IDs of a .nfo file holding the URLs of a title
+func parseURL(data []byte) ([]*document, error) {
+	var doc document
+	if m := imdbURL.FindSubmatch(data); m != nil {
+		doc.UniqueIDs = append(doc.UniqueIDs, uniqueID{Type: "imdb", Value: string(m[1])})
+	}
+	if m := tmdbURL.FindSubmatch(data); m != nil {
+		doc.UniqueIDs = append(doc.UniqueIDs, uniqueID{Type: "tmdb", Value: string(m[2])})
+		if string(m[1]) == "tv" {
+			doc.XMLName.Local = rootTVShow
+		} else {
+			doc.XMLName.Local = rootMovie
+		}
+	}
+	if m := tvdbURL.FindSubmatch(data); m != nil {
+		doc.UniqueIDs = append(doc.UniqueIDs, uniqueID{Type: "tvdb", Value: string(m[1])})
+	}
+
+	if len(doc.UniqueIDs) == 0 {
+		return nil, errors.New("neither XML nor a database URL")
+	}
+
+	return []*document{&doc}, nil
+}
+
+// charsetReader decodes the .nfo files not written in UTF-8
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	encoding, err := ianaindex.IANA.Encoding(label)
+	if err != nil {
+		return nil, err
+	}
+	if encoding == nil {
+		return nil, fmt.Errorf("unsupported encoding %q", label)
+	}
+	return encoding.NewDecoder().Reader(input), nil
+}
+
+// externalIDs returns the IDs of the document. The legacy id element is the TVDB ID
+// of the shows and episodes scraped by Kodi, and the TMDB ID of the movies, unless it
+// is an IMDb ID.
+func (d *document) externalIDs() models.ExternalIDs {
+	ids := models.ExternalIDs{
+		IMDBID: strings.TrimSpace(d.IMDBID),
+		TMDBID: strings.TrimSpace(d.TMDBID),
+		TVDBID: strings.TrimSpace(d.TVDBID),
+	}
+
+	for _, u := range d.UniqueIDs {
+		value := strings.TrimSpace(u.Value)
+		switch strings.ToLower(u.Type) {
+		case "imdb":
+			ids.IMDBID = value
+		case "tmdb":
+			ids.TMDBID = value
+		case "tvdb":
+			ids.TVDBID = value
+		case "anidb":
+			ids.AniDBID = value
+		}
+	}
+
+	if id := strings.TrimSpace(d.ID); id != "" {
+		switch {
+		case strings.HasPrefix(id, "tt"):
+			if ids.IMDBID == "" {
+				ids.IMDBID = id
+			}
+		case d.XMLName.Local == rootMovie:
+			if ids.TMDBID == "" {
+				ids.TMDBID = id
+			}
+		default:
+			if ids.TVDBID == "" {
+				ids.TVDBID = id
+			}
+		}
+	}
+
+	return ids
+}
+
+// year returns the year of the title, read from the release date when missing
+func (d *document) year() int {
+	if year := atoi(d.Year); year > 0 {
+		return year
+	}
+	if date := d.date(); !date.IsZero() {
+		return date.Year()
+	}
+	return 0
+}
+
+// date returns the release date of a movie, or the air date of a show or an episode
+func (d *document) date() time.Time {
+	for _, value := range []string{d.Premiered, d.Aired} {
+		if date, err := time.Parse("2006-01-02", strings.TrimSpace(value)); err == nil {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+func (d *document) toMovie() *models.Movie {
+	ids := d.externalIDs()
+	movie := &models.Movie{
+		ID:            ids.TMDBID,
+		Title:         strings.TrimSpace(d.Title),
+		OriginalTitle: strings.TrimSpace(d.OriginalTitle),
+		ReleaseDate:   d.date(),
+		ExternalIDs:   ids,
+		Overview:      strings.TrimSpace(d.Plot),
+		Genres:        d.genres(),
+	}
+	if len(movie.Genres) > 0 {
+		movie.Genre = models.Genre(movie.Genres[0])
+	}
+	if len(d.Directors) > 0 {
+		movie.Director = strings.TrimSpace(d.Directors[0])
+	}
+	if movie.ReleaseDate.IsZero() && d.year() > 0 {
+		movie.ReleaseDate = time.Date(d.year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return movie
+}
+
+func (d *document) toTVShow() *models.TVShow {
+	ids := d.externalIDs()
+	show := &models.TVShow{
+		ID:           ids.TMDBID,
+		Name:         strings.TrimSpace(d.Title),
+		OriginalName: strings.TrimSpace(d.OriginalTitle),
+		FirstAirDate: d.date(),
+		ExternalIDs:  ids,
+		Overview:     strings.TrimSpace(d.Plot),
+		Genres:       d.genres(),
+	}
+	if len(show.Genres) > 0 {
+		show.Genre = show.Genres[0]
+	}
+	if show.FirstAirDate.IsZero() && d.year() > 0 {
+		show.FirstAirDate = time.Date(d.year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return show
+}
+
+func (d *document) toEpisode() *models.Episode {
+	return &models.Episode{
+		Title:       strings.TrimSpace(d.Title),
+		Season:      atoi(d.Season),
+		Episode:     atoi(d.Episode),
+		AirDate:     d.date(),
+		Summary:     strings.TrimSpace(d.Plot),
+		ExternalIDs: d.externalIDs(),
+	}
+}
+
+// genres returns the genres, some tools writing them in a single element
+func (d *document) genres() []string {
+	var genres []string
+	for _, value := range d.Genres {
+		for _, genre := range strings.Split(value, " / ") {
+			if genre = strings.TrimSpace(genre); genre != "" {
+				genres = append(genres, genre)
+			}
+		}
+	}
+	return genres
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
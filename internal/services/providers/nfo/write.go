@@ -0,0 +1,134 @@
+package nfo
+
+import (
+	"encoding/xml"
+	"time"
+
+	"goru/internal/models"
+)
+
+// movieFile is the .nfo file of a movie written by goru
+type movieFile struct {
+	XMLName       xml.Name       `xml:"movie"`
+	Title         string         `xml:"title"`
+	OriginalTitle string         `xml:"originaltitle,omitempty"`
+	Year          int            `xml:"year,omitempty"`
+	Premiered     string         `xml:"premiered,omitempty"`
+	Plot          string         `xml:"plot,omitempty"`
+	Genres        []string       `xml:"genre"`
+	Director      string         `xml:"director,omitempty"`
+	UniqueIDs     []uniqueIDFile `xml:"uniqueid"`
+}
+
+// tvshowFile is the .nfo file of a TV show written by goru
+type tvshowFile struct {
+	XMLName       xml.Name       `xml:"tvshow"`
+	Title         string         `xml:"title"`
+	OriginalTitle string         `xml:"originaltitle,omitempty"`
+	Year          int            `xml:"year,omitempty"`
+	Premiered     string         `xml:"premiered,omitempty"`
+	Plot          string         `xml:"plot,omitempty"`
+	Genres        []string       `xml:"genre"`
+	UniqueIDs     []uniqueIDFile `xml:"uniqueid"`
+}
+
+// episodeFile is the .nfo file of an episode written by goru
+type episodeFile struct {
+	XMLName   xml.Name       `xml:"episodedetails"`
+	Title     string         `xml:"title"`
+	ShowTitle string         `xml:"showtitle,omitempty"`
+	Season    int            `xml:"season"`
+	Episode   int            `xml:"episode"`
+	Aired     string         `xml:"aired,omitempty"`
+	Plot      string         `xml:"plot,omitempty"`
+	UniqueIDs []uniqueIDFile `xml:"uniqueid"`
+}
+
+// uniqueIDFile is an ID of a title, the default one being used by Kodi to scrape it
+type uniqueIDFile struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr,omitempty"`
+	Value   string `xml:",chardata"`
+}
+
+// MarshalMovie returns the Kodi .nfo file of a movie
+func MarshalMovie(movie *models.Movie) ([]byte, error) {
+	return marshal(movieFile{
+		Title:         movie.Title,
+		OriginalTitle: movie.OriginalTitle,
+		Year:          yearOf(movie.ReleaseDate),
+		Premiered:     dateOf(movie.ReleaseDate),
+		Plot:          movie.Overview,
+		Genres:        movie.Genres,
+		Director:      movie.Director,
+		UniqueIDs:     uniqueIDsOf(movie.ExternalIDs),
+	})
+}
+
+// MarshalTVShow returns the Kodi .nfo file of a TV show
+func MarshalTVShow(show *models.TVShow) ([]byte, error) {
+	return marshal(tvshowFile{
+		Title:         show.Name,
+		OriginalTitle: show.OriginalName,
+		Year:          yearOf(show.FirstAirDate),
+		Premiered:     dateOf(show.FirstAirDate),
+		Plot:          show.Overview,
+		Genres:        show.Genres,
+		UniqueIDs:     uniqueIDsOf(show.ExternalIDs),
+	})
+}
+
+// MarshalEpisode returns the Kodi .nfo file of an episode
+func MarshalEpisode(episode *models.Episode) ([]byte, error) {
+	return marshal(episodeFile{
+		Title:     episode.Title,
+		ShowTitle: episode.TVShow.Name,
+		Season:    episode.Season,
+		Episode:   episode.Episode,
+		Aired:     dateOf(episode.AirDate),
+		Plot:      episode.Summary,
+		UniqueIDs: uniqueIDsOf(episode.ExternalIDs),
+	})
+}
+
+func marshal(file interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// uniqueIDsOf returns the known IDs, the TMDB one being the default
+func uniqueIDsOf(ids models.ExternalIDs) []uniqueIDFile {
+	var uniqueIDs []uniqueIDFile
+	for _, id := range []uniqueIDFile{
+		{Type: "tmdb", Value: ids.TMDBID},
+		{Type: "imdb", Value: ids.IMDBID},
+		{Type: "tvdb", Value: ids.TVDBID},
+		{Type: "anidb", Value: ids.AniDBID},
+	} {
+		if id.Value != "" {
+			uniqueIDs = append(uniqueIDs, id)
+		}
+	}
+	if len(uniqueIDs) > 0 {
+		uniqueIDs[0].Default = true
+	}
+	return uniqueIDs
+}
+
+func yearOf(date time.Time) int {
+	if date.IsZero() {
+		return 0
+	}
+	return date.Year()
+}
+
+func dateOf(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
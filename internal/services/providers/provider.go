@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"errors"
 	"goru/internal/models"
 	"strconv"
@@ -8,23 +9,36 @@ import (
 )
 
 type Provider interface {
-	GetMovie(title string, year int) (*models.Movie, error)
-	GetMovieByID(id string) (*models.Movie, error)
-	SearchMovies(title string, year int) ([]*models.Movie, error)
+	GetMovie(ctx context.Context, title string, year int) (*models.Movie, error)
+	GetMovieByID(ctx context.Context, id string) (*models.Movie, error)
+	SearchMovies(ctx context.Context, title string, year int) ([]*models.Movie, error)
 
-	GetTVShow(title string, year int) (*models.TVShow, error)
-	GetTVShowByID(id string) (*models.TVShow, error)
-	SearchTVShows(title string, year int) ([]*models.TVShow, error)
+	GetTVShow(ctx context.Context, title string, year int) (*models.TVShow, error)
+	GetTVShowByID(ctx context.Context, id string) (*models.TVShow, error)
+	SearchTVShows(ctx context.Context, title string, year int) ([]*models.TVShow, error)
 
-	GetEpisode(showID, season, episode int) (*models.Episode, error)
-	ListEpisodes(showID, season int) ([]*models.Episode, error)
+	GetEpisode(ctx context.Context, showID, season, episode int) (*models.Episode, error)
+	ListEpisodes(ctx context.Context, showID, season int) ([]*models.Episode, error)
 
-	// Provide video file with metadata information coming from the Internet
-	Provide(file *models.VideoFile) error
+	// Provide video file with metadata information coming from the Internet.
+	// The context allows to cancel the lookup.
+	Provide(ctx context.Context, file *models.VideoFile) error
 
 	Name() string
 }
 
+// Pinger is implemented by the providers able to check that their API is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Finder is implemented by the providers able to look up a title by its ID in another
+// database, such as IMDb
+type Finder interface {
+	FindMovie(ctx context.Context, ids models.ExternalIDs) (*models.Movie, error)
+	FindTVShow(ctx context.Context, ids models.ExternalIDs) (*models.TVShow, error)
+}
+
 // ExtractYear tries to extract a year from a filename
 func ExtractYear(filename string) int {
 	// Look for 4-digit years (1900-2099)
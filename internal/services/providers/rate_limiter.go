@@ -1,17 +1,25 @@
 package providers
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"goru/internal/services/metrics"
+)
 
 // RateLimiter implements a token bucket rate limiter for TMDB API
 type RateLimiter struct {
+	name     string
 	bucket   chan struct{}
 	ticker   *time.Ticker
 	stopChan chan struct{}
 }
 
-// NewRateLimiter creates a new rate limiter that allows up to 'rate' requests per second
-func NewRateLimiter(rate int) *RateLimiter {
+// NewRateLimiter creates a new rate limiter that allows up to 'rate' requests per second.
+// The name labels the wait time metric.
+func NewRateLimiter(name string, rate int) *RateLimiter {
 	rl := &RateLimiter{
+		name:     name,
 		bucket:   make(chan struct{}, rate),
 		ticker:   time.NewTicker(time.Second / time.Duration(rate)),
 		stopChan: make(chan struct{}),
@@ -28,9 +36,17 @@ func NewRateLimiter(rate int) *RateLimiter {
 	return rl
 }
 
-// Wait blocks until a request can be made
-func (rl *RateLimiter) Wait() {
-	<-rl.bucket
+// Wait blocks until a request can be made, or until the context is done
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+
+	select {
+	case <-rl.bucket:
+		metrics.RateLimiterWait.WithLabelValues(rl.name).Observe(time.Since(start).Seconds())
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // refill periodically adds tokens to the bucket
@@ -83,7 +83,12 @@ func tmdbMovieToModel(tmdbMovie TMDBMovie) (*models.Movie, error) {
 		ExternalIDs: models.ExternalIDs{
 			TMDBID: strconv.FormatInt(tmdbMovie.ID, 10),
 		},
+		Overview:     tmdbMovie.Overview,
+		Genres:       genreNames(tmdbMovie.GenreIDs),
+		PosterPath:   tmdbMovie.PosterPath,
+		BackdropPath: tmdbMovie.BackdropPath,
 	}
+	setGenre(movieModel)
 
 	// Extract year from release date
 	if tmdbMovie.ReleaseDate != "" {
@@ -105,8 +110,17 @@ func tmdbMovieDetailsToModel(tmdbMovie *tmdb.MovieDetails) (*models.Movie, error
 		OriginalTitle: tmdbMovie.OriginalTitle,
 		ExternalIDs: models.ExternalIDs{
 			TMDBID: strconv.FormatInt(tmdbMovie.ID, 10),
+			IMDBID: tmdbMovie.IMDbID,
 		},
+		Overview:     tmdbMovie.Overview,
+		PosterPath:   tmdbMovie.PosterPath,
+		BackdropPath: tmdbMovie.BackdropPath,
+		Runtime:      tmdbMovie.Runtime,
 	}
+	for _, genre := range tmdbMovie.Genres {
+		movieModel.Genres = append(movieModel.Genres, genre.Name)
+	}
+	setGenre(movieModel)
 
 	// Extract year from release date
 	if tmdbMovie.ReleaseDate != "" {
@@ -129,6 +143,13 @@ func tmdbShowToModel(tmdbShow TMDBTVShow) (*models.TVShow, error) {
 		ExternalIDs: models.ExternalIDs{
 			TMDBID: strconv.FormatInt(tmdbShow.ID, 10),
 		},
+		Overview:     tmdbShow.Overview,
+		Genres:       genreNames(tmdbShow.GenreIDs),
+		PosterPath:   tmdbShow.PosterPath,
+		BackdropPath: tmdbShow.BackdropPath,
+	}
+	if len(tvShowModel.Genres) > 0 {
+		tvShowModel.Genre = tvShowModel.Genres[0]
 	}
 
 	// Extract first air date
@@ -149,9 +170,20 @@ func tmdbShowDetailsToModel(tmdbShow *tmdb.TVDetails) (*models.TVShow, error) {
 		ID:           strconv.FormatInt(tmdbShow.ID, 10),
 		Name:         tmdbShow.Name,
 		OriginalName: tmdbShow.OriginalName,
+		Seasons:      tmdbShow.NumberOfSeasons,
+		Episodes:     tmdbShow.NumberOfEpisodes,
 		ExternalIDs: models.ExternalIDs{
 			TMDBID: strconv.FormatInt(tmdbShow.ID, 10),
 		},
+		Overview:     tmdbShow.Overview,
+		PosterPath:   tmdbShow.PosterPath,
+		BackdropPath: tmdbShow.BackdropPath,
+	}
+	for _, genre := range tmdbShow.Genres {
+		tvShowModel.Genres = append(tvShowModel.Genres, genre.Name)
+	}
+	if len(tvShowModel.Genres) > 0 {
+		tvShowModel.Genre = tvShowModel.Genres[0]
 	}
 
 	// Extract first air date
@@ -166,3 +198,61 @@ func tmdbShowDetailsToModel(tmdbShow *tmdb.TVDetails) (*models.TVShow, error) {
 
 	return tvShowModel, nil
 }
+
+// genres are the names of the TMDB genres of the movies and TV shows, the search
+// results only holding their IDs
+var genres = map[int64]string{
+	12:    "Adventure",
+	14:    "Fantasy",
+	16:    "Animation",
+	18:    "Drama",
+	27:    "Horror",
+	28:    "Action",
+	35:    "Comedy",
+	36:    "History",
+	37:    "Western",
+	53:    "Thriller",
+	80:    "Crime",
+	99:    "Documentary",
+	878:   "Science Fiction",
+	9648:  "Mystery",
+	10402: "Music",
+	10749: "Romance",
+	10751: "Family",
+	10752: "War",
+	10759: "Action & Adventure",
+	10762: "Kids",
+	10763: "News",
+	10764: "Reality",
+	10765: "Sci-Fi & Fantasy",
+	10766: "Soap",
+	10767: "Talk",
+	10768: "War & Politics",
+	10770: "TV Movie",
+}
+
+// genreNames returns the names of the known genres
+func genreNames(ids []int64) []string {
+	var names []string
+	for _, id := range ids {
+		if name, ok := genres[id]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// setGenre sets the main genre of a movie, used by the templates
+func setGenre(movie *models.Movie) {
+	if len(movie.Genres) > 0 {
+		movie.Genre = models.Genre(movie.Genres[0])
+	}
+}
+
+// yearOf returns the year of a date, or 0 when the date is unknown
+func yearOf(date time.Time) int {
+	if date.IsZero() {
+		return 0
+	}
+	return date.Year()
+}
@@ -1,11 +1,14 @@
 package tmdb
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"strconv"
 	"time"
 
 	"goru/internal/models"
+	"goru/internal/services/metrics"
 	"goru/internal/services/providers"
 	"goru/internal/utils"
 	"goru/pkg/log"
@@ -17,6 +20,10 @@ import (
 // TMDB allows up to 50 requests per second
 const TMDBRateLimit = 50
 
+// maxRuntimeCandidates is the number of search results whose runtime is compared with
+// the duration of a file, to tell apart the titles sharing a name
+const maxRuntimeCandidates = 3
+
 type tmdbProvider struct {
 	client      *tmdb.Client
 	apiKey      string
@@ -34,8 +41,14 @@ func New(apiKey string) (providers.Provider, error) {
 		return nil, fmt.Errorf("failed to initialize TMDB client: %w", err)
 	}
 
+	// Count the requests and observe their latency
+	client.SetClientConfig(http.Client{
+		Transport: metrics.InstrumentTransport("tmdb", nil),
+		Timeout:   30 * time.Second,
+	})
+
 	// Create rate limiter for 50 requests per second (TMDB API limit)
-	rateLimiter := providers.NewRateLimiter(TMDBRateLimit)
+	rateLimiter := providers.NewRateLimiter("tmdb", TMDBRateLimit)
 
 	return &tmdbProvider{
 		client:      client,
@@ -48,49 +61,154 @@ func (d *tmdbProvider) Name() string {
 	return "tmdb"
 }
 
-func (d *tmdbProvider) Provide(file *models.VideoFile) error {
+// Ping checks that the TMDB API is reachable and accepts the API key
+func (d *tmdbProvider) Ping(ctx context.Context) error {
+	if err := d.rateLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	if _, err := d.client.GetConfigurationAPI(); err != nil {
+		return fmt.Errorf("TMDB is unreachable: %w", err)
+	}
+
+	return nil
+}
+
+func (d *tmdbProvider) Provide(ctx context.Context, file *models.VideoFile) error {
 	// Clean the filename for searching
 	cleanName := utils.CleanFilename(file.Filename, file.MediaType)
 	year := providers.ExtractYear(file.Filename)
+	if file.Query != nil && file.Query.Title != "" {
+		cleanName = file.Query.Title
+	}
+	if file.Query != nil && file.Query.Year != 0 {
+		year = file.Query.Year
+	}
+
+	log.Debug("providing metadata", zap.String("file", file.Filename), zap.String("clean_name", cleanName), zap.Int("year", year), zap.Int("media_type", int(file.MediaType)))
 
-	log.Debug("providing metadata", zap.String("file", file.Filename), zap.String("clean_name", cleanName), zap.Int("year", year), zap.String("media_type", string(file.MediaType)))
+	var duration time.Duration
+	if file.MediaInfo != nil {
+		duration = file.MediaInfo.Duration
+	}
 
 	switch file.MediaType {
 	case models.MediaTypeMovie:
 		// Fetch movie metadata from TMDB
-		movie, err := d.SearchMovies(cleanName, year)
+		movie, err := d.matchMovie(ctx, cleanName, year, duration)
 		if err != nil {
 			return fmt.Errorf("failed to fetch movie metadata: %w", err)
 		}
 
 		file.Metadata = movie
+		file.Confidence = &models.Confidence{
+			Score: providers.MatchConfidence(cleanName, year, movie.Title, yearOf(movie.ReleaseDate)),
+		}
 	case models.MediaTypeTVShow:
 		season, episode := utils.ExtractSeasonEpisode(file.Filename)
+		if file.Query != nil && file.Query.Season != 0 && file.Query.Episode != 0 {
+			season, episode = file.Query.Season, file.Query.Episode
+		}
 		if season == 0 || episode == 0 {
 			return fmt.Errorf("could not extract season/episode from filename: %s", file.Filename)
 		}
 
-		show, err := d.GetTVShow(cleanName, year)
+		episodeInfo, err := d.matchEpisode(ctx, cleanName, year, season, episode, duration)
+		if err != nil {
+			return err
+		}
+		show := &episodeInfo.TVShow
+
+		file.Metadata = episodeInfo
+		file.Confidence = &models.Confidence{
+			Score: providers.MatchConfidence(cleanName, year, show.Name, yearOf(show.FirstAirDate)),
+		}
+	}
+
+	return nil
+}
+
+// matchMovie returns the best movie found. When the duration of the file is known, the
+// runtimes of the first results are fetched and weigh in the choice.
+func (d *tmdbProvider) matchMovie(ctx context.Context, title string, year int, duration time.Duration) (*models.Movie, error) {
+	movies, err := d.SearchMovies(ctx, title, year)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search movies: %w", err)
+	}
+	if len(movies) == 0 {
+		return nil, providers.ErrNoMoviesFound
+	}
+	if duration == 0 {
+		return movies[0], nil
+	}
+
+	var best *models.Movie
+	bestScore := -1.0
+	for _, movie := range movies[:min(len(movies), maxRuntimeCandidates)] {
+		details, err := d.GetMovieByID(ctx, movie.ID)
 		if err != nil {
-			return fmt.Errorf("failed to get TV show: %w", err)
+			log.Debug("failed to get the runtime of a candidate", zap.String("id", movie.ID), zap.Error(err))
+			continue
 		}
 
-		// Get episode information
-		episodeInfo, err := d.getEpisodeInfo(show, season, episode)
+		score := providers.MatchConfidence(title, year, details.Title, yearOf(details.ReleaseDate)) *
+			providers.RuntimeConfidence(duration, details.Runtime)
+		if score > bestScore {
+			best, bestScore = details, score
+		}
+	}
+
+	if best == nil {
+		return movies[0], nil
+	}
+	return best, nil
+}
+
+// matchEpisode returns the episode of the best TV show found. When the duration of the
+// file is known, the episodes of the first results are compared with it.
+func (d *tmdbProvider) matchEpisode(ctx context.Context, name string, year, season, episode int, duration time.Duration) (*models.Episode, error) {
+	shows, err := d.SearchTVShows(ctx, name, year)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TV show: %w", err)
+	}
+	if len(shows) == 0 {
+		return nil, fmt.Errorf("failed to get TV show: %w", providers.ErrNoTVShowsFound)
+	}
+
+	candidates := shows[:1]
+	if duration > 0 {
+		candidates = shows[:min(len(shows), maxRuntimeCandidates)]
+	}
+
+	var best *models.Episode
+	var firstErr error
+	bestScore := -1.0
+	for _, show := range candidates {
+		episodeInfo, err := d.getEpisodeInfo(ctx, show, season, episode)
 		if err != nil {
-			return fmt.Errorf("failed to get episode info: %w", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
 		}
 		episodeInfo.TVShow = *show
 
-		file.Metadata = episodeInfo
+		score := providers.MatchConfidence(name, year, show.Name, yearOf(show.FirstAirDate)) *
+			providers.RuntimeConfidence(duration, episodeInfo.Runtime)
+		if score > bestScore {
+			best, bestScore = episodeInfo, score
+		}
 	}
 
-	return nil
+	if best == nil {
+		return nil, fmt.Errorf("failed to get episode info: %w", firstErr)
+	}
+	return best, nil
 }
 
 // SearchMovie searches for movies by title with improved matching
-func (d *tmdbProvider) GetMovie(title string, year int) (*models.Movie, error) {
-	movies, err := d.SearchMovies(title, year)
+func (d *tmdbProvider) GetMovie(ctx context.Context, title string, year int) (*models.Movie, error) {
+	movies, err := d.SearchMovies(ctx, title, year)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search movies: %w", err)
 	}
@@ -100,8 +218,10 @@ func (d *tmdbProvider) GetMovie(title string, year int) (*models.Movie, error) {
 	return movies[0], nil
 }
 
-func (d *tmdbProvider) GetMovieByID(id string) (*models.Movie, error) {
-	d.rateLimiter.Wait()
+func (d *tmdbProvider) GetMovieByID(ctx context.Context, id string) (*models.Movie, error) {
+	if err := d.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 
 	parsedID, err := strconv.Atoi(id)
 	if err != nil {
@@ -121,9 +241,40 @@ func (d *tmdbProvider) GetMovieByID(id string) (*models.Movie, error) {
 	return movie, nil
 }
 
+// FindMovie looks up a movie by its IMDb ID
+func (d *tmdbProvider) FindMovie(ctx context.Context, ids models.ExternalIDs) (*models.Movie, error) {
+	if ids.IMDBID == "" {
+		return nil, providers.ErrNoMoviesFound
+	}
+
+	resp, err := d.find(ctx, ids.IMDBID, "imdb_id")
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.MovieResults) == 0 {
+		return nil, providers.ErrNoMoviesFound
+	}
+
+	result := resp.MovieResults[0]
+	movie, err := tmdbMovieToModel(TMDBMovie{
+		ID:            result.ID,
+		Title:         result.Title,
+		OriginalTitle: result.OriginalTitle,
+		ReleaseDate:   result.ReleaseDate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert TMDB movie to model: %w", err)
+	}
+	movie.ExternalIDs.IMDBID = ids.IMDBID
+
+	return movie, nil
+}
+
 // SearchMovie searches for movies by title with improved matching
-func (d *tmdbProvider) SearchMovies(title string, year int) ([]*models.Movie, error) {
-	d.rateLimiter.Wait()
+func (d *tmdbProvider) SearchMovies(ctx context.Context, title string, year int) ([]*models.Movie, error) {
+	if err := d.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 
 	options := map[string]string{}
 	if year > 0 {
@@ -166,8 +317,8 @@ func (d *tmdbProvider) SearchMovies(title string, year int) ([]*models.Movie, er
 
 }
 
-func (d *tmdbProvider) GetTVShow(name string, year int) (*models.TVShow, error) {
-	tvShows, err := d.SearchTVShows(name, year)
+func (d *tmdbProvider) GetTVShow(ctx context.Context, name string, year int) (*models.TVShow, error) {
+	tvShows, err := d.SearchTVShows(ctx, name, year)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search TV shows: %w", err)
 	}
@@ -177,8 +328,10 @@ func (d *tmdbProvider) GetTVShow(name string, year int) (*models.TVShow, error)
 	return tvShows[0], nil
 }
 
-func (d *tmdbProvider) GetTVShowByID(id string) (*models.TVShow, error) {
-	d.rateLimiter.Wait()
+func (d *tmdbProvider) GetTVShowByID(ctx context.Context, id string) (*models.TVShow, error) {
+	if err := d.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 
 	parsedID, err := strconv.Atoi(id)
 	if err != nil {
@@ -198,9 +351,50 @@ func (d *tmdbProvider) GetTVShowByID(id string) (*models.TVShow, error) {
 	return tvshow, nil
 }
 
+// FindTVShow looks up a TV show by its TVDB or IMDb ID
+func (d *tmdbProvider) FindTVShow(ctx context.Context, ids models.ExternalIDs) (*models.TVShow, error) {
+	sources := []struct{ id, source string }{
+		{ids.TVDBID, "tvdb_id"},
+		{ids.IMDBID, "imdb_id"},
+	}
+
+	for _, s := range sources {
+		if s.id == "" {
+			continue
+		}
+
+		resp, err := d.find(ctx, s.id, s.source)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.TvResults) == 0 {
+			continue
+		}
+
+		result := resp.TvResults[0]
+		show, err := tmdbShowToModel(TMDBTVShow{
+			ID:           result.ID,
+			Name:         result.Name,
+			OriginalName: result.OriginalName,
+			FirstAirDate: result.FirstAirDate,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert TMDB TV show to model: %w", err)
+		}
+		show.ExternalIDs.TVDBID = ids.TVDBID
+		show.ExternalIDs.IMDBID = ids.IMDBID
+
+		return show, nil
+	}
+
+	return nil, providers.ErrNoTVShowsFound
+}
+
 // SearchTVShow searches for TV shows by name with improved matching
-func (d *tmdbProvider) SearchTVShows(name string, year int) ([]*models.TVShow, error) {
-	d.rateLimiter.Wait()
+func (d *tmdbProvider) SearchTVShows(ctx context.Context, name string, year int) ([]*models.TVShow, error) {
+	if err := d.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 	log.Debug("searching TV show", zap.String("name", name), zap.Int("year", year))
 
 	options := map[string]string{}
@@ -231,8 +425,10 @@ func (d *tmdbProvider) SearchTVShows(name string, year int) ([]*models.TVShow, e
 }
 
 // GetEpisode gets episode information for a specific TV show
-func (d *tmdbProvider) GetEpisode(tvShowID, seasonNumber, episodeNumber int) (*models.Episode, error) {
-	d.rateLimiter.Wait()
+func (d *tmdbProvider) GetEpisode(ctx context.Context, tvShowID, seasonNumber, episodeNumber int) (*models.Episode, error) {
+	if err := d.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 
 	episode, err := d.client.GetTVEpisodeDetails(tvShowID, seasonNumber, episodeNumber, nil)
 	if err != nil {
@@ -254,6 +450,7 @@ func (d *tmdbProvider) GetEpisode(tvShowID, seasonNumber, episodeNumber int) (*m
 		AirDate:   airDate,
 		Summary:   episode.Overview,
 		Thumbnail: episode.StillPath,
+		Runtime:   episode.Runtime,
 		ExternalIDs: models.ExternalIDs{
 			TMDBID: strconv.FormatInt(episode.ID, 10),
 		},
@@ -261,8 +458,10 @@ func (d *tmdbProvider) GetEpisode(tvShowID, seasonNumber, episodeNumber int) (*m
 }
 
 // GetEpisode gets episode information for a specific TV show
-func (d *tmdbProvider) ListEpisodes(tvShowID, seasonNumber int) ([]*models.Episode, error) {
-	d.rateLimiter.Wait()
+func (d *tmdbProvider) ListEpisodes(ctx context.Context, tvShowID, seasonNumber int) ([]*models.Episode, error) {
+	if err := d.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 
 	resp, err := d.client.GetTVSeasonDetails(tvShowID, seasonNumber, nil)
 	if err != nil {
@@ -285,6 +484,7 @@ func (d *tmdbProvider) ListEpisodes(tvShowID, seasonNumber int) ([]*models.Episo
 			AirDate:   airDate,
 			Summary:   episode.Overview,
 			Thumbnail: episode.StillPath,
+			Runtime:   episode.Runtime,
 			ExternalIDs: models.ExternalIDs{
 				TMDBID: strconv.FormatInt(episode.ID, 10),
 			},
@@ -296,15 +496,29 @@ func (d *tmdbProvider) ListEpisodes(tvShowID, seasonNumber int) ([]*models.Episo
 
 // -------------------- Helper Functions -----------------------------
 
+// find looks up the titles having an ID in another database
+func (d *tmdbProvider) find(ctx context.Context, id, source string) (*tmdb.FindByID, error) {
+	if err := d.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.GetFindByID(id, map[string]string{"external_source": source})
+	if err != nil {
+		return nil, fmt.Errorf("TMDB find failed: %w", err)
+	}
+
+	return resp, nil
+}
+
 // getEpisodeInfo helper method to get episode information
-func (d *tmdbProvider) getEpisodeInfo(show *models.TVShow, season, episode int) (*models.Episode, error) {
+func (d *tmdbProvider) getEpisodeInfo(ctx context.Context, show *models.TVShow, season, episode int) (*models.Episode, error) {
 	// Try to get episode from database service first
 	showID, err := strconv.Atoi(show.ExternalIDs.TMDBID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid show ID: %w", err)
 	}
 
-	episodeInfo, err := d.GetEpisode(showID, season, episode)
+	episodeInfo, err := d.GetEpisode(ctx, showID, season, episode)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get episode from database: %w", err)
 	}
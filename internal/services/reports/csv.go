@@ -0,0 +1,51 @@
+package reports
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// The statuses of the rows of the CSV report
+const (
+	StatusMissing   = "missing"
+	StatusExtra     = "extra"
+	StatusDuplicate = "duplicate"
+	StatusUnknown   = "unknown"
+)
+
+// WriteMissingCSV writes one row per missing episode, and per extra, duplicated or
+// unknown file
+func WriteMissingCSV(w io.Writer, report *MissingReport) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"show", "year", "tmdb_id", "status", "season", "episode", "title", "air_date", "path"})
+
+	for _, show := range report.Shows {
+		year := ""
+		if !show.Show.FirstAirDate.IsZero() {
+			year = strconv.Itoa(show.Show.FirstAirDate.Year())
+		}
+		row := func(status string, season, episode int, title, airDate, path string) {
+			writer.Write([]string{show.Show.Name, year, show.Show.ID, status, strconv.Itoa(season), strconv.Itoa(episode), title, airDate, path})
+		}
+
+		for _, missing := range show.Missing {
+			row(StatusMissing, missing.Season, missing.Episode, missing.Title, missing.AirDate, "")
+		}
+		for _, extra := range show.Extra {
+			row(StatusExtra, extra.Season, extra.Episode, "", "", extra.Path)
+		}
+		for _, duplicate := range show.Duplicates {
+			for _, path := range duplicate.Paths {
+				row(StatusDuplicate, duplicate.Season, duplicate.Episode, "", "", path)
+			}
+		}
+	}
+
+	for _, unknown := range report.Unknown {
+		writer.Write([]string{"", "", "", StatusUnknown, "", "", unknown.Reason, "", unknown.Path})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
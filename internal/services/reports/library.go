@@ -0,0 +1,52 @@
+package reports
+
+import (
+	"fmt"
+	"strings"
+
+	"goru/internal/models"
+	"goru/internal/services/files"
+)
+
+// SelectDirectories returns the directory of the given name, or every directory that
+// may hold shows when the name is empty
+func SelectDirectories(directories []models.Directory, name string) ([]models.Directory, error) {
+	var selected []models.Directory
+	for _, directory := range directories {
+		switch {
+		case name != "" && strings.EqualFold(directory.Name, name):
+			return []models.Directory{directory}, nil
+		case name == "" && directory.Type != "movie":
+			selected = append(selected, directory)
+		}
+	}
+
+	if name != "" {
+		return nil, fmt.Errorf("unknown directory %q", name)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no TV show directory is configured")
+	}
+	return selected, nil
+}
+
+// Scan lists the video files of the directories, and the paths that could not be read
+func Scan(fileService *files.FileService, directories []models.Directory) ([]*models.VideoFile, []files.ScanError, error) {
+	var (
+		videoFiles []*models.VideoFile
+		scanErrors []files.ScanError
+	)
+	for _, directory := range directories {
+		if directory.Type == "" {
+			directory.Type = "auto"
+		}
+
+		found, unreadable, err := fileService.ScanDirectory(directory)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan %s: %w", directory.Path, err)
+		}
+		videoFiles = append(videoFiles, found...)
+		scanErrors = append(scanErrors, unreadable...)
+	}
+	return videoFiles, scanErrors, nil
+}
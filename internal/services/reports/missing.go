@@ -0,0 +1,334 @@
+// Package reports compares the library on disk with the providers.
+package reports
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/files"
+	"goru/internal/services/providers"
+	"goru/internal/utils"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// MissingReport compares the episodes on disk with the seasons listed by the provider
+type MissingReport struct {
+	Shows []ShowReport `json:"shows"`
+
+	// Unknown are the files whose show or episode cannot be told
+	Unknown []UnknownFile `json:"unknown"`
+}
+
+// ShowReport is the completeness of a show
+type ShowReport struct {
+	Show models.TVShow `json:"show"`
+
+	// Episodes is the number of episodes on disk, Expected the number of episodes of
+	// the regular seasons listed by the provider
+	Episodes int `json:"episodes"`
+	Expected int `json:"expected"`
+
+	Missing []MissingEpisode `json:"missing"`
+
+	// Extra are the episodes on disk that the provider does not list, such as the
+	// specials numbered after another database
+	Extra []EpisodeFile `json:"extra"`
+
+	// Duplicates are the episodes found in several files
+	Duplicates []Duplicate `json:"duplicates"`
+}
+
+// MissingEpisode is an episode listed by the provider and not found on disk
+type MissingEpisode struct {
+	Season  int    `json:"season"`
+	Episode int    `json:"episode"`
+	Title   string `json:"title,omitempty"`
+
+	// AirDate is formatted as 2006-01-02, empty when unknown
+	AirDate string `json:"air_date,omitempty"`
+}
+
+// EpisodeFile is an episode found on disk
+type EpisodeFile struct {
+	Season  int    `json:"season"`
+	Episode int    `json:"episode"`
+	Path    string `json:"path"`
+}
+
+// Duplicate is an episode found in several files
+type Duplicate struct {
+	Season  int      `json:"season"`
+	Episode int      `json:"episode"`
+	Paths   []string `json:"paths"`
+}
+
+// UnknownFile is a file left out of the report
+type UnknownFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// MissingOptions filters the report
+type MissingOptions struct {
+	// Aired only expects the episodes aired before now
+	Aired bool
+}
+
+// AddScanErrors lists the paths that could not be read by the scan with the unknown
+// files
+func (r *MissingReport) AddScanErrors(scanErrors []files.ScanError) {
+	for _, scanError := range scanErrors {
+		r.Unknown = append(r.Unknown, UnknownFile{Path: scanError.Path, Reason: scanError.Err.Error()})
+	}
+	sort.Slice(r.Unknown, func(i, j int) bool {
+		return r.Unknown[i].Path < r.Unknown[j].Path
+	})
+}
+
+// Complete returns true when nothing is missing, extra or duplicated
+func (r *ShowReport) Complete() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Duplicates) == 0
+}
+
+var (
+	// specialPattern tells the specials, numbered S00Exx, from the files numbered
+	// with an episode only
+	specialPattern = regexp.MustCompile(`(?i)s0{1,2}[.\s_-]*e\d`)
+
+	yearPattern   = regexp.MustCompile(`\(((?:19|20)\d{2})\)`)
+	seasonFolder  = regexp.MustCompile(`(?i)^(?:(?:season|saison|staffel|series)[ ._-]*\d+|specials)$`)
+	episodeNumber = regexp.MustCompile(`(?i)^(s\d{1,2}e\d{1,3}|\d{1,2}x\d{1,3})`)
+)
+
+// showFiles are the episode files of a show, before its lookup
+type showFiles struct {
+	name  string
+	year  int
+	files []EpisodeFile
+}
+
+// Missing looks up the shows of the episode files, then compares their episodes with
+// the seasons listed by the provider. The movies and the extras are skipped.
+func Missing(ctx context.Context, provider providers.Provider, files []*models.VideoFile, options MissingOptions) (*MissingReport, error) {
+	report := &MissingReport{Shows: []ShowReport{}, Unknown: []UnknownFile{}}
+
+	groups := make(map[string]*showFiles)
+	for _, file := range files {
+		if file.MediaType == models.MediaTypeMovie || file.Extra != "" {
+			continue
+		}
+
+		name, year := showName(file.Path)
+		season, episode := utils.ExtractSeasonEpisode(file.Filename)
+		switch {
+		case name == "":
+			report.Unknown = append(report.Unknown, UnknownFile{Path: file.Path, Reason: "no show name"})
+			continue
+		case episode == 0 || (season == 0 && !specialPattern.MatchString(file.Filename)):
+			report.Unknown = append(report.Unknown, UnknownFile{Path: file.Path, Reason: "no season and episode number"})
+			continue
+		}
+
+		key := strings.ToLower(name) + "|" + strconv.Itoa(year)
+		group, ok := groups[key]
+		if !ok {
+			group = &showFiles{name: name, year: year}
+			groups[key] = group
+		}
+		group.files = append(group.files, EpisodeFile{Season: season, Episode: episode, Path: file.Path})
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// The names of a show found in several spellings are merged by its ID
+	shows := make(map[string]*models.TVShow)
+	filesByShow := make(map[string][]EpisodeFile)
+	var ids []string
+	for _, key := range keys {
+		group := groups[key]
+
+		show, err := lookupShow(ctx, provider, group.name, group.year)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			for _, file := range group.files {
+				report.Unknown = append(report.Unknown, UnknownFile{Path: file.Path, Reason: fmt.Sprintf("show %q not found: %s", group.name, err)})
+			}
+			continue
+		}
+
+		if _, ok := shows[show.ID]; !ok {
+			shows[show.ID] = show
+			ids = append(ids, show.ID)
+		}
+		filesByShow[show.ID] = append(filesByShow[show.ID], group.files...)
+	}
+
+	for _, id := range ids {
+		showReport, err := compare(ctx, provider, shows[id], filesByShow[id], options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the episodes of %s: %w", shows[id].Name, err)
+		}
+		report.Shows = append(report.Shows, *showReport)
+	}
+
+	sort.SliceStable(report.Shows, func(i, j int) bool {
+		return strings.ToLower(report.Shows[i].Show.Name) < strings.ToLower(report.Shows[j].Show.Name)
+	})
+	sort.Slice(report.Unknown, func(i, j int) bool {
+		return report.Unknown[i].Path < report.Unknown[j].Path
+	})
+	return report, nil
+}
+
+// lookupShow finds a show, with its number of seasons
+func lookupShow(ctx context.Context, provider providers.Provider, name string, year int) (*models.TVShow, error) {
+	show, err := provider.GetTVShow(ctx, name, year)
+	if err != nil {
+		return nil, err
+	}
+
+	// The search results do not count the seasons
+	details, err := provider.GetTVShowByID(ctx, show.ID)
+	if err != nil {
+		log.Debug("failed to get the details of the show", zap.String("show", show.Name), zap.Error(err))
+		return show, nil
+	}
+	return details, nil
+}
+
+// compare compares the episodes on disk with the seasons of the show. The specials are
+// only listed when some are on disk, they are never missing.
+func compare(ctx context.Context, provider providers.Provider, show *models.TVShow, files []EpisodeFile, options MissingOptions) (*ShowReport, error) {
+	report := &ShowReport{
+		Show:       *show,
+		Missing:    []MissingEpisode{},
+		Extra:      []EpisodeFile{},
+		Duplicates: []Duplicate{},
+	}
+
+	onDisk := make(map[[2]int][]string)
+	specials := false
+	for _, file := range files {
+		key := [2]int{file.Season, file.Episode}
+		onDisk[key] = append(onDisk[key], file.Path)
+		specials = specials || file.Season == 0
+	}
+	report.Episodes = len(onDisk)
+
+	seasons := make([]int, 0, show.Seasons+1)
+	if specials {
+		seasons = append(seasons, 0)
+	}
+	for season := 1; season <= show.Seasons; season++ {
+		seasons = append(seasons, season)
+	}
+
+	showID, err := strconv.Atoi(show.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid show ID %q", show.ID)
+	}
+
+	now := time.Now()
+	listed := make(map[[2]int]bool)
+	for _, season := range seasons {
+		episodes, err := provider.ListEpisodes(ctx, showID, season)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, episode := range episodes {
+			key := [2]int{season, episode.Episode}
+			listed[key] = true
+			if season == 0 {
+				continue
+			}
+
+			aired := !episode.AirDate.IsZero() && episode.AirDate.Before(now)
+			if options.Aired && !aired {
+				continue
+			}
+			report.Expected++
+
+			if _, ok := onDisk[key]; !ok {
+				missing := MissingEpisode{Season: season, Episode: episode.Episode, Title: episode.Title}
+				if !episode.AirDate.IsZero() {
+					missing.AirDate = episode.AirDate.Format("2006-01-02")
+				}
+				report.Missing = append(report.Missing, missing)
+			}
+		}
+	}
+
+	for key, paths := range onDisk {
+		sort.Strings(paths)
+		if !listed[key] {
+			for _, path := range paths {
+				report.Extra = append(report.Extra, EpisodeFile{Season: key[0], Episode: key[1], Path: path})
+			}
+		}
+		if len(paths) > 1 {
+			report.Duplicates = append(report.Duplicates, Duplicate{Season: key[0], Episode: key[1], Paths: paths})
+		}
+	}
+
+	sort.Slice(report.Missing, func(i, j int) bool {
+		return episodeLess(report.Missing[i].Season, report.Missing[i].Episode, report.Missing[j].Season, report.Missing[j].Episode)
+	})
+	sort.Slice(report.Extra, func(i, j int) bool {
+		a, b := report.Extra[i], report.Extra[j]
+		if a.Season == b.Season && a.Episode == b.Episode {
+			return a.Path < b.Path
+		}
+		return episodeLess(a.Season, a.Episode, b.Season, b.Episode)
+	})
+	sort.Slice(report.Duplicates, func(i, j int) bool {
+		return episodeLess(report.Duplicates[i].Season, report.Duplicates[i].Episode, report.Duplicates[j].Season, report.Duplicates[j].Episode)
+	})
+
+	return report, nil
+}
+
+// showName returns the name and the year of the show of an episode file. The files
+// named after their episode only, such as Season 1/S01E02.mkv, are named after the
+// folder of the show.
+func showName(path string) (string, int) {
+	filename := filepath.Base(path)
+	if episodeNumber.MatchString(filename) {
+		dir := filepath.Dir(path)
+		if seasonFolder.MatchString(filepath.Base(dir)) {
+			dir = filepath.Dir(dir)
+		}
+		filename = filepath.Base(dir) + " S00E00.mkv"
+	}
+
+	name := utils.CleanFilename(filename, models.MediaTypeTVShow)
+
+	var year int
+	if m := yearPattern.FindStringSubmatchIndex(name); m != nil {
+		year, _ = strconv.Atoi(name[m[2]:m[3]])
+		name = strings.TrimSpace(name[:m[0]] + name[m[1]:])
+	}
+	return strings.Join(strings.Fields(name), " "), year
+}
+
+func episodeLess(season1, episode1, season2, episode2 int) bool {
+	if season1 != season2 {
+		return season1 < season2
+	}
+	return episode1 < episode2
+}
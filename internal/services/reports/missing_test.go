@@ -0,0 +1,132 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/providers"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+// fakeProvider lists the two seasons of Breaking Bad, the second one not aired yet
+type fakeProvider struct {
+	providers.Provider
+}
+
+func (f *fakeProvider) GetTVShow(ctx context.Context, name string, year int) (*models.TVShow, error) {
+	if name != "breaking bad" {
+		return nil, providers.ErrNoTVShowsFound
+	}
+	return &models.TVShow{ID: "1396", Name: "Breaking Bad"}, nil
+}
+
+func (f *fakeProvider) GetTVShowByID(ctx context.Context, id string) (*models.TVShow, error) {
+	return &models.TVShow{ID: id, Name: "Breaking Bad", Seasons: 2, FirstAirDate: time.Date(2008, 1, 20, 0, 0, 0, 0, time.UTC)}, nil
+}
+
+func (f *fakeProvider) ListEpisodes(ctx context.Context, showID, season int) ([]*models.Episode, error) {
+	aired := time.Date(2008, 1, 20, 0, 0, 0, 0, time.UTC)
+	switch season {
+	case 0:
+		return []*models.Episode{{Season: 0, Episode: 1, Title: "Good Cop Bad Cop", AirDate: aired}}, nil
+	case 1:
+		return []*models.Episode{
+			{Season: 1, Episode: 1, Title: "Pilot", AirDate: aired},
+			{Season: 1, Episode: 2, Title: "Cat's in the Bag...", AirDate: aired},
+			{Season: 1, Episode: 3, Title: "...And the Bag's in the River", AirDate: aired},
+		}, nil
+	case 2:
+		return []*models.Episode{{Season: 2, Episode: 1, Title: "Seven Thirty-Seven", AirDate: time.Now().AddDate(1, 0, 0)}}, nil
+	}
+	return nil, nil
+}
+
+func episodes(paths ...string) []*models.VideoFile {
+	var files []*models.VideoFile
+	for _, path := range paths {
+		file := models.NewVideoFile(path, models.DefaultConflictStrategy)
+		file.MediaType = models.MediaTypeTVShow
+		files = append(files, file)
+	}
+	return files
+}
+
+func TestMissing(t *testing.T) {
+	files := episodes(
+		"/tv/Breaking Bad (2008)/Season 01/Breaking Bad (2008) - S01E01 - Pilot.mkv",
+		"/tv/Breaking.Bad.S01E01.720p.HDTV.x264.mkv",
+		"/tv/Breaking Bad (2008)/Season 01/S01E02.mkv",
+		"/tv/Breaking Bad (2008)/Specials/Breaking Bad - S00E05.mkv",
+		"/tv/Breaking Bad (2008)/Breaking Bad - Behind the Scenes.mkv",
+		"/tv/The Wire/The.Wire.S01E01.mkv",
+	)
+
+	report, err := Missing(context.Background(), &fakeProvider{}, files, MissingOptions{Aired: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Shows) != 1 {
+		t.Fatalf("got shows %+v", report.Shows)
+	}
+	show := report.Shows[0]
+	if show.Episodes != 3 || show.Expected != 3 {
+		t.Errorf("got %d episodes on disk, %d expected", show.Episodes, show.Expected)
+	}
+	if len(show.Missing) != 1 || show.Missing[0].Season != 1 || show.Missing[0].Episode != 3 || show.Missing[0].AirDate != "2008-01-20" {
+		t.Errorf("got missing %+v", show.Missing)
+	}
+	if len(show.Extra) != 1 || show.Extra[0].Season != 0 || show.Extra[0].Episode != 5 {
+		t.Errorf("got extra %+v", show.Extra)
+	}
+	if len(show.Duplicates) != 1 || show.Duplicates[0].Episode != 1 || len(show.Duplicates[0].Paths) != 2 {
+		t.Errorf("got duplicates %+v", show.Duplicates)
+	}
+
+	if len(report.Unknown) != 2 || !strings.Contains(report.Unknown[0].Reason, "no season") || !strings.Contains(report.Unknown[1].Reason, "not found") {
+		t.Errorf("got unknown %+v", report.Unknown)
+	}
+
+	// The episode to come is expected when the report is not limited to the aired ones
+	report, err = Missing(context.Background(), &fakeProvider{}, files, MissingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := report.Shows[0]; got.Expected != 4 || len(got.Missing) != 2 {
+		t.Errorf("got %d expected, missing %+v", got.Expected, got.Missing)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMissingCSV(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 8 || lines[1] != "Breaking Bad,2008,1396,missing,1,3,...And the Bag's in the River,2008-01-20," {
+		t.Errorf("got CSV %q", buf.String())
+	}
+}
+
+func TestShowName(t *testing.T) {
+	tests := []struct {
+		path string
+		name string
+		year int
+	}{
+		{"/tv/Breaking Bad (2008)/Season 01/Breaking Bad (2008) - S01E01 - Pilot.mkv", "breaking bad", 2008},
+		{"/tv/Mr. Robot/Season 2/S02E01.mkv", "mr robot", 0},
+		{"/tv/the.office.us.s03e10.mkv", "the office us", 0},
+	}
+	for _, tt := range tests {
+		if name, year := showName(tt.path); name != tt.name || year != tt.year {
+			t.Errorf("showName(%q) = %q, %d, want %q, %d", tt.path, name, year, tt.name, tt.year)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package sidecars
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"goru/internal/models"
+	"goru/internal/services/metrics"
+	"goru/internal/services/plans"
+	"goru/internal/services/providers/nfo"
+	"goru/internal/services/states"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultImageURL is the base URL of the TMDB images, in their original size
+	DefaultImageURL = "https://image.tmdb.org/t/p/original"
+
+	// DefaultTimeout bounds each artwork download
+	DefaultTimeout = 30 * time.Second
+
+	// maxImageSize is the largest artwork downloaded
+	maxImageSize = 50 << 20
+)
+
+// naming is the suffixes appended to the name of a video file, without its
+// extension, for its artwork
+type naming struct {
+	poster string
+	fanart string
+	thumb  string
+}
+
+// namings are the conventions of the media servers. They all read tvshow.nfo,
+// poster.jpg and fanart.jpg in the folder of a show.
+var namings = map[string]naming{
+	"kodi":     {poster: "-poster", fanart: "-fanart", thumb: "-thumb"},
+	"emby":     {poster: "-poster", fanart: "-fanart", thumb: "-thumb"},
+	"jellyfin": {poster: "-poster", fanart: "-fanart", thumb: "-thumb"},
+	"plex":     {poster: "", fanart: "-fanart", thumb: ""},
+}
+
+// seasonFolder matches the folders of the seasons, inside the folder of a show
+var seasonFolder = regexp.MustCompile(`(?i)^(season|series|saison|staffel|temporada)\s*\d+$|^specials$`)
+
+// Writer writes the .nfo and artwork files of the renamed files
+type Writer struct {
+	nfo      bool
+	artwork  bool
+	naming   naming
+	imageURL string
+	client   *http.Client
+}
+
+// New creates the writer of the sidecar files, nil when they are disabled. The
+// naming defaults to the one of the format preset, then to the one of Kodi.
+func New(config models.Sidecars, preset string) *Writer {
+	if !config.NFO && !config.Artwork {
+		return nil
+	}
+
+	n, ok := namings[config.Naming]
+	if !ok {
+		if n, ok = namings[preset]; !ok {
+			n = namings["kodi"]
+		}
+	}
+
+	imageURL := config.ImageURL
+	if imageURL == "" {
+		imageURL = DefaultImageURL
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Writer{
+		nfo:      config.NFO,
+		artwork:  config.Artwork,
+		naming:   n,
+		imageURL: strings.TrimSuffix(imageURL, "/"),
+		client: &http.Client{
+			Transport: metrics.InstrumentTransport("artwork", nil),
+			Timeout:   timeout,
+		},
+	}
+}
+
+// Write writes the sidecar files of the applied renames and tracks them in the state
+// entries of the renames. A failure is logged and does not stop the others.
+func (w *Writer) Write(ctx context.Context, plan *plans.Plan, result *plans.ApplyResult, stateService *states.StateService) {
+	if w == nil || result == nil {
+		return
+	}
+
+	changes := make(map[string]*plans.Change, len(plan.Changes))
+	for i := range plan.Changes {
+		changes[plan.Changes[i].ID] = &plan.Changes[i]
+	}
+
+	for i := range result.Changes {
+		changeResult := &result.Changes[i]
+		change, ok := changes[changeResult.ChangeID]
+		if !ok || changeResult.Status != plans.ChangeStatusApplied {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		written, err := w.writeFiles(ctx, changeResult.After, change.After)
+		if err != nil {
+			log.Error("failed to write the sidecar files", zap.String("file", changeResult.After), zap.Error(err))
+		}
+		if len(written) == 0 {
+			continue
+		}
+
+		changeResult.Sidecars = written
+		if changeResult.StateID != "" {
+			if err := stateService.AddSidecars(changeResult.StateID, written); err != nil {
+				log.Error("failed to add the sidecar files to state", zap.String("id", changeResult.StateID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// writeFiles writes the sidecar files of a renamed file and returns their paths
+func (w *Writer) writeFiles(ctx context.Context, path string, file models.VideoFile) ([]string, error) {
+	video := strings.TrimSuffix(path, filepath.Ext(path))
+
+	var written []string
+	var errs []error
+	write := func(path string, data func() ([]byte, error)) {
+		ok, err := w.create(path, data)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ok {
+			written = append(written, path)
+		}
+	}
+
+	switch metadata := decodeMetadata(file).(type) {
+	case *models.Movie:
+		if w.nfo {
+			write(video+".nfo", func() ([]byte, error) { return nfo.MarshalMovie(metadata) })
+		}
+		if w.artwork {
+			w.writeImage(ctx, write, video+w.naming.poster, metadata.PosterPath)
+			w.writeImage(ctx, write, video+w.naming.fanart, metadata.BackdropPath)
+		}
+
+	case *models.Episode:
+		if w.nfo {
+			write(video+".nfo", func() ([]byte, error) { return nfo.MarshalEpisode(metadata) })
+		}
+		if w.artwork {
+			w.writeImage(ctx, write, video+w.naming.thumb, metadata.Thumbnail)
+		}
+
+		// The files of the show are only written in a folder named after it
+		show, ok := showFolder(filepath.Dir(path), metadata.TVShow.Name)
+		if !ok {
+			break
+		}
+		if w.nfo {
+			write(filepath.Join(show, "tvshow.nfo"), func() ([]byte, error) { return nfo.MarshalTVShow(&metadata.TVShow) })
+		}
+		if w.artwork {
+			w.writeImage(ctx, write, filepath.Join(show, "poster"), metadata.TVShow.PosterPath)
+			w.writeImage(ctx, write, filepath.Join(show, "fanart"), metadata.TVShow.BackdropPath)
+		}
+
+	default:
+		return nil, fmt.Errorf("no metadata")
+	}
+
+	return written, errors.Join(errs...)
+}
+
+// writeImage downloads an artwork, named after the base path and the extension of
+// the image
+func (w *Writer) writeImage(ctx context.Context, write func(string, func() ([]byte, error)), base, image string) {
+	if image == "" {
+		return
+	}
+
+	ext := filepath.Ext(image)
+	if ext == "" {
+		ext = ".jpg"
+	}
+	write(base+ext, func() ([]byte, error) { return w.download(ctx, image) })
+}
+
+// create writes a file unless it already exists. It returns true when the file has
+// been written.
+func (w *Writer) create(path string, data func() ([]byte, error)) (bool, error) {
+	if _, err := os.Lstat(path); err == nil {
+		log.Debug("keeping the existing sidecar file", zap.String("path", path))
+		return false, nil
+	}
+
+	content, err := data()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(path)
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return false, err
+	}
+
+	log.Debug("wrote sidecar file", zap.String("path", path))
+	return true, nil
+}
+
+// download fetches an artwork, given by its path on the image server or its URL
+func (w *Writer) download(ctx context.Context, image string) ([]byte, error) {
+	url := image
+	if !strings.HasPrefix(image, "http://") && !strings.HasPrefix(image, "https://") {
+		url = w.imageURL + "/" + strings.TrimPrefix(image, "/")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: unexpected status %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("downloading %s: larger than %d bytes", url, maxImageSize)
+	}
+
+	return data, nil
+}
+
+// Remove removes the sidecar files tracked by a state entry. The files already
+// removed are ignored.
+func Remove(entry states.StateEntry) error {
+	var errs []error
+	for _, path := range entry.Sidecars {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// decodeMetadata returns the metadata of a file as a movie or an episode. The plans
+// loaded from the store hold it decoded as a map.
+func decodeMetadata(file models.VideoFile) interface{} {
+	switch metadata := file.Metadata.(type) {
+	case *models.Movie, *models.Episode:
+		return metadata
+	case nil:
+		return nil
+	}
+
+	data, err := json.Marshal(file.Metadata)
+	if err != nil {
+		return nil
+	}
+
+	switch file.MediaType {
+	case models.MediaTypeMovie:
+		var movie models.Movie
+		if err := json.Unmarshal(data, &movie); err == nil {
+			return &movie
+		}
+	case models.MediaTypeTVShow, models.MediaTypeAnime:
+		var episode models.Episode
+		if err := json.Unmarshal(data, &episode); err == nil {
+			return &episode
+		}
+	}
+
+	return nil
+}
+
+// showFolder returns the folder of a show, the parent of a season folder. The folder
+// has to be named after the show, so that a library of episodes in a single folder
+// does not get the files of one of them.
+func showFolder(dir, show string) (string, bool) {
+	if seasonFolder.MatchString(filepath.Base(dir)) {
+		dir = filepath.Dir(dir)
+	}
+
+	name, folder := normalize(show), normalize(filepath.Base(dir))
+	if name == "" || !strings.HasPrefix(folder, name) {
+		return "", false
+	}
+	return dir, true
+}
+
+// normalize lowers a name and keeps only its letters and digits
+func normalize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
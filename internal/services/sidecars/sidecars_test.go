@@ -0,0 +1,232 @@
+package sidecars
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/internal/services/states"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+// setup returns an image server, the library folder and the state service
+func setup(t *testing.T) (*httptest.Server, string, *states.StateService) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "missing.jpg") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("image " + r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("HOME", t.TempDir())
+	stateService, err := states.NewStateService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, t.TempDir(), stateService
+}
+
+// apply records the rename of a file in the state and returns the plan and its result
+func apply(t *testing.T, stateService *states.StateService, path string, file models.VideoFile) (*plans.Plan, *plans.ApplyResult) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := stateService.AddRenameOperation("/old/file.mkv", path, "file.mkv", filepath.Base(path), file.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file.Path = path
+	plan := &plans.Plan{Changes: []plans.Change{{ID: "c1", After: file}}}
+	result := &plans.ApplyResult{Changes: []plans.ChangeResult{
+		{ChangeID: "c1", After: path, Status: plans.ChangeStatusApplied, StateID: entry.ID},
+	}}
+	return plan, result
+}
+
+func names(root string, paths []string) []string {
+	var names []string
+	for _, path := range paths {
+		rel, _ := filepath.Rel(root, path)
+		names = append(names, filepath.ToSlash(rel))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestWrite_Movie(t *testing.T) {
+	server, root, stateService := setup(t)
+
+	movie := models.VideoFile{
+		MediaType: models.MediaTypeMovie,
+		Metadata: &models.Movie{
+			Title:        "The Matrix",
+			ReleaseDate:  time.Date(1999, 3, 31, 0, 0, 0, 0, time.UTC),
+			Overview:     "A hacker learns the truth.",
+			Genres:       []string{"Action", "Science Fiction"},
+			PosterPath:   "/poster.jpg",
+			BackdropPath: "/missing.jpg",
+			ExternalIDs:  models.ExternalIDs{TMDBID: "603", IMDBID: "tt0133093"},
+		},
+	}
+	path := filepath.Join(root, "The Matrix (1999)", "The Matrix (1999).mkv")
+	plan, result := apply(t, stateService, path, movie)
+
+	w := New(models.Sidecars{NFO: true, Artwork: true, ImageURL: server.URL}, "kodi")
+	w.Write(context.Background(), plan, result, stateService)
+
+	want := []string{"The Matrix (1999)/The Matrix (1999)-poster.jpg", "The Matrix (1999)/The Matrix (1999).nfo"}
+	got := names(root, result.Changes[0].Sidecars)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got sidecars %v, want %v", got, want)
+	}
+
+	nfo, err := os.ReadFile(filepath.Join(root, "The Matrix (1999)", "The Matrix (1999).nfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"<title>The Matrix</title>",
+		"<year>1999</year>",
+		"<premiered>1999-03-31</premiered>",
+		"<plot>A hacker learns the truth.</plot>",
+		"<genre>Science Fiction</genre>",
+		`<uniqueid type="tmdb" default="true">603</uniqueid>`,
+		`<uniqueid type="imdb">tt0133093</uniqueid>`,
+	} {
+		if !strings.Contains(string(nfo), s) {
+			t.Errorf("nfo file misses %s:\n%s", s, nfo)
+		}
+	}
+
+	entry, err := stateService.GetEntryByID(result.Changes[0].StateID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Sidecars) != 2 {
+		t.Errorf("got tracked sidecars %v, want 2 files", entry.Sidecars)
+	}
+
+	if err := Remove(*entry); err != nil {
+		t.Fatal(err)
+	}
+	for _, sidecar := range entry.Sidecars {
+		if _, err := os.Stat(sidecar); !os.IsNotExist(err) {
+			t.Errorf("sidecar %s not removed", sidecar)
+		}
+	}
+}
+
+func TestWrite_KeepsExistingFiles(t *testing.T) {
+	server, root, stateService := setup(t)
+
+	movie := models.VideoFile{MediaType: models.MediaTypeMovie, Metadata: &models.Movie{Title: "The Matrix"}}
+	path := filepath.Join(root, "matrix.mkv")
+	plan, result := apply(t, stateService, path, movie)
+	if err := os.WriteFile(filepath.Join(root, "matrix.nfo"), []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	New(models.Sidecars{NFO: true, ImageURL: server.URL}, "").Write(context.Background(), plan, result, stateService)
+
+	if len(result.Changes[0].Sidecars) != 0 {
+		t.Errorf("got sidecars %v, want the existing file kept", result.Changes[0].Sidecars)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "matrix.nfo")); string(data) != "mine" {
+		t.Errorf("existing file overwritten: %s", data)
+	}
+}
+
+func TestWrite_Episode(t *testing.T) {
+	server, root, stateService := setup(t)
+
+	episode := &models.Episode{
+		Title:     "Pilot",
+		Season:    1,
+		Episode:   1,
+		Thumbnail: "/still.png",
+		TVShow: models.TVShow{
+			Name:       "Breaking Bad",
+			PosterPath: "/show.jpg",
+		},
+	}
+
+	// The plans loaded from the store hold the metadata as a map
+	var metadata map[string]interface{}
+	data, _ := json.Marshal(episode)
+	json.Unmarshal(data, &metadata)
+
+	file := models.VideoFile{MediaType: models.MediaTypeTVShow, Metadata: metadata}
+	path := filepath.Join(root, "Breaking Bad (2008)", "Season 1", "Breaking Bad - S01E01 - Pilot.mkv")
+	plan, result := apply(t, stateService, path, file)
+
+	New(models.Sidecars{NFO: true, Artwork: true, ImageURL: server.URL}, "plex").Write(context.Background(), plan, result, stateService)
+
+	want := []string{
+		"Breaking Bad (2008)/Season 1/Breaking Bad - S01E01 - Pilot.nfo",
+		"Breaking Bad (2008)/Season 1/Breaking Bad - S01E01 - Pilot.png",
+		"Breaking Bad (2008)/poster.jpg",
+		"Breaking Bad (2008)/tvshow.nfo",
+	}
+	got := names(root, result.Changes[0].Sidecars)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got sidecars %v, want %v", got, want)
+	}
+
+	thumb, _ := os.ReadFile(filepath.Join(root, "Breaking Bad (2008)", "Season 1", "Breaking Bad - S01E01 - Pilot.png"))
+	if string(thumb) != "image /still.png" {
+		t.Errorf("got thumb %q", thumb)
+	}
+}
+
+func TestWrite_EpisodeOutsideShowFolder(t *testing.T) {
+	_, root, stateService := setup(t)
+
+	file := models.VideoFile{
+		MediaType: models.MediaTypeTVShow,
+		Metadata:  &models.Episode{Title: "Pilot", Season: 1, Episode: 1, TVShow: models.TVShow{Name: "Breaking Bad"}},
+	}
+	path := filepath.Join(root, "TV", "Breaking Bad - S01E01 - Pilot.mkv")
+	plan, result := apply(t, stateService, path, file)
+
+	New(models.Sidecars{NFO: true}, "kodi").Write(context.Background(), plan, result, stateService)
+
+	got := names(root, result.Changes[0].Sidecars)
+	if len(got) != 1 || got[0] != "TV/Breaking Bad - S01E01 - Pilot.nfo" {
+		t.Errorf("got sidecars %v, want only the episode .nfo file", got)
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	if New(models.Sidecars{}, "plex") != nil {
+		t.Error("expected no writer when the sidecars are disabled")
+	}
+
+	// A nil writer does nothing
+	var w *Writer
+	w.Write(context.Background(), &plans.Plan{}, &plans.ApplyResult{}, nil)
+}
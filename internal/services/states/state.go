@@ -2,6 +2,7 @@ package states
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,6 +14,11 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	ErrEntryNotFound = errors.New("entry not found")
+	ErrNoActiveEntry = errors.New("no active entries found")
+)
+
 // State represents the complete state file
 type State struct {
 	Version string       `json:"version"`
@@ -29,6 +35,9 @@ type StateEntry struct {
 	NewName      string      `json:"new_name"`
 	MediaInfo    interface{} `json:"media_info,omitempty"`
 	Reverted     bool        `json:"reverted"`
+
+	// Sidecars are the files written next to the renamed file, removed on revert
+	Sidecars []string `json:"sidecars,omitempty"`
 }
 
 // StateService handles state file operations
@@ -56,6 +65,17 @@ func NewStateService() (*StateService, error) {
 	}, nil
 }
 
+// CheckWritable checks that the state file can be written, without modifying it
+func (s *StateService) CheckWritable() error {
+	file, err := os.CreateTemp(filepath.Dir(s.statePath), ".state-*.tmp")
+	if err != nil {
+		return fmt.Errorf("state directory is not writable: %w", err)
+	}
+	file.Close()
+
+	return os.Remove(file.Name())
+}
+
 // LoadState loads the state from file
 func (s *StateService) LoadState() (*State, error) {
 	if _, err := os.Stat(s.statePath); os.IsNotExist(err) {
@@ -94,10 +114,10 @@ func (s *StateService) SaveState(state *State) error {
 }
 
 // AddRenameOperation adds a rename operation to the state
-func (s *StateService) AddRenameOperation(originalPath, newPath, originalName, newName string, mediaInfo interface{}) error {
+func (s *StateService) AddRenameOperation(originalPath, newPath, originalName, newName string, mediaInfo interface{}) (*StateEntry, error) {
 	state, err := s.LoadState()
 	if err != nil {
-		return fmt.Errorf("failed to load state: %w", err)
+		return nil, fmt.Errorf("failed to load state: %w", err)
 	}
 
 	entry := StateEntry{
@@ -114,7 +134,7 @@ func (s *StateService) AddRenameOperation(originalPath, newPath, originalName, n
 	state.Entries = append(state.Entries, entry)
 
 	if err := s.SaveState(state); err != nil {
-		return fmt.Errorf("failed to save state: %w", err)
+		return nil, fmt.Errorf("failed to save state: %w", err)
 	}
 
 	log.Debug("Added rename operation to state",
@@ -122,7 +142,28 @@ func (s *StateService) AddRenameOperation(originalPath, newPath, originalName, n
 		zap.String("original", originalName),
 		zap.String("new", newName))
 
-	return nil
+	return &entry, nil
+}
+
+// AddSidecars tracks the files written next to a renamed file
+func (s *StateService) AddSidecars(id string, paths []string) error {
+	state, err := s.LoadState()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+
+	for i := range state.Entries {
+		if state.Entries[i].ID == id {
+			state.Entries[i].Sidecars = append(state.Entries[i].Sidecars, paths...)
+
+			if err := s.SaveState(state); err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("entry with ID %s not found", id)
 }
 
 // GetActiveEntries returns all non-reverted entries
@@ -155,7 +196,7 @@ func (s *StateService) GetEntryByID(id string) (*StateEntry, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("entry with ID %s not found", id)
+	return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, id)
 }
 
 // GetLastActiveEntry returns the most recent non-reverted entry
@@ -166,7 +207,7 @@ func (s *StateService) GetLastActiveEntry() (*StateEntry, error) {
 	}
 
 	if len(active) == 0 {
-		return nil, fmt.Errorf("no active entries found")
+		return nil, ErrNoActiveEntry
 	}
 
 	// Find the most recent entry
@@ -0,0 +1,230 @@
+// Package addic7ed finds the subtitles of the episodes on Addic7ed, which has no API:
+// the subtitles are scraped from the page of each episode.
+package addic7ed
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/metrics"
+	"goru/internal/services/providers"
+	"goru/internal/services/subtitles"
+)
+
+const (
+	// DefaultURL is the address of Addic7ed
+	DefaultURL = "https://www.addic7ed.com"
+
+	// RateLimit is the number of requests per second, Addic7ed banning the scrapers
+	// sending more
+	RateLimit = 1
+
+	// maxPageSize is the largest page read
+	maxPageSize = 5 << 20
+
+	// maxSubtitleSize is the largest subtitle downloaded
+	maxSubtitleSize = 10 << 20
+)
+
+// languages are the codes of the languages named on the pages
+var languages = map[string]string{
+	"English":                 "en",
+	"French":                  "fr",
+	"German":                  "de",
+	"Spanish":                 "es",
+	"Spanish (Spain)":         "es",
+	"Spanish (Latin America)": "es-mx",
+	"Italian":                 "it",
+	"Portuguese":              "pt",
+	"Portuguese (Brazilian)":  "pt-br",
+	"Dutch":                   "nl",
+	"Polish":                  "pl",
+	"Swedish":                 "sv",
+	"Danish":                  "da",
+	"Norwegian":               "no",
+	"Finnish":                 "fi",
+	"Greek":                   "el",
+	"Turkish":                 "tr",
+	"Russian":                 "ru",
+	"Czech":                   "cs",
+	"Hungarian":               "hu",
+	"Romanian":                "ro",
+	"Croatian":                "hr",
+	"Serbian (Latin)":         "sr",
+	"Hebrew":                  "he",
+	"Arabic":                  "ar",
+	"Chinese (Simplified)":    "zh",
+	"Japanese":                "ja",
+	"Korean":                  "ko",
+}
+
+var (
+	versionPattern   = regexp.MustCompile(`class="NewsTitle"[^>]*>(?:<img[^>]*>)?\s*Version ([^,<]+)`)
+	languagePattern  = regexp.MustCompile(`^[^>]*>\s*([^<]+)`)
+	updatedPattern   = regexp.MustCompile(`href="(/updated/[^"]+)"`)
+	originalPattern  = regexp.MustCompile(`href="(/original/[^"]+)"`)
+	downloadsPattern = regexp.MustCompile(`(\d+) Downloads`)
+)
+
+func init() {
+	subtitles.Register("addic7ed", func(config models.Provider) (subtitles.SubtitleProvider, error) {
+		return New(config), nil
+	})
+}
+
+// Addic7ed is the subtitle provider of Addic7ed, for the episodes only
+type Addic7ed struct {
+	url         string
+	client      *http.Client
+	rateLimiter *providers.RateLimiter
+}
+
+// New creates the Addic7ed provider. The URL defaults to the one of Addic7ed.
+func New(config models.Provider) *Addic7ed {
+	baseURL := config.URL
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+
+	return &Addic7ed{
+		url: strings.TrimSuffix(baseURL, "/"),
+		client: &http.Client{
+			Transport: metrics.InstrumentTransport("addic7ed", nil),
+			Timeout:   30 * time.Second,
+		},
+		rateLimiter: providers.NewRateLimiter("addic7ed", RateLimit),
+	}
+}
+
+func (a *Addic7ed) Name() string {
+	return "addic7ed"
+}
+
+// Search scrapes the completed subtitles of an episode, in all the languages of the
+// page. The movies are skipped.
+func (a *Addic7ed) Search(ctx context.Context, query subtitles.Query) ([]models.Subtitle, error) {
+	if query.MediaType == models.MediaTypeMovie || query.Title == "" || query.Season == 0 || query.Episode == 0 {
+		return nil, nil
+	}
+
+	show := url.PathEscape(strings.ReplaceAll(query.Title, " ", "_"))
+	page, err := a.get(ctx, fmt.Sprintf("/serie/%s/%d/%d/0", show, query.Season, query.Episode), maxPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search subtitles: %w", err)
+	}
+
+	var found []models.Subtitle
+	for _, sub := range parsePage(string(page)) {
+		if slices.Contains(query.Languages, sub.Language) {
+			found = append(found, sub)
+		}
+	}
+	return found, nil
+}
+
+// parsePage reads the subtitles of the page of an episode. Each version, a release,
+// lists its subtitles by language.
+func parsePage(page string) []models.Subtitle {
+	var found []models.Subtitle
+
+	// A version runs until the next one
+	matches := versionPattern.FindAllStringSubmatchIndex(page, -1)
+	for i, match := range matches {
+		end := len(page)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		version := page[match[1]:end]
+		release := strings.TrimSpace(html.UnescapeString(page[match[2]:match[3]]))
+
+		for _, row := range strings.Split(version, `class="language"`)[1:] {
+			match := languagePattern.FindStringSubmatch(row)
+			if match == nil {
+				continue
+			}
+			language, ok := languages[strings.TrimSpace(html.UnescapeString(match[1]))]
+			if !ok || !strings.Contains(row, "<b>Completed</b>") {
+				continue
+			}
+
+			// The updated subtitle fixes the original one
+			link := updatedPattern.FindStringSubmatch(row)
+			if link == nil {
+				if link = originalPattern.FindStringSubmatch(row); link == nil {
+					continue
+				}
+			}
+
+			sub := models.Subtitle{
+				Provider:        "addic7ed",
+				ID:              html.UnescapeString(link[1]),
+				Language:        language,
+				Release:         release,
+				Format:          "srt",
+				HearingImpaired: strings.Contains(row, `title="Hearing Impaired"`),
+			}
+			if match := downloadsPattern.FindStringSubmatch(row); match != nil {
+				sub.Downloads, _ = strconv.Atoi(match[1])
+			}
+			found = append(found, sub)
+		}
+	}
+
+	return found
+}
+
+// Download downloads a subtitle, given by the path of its link
+func (a *Addic7ed) Download(ctx context.Context, subtitle models.Subtitle) ([]byte, error) {
+	if !strings.HasPrefix(subtitle.ID, "/original/") && !strings.HasPrefix(subtitle.ID, "/updated/") {
+		return nil, fmt.Errorf("invalid subtitle link %q", subtitle.ID)
+	}
+	return a.get(ctx, subtitle.ID, maxSubtitleSize)
+}
+
+// get fetches a page or a subtitle of the site. Addic7ed redirects the downloads over
+// the daily limit to a page.
+func (a *Addic7ed) get(ctx context.Context, path string, limit int64) ([]byte, error) {
+	if err := a.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The downloads are refused without a referer of the site
+	req.Header.Set("Referer", a.url+"/")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64)")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if strings.Contains(resp.Request.URL.Path, "downloadexceeded") {
+		return nil, fmt.Errorf("the daily download limit of Addic7ed is exceeded")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %s", path, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s: larger than %d bytes", path, limit)
+	}
+	return data, nil
+}
@@ -0,0 +1,75 @@
+package addic7ed
+
+import (
+	"context"
+	"testing"
+
+	"goru/internal/models"
+	"goru/internal/services/subtitles"
+	"goru/internal/services/subtitles/subtitlestest"
+)
+
+func TestSearch(t *testing.T) {
+	server := subtitlestest.Replay(t, map[string]string{
+		"GET /serie/Breaking_Bad/1/2/0": "breaking_bad_1x02.html",
+	})
+	a := New(models.Provider{URL: server.URL})
+
+	found, err := a.Search(context.Background(), subtitles.Query{
+		MediaType: models.MediaTypeTVShow,
+		Title:     "Breaking Bad",
+		Season:    1,
+		Episode:   2,
+		Languages: []string{"en", "fr", "it"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The Italian subtitle is not completed
+	want := []models.Subtitle{
+		{Provider: "addic7ed", ID: "/updated/1/61342/0", Language: "en", Release: "DIMENSION", Format: "srt", HearingImpaired: true, Downloads: 21540},
+		{Provider: "addic7ed", ID: "/original/61342/1", Language: "fr", Release: "DIMENSION", Format: "srt", Downloads: 3255},
+		{Provider: "addic7ed", ID: "/original/98211/0", Language: "en", Release: "720p.WEB-DL.AAC2.0.H.264-ViSUM", Format: "srt", Downloads: 842},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("got %+v", found)
+	}
+	for i := range want {
+		if found[i] != want[i] {
+			t.Errorf("got %+v, want %+v", found[i], want[i])
+		}
+	}
+}
+
+func TestSearch_SkipsMovies(t *testing.T) {
+	server := subtitlestest.Replay(t, nil)
+	a := New(models.Provider{URL: server.URL})
+
+	found, err := a.Search(context.Background(), subtitles.Query{MediaType: models.MediaTypeMovie, Title: "The Matrix", Languages: []string{"en"}})
+	if err != nil || found != nil || len(server.Requests()) != 0 {
+		t.Errorf("got %v, %v after %d requests", found, err, len(server.Requests()))
+	}
+}
+
+func TestDownload(t *testing.T) {
+	server := subtitlestest.Replay(t, map[string]string{
+		"GET /updated/1/61342/0": "61342.srt",
+	})
+	a := New(models.Provider{URL: server.URL})
+
+	data, err := a.Download(context.Background(), models.Subtitle{ID: "/updated/1/61342/0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "1\r\n00:00:05,130 --> 00:00:07,420\r\nWhat are you doing?\r\n" {
+		t.Errorf("got %q", data)
+	}
+	if referer := server.Requests()[0].Header.Get("Referer"); referer != server.URL+"/" {
+		t.Errorf("got referer %q", referer)
+	}
+
+	if _, err := a.Download(context.Background(), models.Subtitle{ID: "https://example.com/sub.srt"}); err == nil {
+		t.Error("expected an error for a link outside of the site")
+	}
+}
@@ -0,0 +1,270 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// assStyle is the part of a style of ASS kept in the cues
+type assStyle struct {
+	bold, italic, underline bool
+	alignment               int
+}
+
+var (
+	assTime = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})\.(\d{1,3})$`)
+
+	// assOverride matches a block of override tags, such as {\i1\c&H00FFFF&}
+	assOverride = regexp.MustCompile(`\{([^}]*)\}`)
+	assTag      = regexp.MustCompile(`\\(\d?c|[a-z]+)([^\\]*)`)
+)
+
+// ssaAlignments are the numpad alignments of the legacy SSA ones
+var ssaAlignments = map[int]int{1: 1, 2: 2, 3: 3, 5: 7, 6: 8, 7: 9, 9: 4, 10: 5, 11: 6}
+
+func parseASS(text string) (*Subtitle, error) {
+	var (
+		sub     = &Subtitle{}
+		section string
+		legacy  bool
+		format  []string
+		styles  = make(map[string]assStyle)
+	)
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, format = strings.ToLower(line), nil
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case key == "ScriptType":
+			legacy = !strings.Contains(value, "+")
+
+		case key == "Format":
+			format = strings.Split(value, ",")
+			for i := range format {
+				format[i] = strings.ToLower(strings.TrimSpace(format[i]))
+			}
+
+		case key == "Style" && strings.Contains(section, "styles"):
+			fields := assFields(format, value)
+			style := assStyle{
+				bold:      fields["bold"] != "" && fields["bold"] != "0",
+				italic:    fields["italic"] != "" && fields["italic"] != "0",
+				underline: fields["underline"] != "" && fields["underline"] != "0",
+			}
+			style.alignment, _ = strconv.Atoi(fields["alignment"])
+			if legacy {
+				style.alignment = ssaAlignments[style.alignment]
+			}
+			styles[strings.TrimPrefix(fields["name"], "*")] = style
+
+		case key == "Dialogue" && section == "[events]":
+			if format == nil {
+				return nil, fmt.Errorf("dialogue before the format of the events")
+			}
+			fields := assFields(format, value)
+
+			start := assTime.FindStringSubmatch(fields["start"])
+			end := assTime.FindStringSubmatch(fields["end"])
+			if start == nil || end == nil {
+				return nil, fmt.Errorf("invalid ASS timing %q", line)
+			}
+
+			cue, ok := assCue(fields["text"], styles[strings.TrimPrefix(fields["style"], "*")])
+			if !ok {
+				continue
+			}
+			cue.Start = clock(start[1], start[2], start[3], start[4]+"0")
+			cue.End = clock(end[1], end[2], end[3], end[4]+"0")
+			sub.Cues = append(sub.Cues, cue)
+		}
+	}
+
+	return sub, nil
+}
+
+// assFields returns the fields of a line by name. The text is the last field, it may
+// hold commas.
+func assFields(format []string, value string) map[string]string {
+	values := strings.SplitN(value, ",", len(format))
+	fields := make(map[string]string, len(format))
+	for i, name := range format {
+		if i < len(values) {
+			fields[name] = strings.TrimSpace(values[i])
+		}
+	}
+	if i := len(format) - 1; i >= 0 && format[i] == "text" && i < len(values) {
+		fields["text"] = values[i]
+	}
+	return fields
+}
+
+// assCue converts the text of a dialogue, its override tags and the ones of its style
+// to the tags of SRT. The drawings are not cues.
+func assCue(text string, style assStyle) (Cue, bool) {
+	cue := Cue{Position: style.alignment}
+
+	var (
+		b    strings.Builder
+		open []htmlTag
+	)
+	set := func(tag htmlTag, on bool) {
+		for i := range open {
+			if open[i].name != tag.name {
+				continue
+			}
+			if on && open[i].open == tag.open {
+				return
+			}
+			// The tags opened after it are closed first, then opened again
+			for j := len(open) - 1; j >= i; j-- {
+				fmt.Fprintf(&b, "</%s>", open[j].name)
+			}
+			reopen := append([]htmlTag(nil), open[i+1:]...)
+			open = open[:i]
+			for _, t := range reopen {
+				b.WriteString(t.open)
+				open = append(open, t)
+			}
+			break
+		}
+		if on {
+			b.WriteString(tag.open)
+			open = append(open, tag)
+		}
+	}
+	reset := func() {
+		set(htmlTag{"b", "<b>"}, style.bold)
+		set(htmlTag{"i", "<i>"}, style.italic)
+		set(htmlTag{"u", "<u>"}, style.underline)
+	}
+	reset()
+
+	last := 0
+	for _, m := range assOverride.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(assText(text[last:m[0]]))
+		last = m[1]
+
+		for _, tag := range assTag.FindAllStringSubmatch(text[m[2]:m[3]], -1) {
+			name, arg := tag[1], strings.TrimSpace(tag[2])
+			n, err := strconv.Atoi(arg)
+
+			switch name {
+			case "b", "i", "u":
+				if err == nil {
+					set(htmlTag{name, "<" + name + ">"}, n != 0)
+				}
+			case "c", "1c":
+				// &HBBGGRR& is the primary color, none resets it to the one of the style
+				color := strings.Trim(arg, "&Hh")
+				if len(color) == 6 {
+					set(htmlTag{"font", `<font color="#` + strings.ToLower(color[4:6]+color[2:4]+color[0:2]) + `">`}, true)
+				} else {
+					set(htmlTag{name: "font"}, false)
+				}
+			case "an":
+				cue.Position = n
+			case "a":
+				// The legacy alignments of SSA
+				cue.Position = ssaAlignments[n]
+			case "p":
+				if n > 0 {
+					return Cue{}, false
+				}
+			case "r":
+				for len(open) > 0 {
+					set(open[len(open)-1], false)
+				}
+				reset()
+			}
+		}
+	}
+	b.WriteString(assText(text[last:]))
+
+	for j := len(open) - 1; j >= 0; j-- {
+		fmt.Fprintf(&b, "</%s>", open[j].name)
+	}
+
+	cue.Text = strings.TrimSpace(emptyTags.ReplaceAllString(b.String(), ""))
+	if cue.Position == 2 {
+		cue.Position = 0
+	}
+	return cue, cue.Text != ""
+}
+
+// htmlTag is a tag of SRT kept open
+type htmlTag struct {
+	name string
+	open string
+}
+
+// emptyTags matches the tags closed right after they are opened
+var emptyTags = regexp.MustCompile(`<(?:i|b|u|font)(?: [^>]*)?></(?:i|b|u|font)>`)
+
+// assText converts the line breaks and the hard spaces of ASS
+func assText(text string) string {
+	return strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(text)
+}
+
+func writeASS(b *strings.Builder, sub *Subtitle) {
+	b.WriteString(`[Script Info]
+ScriptType: v4.00+
+PlayResX: 384
+PlayResY: 288
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,20,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,1,1,2,10,10,10,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`)
+
+	for _, cue := range sub.Cues {
+		fmt.Fprintf(b, "Dialogue: 0,%s,%s,Default,,0,0,0,,", assTimestamp(cue.Start), assTimestamp(cue.End))
+		if cue.Position != 0 && cue.Position != 2 {
+			fmt.Fprintf(b, "{\\an%d}", cue.Position)
+		}
+
+		text := strings.ReplaceAll(cue.Text, "\n", `\N`)
+		last := 0
+		for _, m := range styleTag.FindAllStringSubmatchIndex(text, -1) {
+			b.WriteString(text[last:m[0]])
+			last = m[1]
+
+			closing := m[3] > m[2]
+			switch name := strings.ToLower(text[m[4]:m[5]]); {
+			case name != "font" && closing:
+				fmt.Fprintf(b, "{\\%s0}", name)
+			case name != "font":
+				fmt.Fprintf(b, "{\\%s1}", name)
+			case closing:
+				b.WriteString(`{\c}`)
+			case m[6] >= 0:
+				color := strings.ToUpper(text[m[6]:m[7]])
+				fmt.Fprintf(b, `{\c&H%s%s%s&}`, color[4:6], color[2:4], color[0:2])
+			}
+		}
+		b.WriteString(text[last:])
+		b.WriteString("\n")
+	}
+}
+
+func assTimestamp(d time.Duration) string {
+	h, m, s, ms := splitTime(d)
+	return fmt.Sprintf("%d:%02d:%02d.%02d", h, m, s, ms/10)
+}
@@ -0,0 +1,235 @@
+// Package convert normalizes the subtitles to UTF-8 and converts them between SRT,
+// WebVTT and ASS, keeping the italics, the bold, the underline, the colors and the
+// position of the cues. The MicroDVD subtitles are read only.
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The formats, named after their extension
+const (
+	SRT      = "srt"
+	VTT      = "vtt"
+	ASS      = "ass"
+	SSA      = "ssa"
+	MicroDVD = "sub"
+)
+
+// DefaultFPS is the frame rate of the MicroDVD subtitles that do not give theirs
+const DefaultFPS = 23.976
+
+// ErrUnsupported is returned for the formats that cannot be read or written
+var ErrUnsupported = errors.New("unsupported subtitle format")
+
+// Cue is a text displayed between two times. Its text is styled with the tags of
+// SRT, <i>, <b>, <u> and <font color="#rrggbb">, its lines separated by \n.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+
+	// Position is the alignment of the cue on a numpad as in ASS, 7 being the top
+	// left corner. 0 is the default, bottom center.
+	Position int
+}
+
+// Subtitle is a parsed subtitle, its cues sorted by start time
+type Subtitle struct {
+	Cues []Cue
+}
+
+// Options are the changes made by Convert
+type Options struct {
+	// Format is the format written. The format read is kept when empty, MicroDVD
+	// being written as SRT.
+	Format string
+
+	// Encoding is the encoding read, detected when empty
+	Encoding string
+
+	// Language is the ISO 639-1 code of the subtitle, telling its legacy encoding
+	Language string
+
+	// FPS is the frame rate of the MicroDVD subtitles not giving theirs
+	FPS float64
+
+	// FromFPS and ToFPS resample the timings made for a video at a frame rate to
+	// the same video at another one, such as 25 to 23.976 for a PAL release
+	FromFPS float64
+	ToFPS   float64
+
+	// Offset shifts the timings, after the resampling
+	Offset time.Duration
+}
+
+// Convert decodes a subtitle of a format, detected when empty, applies the options
+// and writes it in UTF-8
+func Convert(data []byte, format string, options Options) ([]byte, error) {
+	text, _, err := ToUTF8(data, options.Encoding, options.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = Detect(text)
+	}
+	sub, err := Parse(text, format, options.FPS)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.Resample(options.FromFPS, options.ToFPS)
+	sub.Shift(options.Offset)
+
+	return sub.Format(Target(format, options.Format))
+}
+
+// Target returns the format a subtitle is written in: the one wanted, or the one
+// read when it can be written
+func Target(from, to string) string {
+	switch {
+	case to != "":
+		return strings.ToLower(to)
+	case from == SSA:
+		return ASS
+	case Writable(from):
+		return from
+	}
+	return SRT
+}
+
+// Writable returns true for the formats that can be written
+func Writable(format string) bool {
+	switch strings.ToLower(format) {
+	case SRT, VTT, ASS:
+		return true
+	}
+	return false
+}
+
+var microDVDLine = regexp.MustCompile(`^\{(\d+)\}\{(\d*)\}(.*)$`)
+
+// Detect returns the format of a subtitle from its content, empty when unknown
+func Detect(text string) string {
+	text = strings.TrimLeft(text, "\ufeff \t\r\n")
+	switch {
+	case strings.HasPrefix(text, "WEBVTT"):
+		return VTT
+	case strings.HasPrefix(text, "[Script Info]"):
+		return ASS
+	}
+
+	first, _, _ := strings.Cut(text, "\n")
+	if microDVDLine.MatchString(strings.TrimSpace(first)) {
+		return MicroDVD
+	}
+	if strings.Contains(text, "-->") {
+		return SRT
+	}
+	return ""
+}
+
+// Parse reads a subtitle in UTF-8. The frame rate is the one of the MicroDVD subtitles
+// not giving theirs, DefaultFPS when 0.
+func Parse(text string, format string, fps float64) (*Subtitle, error) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var (
+		sub *Subtitle
+		err error
+	)
+	switch strings.ToLower(format) {
+	case SRT:
+		sub, err = parseSRT(text)
+	case VTT:
+		sub, err = parseVTT(text)
+	case ASS, SSA:
+		sub, err = parseASS(text)
+	case MicroDVD:
+		sub, err = parseMicroDVD(text, fps)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupported, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(sub.Cues) == 0 {
+		return nil, fmt.Errorf("no cues in the %s subtitle", format)
+	}
+
+	sort.SliceStable(sub.Cues, func(i, j int) bool {
+		return sub.Cues[i].Start < sub.Cues[j].Start
+	})
+	return sub, nil
+}
+
+// Format writes the subtitle in a format
+func (s *Subtitle) Format(format string) ([]byte, error) {
+	var b strings.Builder
+	switch strings.ToLower(format) {
+	case SRT:
+		writeSRT(&b, s)
+	case VTT:
+		writeVTT(&b, s)
+	case ASS:
+		writeASS(&b, s)
+	default:
+		return nil, fmt.Errorf("%w: cannot write %q", ErrUnsupported, format)
+	}
+	return []byte(b.String()), nil
+}
+
+// Shift moves the cues by an offset. The cues ending before the start of the video
+// are removed, the ones starting before it are cut.
+func (s *Subtitle) Shift(offset time.Duration) {
+	if offset == 0 {
+		return
+	}
+
+	cues := s.Cues[:0]
+	for _, cue := range s.Cues {
+		cue.Start += offset
+		cue.End += offset
+		if cue.End <= 0 {
+			continue
+		}
+		cue.Start = max(cue.Start, 0)
+		cues = append(cues, cue)
+	}
+	s.Cues = cues
+}
+
+// Resample moves the cues made for a video at a frame rate to the same video at
+// another one, the frames being played faster or slower
+func (s *Subtitle) Resample(from, to float64) {
+	if from <= 0 || to <= 0 || from == to {
+		return
+	}
+
+	ratio := from / to
+	for i := range s.Cues {
+		s.Cues[i].Start = time.Duration(float64(s.Cues[i].Start) * ratio)
+		s.Cues[i].End = time.Duration(float64(s.Cues[i].End) * ratio)
+	}
+}
+
+// styleTag matches the tags of the cue texts
+var styleTag = regexp.MustCompile(`(?i)<(/?)(i|b|u|font)(?:\s+color\s*=\s*"?#?([0-9a-f]{6})"?)?[^>]*>`)
+
+// anTag matches the position override of ASS kept in SRT, such as {\an8}
+var anTag = regexp.MustCompile(`^\{\\an([1-9])\}`)
+
+// splitTime splits a duration into hours, minutes, seconds and milliseconds
+func splitTime(d time.Duration) (h, m, s, ms int64) {
+	d = max(d, 0)
+	ms = d.Milliseconds()
+	return ms / 3600000, ms / 60000 % 60, ms / 1000 % 60, ms % 1000
+}
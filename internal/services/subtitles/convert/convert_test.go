@@ -0,0 +1,148 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const srt = "1\r\n00:00:01,500 --> 00:00:03,000\r\n<i>Wake up, Neo...</i>\r\n\r\n2\r\n00:00:04,000 --> 00:00:06,250\r\n{\\an8}<font color=\"#ff0000\">The Matrix</font> has you\r\nFollow the <b>white</b> rabbit & knock\r\n"
+
+func TestConvert_SRTToVTT(t *testing.T) {
+	got, err := Convert([]byte(srt), SRT, Options{Format: VTT})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "WEBVTT\n\n" +
+		"00:00:01.500 --> 00:00:03.000\n<i>Wake up, Neo...</i>\n\n" +
+		"00:00:04.000 --> 00:00:06.250 line:0\nThe Matrix has you\nFollow the <b>white</b> rabbit &amp; knock\n\n"
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestConvert_RoundTrips(t *testing.T) {
+	sub, err := Parse(srt, SRT, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{SRT, ASS} {
+		t.Run(format, func(t *testing.T) {
+			data, err := sub.Format(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Parse(string(data), format, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got.Cues) != len(sub.Cues) {
+				t.Fatalf("got %+v", got.Cues)
+			}
+			for i := range sub.Cues {
+				if got.Cues[i] != sub.Cues[i] {
+					t.Errorf("got %+v, want %+v", got.Cues[i], sub.Cues[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParse_ASS(t *testing.T) {
+	ass := `[Script Info]
+ScriptType: v4.00+
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,20,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,1,1,2,10,10,10,1
+Style: Sign,Arial,20,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,-1,0,0,0,100,100,0,0,1,1,1,8,10,10,10,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:10.00,0:00:12.50,Default,,0,0,0,,Hello, {\i1}world{\i0}!\NSecond line
+Dialogue: 0,0:00:05.00,0:00:06.00,Sign,,0,0,0,,RESTAURANT
+Dialogue: 0,0:00:07.00,0:00:08.00,Default,,0,0,0,,{\p1}m 0 0 l 100 0 100 100{\p0}
+Dialogue: 0,0:00:08.00,0:00:09.00,Default,,0,0,0,,{\c&H0000FF&\an4}Red{\c} text
+`
+	sub, err := Parse(ass, ASS, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Cue{
+		{Start: 5 * time.Second, End: 6 * time.Second, Text: "<b>RESTAURANT</b>", Position: 8},
+		{Start: 8 * time.Second, End: 9 * time.Second, Text: `<font color="#ff0000">Red</font> text`, Position: 4},
+		{Start: 10 * time.Second, End: 12500 * time.Millisecond, Text: "Hello, <i>world</i>!\nSecond line"},
+	}
+	if len(sub.Cues) != len(want) {
+		t.Fatalf("got %+v", sub.Cues)
+	}
+	for i := range want {
+		if sub.Cues[i] != want[i] {
+			t.Errorf("got %+v, want %+v", sub.Cues[i], want[i])
+		}
+	}
+}
+
+func TestParse_MicroDVD(t *testing.T) {
+	sub, err := Parse("{1}{1}25\n{250}{300}{Y:i}Hello|world\n{500}{}{y:b}Bold|plain\n", Detect("{1}{1}25\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Cue{
+		{Start: 10 * time.Second, End: 12 * time.Second, Text: "<i>Hello\nworld</i>"},
+		{Start: 20 * time.Second, End: 22 * time.Second, Text: "<b>Bold</b>\nplain"},
+	}
+	if len(sub.Cues) != len(want) || sub.Cues[0] != want[0] || sub.Cues[1] != want[1] {
+		t.Errorf("got %+v, want %+v", sub.Cues, want)
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		language string
+		want     string
+		encoding string
+	}{
+		{"utf-8 with BOM", []byte("\xef\xbb\xbfDéjà vu"), "fr", "Déjà vu", "utf-8"},
+		{"latin-1", []byte("D\xe9j\xe0 vu \x85"), "fr", "Déjà vu …", "windows-1252"},
+		{"cyrillic", []byte("\xcf\xf0\xe8\xe2\xe5\xf2"), "ru", "Привет", "windows-1251"},
+		{"utf-16 without mark", []byte("H\x00i\x00!\x00\n\x00"), "", "Hi!\n", "utf-16le"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoding, err := ToUTF8(tt.data, "", tt.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || encoding != tt.encoding {
+				t.Errorf("got %q in %s, want %q in %s", got, encoding, tt.want, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestShiftAndResample(t *testing.T) {
+	sub := &Subtitle{Cues: []Cue{
+		{Start: time.Second, End: 2 * time.Second, Text: "dropped"},
+		{Start: 2500 * time.Millisecond, End: 4 * time.Second, Text: "cut"},
+		{Start: 25 * time.Second, End: 50 * time.Second, Text: "kept"},
+	}}
+
+	sub.Resample(25, 25)
+	sub.Shift(-3 * time.Second)
+	if len(sub.Cues) != 2 || sub.Cues[0].Start != 0 || sub.Cues[0].End != time.Second {
+		t.Fatalf("got %+v", sub.Cues)
+	}
+
+	sub.Resample(23.976, 25)
+	if got := sub.Cues[1]; got.Start != 21098880*time.Microsecond || !strings.Contains(got.Text, "kept") {
+		t.Errorf("got %+v", got)
+	}
+}
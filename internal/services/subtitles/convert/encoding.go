@@ -0,0 +1,114 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// legacyEncodings are the Windows code pages the subtitles of a language are usually
+// written in, Windows-1252 for the others
+var legacyEncodings = map[string]string{
+	"cs": "windows-1250",
+	"hu": "windows-1250",
+	"pl": "windows-1250",
+	"ro": "windows-1250",
+	"hr": "windows-1250",
+	"sk": "windows-1250",
+	"sl": "windows-1250",
+	"bs": "windows-1250",
+	"sq": "windows-1250",
+	"sr": "windows-1250",
+	"ru": "windows-1251",
+	"uk": "windows-1251",
+	"bg": "windows-1251",
+	"be": "windows-1251",
+	"mk": "windows-1251",
+	"el": "windows-1253",
+	"tr": "windows-1254",
+	"he": "windows-1255",
+	"ar": "windows-1256",
+	"fa": "windows-1256",
+	"et": "windows-1257",
+	"lv": "windows-1257",
+	"lt": "windows-1257",
+	"vi": "windows-1258",
+	"zh": "gbk",
+	"ja": "shift_jis",
+	"ko": "euc-kr",
+}
+
+// ToUTF8 decodes a subtitle to UTF-8 and returns the encoding read. When it is not
+// given, the encoding is detected: a byte order mark, valid UTF-8, UTF-16 without
+// mark, then the legacy code page of the language.
+func ToUTF8(data []byte, name, language string) (string, string, error) {
+	if name == "" {
+		name = detectEncoding(data, language)
+	}
+	name = strings.ToLower(name)
+
+	var enc encoding.Encoding
+	switch name {
+	case "utf-8":
+		return string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), name, nil
+	case "utf-16le":
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case "utf-16be":
+		enc = unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	default:
+		var err error
+		if enc, err = htmlindex.Get(name); err != nil {
+			return "", "", fmt.Errorf("unknown encoding %q", name)
+		}
+	}
+
+	text, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode the subtitle from %s: %w", name, err)
+	}
+	return strings.TrimPrefix(string(text), "\ufeff"), name, nil
+}
+
+func detectEncoding(data []byte, language string) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xef\xbb\xbf")):
+		return "utf-8"
+	case bytes.HasPrefix(data, []byte("\xff\xfe")):
+		return "utf-16le"
+	case bytes.HasPrefix(data, []byte("\xfe\xff")):
+		return "utf-16be"
+	}
+
+	// The text of UTF-16 has a zero byte in most of its ASCII characters
+	if len(data) >= 4 {
+		var even, odd int
+		for i, b := range data {
+			if b == 0 && i%2 == 0 {
+				even++
+			} else if b == 0 {
+				odd++
+			}
+		}
+		switch {
+		case odd > len(data)/4:
+			return "utf-16le"
+		case even > len(data)/4:
+			return "utf-16be"
+		}
+	}
+
+	if utf8.Valid(data) {
+		return "utf-8"
+	}
+
+	language, _, _ = strings.Cut(strings.ToLower(language), "-")
+	if name, ok := legacyEncodings[language]; ok {
+		return name
+	}
+	return "windows-1252"
+}
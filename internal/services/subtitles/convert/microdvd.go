@@ -0,0 +1,117 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// microDVDCode matches the control codes of MicroDVD, {y:i} styling a line and {Y:i}
+// all the lines of a cue
+var microDVDCode = regexp.MustCompile(`\{([a-zA-Z]):([^}]*)\}`)
+
+// microDVDDuration is the duration of the cues without an end
+const microDVDDuration = 2 * time.Second
+
+// parseMicroDVD reads the subtitles timed in frames. The frame rate is given by the
+// first cue of most files, such as {1}{1}23.976.
+func parseMicroDVD(text string, fps float64) (*Subtitle, error) {
+	sub := &Subtitle{}
+	if fps <= 0 {
+		fps = DefaultFPS
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		match := microDVDLine.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("invalid MicroDVD line %d", i+1)
+		}
+
+		start, _ := strconv.ParseInt(match[1], 10, 64)
+		if len(sub.Cues) == 0 && start <= 1 {
+			if rate, err := strconv.ParseFloat(strings.TrimSpace(match[3]), 64); err == nil && rate > 0 {
+				fps = rate
+				continue
+			}
+		}
+
+		cue := Cue{
+			Start: frameTime(start, fps),
+			Text:  microDVDText(match[3]),
+		}
+		if end, err := strconv.ParseInt(match[2], 10, 64); err == nil {
+			cue.End = frameTime(end, fps)
+		} else {
+			cue.End = cue.Start + microDVDDuration
+		}
+		if cue.Text != "" {
+			sub.Cues = append(sub.Cues, cue)
+		}
+	}
+
+	return sub, nil
+}
+
+func frameTime(frame int64, fps float64) time.Duration {
+	return time.Duration(float64(frame) / fps * float64(time.Second))
+}
+
+// microDVDText converts the lines of a cue, separated by |, and their styles
+func microDVDText(text string) string {
+	var global []string
+	lines := strings.Split(text, "|")
+	for i, line := range lines {
+		var local []string
+		line = microDVDCode.ReplaceAllStringFunc(line, func(code string) string {
+			m := microDVDCode.FindStringSubmatch(code)
+			tags := microDVDTags(strings.ToLower(m[1]), m[2])
+			if m[1] == strings.ToUpper(m[1]) {
+				global = append(global, tags...)
+			} else {
+				local = append(local, tags...)
+			}
+			return ""
+		})
+		lines[i] = wrapTags(strings.TrimSpace(line), local)
+	}
+	return wrapTags(strings.Join(lines, "\n"), global)
+}
+
+// microDVDTags returns the tags of SRT of a control code
+func microDVDTags(code, value string) []string {
+	switch code {
+	case "y":
+		var tags []string
+		for _, style := range strings.Split(value, ",") {
+			switch style = strings.TrimSpace(style); style {
+			case "i", "b", "u":
+				tags = append(tags, style)
+			}
+		}
+		return tags
+	case "c":
+		// $BBGGRR
+		if color := strings.TrimPrefix(value, "$"); len(color) == 6 {
+			return []string{`font color="#` + strings.ToLower(color[4:6]+color[2:4]+color[0:2]) + `"`}
+		}
+	}
+	return nil
+}
+
+// wrapTags wraps a text in tags, given with their attributes
+func wrapTags(text string, tags []string) string {
+	if text == "" {
+		return ""
+	}
+	for _, tag := range tags {
+		name, _, _ := strings.Cut(tag, " ")
+		text = "<" + tag + ">" + text + "</" + name + ">"
+	}
+	return text
+}
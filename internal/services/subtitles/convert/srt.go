@@ -0,0 +1,85 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trailingSpaces matches the spaces ending the lines, which would hide the blank
+// lines between the cues
+var trailingSpaces = regexp.MustCompile(`[ \t]+\n`)
+
+// srtTiming matches the timing line of a cue, the coordinates of some files after it
+// being ignored
+var srtTiming = regexp.MustCompile(`(\d+):(\d{1,2}):(\d{1,2})[,.:](\d{1,3})\s*-->\s*(\d+):(\d{1,2}):(\d{1,2})[,.:](\d{1,3})`)
+
+func parseSRT(text string) (*Subtitle, error) {
+	sub := &Subtitle{}
+
+	text = trailingSpaces.ReplaceAllString(text, "\n")
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+
+		// The timing follows the index, which some files omit
+		i := 0
+		for i < len(lines) && i < 2 && !strings.Contains(lines[i], "-->") {
+			i++
+		}
+		if i == len(lines) {
+			continue
+		}
+		match := srtTiming.FindStringSubmatch(lines[i])
+		if match == nil {
+			if i < 2 {
+				return nil, fmt.Errorf("invalid SRT timing %q", lines[i])
+			}
+			continue
+		}
+
+		cue := Cue{
+			Start: clock(match[1], match[2], match[3], match[4]),
+			End:   clock(match[5], match[6], match[7], match[8]),
+			Text:  strings.TrimSpace(strings.Join(lines[i+1:], "\n")),
+		}
+		if m := anTag.FindStringSubmatch(cue.Text); m != nil {
+			cue.Position, _ = strconv.Atoi(m[1])
+			cue.Text = strings.TrimSpace(cue.Text[len(m[0]):])
+		}
+		if cue.Text != "" {
+			sub.Cues = append(sub.Cues, cue)
+		}
+	}
+
+	return sub, nil
+}
+
+func writeSRT(b *strings.Builder, sub *Subtitle) {
+	for i, cue := range sub.Cues {
+		fmt.Fprintf(b, "%d\n%s --> %s\n", i+1, srtTime(cue.Start), srtTime(cue.End))
+		if cue.Position != 0 && cue.Position != 2 {
+			fmt.Fprintf(b, "{\\an%d}", cue.Position)
+		}
+		b.WriteString(cue.Text)
+		b.WriteString("\n\n")
+	}
+}
+
+func srtTime(d time.Duration) string {
+	h, m, s, ms := splitTime(d)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", h, m, s, ms)
+}
+
+// clock returns the duration of a time given in parts, the fraction being padded
+// to milliseconds
+func clock(hours, minutes, seconds, fraction string) time.Duration {
+	h, _ := strconv.Atoi(hours)
+	m, _ := strconv.Atoi(minutes)
+	s, _ := strconv.Atoi(seconds)
+	ms, _ := strconv.Atoi((fraction + "00")[:3])
+
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second + time.Duration(ms)*time.Millisecond
+}
@@ -0,0 +1,154 @@
+package convert
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	vttTiming = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})\s+-->\s+(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})(.*)$`)
+
+	// vttTag matches the tags of the cue texts: the styles, the classes, the voices
+	// and the timestamps of karaoke
+	vttTag = regexp.MustCompile(`<(/?)([^>\s.]*)[^>]*>`)
+)
+
+func parseVTT(text string) (*Subtitle, error) {
+	if !strings.HasPrefix(text, "WEBVTT") {
+		return nil, fmt.Errorf("missing WEBVTT header")
+	}
+	sub := &Subtitle{}
+
+	text = trailingSpaces.ReplaceAllString(text, "\n")
+	blocks := strings.Split(text, "\n\n")
+	for _, block := range blocks[1:] {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+
+		// The cue identifier is optional, the notes and the style blocks have no timing
+		i := 0
+		if len(lines) > 1 && !strings.Contains(lines[0], "-->") {
+			i = 1
+		}
+		match := vttTiming.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+
+		cue := Cue{
+			Start:    clock(match[1], match[2], match[3], match[4]),
+			End:      clock(match[5], match[6], match[7], match[8]),
+			Text:     vttText(strings.Join(lines[i+1:], "\n")),
+			Position: vttPosition(strings.Fields(match[9])),
+		}
+		if cue.Text != "" {
+			sub.Cues = append(sub.Cues, cue)
+		}
+	}
+
+	return sub, nil
+}
+
+// vttText keeps the italics, the bold and the underline of a cue text
+func vttText(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range vttTag.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(html.UnescapeString(text[last:m[0]]))
+		last = m[1]
+
+		switch name := strings.ToLower(text[m[4]:m[5]]); name {
+		case "i", "b", "u":
+			fmt.Fprintf(&b, "<%s%s>", text[m[2]:m[3]], name)
+		}
+	}
+	b.WriteString(html.UnescapeString(text[last:]))
+	return strings.TrimSpace(b.String())
+}
+
+// vttPosition returns the numpad alignment of the settings of a cue
+func vttPosition(settings []string) int {
+	row, column := 0, 1
+	for _, setting := range settings {
+		name, value, _ := strings.Cut(setting, ":")
+		value, _, _ = strings.Cut(value, ",")
+
+		switch name {
+		case "line":
+			if percent, ok := strings.CutSuffix(value, "%"); ok {
+				p, _ := strconv.ParseFloat(percent, 64)
+				switch {
+				case p < 40:
+					row = 6
+				case p < 60:
+					row = 3
+				}
+			} else if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				// The positive line numbers count from the top
+				row = 6
+			}
+		case "align":
+			switch value {
+			case "start", "left":
+				column = 0
+			case "end", "right":
+				column = 2
+			}
+		}
+	}
+
+	if position := row + column + 1; position != 2 {
+		return position
+	}
+	return 0
+}
+
+func writeVTT(b *strings.Builder, sub *Subtitle) {
+	b.WriteString("WEBVTT\n\n")
+	for _, cue := range sub.Cues {
+		fmt.Fprintf(b, "%s --> %s", vttTime(cue.Start), vttTime(cue.End))
+		if cue.Position != 0 {
+			b.WriteString(vttSettings(cue.Position))
+		}
+		b.WriteString("\n")
+
+		// The colors have no tag in WebVTT
+		last := 0
+		for _, m := range styleTag.FindAllStringSubmatchIndex(cue.Text, -1) {
+			b.WriteString(vttEscaper.Replace(cue.Text[last:m[0]]))
+			last = m[1]
+			if name := strings.ToLower(cue.Text[m[4]:m[5]]); name != "font" {
+				fmt.Fprintf(b, "<%s%s>", cue.Text[m[2]:m[3]], name)
+			}
+		}
+		b.WriteString(vttEscaper.Replace(cue.Text[last:]))
+		b.WriteString("\n\n")
+	}
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func vttSettings(position int) string {
+	var settings string
+	switch (position - 1) / 3 {
+	case 1:
+		settings = " line:50%"
+	case 2:
+		settings = " line:0"
+	}
+	switch (position - 1) % 3 {
+	case 0:
+		settings += " align:left"
+	case 2:
+		settings += " align:right"
+	}
+	return settings
+}
+
+func vttTime(d time.Duration) string {
+	h, m, s, ms := splitTime(d)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, ms)
+}
@@ -0,0 +1,44 @@
+package subtitles
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// hashChunkSize is the size of the chunks hashed at the start and the end of a file
+const hashChunkSize = 64 << 10
+
+// Hash returns the hash of a video file introduced by OpenSubtitles and shared by the
+// subtitle sources: its size plus the sums of the 64-bit little-endian words of its
+// first and last 64 KiB
+func Hash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := stat.Size()
+	if size < 2*hashChunkSize {
+		return "", fmt.Errorf("file of %d bytes too small to be hashed", size)
+	}
+
+	hash := uint64(size)
+	buf := make([]byte, hashChunkSize)
+	for _, offset := range []int64{0, size - hashChunkSize} {
+		if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return "", err
+		}
+		for i := 0; i < hashChunkSize; i += 8 {
+			hash += binary.LittleEndian.Uint64(buf[i:])
+		}
+	}
+
+	return fmt.Sprintf("%016x", hash), nil
+}
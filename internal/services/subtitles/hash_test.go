@@ -0,0 +1,33 @@
+package subtitles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.mkv")
+	data := make([]byte, 2*hashChunkSize+8)
+	data[0] = 1                  // First chunk
+	data[len(data)-8] = 2        // Last chunk
+	data[hashChunkSize+4] = 0xFF // Neither
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := Hash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "000000000002000b"; hash != want {
+		t.Errorf("got %s, want %s", hash, want)
+	}
+
+	if err := os.WriteFile(path, []byte("small"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Hash(path); err == nil {
+		t.Error("expected an error for a small file")
+	}
+}
@@ -0,0 +1,283 @@
+// Package local finds the subtitles of the video files in an archive directory, by
+// the hash of the files or by their name.
+package local
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"goru/internal/models"
+	"goru/internal/services/subtitles"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// nameSync is the confidence given to a subtitle named after the video file, made
+	// for its release
+	nameSync = 0.9
+
+	// overrunSync is the confidence given to a subtitle lasting longer than the video,
+	// made for another cut
+	overrunSync = 0.1
+
+	// overrunTolerance is how long a subtitle may last after the end of the video, the
+	// credits being sometimes subtitled
+	overrunTolerance = time.Minute
+)
+
+// extensions are the formats of the subtitles read in the archive
+var extensions = map[string]bool{".srt": true, ".ass": true, ".ssa": true, ".vtt": true}
+
+var (
+	languageCode = regexp.MustCompile(`^[a-z]{2}(-[a-z]{2})?$`)
+	episodeCode  = regexp.MustCompile(`(?i)s(\d{1,2})e(\d{1,3})`)
+	timestamp    = regexp.MustCompile(`-->\s*(\d+):(\d{2}):(\d{2})[,.](\d{3})`)
+)
+
+func init() {
+	subtitles.Register("local", func(config models.Provider) (subtitles.SubtitleProvider, error) {
+		return New(config)
+	})
+}
+
+// entry is a subtitle of the archive
+type entry struct {
+	// path is relative to the archive
+	path     string
+	name     string
+	language string
+	format   string
+	hi       bool
+	forced   bool
+}
+
+// Local is the subtitle provider reading an archive directory. The subtitles are named
+// after the hash of a video or after its filename, followed by their language and
+// flags, such as 8e245d9679d31e12.en.srt or The.Matrix.1999.1080p.BluRay.x264-AMIABLE.fr.forced.srt.
+// The archive is indexed once.
+type Local struct {
+	root string
+
+	once  sync.Once
+	index []entry
+	err   error
+}
+
+// New creates the local provider of the archive at the path of the configuration
+func New(config models.Provider) (*Local, error) {
+	if config.Path == "" {
+		return nil, errors.New("the path of the subtitle archive is required")
+	}
+
+	stat, err := os.Stat(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", config.Path)
+	}
+
+	return &Local{root: config.Path}, nil
+}
+
+func (l *Local) Name() string {
+	return "local"
+}
+
+// Search returns the subtitles of the archive named after the hash of the video, its
+// filename, or its title with its year or its episode
+func (l *Local) Search(ctx context.Context, query subtitles.Query) ([]models.Subtitle, error) {
+	l.once.Do(func() { l.index, l.err = l.scan() })
+	if l.err != nil {
+		return nil, fmt.Errorf("failed to index the subtitle archive: %w", l.err)
+	}
+
+	hash, err := subtitles.Hash(query.Path)
+	if err != nil {
+		log.Debug("failed to hash the video file", zap.String("file", query.Path), zap.Error(err))
+	}
+	video := strings.TrimSuffix(filepath.Base(query.Path), filepath.Ext(query.Path))
+
+	var found []models.Subtitle
+	for _, e := range l.index {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !slices.Contains(query.Languages, e.language) {
+			continue
+		}
+
+		sub := models.Subtitle{
+			Provider:        l.Name(),
+			ID:              e.path,
+			Language:        e.language,
+			Release:         e.name,
+			Format:          e.format,
+			HearingImpaired: e.hi,
+			Forced:          e.forced,
+		}
+
+		switch {
+		case hash != "" && strings.EqualFold(e.name, hash):
+			sub.HashMatch, sub.Sync, sub.Release = true, 1, ""
+		case normalize(e.name) == normalize(video):
+			sub.Sync = nameSync
+		case !matchesTitle(e.name, query):
+			continue
+		}
+
+		// A subtitle lasting longer than the video is made for another cut
+		if query.Duration > 0 {
+			if end := l.end(e); end > query.Duration+overrunTolerance {
+				sub.Sync = min(sub.Sync, overrunSync)
+				sub.HashMatch = false
+			}
+		}
+		found = append(found, sub)
+	}
+
+	return found, nil
+}
+
+// Download reads a subtitle of the archive
+func (l *Local) Download(ctx context.Context, subtitle models.Subtitle) ([]byte, error) {
+	if !filepath.IsLocal(subtitle.ID) {
+		return nil, fmt.Errorf("invalid subtitle path %q", subtitle.ID)
+	}
+	return os.ReadFile(filepath.Join(l.root, subtitle.ID))
+}
+
+// scan indexes the subtitles of the archive
+func (l *Local) scan() ([]entry, error) {
+	var index []entry
+	err := filepath.WalkDir(l.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Warn("failed to read the subtitle archive", zap.String("path", path), zap.Error(err))
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !extensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+		if e, ok := parseName(filepath.Base(path)); ok {
+			e.path = rel
+			index = append(index, e)
+		}
+		return nil
+	})
+	return index, err
+}
+
+// parseName reads the language and the flags following the name of a subtitle. The
+// subtitles without a language are left out.
+func parseName(filename string) (entry, bool) {
+	ext := filepath.Ext(filename)
+	e := entry{format: strings.ToLower(strings.TrimPrefix(ext, "."))}
+	parts := strings.Split(strings.TrimSuffix(filename, ext), ".")
+
+	// The flags follow the language. hi is left to Hindi.
+	for len(parts) > 1 {
+		switch strings.ToLower(parts[len(parts)-1]) {
+		case "forced":
+			e.forced = true
+		case "sdh", "cc":
+			e.hi = true
+		default:
+			return withLanguage(e, parts)
+		}
+		parts = parts[:len(parts)-1]
+	}
+	return e, false
+}
+
+func withLanguage(e entry, parts []string) (entry, bool) {
+	language := strings.ToLower(parts[len(parts)-1])
+	if len(parts) < 2 || !languageCode.MatchString(language) {
+		return e, false
+	}
+
+	e.language = language
+	e.name = strings.Join(parts[:len(parts)-1], ".")
+	return e, true
+}
+
+// matchesTitle returns true when the name of a subtitle starts with the title of the
+// query, followed by its year or its episode when known
+func matchesTitle(name string, query subtitles.Query) bool {
+	title := normalize(query.Title)
+	if title == "" || !strings.HasPrefix(normalize(name), title) {
+		return false
+	}
+
+	if query.Season > 0 && query.Episode > 0 {
+		match := episodeCode.FindStringSubmatch(name)
+		return match != nil && atoi(match[1]) == query.Season && atoi(match[2]) == query.Episode
+	}
+	if query.Year > 0 {
+		return strings.Contains(name, fmt.Sprint(query.Year))
+	}
+	return true
+}
+
+// end returns the time of the last cue of a SubRip or WebVTT subtitle, 0 when unknown
+func (l *Local) end(e entry) time.Duration {
+	if e.format != "srt" && e.format != "vtt" {
+		return 0
+	}
+
+	data, err := os.ReadFile(filepath.Join(l.root, e.path))
+	if err != nil {
+		return 0
+	}
+
+	var end time.Duration
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if match := timestamp.FindStringSubmatch(scanner.Text()); match != nil {
+			end = max(end, time.Duration(atoi(match[1]))*time.Hour+
+				time.Duration(atoi(match[2]))*time.Minute+
+				time.Duration(atoi(match[3]))*time.Second+
+				time.Duration(atoi(match[4]))*time.Millisecond)
+		}
+	}
+	return end
+}
+
+// normalize lowers a name and keeps only its letters and digits
+func normalize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func atoi(s string) int {
+	n := 0
+	for _, c := range s {
+		n = n*10 + int(c-'0')
+	}
+	return n
+}
@@ -0,0 +1,108 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/subtitles"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+const cue = "1\n00:00:01,000 --> 00:00:02,000\nWake up, Neo.\n\n"
+
+func TestSearch(t *testing.T) {
+	archive := t.TempDir()
+	for name, content := range map[string]string{
+		// The hash of the video, 3 chunks of zeros
+		"0000000000030000.en.srt":                                 cue,
+		"The.Matrix.1999.1080p.BluRay.x264-AMIABLE.fr.forced.srt": cue,
+		"movies/The Matrix (1999) DVDRip.de.sdh.srt":              cue,
+		"The.Matrix.1999.1080p.BluRay.x264-AMIABLE.es.srt":        cue + "2\n02:58:00,000 --> 02:58:02,000\nThe end.\n",
+		"movies/The Matrix Reloaded (2003).en.srt":                cue,
+		"movies/The.Matrix.1999.it.srt":                           cue,
+		"movies/The.Matrix.1999.srt":                              cue,
+		"notes.txt":                                               "",
+	} {
+		path := filepath.Join(archive, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	video := filepath.Join(t.TempDir(), "The.Matrix.1999.1080p.BluRay.x264-AMIABLE.mkv")
+	if err := os.WriteFile(video, make([]byte, 3<<16), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := New(models.Provider{Path: archive})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := l.Search(context.Background(), subtitles.Query{
+		Path:      video,
+		Duration:  136 * time.Minute,
+		MediaType: models.MediaTypeMovie,
+		Title:     "The Matrix",
+		Year:      1999,
+		Languages: []string{"en", "fr", "de", "es"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]models.Subtitle{
+		"en": {Provider: "local", ID: "0000000000030000.en.srt", Language: "en", Format: "srt", HashMatch: true, Sync: 1},
+		"fr": {Provider: "local", ID: "The.Matrix.1999.1080p.BluRay.x264-AMIABLE.fr.forced.srt", Language: "fr", Release: "The.Matrix.1999.1080p.BluRay.x264-AMIABLE", Format: "srt", Forced: true, Sync: nameSync},
+		"de": {Provider: "local", ID: filepath.Join("movies", "The Matrix (1999) DVDRip.de.sdh.srt"), Language: "de", Release: "The Matrix (1999) DVDRip", Format: "srt", HearingImpaired: true},
+		// Named after the video, but longer than it
+		"es": {Provider: "local", ID: "The.Matrix.1999.1080p.BluRay.x264-AMIABLE.es.srt", Language: "es", Release: "The.Matrix.1999.1080p.BluRay.x264-AMIABLE", Format: "srt", Sync: overrunSync},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("got %+v", found)
+	}
+	for _, sub := range found {
+		if sub != want[sub.Language] {
+			t.Errorf("got %+v, want %+v", sub, want[sub.Language])
+		}
+	}
+
+	data, err := l.Download(context.Background(), want["en"])
+	if err != nil || string(data) != cue {
+		t.Errorf("got %q, %v", data, err)
+	}
+	if _, err := l.Download(context.Background(), models.Subtitle{ID: "../secret.srt"}); err == nil {
+		t.Error("expected an error for a path outside of the archive")
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     entry
+		ok       bool
+	}{
+		{"Movie.en.srt", entry{name: "Movie", language: "en", format: "srt"}, true},
+		{"Movie.pt-br.sdh.forced.ass", entry{name: "Movie", language: "pt-br", format: "ass", hi: true, forced: true}, true},
+		{"Movie.hi.srt", entry{name: "Movie", language: "hi", format: "srt"}, true},
+		{"Movie.srt", entry{format: "srt"}, false},
+		{"en.srt", entry{format: "srt"}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseName(tt.filename)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseName(%q) = %+v, %v, want %+v, %v", tt.filename, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -1,45 +1,307 @@
+// Package opensubtitles searches and downloads subtitles with the REST API of
+// OpenSubtitles.com, by the hash of the video files and their IMDb or TMDB IDs.
 package opensubtitles
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"goru/internal/models"
+	"goru/internal/services/metrics"
+	"goru/internal/services/providers"
 	"goru/internal/services/subtitles"
+	"goru/pkg/log"
 
-	"github.com/oz/osdb"
+	"go.uber.org/zap"
 )
 
-type openSubtitles struct {
-	apiKey string
-	client *osdb.Client
+const (
+	// DefaultURL is the base URL of the REST API
+	DefaultURL = "https://api.opensubtitles.com/api/v1"
+
+	// UserAgent names the application, as required by the API
+	UserAgent = "goru v1"
+
+	// RateLimit is the number of requests per second allowed by the API
+	RateLimit = 5
+
+	// maxSubtitleSize is the largest subtitle downloaded
+	maxSubtitleSize = 10 << 20
+)
+
+// ErrNoAPIKey is returned when the API key of OpenSubtitles is not configured
+var ErrNoAPIKey = errors.New("the OpenSubtitles API key is required")
+
+func init() {
+	subtitles.Register("opensubtitles", func(config models.Provider) (subtitles.SubtitleProvider, error) {
+		return New(config)
+	})
+}
+
+// OpenSubtitles is the subtitle provider of OpenSubtitles.com. The users logged in
+// with their username and password get a larger download quota.
+type OpenSubtitles struct {
+	url         string
+	apiKey      string
+	username    string
+	password    string
+	client      *http.Client
+	rateLimiter *providers.RateLimiter
+
+	mu    sync.Mutex
+	token string
+}
+
+// New creates the OpenSubtitles provider. The URL defaults to the one of the API.
+func New(config models.Provider) (*OpenSubtitles, error) {
+	if config.APIKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	baseURL := config.URL
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+
+	return &OpenSubtitles{
+		url:      strings.TrimSuffix(baseURL, "/"),
+		apiKey:   config.APIKey,
+		username: config.Username,
+		password: config.Password,
+		client: &http.Client{
+			Transport: metrics.InstrumentTransport("opensubtitles", nil),
+			Timeout:   30 * time.Second,
+		},
+		rateLimiter: providers.NewRateLimiter("opensubtitles", RateLimit),
+	}, nil
+}
+
+func (o *OpenSubtitles) Name() string {
+	return "opensubtitles"
 }
 
-func New(apiKey string) subtitles.SubtitleProvider {
-	c, _ := osdb.NewClient()
-	return &openSubtitles{apiKey: apiKey, client: c}
+// searchResponse is the response of /subtitles
+type searchResponse struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Language         string `json:"language"`
+			DownloadCount    int    `json:"download_count"`
+			HearingImpaired  bool   `json:"hearing_impaired"`
+			ForeignPartsOnly bool   `json:"foreign_parts_only"`
+			Release          string `json:"release"`
+			MoviehashMatch   bool   `json:"moviehash_match"`
+			Files            []struct {
+				FileID   int64  `json:"file_id"`
+				FileName string `json:"file_name"`
+			} `json:"files"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// Search searches the subtitles by the hash of the file and the IDs of its title,
+// or by its title when the IDs are unknown
+func (o *OpenSubtitles) Search(ctx context.Context, query subtitles.Query) ([]models.Subtitle, error) {
+	params := searchParams(query)
+	if query.Path != "" {
+		hash, err := subtitles.Hash(query.Path)
+		if err != nil {
+			log.Debug("failed to hash the video file", zap.String("file", query.Path), zap.Error(err))
+		} else {
+			params.Set("moviehash", hash)
+		}
+	}
+
+	var response searchResponse
+	if err := o.do(ctx, http.MethodGet, "/subtitles?"+params.Encode(), "", nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to search subtitles: %w", err)
+	}
+
+	var found []models.Subtitle
+	for _, data := range response.Data {
+		attributes := data.Attributes
+		// A subtitle of several files is split in several parts, such as CDs
+		if len(attributes.Files) != 1 {
+			continue
+		}
+
+		found = append(found, models.Subtitle{
+			Provider:        o.Name(),
+			ID:              strconv.FormatInt(attributes.Files[0].FileID, 10),
+			Language:        strings.ToLower(attributes.Language),
+			Release:         attributes.Release,
+			Format:          "srt",
+			HearingImpaired: attributes.HearingImpaired,
+			Forced:          attributes.ForeignPartsOnly,
+			HashMatch:       attributes.MoviehashMatch,
+			Downloads:       attributes.DownloadCount,
+		})
+	}
+
+	return found, nil
+}
+
+// searchParams returns the parameters of a search. The API redirects the requests
+// whose parameters are not sorted nor in lower case, url.Values sorting them.
+func searchParams(query subtitles.Query) url.Values {
+	params := url.Values{}
+	if len(query.Languages) > 0 {
+		params.Set("languages", strings.ToLower(strings.Join(query.Languages, ",")))
+	}
+
+	imdbID := strings.TrimLeft(strings.TrimPrefix(strings.ToLower(query.IMDBID), "tt"), "0")
+	episode := query.MediaType != models.MediaTypeMovie && query.Season > 0 && query.Episode > 0
+
+	switch {
+	case episode && imdbID != "":
+		params.Set("parent_imdb_id", imdbID)
+	case episode && query.TMDBID != "":
+		params.Set("parent_tmdb_id", query.TMDBID)
+	case imdbID != "":
+		params.Set("imdb_id", imdbID)
+	case query.TMDBID != "":
+		params.Set("tmdb_id", query.TMDBID)
+	case query.Title != "":
+		params.Set("query", strings.ToLower(query.Title))
+		if query.Year > 0 {
+			params.Set("year", strconv.Itoa(query.Year))
+		}
+	}
+
+	if episode {
+		params.Set("season_number", strconv.Itoa(query.Season))
+		params.Set("episode_number", strconv.Itoa(query.Episode))
+	}
+	return params
 }
 
-func (o *openSubtitles) Get(videoFile *models.VideoFile, language string) (string, error) {
-	o.client.LogIn("", "", "")
+// Download requests the link of a subtitle, then downloads it
+func (o *OpenSubtitles) Download(ctx context.Context, subtitle models.Subtitle) ([]byte, error) {
+	fileID, err := strconv.ParseInt(subtitle.ID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file ID %q", subtitle.ID)
+	}
+
+	token, err := o.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Link string `json:"link"`
+	}
+	request := map[string]any{"file_id": fileID, "sub_format": "srt"}
+	if err := o.do(ctx, http.MethodPost, "/download", token, request, &response); err != nil {
+		return nil, fmt.Errorf("failed to request the subtitle: %w", err)
+	}
+	if response.Link == "" {
+		return nil, fmt.Errorf("no link to download the subtitle %s", subtitle.ID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, response.Link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading the subtitle %s: unexpected status %s", subtitle.ID, resp.Status)
+	}
 
-	subs, err := o.client.FileSearch(videoFile.Path, []string{language})
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSubtitleSize+1))
 	if err != nil {
-		return "", fmt.Errorf("failed to search subtitles: %w", err)
+		return nil, err
 	}
-	return subs[0].SubFileName, nil
+	if len(data) > maxSubtitleSize {
+		return nil, fmt.Errorf("downloading the subtitle %s: larger than %d bytes", subtitle.ID, maxSubtitleSize)
+	}
+	return data, nil
+}
+
+// login returns the token of the user, logging in once. It is empty when no username
+// is configured.
+func (o *OpenSubtitles) login(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.username == "" || o.token != "" {
+		return o.token, nil
+	}
+
+	var response struct {
+		Token string `json:"token"`
+	}
+	request := map[string]string{"username": o.username, "password": o.password}
+	if err := o.do(ctx, http.MethodPost, "/login", "", request, &response); err != nil {
+		return "", fmt.Errorf("failed to log in to OpenSubtitles: %w", err)
+	}
+
+	o.token = response.Token
+	return o.token, nil
 }
 
-func (o *openSubtitles) Download(videoFile *models.VideoFile, language string) error {
-	o.client.LogIn("", "", "")
+// do sends a request to the API, authenticated by the token when given, and decodes
+// its JSON response
+func (o *OpenSubtitles) do(ctx context.Context, method, path, token string, request, response any) error {
+	if err := o.rateLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, o.url+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Api-Key", o.apiKey)
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "application/json")
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
-	subs, err := o.client.FileSearch(videoFile.Path, []string{language})
+	resp, err := o.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to search subtitles: %w", err)
+		return err
 	}
+	defer resp.Body.Close()
 
-	if err := o.client.Download(&subs[0]); err != nil {
-		return fmt.Errorf("failed to download subtitles: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&apiError)
+		if apiError.Message != "" {
+			return fmt.Errorf("unexpected status %s: %s", resp.Status, apiError.Message)
+		}
+		return fmt.Errorf("unexpected status %s", resp.Status)
 	}
 
-	return nil
+	return json.NewDecoder(resp.Body).Decode(response)
 }
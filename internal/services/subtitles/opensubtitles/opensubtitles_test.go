@@ -0,0 +1,167 @@
+package opensubtitles
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"goru/internal/models"
+	"goru/internal/services/subtitles"
+	"goru/internal/services/subtitles/subtitlestest"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+// routes are the responses recorded from the API
+var routes = map[string]string{
+	"GET /subtitles": "search.json",
+	"POST /login":    "login.json",
+	"POST /download": "download.json",
+	"GET /download/4012345/The.Matrix.1999.1080p.BluRay.x264-AMIABLE.srt": "matrix.srt",
+}
+
+func setup(t *testing.T, routes map[string]string) (*OpenSubtitles, *subtitlestest.Server) {
+	server := subtitlestest.Replay(t, routes)
+
+	o, err := New(models.Provider{APIKey: "key", Username: "neo", Password: "zion", URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o, server
+}
+
+func TestNew_RequiresAPIKey(t *testing.T) {
+	if _, err := New(models.Provider{}); err != ErrNoAPIKey {
+		t.Errorf("got error %v, want ErrNoAPIKey", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "The.Matrix.1999.1080p.BluRay.x264-AMIABLE.mkv")
+	if err := os.WriteFile(path, make([]byte, 3<<16), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	o, server := setup(t, routes)
+
+	found, err := o.Search(context.Background(), subtitles.Query{
+		Path:      path,
+		MediaType: models.MediaTypeMovie,
+		Title:     "The Matrix",
+		IMDBID:    "tt0133093",
+		TMDBID:    "603",
+		Languages: []string{"en", "pt-br"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The parameters are sorted, the hash being the size of the zeroed file
+	request := server.Requests()[0]
+	if want := "/subtitles?imdb_id=133093&languages=en%2Cpt-br&moviehash=0000000000030000"; request.URL != want {
+		t.Errorf("got request %q, want %q", request.URL, want)
+	}
+	if request.Header.Get("Api-Key") != "key" || request.Header.Get("User-Agent") != UserAgent {
+		t.Errorf("got headers %v", request.Header)
+	}
+
+	want := []models.Subtitle{
+		{Provider: "opensubtitles", ID: "4012345", Language: "en", Release: "The.Matrix.1999.1080p.BluRay.x264-AMIABLE", Format: "srt", HashMatch: true, Downloads: 51423},
+		{Provider: "opensubtitles", ID: "4012346", Language: "pt-br", Release: "The Matrix (1999) DVDRip", Format: "srt", HearingImpaired: true, Downloads: 1200},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("got %+v, want %+v", found, want)
+	}
+	for i := range want {
+		if found[i] != want[i] {
+			t.Errorf("got %+v, want %+v", found[i], want[i])
+		}
+	}
+}
+
+func TestSearch_NoResult(t *testing.T) {
+	o, _ := setup(t, map[string]string{"GET /subtitles": "search_empty.json"})
+
+	found, err := o.Search(context.Background(), subtitles.Query{Title: "Unknown", Languages: []string{"en"}})
+	if err != nil || len(found) != 0 {
+		t.Errorf("got %v, %v, want no subtitle", found, err)
+	}
+}
+
+func TestSearchParams_Episode(t *testing.T) {
+	params := searchParams(subtitles.Query{
+		MediaType: models.MediaTypeTVShow,
+		Title:     "Breaking Bad",
+		TMDBID:    "1396",
+		Season:    1,
+		Episode:   2,
+		Languages: []string{"fr"},
+	})
+	if want := "episode_number=2&languages=fr&parent_tmdb_id=1396&season_number=1"; params.Encode() != want {
+		t.Errorf("got %q, want %q", params.Encode(), want)
+	}
+
+	params = searchParams(subtitles.Query{MediaType: models.MediaTypeMovie, Title: "The Matrix", Year: 1999})
+	if want := "query=the+matrix&year=1999"; params.Encode() != want {
+		t.Errorf("got %q, want %q", params.Encode(), want)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	o, server := setup(t, routes)
+
+	for range 2 {
+		data, err := o.Download(context.Background(), models.Subtitle{Provider: "opensubtitles", ID: "4012345"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "1\r\n00:00:01,000 --> 00:00:02,000\r\nWake up, Neo.\r\n" {
+			t.Errorf("got %q", data)
+		}
+	}
+
+	var logins int
+	for _, request := range server.Requests() {
+		switch request.URL {
+		case "/login":
+			logins++
+		case "/download":
+			var body struct {
+				FileID    int64  `json:"file_id"`
+				SubFormat string `json:"sub_format"`
+			}
+			json.Unmarshal(request.Body, &body)
+			if request.Header.Get("Authorization") != "Bearer eyJhbGciOiJIUzI1NiJ9.token" || body.FileID != 4012345 || body.SubFormat != "srt" {
+				t.Errorf("unexpected download request %v %s", request.Header, request.Body)
+			}
+		}
+	}
+	if logins != 1 {
+		t.Errorf("logged in %d times, want once", logins)
+	}
+}
+
+func TestDownload_QuotaExceeded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotAcceptable)
+		w.Write([]byte(`{"message": "You have downloaded your allowed 20 subtitles for 24h"}`))
+	}))
+	defer server.Close()
+
+	o, err := New(models.Provider{APIKey: "key", URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Download(context.Background(), models.Subtitle{ID: "1"})
+	if err == nil || !strings.Contains(err.Error(), "allowed 20 subtitles") {
+		t.Fatalf("got error %v, want the message of the API", err)
+	}
+}
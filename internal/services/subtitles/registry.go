@@ -0,0 +1,65 @@
+package subtitles
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"goru/internal/models"
+)
+
+// Factory creates a subtitle provider from its configuration, the entry of its name
+// in the providers
+type Factory func(config models.Provider) (SubtitleProvider, error)
+
+var (
+	registryMux sync.RWMutex
+	registry    = make(map[string]Factory)
+)
+
+// Register makes a subtitle provider available to the configuration. It panics when
+// the name is already registered, it is meant to be called from init.
+func Register(name string, factory Factory) {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+
+	if factory == nil {
+		panic("subtitles: Register factory is nil")
+	}
+	if _, ok := registry[name]; ok {
+		panic("subtitles: Register called twice for provider " + name)
+	}
+	registry[name] = factory
+}
+
+// Registered returns the names of the registered providers, sorted
+func Registered() []string {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Create creates a registered provider
+func Create(name string, config models.Provider) (SubtitleProvider, error) {
+	registryMux.RLock()
+	factory, ok := registry[name]
+	registryMux.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown subtitle provider %q, registered providers are %v", name, Registered())
+	}
+
+	provider, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subtitle provider %q: %w", name, err)
+	}
+
+	return provider, nil
+}
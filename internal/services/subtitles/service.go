@@ -0,0 +1,315 @@
+package subtitles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"goru/internal/models"
+	"goru/internal/services/plans"
+	"goru/internal/services/providers"
+	"goru/internal/services/states"
+	"goru/internal/services/subtitles/convert"
+	"goru/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// The weights of the score of a subtitle, summing to 1
+const (
+	syncWeight       = 0.45
+	releaseWeight    = 0.25
+	preferenceWeight = 0.1
+	popularWeight    = 0.1
+
+	// popularDownloads is the number of downloads of the most popular subtitles
+	popularDownloads = 100000
+)
+
+var (
+	// DefaultLanguages are searched when none is configured
+	DefaultLanguages = []string{"en"}
+
+	// DefaultProviders are searched when none is configured
+	DefaultProviders = []string{"opensubtitles"}
+)
+
+// Service chooses the subtitles of the video files and downloads them once the files
+// are renamed
+type Service struct {
+	config    models.Subtitles
+	providers map[string]SubtitleProvider
+	order     []SubtitleProvider
+}
+
+// New creates the subtitle service, nil when the subtitles are disabled or no provider
+// is given. The providers are searched in their order.
+func New(config models.Subtitles, subtitleProviders ...SubtitleProvider) *Service {
+	if !config.Enabled || len(subtitleProviders) == 0 {
+		return nil
+	}
+
+	if len(config.Languages) == 0 {
+		config.Languages = DefaultLanguages
+	}
+	for i, language := range config.Languages {
+		config.Languages[i] = strings.ToLower(language)
+	}
+	if config.HearingImpaired == "" {
+		config.HearingImpaired = models.SubtitleInclude
+	}
+	if config.Forced == "" {
+		config.Forced = models.SubtitleInclude
+	}
+
+	s := &Service{
+		config:    config,
+		providers: make(map[string]SubtitleProvider, len(subtitleProviders)),
+	}
+	for _, provider := range subtitleProviders {
+		s.providers[provider.Name()] = provider
+		s.order = append(s.order, provider)
+	}
+	return s
+}
+
+// Find searches the subtitles of a file with the providers and returns the best one
+// of each language, in the order of the languages. The next providers are skipped once
+// every language has a subtitle matching the hash of the file. The failures of the
+// providers are logged.
+func (s *Service) Find(ctx context.Context, file *models.VideoFile) []models.Subtitle {
+	if s == nil {
+		return nil
+	}
+
+	query := NewQuery(file, s.config.Languages)
+
+	var found []models.Subtitle
+	for _, provider := range s.order {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		subs, err := provider.Search(ctx, query)
+		if err != nil {
+			log.Warn("failed to search subtitles", zap.String("provider", provider.Name()), zap.String("file", file.Path), zap.Error(err))
+			continue
+		}
+		found = append(found, subs...)
+
+		if s.complete(found) {
+			break
+		}
+	}
+
+	return s.choose(file, found)
+}
+
+// complete returns true when the subtitles found match the hash of the file in all
+// the languages wanted
+func (s *Service) complete(found []models.Subtitle) bool {
+	matched := make(map[string]bool)
+	for _, sub := range found {
+		if sub.HashMatch && accepts(s.config.HearingImpaired, sub.HearingImpaired) && accepts(s.config.Forced, sub.Forced) {
+			matched[strings.ToLower(sub.Language)] = true
+		}
+	}
+
+	count := 0
+	for _, language := range s.config.Languages {
+		if matched[language] {
+			count++
+		} else if s.config.MaxLanguages == 0 || count < s.config.MaxLanguages {
+			// A language of higher priority is missing
+			return false
+		}
+	}
+	return count > 0
+}
+
+// choose scores the subtitles and keeps the best one of each language
+func (s *Service) choose(file *models.VideoFile, found []models.Subtitle) []models.Subtitle {
+	best := make(map[string]models.Subtitle)
+	for _, sub := range found {
+		sub.Language = strings.ToLower(sub.Language)
+		if !accepts(s.config.HearingImpaired, sub.HearingImpaired) || !accepts(s.config.Forced, sub.Forced) {
+			continue
+		}
+
+		sub.Score = s.score(file, sub)
+		if sub.Score < s.config.MinScore {
+			continue
+		}
+		if current, ok := best[sub.Language]; !ok || sub.Score > current.Score {
+			best[sub.Language] = sub
+		}
+	}
+
+	var chosen []models.Subtitle
+	for _, language := range s.config.Languages {
+		if s.config.MaxLanguages > 0 && len(chosen) == s.config.MaxLanguages {
+			break
+		}
+		if sub, ok := best[language]; ok {
+			if s.config.Convert.Enabled {
+				sub.Convert = convert.Target(strings.ToLower(sub.Format), s.config.Convert.Format)
+			}
+			chosen = append(chosen, sub)
+		}
+	}
+	return chosen
+}
+
+// score ranks a subtitle between 0 and 1: the confidence that it is in sync with the
+// file first, then the similarity of its release to the filename, the preferences and
+// its popularity
+func (s *Service) score(file *models.VideoFile, sub models.Subtitle) float64 {
+	name := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	score := syncWeight * syncConfidence(name, sub)
+
+	if sub.Release != "" {
+		score += releaseWeight * providers.MatchConfidence(name, 0, sub.Release, 0)
+	}
+
+	score += preferenceWeight / 2 * preference(s.config.HearingImpaired, sub.HearingImpaired)
+	score += preferenceWeight / 2 * preference(s.config.Forced, sub.Forced)
+
+	if sub.Downloads > 0 {
+		score += popularWeight * min(1, math.Log10(float64(sub.Downloads)+1)/math.Log10(popularDownloads))
+	}
+
+	return math.Round(score*1000) / 1000
+}
+
+// accepts returns true when a hearing impaired or forced subtitle, or a regular one,
+// is accepted by the preference
+func accepts(preference string, flagged bool) bool {
+	switch preference {
+	case models.SubtitleExclude:
+		return !flagged
+	case models.SubtitleOnly:
+		return flagged
+	}
+	return true
+}
+
+// preference returns 1 when the subtitle is the kind preferred
+func preference(preference string, flagged bool) float64 {
+	if (preference == models.SubtitlePrefer) == flagged {
+		return 1
+	}
+	return 0
+}
+
+// Download downloads the subtitles of the applied plan next to the videos, named after
+// their final path, and tracks them in the state entries of the renames so that a
+// revert removes them. A failure is recorded in the result and does not stop the others.
+func (s *Service) Download(ctx context.Context, plan *plans.Plan, result *plans.ApplyResult, stateService *states.StateService) {
+	if s == nil || result == nil {
+		return
+	}
+
+	renamed := make(map[string]plans.ChangeResult, len(result.Changes))
+	for _, changeResult := range result.Changes {
+		if changeResult.Status == plans.ChangeStatusApplied && changeResult.Before != "" {
+			renamed[changeResult.Before] = changeResult
+		}
+	}
+
+	for _, change := range plan.Changes {
+		if change.Action != plans.ActionCreate || change.Subtitle == nil || !change.IsApplicable() {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		// The video stays at its path when it has not been renamed
+		video, stateID := change.Before.Path, ""
+		if rename, ok := renamed[video]; ok {
+			video, stateID = rename.After, rename.StateID
+		}
+		if _, err := os.Stat(video); err != nil {
+			log.Warn("skipping the subtitle of a missing video", zap.String("file", video), zap.Error(err))
+			continue
+		}
+
+		changeResult := plans.ChangeResult{
+			ChangeID: change.ID,
+			After:    change.Subtitle.Path(video),
+			Status:   plans.ChangeStatusApplied,
+			StateID:  stateID,
+		}
+
+		written, err := s.write(ctx, *change.Subtitle, changeResult.After)
+		if err != nil {
+			log.Error("failed to download the subtitle", zap.String("file", changeResult.After), zap.Error(err))
+			changeResult.Status = plans.ChangeStatusFailed
+			changeResult.Error = err.Error()
+			result.Changes = append(result.Changes, changeResult)
+			continue
+		}
+		if !written {
+			continue
+		}
+
+		result.Downloaded++
+		result.Changes = append(result.Changes, changeResult)
+		if stateID != "" {
+			if err := stateService.AddSidecars(stateID, []string{changeResult.After}); err != nil {
+				log.Error("failed to add the subtitle to state", zap.String("id", stateID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// write downloads a subtitle unless its file already exists. It returns true when the
+// file has been written.
+func (s *Service) write(ctx context.Context, sub models.Subtitle, path string) (bool, error) {
+	if _, err := os.Lstat(path); err == nil {
+		log.Debug("keeping the existing subtitle", zap.String("path", path))
+		return false, nil
+	}
+
+	provider, ok := s.providers[sub.Provider]
+	if !ok {
+		return false, errors.New("unknown subtitle provider: " + sub.Provider)
+	}
+
+	data, err := provider.Download(ctx, sub)
+	if err != nil {
+		return false, err
+	}
+
+	if sub.Convert != "" {
+		data, err = convert.Convert(data, strings.ToLower(sub.Format), convert.Options{Format: sub.Convert, Language: sub.Language})
+		if err != nil {
+			return false, fmt.Errorf("failed to convert the subtitle: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return false, err
+	}
+
+	log.Debug("downloaded subtitle", zap.String("path", path))
+	return true, nil
+}
@@ -0,0 +1,204 @@
+package subtitles
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goru/internal/models"
+	"goru/internal/services/files"
+	"goru/internal/services/formatters"
+	"goru/internal/services/plans"
+	"goru/internal/services/states"
+	"goru/pkg/log"
+)
+
+func init() {
+	log.Init(false)
+}
+
+// fakeProvider returns the same subtitles for every file
+type fakeProvider struct {
+	found    []models.Subtitle
+	searches int
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Search(ctx context.Context, query Query) ([]models.Subtitle, error) {
+	p.searches++
+	return p.found, nil
+}
+
+func (p *fakeProvider) Download(ctx context.Context, sub models.Subtitle) ([]byte, error) {
+	return []byte("1\n00:00:01,000 --> 00:00:02,000\n" + sub.ID + "\n"), nil
+}
+
+func movie(path string) *models.VideoFile {
+	file := models.NewVideoFile(path, models.DefaultConflictStrategy)
+	file.MediaType = models.MediaTypeMovie
+	file.Metadata = &models.Movie{ID: "603", Title: "The Matrix", ExternalIDs: models.ExternalIDs{TMDBID: "603", IMDBID: "tt0133093"}}
+	return file
+}
+
+func TestFind(t *testing.T) {
+	provider := &fakeProvider{found: []models.Subtitle{
+		{Provider: "fake", ID: "popular", Language: "EN", Release: "The.Matrix.1999.DVDRip", Downloads: 90000},
+		{Provider: "fake", ID: "hash", Language: "en", Release: "The.Matrix.1999.1080p.BluRay", HashMatch: true},
+		{Provider: "fake", ID: "sdh", Language: "en", Release: "The.Matrix.1999.1080p.BluRay", HashMatch: true, HearingImpaired: true},
+		{Provider: "fake", ID: "forced", Language: "fr", Release: "The.Matrix.1999.1080p.BluRay", Forced: true},
+		{Provider: "fake", ID: "german", Language: "de", Downloads: 10},
+	}}
+	file := movie("/media/The.Matrix.1999.1080p.BluRay.mkv")
+
+	tests := []struct {
+		name   string
+		config models.Subtitles
+		want   []string
+	}{
+		{"best of each language by priority", models.Subtitles{Languages: []string{"fr", "en", "de"}}, []string{"forced", "hash", "german"}},
+		{"hearing impaired preferred", models.Subtitles{Languages: []string{"en"}, HearingImpaired: models.SubtitlePrefer}, []string{"sdh"}},
+		{"forced excluded", models.Subtitles{Languages: []string{"fr", "en"}, Forced: models.SubtitleExclude}, []string{"hash"}},
+		{"first languages found", models.Subtitles{Languages: []string{"it", "de", "en"}, MaxLanguages: 1}, []string{"german"}},
+		{"minimum score", models.Subtitles{Languages: []string{"en", "de"}, MinScore: 0.3}, []string{"hash"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Enabled = true
+			found := New(tt.config, provider).Find(context.Background(), file)
+
+			var got []string
+			for _, sub := range found {
+				got = append(got, sub.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFind_Chained(t *testing.T) {
+	archive := &fakeProvider{found: []models.Subtitle{
+		{Provider: "fake", ID: "archive", Language: "en", HashMatch: true},
+	}}
+	online := &fakeProvider{found: []models.Subtitle{
+		{Provider: "fake", ID: "online", Language: "fr", Release: "The.Matrix.1999.1080p.BluRay"},
+	}}
+	file := movie("/media/The.Matrix.1999.1080p.BluRay.mkv")
+
+	found := New(models.Subtitles{Enabled: true, Languages: []string{"en"}}, archive, online).Find(context.Background(), file)
+	if len(found) != 1 || found[0].ID != "archive" || online.searches != 0 {
+		t.Errorf("got %v after %d searches of the next provider", found, online.searches)
+	}
+
+	found = New(models.Subtitles{Enabled: true, Languages: []string{"fr", "en"}}, archive, online).Find(context.Background(), file)
+	if len(found) != 2 || found[0].ID != "online" || found[1].ID != "archive" {
+		t.Errorf("got %v", found)
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	s := New(models.Subtitles{Languages: []string{"en"}}, &fakeProvider{})
+	if s != nil {
+		t.Fatal("expected no service when the subtitles are disabled")
+	}
+	if found := s.Find(context.Background(), movie("/media/movie.mkv")); found != nil {
+		t.Errorf("got %v from a disabled service", found)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	stateService, err := states.NewStateService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	path := filepath.Join(root, "The.Matrix.1999.1080p.BluRay.mkv")
+	if err := os.WriteFile(path, []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file := movie(path)
+	file.Metadata.(*models.Movie).ReleaseDate = time.Date(1999, 3, 31, 0, 0, 0, 0, time.UTC)
+	file.Subtitles = []models.Subtitle{
+		{Provider: "fake", ID: "1", Language: "en", Format: "srt"},
+		{Provider: "fake", ID: "2", Language: "fr", Format: "srt", Forced: true},
+	}
+
+	plan, err := plans.NewPlan([]*models.VideoFile{file}, formatters.NewFormatterService("", "{{.Name}} ({{.Year}})"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := plan.Summary().CreateChanges; got != 2 {
+		t.Fatalf("got %d subtitle changes, want 2", got)
+	}
+	// The reviewer rejects the French subtitle
+	for i := range plan.Changes {
+		if sub := plan.Changes[i].Subtitle; sub != nil && sub.Language == "fr" {
+			plan.Changes[i].Decision = plans.DecisionRejected
+		}
+	}
+
+	result := plan.Apply(context.Background(), files.NewFileService("", "", models.Filters{}), stateService, nil)
+	s := New(models.Subtitles{Enabled: true}, &fakeProvider{})
+	s.Download(context.Background(), plan, result, stateService)
+
+	subtitle := filepath.Join(root, "The Matrix (1999).en.srt")
+	if result.Applied != 1 || result.Downloaded != 1 || len(result.Changes) != 2 {
+		t.Fatalf("got %d renamed, %d downloaded, changes %+v", result.Applied, result.Downloaded, result.Changes)
+	}
+	if got := result.Changes[1]; got.Before != "" || got.After != subtitle || got.Status != plans.ChangeStatusApplied {
+		t.Errorf("got subtitle result %+v", got)
+	}
+	if _, err := os.Stat(subtitle); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "The Matrix (1999).fr.forced.srt")); !os.IsNotExist(err) {
+		t.Errorf("the rejected subtitle has been downloaded: %v", err)
+	}
+
+	entry, err := stateService.GetEntryByID(result.Changes[0].StateID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Sidecars) != 1 || entry.Sidecars[0] != subtitle {
+		t.Errorf("got sidecars %v in state, want the subtitle", entry.Sidecars)
+	}
+}
+
+func TestDownload_Converted(t *testing.T) {
+	config := models.Subtitles{Enabled: true, Languages: []string{"fr"}, Convert: models.SubtitleConversion{Enabled: true, Format: "vtt"}}
+	provider := &fakeProvider{found: []models.Subtitle{{Provider: "fake", ID: "D\xe9j\xe0 vu", Language: "fr", Format: "srt"}}}
+	s := New(config, provider)
+
+	found := s.Find(context.Background(), movie("/media/The.Matrix.1999.1080p.BluRay.mkv"))
+	if len(found) != 1 || found[0].Convert != "vtt" {
+		t.Fatalf("got %+v", found)
+	}
+
+	path := found[0].Path(filepath.Join(t.TempDir(), "The Matrix (1999).mkv"))
+	if filepath.Ext(path) != ".vtt" {
+		t.Errorf("got path %s", path)
+	}
+	if _, err := s.write(context.Background(), found[0], path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nDéjà vu\n\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
@@ -1,8 +1,81 @@
+// Package subtitles finds the subtitles of the video files with the subtitle providers,
+// and downloads the chosen ones next to the renamed files.
 package subtitles
 
-import "goru/internal/models"
+import (
+	"context"
+	"strings"
+	"time"
 
+	"goru/internal/models"
+)
+
+// SubtitleProvider searches and downloads subtitles
 type SubtitleProvider interface {
-	Get(videoFile *models.VideoFile, language string) (string, error)
-	Download(videoFile *models.VideoFile, language string) error
+	// Name identifies the provider in the subtitles it finds
+	Name() string
+
+	// Search returns the subtitles of a video in the languages of the query, none
+	// when nothing is found
+	Search(ctx context.Context, query Query) ([]models.Subtitle, error)
+
+	// Download returns the content of a subtitle found by the provider
+	Download(ctx context.Context, subtitle models.Subtitle) ([]byte, error)
+}
+
+// Query describes the video whose subtitles are searched. The IDs are the ones of
+// the show for the episodes.
+type Query struct {
+	// Path is the video file, hashed by the providers supporting it
+	Path string
+
+	// Duration is the duration of the video, 0 when unknown
+	Duration time.Duration
+
+	MediaType models.MediaType
+	Title     string
+	Year      int
+	IMDBID    string
+	TMDBID    string
+	Season    int
+	Episode   int
+
+	// Languages are ISO 639-1 codes, in lower case
+	Languages []string
+}
+
+// NewQuery creates the query of a video file from its metadata
+func NewQuery(file *models.VideoFile, languages []string) Query {
+	query := Query{
+		Path:      file.Path,
+		MediaType: file.MediaType,
+		Languages: make([]string, 0, len(languages)),
+	}
+	if file.MediaInfo != nil {
+		query.Duration = file.MediaInfo.Duration
+	}
+	for _, language := range languages {
+		query.Languages = append(query.Languages, strings.ToLower(language))
+	}
+
+	switch metadata := file.Metadata.(type) {
+	case *models.Movie:
+		query.Title = metadata.Title
+		query.Year = metadata.ReleaseDate.Year()
+		query.IMDBID = metadata.ExternalIDs.IMDBID
+		query.TMDBID = metadata.ExternalIDs.TMDBID
+	case *models.Episode:
+		query.Title = metadata.TVShow.Name
+		query.Year = metadata.TVShow.FirstAirDate.Year()
+		query.IMDBID = metadata.TVShow.ExternalIDs.IMDBID
+		query.TMDBID = metadata.TVShow.ExternalIDs.TMDBID
+		query.Season = metadata.Season
+		query.Episode = metadata.Episode
+	}
+
+	// The zero time of an unknown date
+	if query.Year <= 1 {
+		query.Year = 0
+	}
+	return query
 }
@@ -0,0 +1,76 @@
+// Package subtitlestest replays the HTTP responses recorded from the subtitle sources,
+// for the tests of their providers.
+package subtitlestest
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Request is a request received by the server
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Server replays the recorded responses
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// Replay starts a server answering each route, a pattern such as "GET /subtitles",
+// with the content of its file in testdata. The {{URL}} placeholders of the files are
+// replaced by the URL of the server, for the links to follow. The other requests get
+// a 404.
+func Replay(t testing.TB, routes map[string]string) *Server {
+	t.Helper()
+
+	s := &Server{}
+	mux := http.NewServeMux()
+	for pattern, file := range routes {
+		data, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contentType := mime.TypeByExtension(filepath.Ext(file))
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			w.Write(bytes.ReplaceAll(data, []byte("{{URL}}"), []byte(s.URL)))
+		})
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, URL: r.URL.String(), Header: r.Header, Body: body})
+		s.mu.Unlock()
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Requests returns the requests received, in their order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
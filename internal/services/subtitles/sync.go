@@ -0,0 +1,92 @@
+package subtitles
+
+import (
+	"regexp"
+	"strings"
+
+	"goru/internal/models"
+)
+
+// The confidences that a subtitle is in sync with a video whose release it does not
+// match by hash
+const (
+	// sameGroupSync is given to the subtitles of the same release group, which encodes
+	// its releases from a single source
+	sameGroupSync = 0.8
+
+	// sameSourceSync is given to the subtitles of another release of the same source,
+	// which usually share the cut and the frame rate
+	sameSourceSync = 0.5
+)
+
+// sources are the tokens naming the source of a release
+var sources = map[string]string{
+	"bluray":  "bluray",
+	"bdrip":   "bluray",
+	"brrip":   "bluray",
+	"bdremux": "bluray",
+	"remux":   "bluray",
+	"webdl":   "web",
+	"webrip":  "web",
+	"web":     "web",
+	"hdtv":    "hdtv",
+	"pdtv":    "hdtv",
+	"dvdrip":  "dvd",
+	"dvd":     "dvd",
+}
+
+var (
+	releaseSeparators = regexp.MustCompile(`[^a-z0-9-]+`)
+	releaseGroup      = regexp.MustCompile(`-([a-z0-9]+)$`)
+)
+
+// syncConfidence returns the confidence between 0 and 1 that a subtitle is in sync with
+// the video of the given name: 1 for a hash match, else the confidence of the provider
+// or the one of the release, sharing its group or source with the video
+func syncConfidence(name string, sub models.Subtitle) float64 {
+	if sub.HashMatch {
+		return 1
+	}
+	return max(sub.Sync, releaseSync(name, sub.Release))
+}
+
+// releaseSync compares the release of a subtitle with the name of a video. A release
+// made of a single word names a group, as the versions of Addic7ed.
+func releaseSync(name, release string) float64 {
+	name, release = strings.ToLower(name), strings.ToLower(strings.TrimSpace(release))
+	if release == "" {
+		return 0
+	}
+
+	group := groupOf(name)
+	if group != "" {
+		if releaseGroup := groupOf(release); releaseGroup == group || release == group {
+			return sameGroupSync
+		}
+	}
+
+	if source := sourceOf(name); source != "" && source == sourceOf(release) {
+		return sameSourceSync
+	}
+	return 0
+}
+
+func groupOf(release string) string {
+	if match := releaseGroup.FindStringSubmatch(strings.TrimSpace(release)); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func sourceOf(release string) string {
+	// The web releases are often written WEB-DL
+	release = strings.ReplaceAll(release, "web-dl", "webdl")
+	for _, token := range releaseSeparators.Split(release, -1) {
+		for _, part := range strings.Split(token, "-") {
+			if source, ok := sources[part]; ok {
+				return source
+			}
+		}
+	}
+	return ""
+}
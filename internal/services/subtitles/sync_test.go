@@ -0,0 +1,22 @@
+package subtitles
+
+import "testing"
+
+func TestReleaseSync(t *testing.T) {
+	tests := []struct {
+		name, release string
+		want          float64
+	}{
+		{"Breaking.Bad.S01E02.720p.HDTV.x264-DIMENSION", "DIMENSION", sameGroupSync},
+		{"The.Matrix.1999.1080p.BluRay.x264-AMIABLE", "The.Matrix.1999.720p.BluRay.x264-AMIABLE", sameGroupSync},
+		{"The.Matrix.1999.1080p.BluRay.x264-AMIABLE", "The.Matrix.1999.BDRip.x264-SPARKS", sameSourceSync},
+		{"Dune.2021.2160p.WEB-DL.DDP5.1.Atmos-FLUX", "Dune.2021.1080p.WEBRip.x264-RARBG", sameSourceSync},
+		{"The.Matrix.1999.1080p.BluRay.x264-AMIABLE", "The Matrix (1999) DVDRip", 0},
+		{"the matrix", "", 0},
+	}
+	for _, tt := range tests {
+		if got := releaseSync(tt.name, tt.release); got != tt.want {
+			t.Errorf("releaseSync(%q, %q) = %v, want %v", tt.name, tt.release, got, tt.want)
+		}
+	}
+}
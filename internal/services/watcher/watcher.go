@@ -1,6 +1,7 @@
 package watcher
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/fs"
@@ -11,6 +12,8 @@ import (
 	"time"
 
 	"goru/internal/models"
+	"goru/internal/services/metrics"
+	"goru/internal/services/notifications"
 	"goru/internal/services/providers"
 	"goru/pkg/log"
 
@@ -19,6 +22,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// fileKeyPrefix prefixes the keys of the file records, the database being shared with the plans
+const fileKeyPrefix = "file:"
+
 type FileRecord struct {
 	Path    string
 	ModTime int64
@@ -33,14 +39,14 @@ type Watcher struct {
 	scanInterval  time.Duration
 	flushInterval time.Duration
 	providers     []providers.Provider
+	notifier      *notifications.Notifier
 }
 
-func New(dbFile string, providers []providers.Provider) (*Watcher, error) {
-	db, err := buntdb.Open(dbFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+// New creates a new watcher. The database is owned by the caller.
+func New(db *buntdb.DB, providers []providers.Provider) (*Watcher, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database is required")
 	}
-	defer db.Close()
 
 	return &Watcher{
 		db:            db,
@@ -52,14 +58,19 @@ func New(dbFile string, providers []providers.Provider) (*Watcher, error) {
 	}, nil
 }
 
+// SetNotifier sets the notifier of the lookup errors
+func (w *Watcher) SetNotifier(notifier *notifications.Notifier) {
+	w.notifier = notifier
+}
+
 func (w *Watcher) Start() {
 	log.Debug("Starting watcher service", zap.String("default_directory", w.defaultDir))
 
 	w.db.View(func(tx *buntdb.Tx) error {
-		tx.Ascend("", func(key, value string) bool {
+		tx.AscendKeys(fileKeyPrefix+"*", func(key, value string) bool {
 			var rec FileRecord
 			if err := json.Unmarshal([]byte(value), &rec); err == nil {
-				w.fileCache[key] = rec
+				w.fileCache[strings.TrimPrefix(key, fileKeyPrefix)] = rec
 			}
 			return true
 		})
@@ -95,6 +106,9 @@ func (w *Watcher) Start() {
 func (w *Watcher) scanDir(root string, parentCfg DirConfig) {
 	log.Debug("Scanning directory", zap.String("directory", root))
 
+	// The files are queued during the walk, then processed
+	var queue []*models.VideoFile
+
 	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil
@@ -150,34 +164,79 @@ func (w *Watcher) scanDir(root string, parentCfg DirConfig) {
 		if strings.HasSuffix(d.Name(), ".mkv") || strings.HasSuffix(d.Name(), ".mp4") {
 			log.Debug("video file found", zap.String("path", path), zap.String("type", parentCfg.Type), zap.String("provider", parentCfg.Provider))
 
+			metrics.FilesScanned.WithLabelValues(metrics.SourceWatcher).Inc()
+
+			// Skip the files already processed and not modified since
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if w.isProcessed(path, info.ModTime()) {
+				metrics.CacheRequests.WithLabelValues("watcher", metrics.CacheHit).Inc()
+				return nil
+			}
+			metrics.CacheRequests.WithLabelValues("watcher", metrics.CacheMiss).Inc()
+
 			videoFile := models.NewVideoFile(path, models.DefaultConflictStrategy)
 
 			// TODO: override media type if needed
 
-			w.processFile(videoFile)
+			queue = append(queue, videoFile)
+			metrics.WatcherQueueDepth.Inc()
 		}
 
 		return nil
 	})
+
+	// The failures are reported once per scan
+	failed := 0
+	for _, videoFile := range queue {
+		if !w.processFile(videoFile) {
+			failed++
+		}
+		metrics.WatcherQueueDepth.Dec()
+	}
+
+	if failed > 0 {
+		w.notifier.Notify(notifications.Error(fmt.Sprintf("Metadata lookup failed for %d of %d file(s) in %s", failed, len(queue), root)))
+	}
+}
+
+// isProcessed returns true if the file was processed since its last modification
+func (w *Watcher) isProcessed(path string, modTime time.Time) bool {
+	w.cacheMux.RLock()
+	defer w.cacheMux.RUnlock()
+
+	rec, ok := w.fileCache[path]
+	return ok && rec.Renamed && rec.ModTime == modTime.UnixNano()
 }
 
-func (w *Watcher) processFile(videoFile *models.VideoFile) {
+// processFile looks the file up and returns false if every provider failed
+func (w *Watcher) processFile(videoFile *models.VideoFile) bool {
 	log.Debug("processing file", zap.String("path", videoFile.Path))
 
+	provided := false
 	for _, p := range w.providers {
-		err := p.Provide(videoFile)
+		err := p.Provide(context.Background(), videoFile)
 		if err != nil {
 			log.Error("providing metadata failed", zap.String("provider", p.Name()), zap.String("file", videoFile.Path), zap.Error(err))
 		} else {
+			provided = true
 			break
 		}
 	}
 
 	w.cacheMux.Lock()
 	rec := w.fileCache[videoFile.Path]
+	rec.Path = videoFile.Path
+	if info, err := os.Stat(videoFile.Path); err == nil {
+		rec.ModTime = info.ModTime().UnixNano()
+	}
 	rec.Renamed = true
 	w.fileCache[videoFile.Path] = rec
 	w.cacheMux.Unlock()
+
+	return provided
 }
 
 func (w *Watcher) flushCache(db *buntdb.DB) {
@@ -187,7 +246,7 @@ func (w *Watcher) flushCache(db *buntdb.DB) {
 	db.Update(func(tx *buntdb.Tx) error {
 		for path, rec := range w.fileCache {
 			data, _ := json.Marshal(rec)
-			tx.Set(path, string(data), nil)
+			tx.Set(fileKeyPrefix+path, string(data), nil)
 		}
 		log.Debug("cache flush complete")
 		return nil
@@ -0,0 +1,110 @@
+// Package ui serves the statically exported web UI.
+//
+// The bundle is embedded from the dist directory, filled by `make ui` with the
+// export of the Next.js application found in web/.
+package ui
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+const (
+	indexFile = "index.html"
+
+	// Next.js fingerprints the files under this directory, they never change
+	immutablePrefix = "_next/static/"
+)
+
+var ErrNoBundle = errors.New("the web UI bundle is missing")
+
+// Bundle returns the web UI bundle, read from dir when given, embedded otherwise
+func Bundle(dir string) (fs.FS, error) {
+	var bundle fs.FS
+	if dir != "" {
+		bundle = os.DirFS(dir)
+	} else {
+		sub, err := fs.Sub(dist, "dist")
+		if err != nil {
+			return nil, err
+		}
+		bundle = sub
+	}
+
+	if _, err := fs.Stat(bundle, indexFile); err != nil {
+		return nil, ErrNoBundle
+	}
+
+	return bundle, nil
+}
+
+// Handler serves the files of the bundle. Unknown pages fall back to the index so that
+// the client side routing can handle them.
+func Handler(bundle fs.FS) http.Handler {
+	fileServer := http.FileServer(http.FS(bundle))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+
+		file, ok := resolve(bundle, name)
+		if !ok {
+			// Missing assets are not pages
+			if path.Ext(name) != "" {
+				http.NotFound(w, r)
+				return
+			}
+			file = indexFile
+		}
+
+		setCacheHeaders(w, file)
+
+		// Serve the resolved file, the file server redirects the requests to index.html
+		// to their directory
+		req := r.Clone(r.Context())
+		req.URL.Path = "/" + strings.TrimSuffix(file, indexFile)
+		fileServer.ServeHTTP(w, req)
+	})
+}
+
+// resolve finds the file serving the given path, following the layout of a Next.js export:
+// "search" is exported as search.html, or search/index.html with trailing slashes.
+func resolve(bundle fs.FS, name string) (string, bool) {
+	if name == "" || name == "." {
+		return indexFile, true
+	}
+
+	candidates := []string{name, name + ".html", path.Join(name, indexFile)}
+	for _, candidate := range candidates {
+		info, err := fs.Stat(bundle, candidate)
+		if err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// setCacheHeaders caches the fingerprinted assets forever, and revalidates the pages
+func setCacheHeaders(w http.ResponseWriter, file string) {
+	switch {
+	case strings.HasPrefix(file, immutablePrefix):
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	case strings.HasSuffix(file, ".html"):
+		w.Header().Set("Cache-Control", "no-cache")
+	default:
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+}
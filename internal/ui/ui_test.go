@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHandler(t *testing.T) {
+	bundle := fstest.MapFS{
+		"index.html":              {Data: []byte("home")},
+		"state.html":              {Data: []byte("state")},
+		"search/index.html":       {Data: []byte("search")},
+		"_next/static/abc/app.js": {Data: []byte("app")},
+		"favicon.ico":             {Data: []byte("icon")},
+	}
+	handler := Handler(bundle)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+		cache  string
+	}{
+		{path: "/", status: http.StatusOK, body: "home", cache: "no-cache"},
+		{path: "/state", status: http.StatusOK, body: "state", cache: "no-cache"},
+		{path: "/search", status: http.StatusOK, body: "search", cache: "no-cache"},
+		{path: "/browse/movies", status: http.StatusOK, body: "home", cache: "no-cache"},
+		{path: "/_next/static/abc/app.js", status: http.StatusOK, body: "app", cache: "public, max-age=31536000, immutable"},
+		{path: "/favicon.ico", status: http.StatusOK, body: "icon", cache: "public, max-age=3600"},
+		{path: "/missing.js", status: http.StatusNotFound},
+		{path: "/../../etc/passwd", status: http.StatusOK, body: "home", cache: "no-cache"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.body != "" && strings.TrimSpace(rec.Body.String()) != tt.body {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.body)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.cache {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.cache)
+			}
+		})
+	}
+}
+
+func TestBundle_Missing(t *testing.T) {
+	if _, err := Bundle(t.TempDir()); err != ErrNoBundle {
+		t.Errorf("Bundle() = %v, want %v", err, ErrNoBundle)
+	}
+}
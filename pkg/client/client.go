@@ -0,0 +1,174 @@
+// Package client is a Go client of the goru server API, following its OpenAPI
+// specification served at /api/v1/openapi.json.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// APIPrefix is the prefix of the version of the API supported by the client
+const APIPrefix = "/api/v1"
+
+// APIKeyHeader is the header carrying the API key
+const APIKeyHeader = "X-API-Key"
+
+// DefaultTimeout is the timeout of the requests, except the streams of events
+const DefaultTimeout = 5 * time.Minute
+
+// Error is an error returned by the API
+type Error struct {
+	StatusCode int      `json:"-"`
+	Code       string   `json:"code"`
+	Message    string   `json:"message"`
+	Details    []string `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("%s (%d): %s", e.Code, e.StatusCode, e.Message)
+	if len(e.Details) > 0 {
+		message += ": " + strings.Join(e.Details, ", ")
+	}
+	return message
+}
+
+// Client calls the API of a goru server
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+}
+
+// Option configures the client
+type Option func(*Client)
+
+// WithAPIKey authenticates the requests with an API key
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithHTTPClient replaces the HTTP client. It needs a cookie jar to log in.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New creates a client of the server at the given URL, e.g. http://nas:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("invalid server URL %q: the scheme must be http or https", baseURL)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: &http.Client{Jar: jar},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// newRequest creates a request to the given path of the API, encoding the body as JSON
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	u := *c.baseURL
+	u.Path += APIPrefix + path
+	if len(query) > 0 {
+		u.RawQuery = query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(APIKeyHeader, c.apiKey)
+	}
+
+	return req, nil
+}
+
+// do sends the request and decodes the JSON response into out, when not nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// decodeError decodes the error envelope of the response
+func decodeError(resp *http.Response) error {
+	var envelope struct {
+		Error *Error `json:"error"`
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Error == nil {
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Code:       "unknown",
+			Message:    strings.TrimSpace(string(data)),
+		}
+	}
+
+	envelope.Error.StatusCode = resp.StatusCode
+	return envelope.Error
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_Requests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(APIKeyHeader) != "0123456789abcdef" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"code":"unauthorized","message":"authentication required"}}`)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/directory/roots":
+			fmt.Fprint(w, `{"roots":["/media/movies"]}`)
+		case "/api/v1/jobs/j1":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"code":"not_found","message":"job not found"}}`)
+		case "/api/v1/plans":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"code":"invalid_request","message":"invalid","details":["id: required"]}}`)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, "bad gateway")
+		}
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL+"/", WithAPIKey("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	roots, err := c.Roots(ctx)
+	if err != nil || len(roots) != 1 || roots[0] != "/media/movies" {
+		t.Errorf("Roots() = %v, %v", roots, err)
+	}
+
+	tests := []struct {
+		call    func() error
+		status  int
+		code    string
+		details int
+	}{
+		{call: func() error { _, err := c.GetJob(ctx, "j1"); return err }, status: http.StatusNotFound, code: "not_found"},
+		{call: func() error { _, err := c.ListPlans(ctx); return err }, status: http.StatusBadRequest, code: "invalid_request", details: 1},
+		{call: func() error { return c.Health(ctx) }, status: http.StatusBadGateway, code: "unknown"},
+	}
+
+	for _, tt := range tests {
+		var apiErr *Error
+		if err := tt.call(); !errors.As(err, &apiErr) {
+			t.Errorf("expected an API error, got %v", err)
+			continue
+		}
+		if apiErr.StatusCode != tt.status || apiErr.Code != tt.code || len(apiErr.Details) != tt.details {
+			t.Errorf("got error %+v, want status %d and code %q", apiErr, tt.status, tt.code)
+		}
+	}
+
+	unauthenticated, _ := New(server.URL)
+	var apiErr *Error
+	if _, err := unauthenticated.Roots(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Roots() without API key returned %v", err)
+	}
+}
+
+func TestClient_WatchJob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: progress\ndata: {\"type\":\"progress\",\"current\":1,\"total\":2}\n\n")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "event: status\ndata: {\"type\":\"status\",\"status\":\"succeeded\"}\n\n")
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []JobEvent
+	if err := c.WatchJob(context.Background(), "j1", func(e JobEvent) { events = append(events, e) }); err != nil {
+		t.Fatalf("WatchJob() returned an error: %v", err)
+	}
+
+	if len(events) != 2 || events[0].Current != 1 || events[1].Status != JobStatusSucceeded {
+		t.Errorf("WatchJob() streamed %+v", events)
+	}
+}
+
+func TestNew_InvalidURL(t *testing.T) {
+	for _, u := range []string{"nas:8080", "ftp://nas", "://"} {
+		if _, err := New(u); err == nil {
+			t.Errorf("New(%q) returned no error", u)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FileType is the container of a video file
+type FileType int
+
+const (
+	FileTypeMKV FileType = iota
+	FileTypeMP4
+	FileTypeAVI
+	FileTypeMOV
+	FileTypeWMV
+	FileTypeFLV
+	FileTypeWEBM
+	FileTypeGIF
+	FileTypeM4V
+	FileTypeMPG
+	FileTypeMPEG
+	FileType3GP
+	FileTypeOGV
+)
+
+// MediaType is the type of media of a video file
+type MediaType int
+
+const (
+	MediaTypeMovie MediaType = iota
+	MediaTypeTVShow
+	MediaTypeAnime
+	MediaTypeUnknown
+)
+
+// Action is the action of a change
+type Action rune
+
+const (
+	ActionNoop   Action = 0
+	ActionRename Action = '~'
+	ActionSkip   Action = '-'
+	ActionCreate Action = '+'
+)
+
+// Decision is the decision of a reviewer on a change
+type Decision string
+
+const (
+	// DecisionPending is the default, the change is applied unless rejected
+	DecisionPending Decision = ""
+
+	DecisionAccepted Decision = "accepted"
+	DecisionRejected Decision = "rejected"
+)
+
+// ConflictType is the type of a conflict between changes
+type ConflictType string
+
+const (
+	ConflictTypeTargetExists   ConflictType = "target_exists"
+	ConflictTypeMultipleSource ConflictType = "multiple_source"
+)
+
+// JobKind is the kind of work performed by a job
+type JobKind string
+
+const (
+	JobKindPlan  JobKind = "plan"
+	JobKindApply JobKind = "apply"
+)
+
+// JobStatus is the status of a job
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// IsFinal returns true if the job will not change anymore
+func (s JobStatus) IsFinal() bool {
+	return s == JobStatusSucceeded || s == JobStatusFailed || s == JobStatusCancelled
+}
+
+// JobEventType is the type of an event emitted by a job
+type JobEventType string
+
+const (
+	JobEventStatus   JobEventType = "status"
+	JobEventProgress JobEventType = "progress"
+	JobEventError    JobEventType = "error"
+	JobEventPlan     JobEventType = "plan"
+	JobEventResult   JobEventType = "result"
+)
+
+// ChangeStatus is the outcome of applying a single change
+type ChangeStatus string
+
+const (
+	ChangeStatusApplied ChangeStatus = "applied"
+	ChangeStatusFailed  ChangeStatus = "failed"
+)
+
+// ExternalIDs are the IDs of a media in the databases of the providers
+type ExternalIDs struct {
+	TMDBID  string `json:"tmdb_id"`
+	TVDBID  string `json:"tvdb_id"`
+	AniDBID string `json:"anidb_id"`
+	IMDBID  string `json:"imdb_id"`
+}
+
+// Movie is a movie of a provider
+type Movie struct {
+	ID            string      `json:"id"`
+	Title         string      `json:"title"`
+	OriginalTitle string      `json:"original_title"`
+	ReleaseDate   time.Time   `json:"release_date"`
+	Genre         string      `json:"genre"`
+	Director      string      `json:"director"`
+	ExternalIDs   ExternalIDs `json:"external_ids"`
+	Overview      string      `json:"overview,omitempty"`
+	Genres        []string    `json:"genres,omitempty"`
+	PosterPath    string      `json:"poster_path,omitempty"`
+	BackdropPath  string      `json:"backdrop_path,omitempty"`
+	Runtime       int         `json:"runtime,omitempty"`
+}
+
+// TVShow is a TV show of a provider
+type TVShow struct {
+	ID           string      `json:"id"`
+	Name         string      `json:"name"`
+	OriginalName string      `json:"original_name"`
+	FirstAirDate time.Time   `json:"first_air_date"`
+	Genre        string      `json:"genre"`
+	Director     string      `json:"director"`
+	Seasons      int         `json:"seasons"`
+	Episodes     int         `json:"episodes"`
+	ExternalIDs  ExternalIDs `json:"external_ids"`
+	Overview     string      `json:"overview,omitempty"`
+	Genres       []string    `json:"genres,omitempty"`
+	PosterPath   string      `json:"poster_path,omitempty"`
+	BackdropPath string      `json:"backdrop_path,omitempty"`
+}
+
+// Episode is an episode of a TV show
+type Episode struct {
+	Title       string      `json:"name"`
+	Season      int         `json:"season_number"`
+	Episode     int         `json:"episode_number"`
+	AirDate     time.Time   `json:"air_date"`
+	Summary     string      `json:"overview"`
+	Thumbnail   string      `json:"still_path"`
+	TVShow      TVShow      `json:"tv_show,omitempty"`
+	Runtime     int         `json:"runtime,omitempty"`
+	ExternalIDs ExternalIDs `json:"external_ids,omitempty"`
+}
+
+// Confidence is the confidence in the match of a video file
+type Confidence struct {
+	Score float64 `json:"score"`
+}
+
+// Query is what was looked up for a video file
+type Query struct {
+	Title   string `json:"title,omitempty"`
+	Year    int    `json:"year,omitempty"`
+	Season  int    `json:"season,omitempty"`
+	Episode int    `json:"episode,omitempty"`
+}
+
+// MediaInfo describes the streams of a video file
+type MediaInfo struct {
+	Container      string          `json:"container"`
+	Duration       time.Duration   `json:"duration"`
+	Title          string          `json:"title,omitempty"`
+	Width          int             `json:"width"`
+	Height         int             `json:"height"`
+	VideoCodec     string          `json:"video_codec"`
+	HDR            string          `json:"hdr,omitempty"`
+	Bitrate        int64           `json:"bitrate,omitempty"`
+	AudioTracks    []AudioTrack    `json:"audio_tracks,omitempty"`
+	SubtitleTracks []SubtitleTrack `json:"subtitle_tracks,omitempty"`
+	Truncated      bool            `json:"truncated,omitempty"`
+}
+
+// AudioTrack is an audio track of a video file
+type AudioTrack struct {
+	Codec    string `json:"codec"`
+	Language string `json:"language,omitempty"`
+	Channels int    `json:"channels,omitempty"`
+	Default  bool   `json:"default,omitempty"`
+}
+
+// SubtitleTrack is a subtitle track of a video file
+type SubtitleTrack struct {
+	Codec    string `json:"codec"`
+	Language string `json:"language,omitempty"`
+	Forced   bool   `json:"forced,omitempty"`
+	Default  bool   `json:"default,omitempty"`
+}
+
+// Subtitle is a subtitle found by a provider
+type Subtitle struct {
+	Provider        string  `json:"provider"`
+	ID              string  `json:"id"`
+	Language        string  `json:"language"`
+	Release         string  `json:"release,omitempty"`
+	Format          string  `json:"format"`
+	Convert         string  `json:"convert,omitempty"`
+	HearingImpaired bool    `json:"hearing_impaired,omitempty"`
+	Forced          bool    `json:"forced,omitempty"`
+	HashMatch       bool    `json:"hash_match,omitempty"`
+	Downloads       int     `json:"downloads,omitempty"`
+	Sync            float64 `json:"sync,omitempty"`
+	Score           float64 `json:"score"`
+}
+
+// VideoFile is a video file before or after a change
+type VideoFile struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	Filename  string    `json:"filename"`
+	FileType  FileType  `json:"file_type"`
+	MediaType MediaType `json:"media_type"`
+
+	// Metadata is a Movie or an Episode, depending on the media type
+	Metadata json.RawMessage `json:"metadata"`
+
+	ConflictStrategy string      `json:"conflict_strategy"`
+	ExternalIDs      ExternalIDs `json:"external_ids"`
+	Confidence       *Confidence `json:"confidence,omitempty"`
+	Query            *Query      `json:"query,omitempty"`
+	MediaInfo        *MediaInfo  `json:"media_info,omitempty"`
+	Extra            string      `json:"extra,omitempty"`
+	Subtitles        []Subtitle  `json:"subtitles,omitempty"`
+}
+
+// RuntimeMismatch flags a change whose file does not last as long as its match
+type RuntimeMismatch struct {
+	Duration time.Duration `json:"duration"`
+	Runtime  time.Duration `json:"runtime"`
+}
+
+// Change is a change of a plan
+type Change struct {
+	ID              string           `json:"id"`
+	Action          Action           `json:"action"`
+	Before          VideoFile        `json:"before"`
+	After           VideoFile        `json:"after"`
+	ConflictIDs     []string         `json:"conflict_ids,omitempty"`
+	Decision        Decision         `json:"decision,omitempty"`
+	RuntimeMismatch *RuntimeMismatch `json:"runtime_mismatch,omitempty"`
+	Subtitle        *Subtitle        `json:"subtitle,omitempty"`
+}
+
+// IsApplicable returns true if this change would be performed when applying the plan
+func (c *Change) IsApplicable() bool {
+	return (c.Action == ActionRename || c.Action == ActionCreate) && len(c.ConflictIDs) == 0 && c.Decision != DecisionRejected
+}
+
+// ConflictResolution is how a conflict was resolved
+type ConflictResolution struct {
+	Strategy      string            `json:"strategy"`
+	Modifications map[string]string `json:"modifications"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
+
+// Conflict is a naming conflict between changes
+type Conflict struct {
+	ID           string             `json:"id"`
+	TargetPath   string             `json:"target_path"`
+	ChangeIDs    []string           `json:"change_ids"`
+	ConflictType ConflictType       `json:"conflict_type"`
+	Resolved     bool               `json:"resolved"`
+	Resolution   ConflictResolution `json:"resolution,omitempty"`
+}
+
+// PlanError is a file that could not be planned
+type PlanError struct {
+	Message string `json:"message"`
+	File    string `json:"file"`
+}
+
+// Plan is a rename plan stored by the server
+type Plan struct {
+	ID        string      `json:"id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Changes   []Change    `json:"changes"`
+	Errors    []PlanError `json:"errors"`
+	Applyable bool        `json:"Applyable"`
+	Conflicts []Conflict  `json:"conflicts"`
+	AppliedAt *time.Time  `json:"applied_at,omitempty"`
+}
+
+// Pending returns true if applying the plan would perform at least one change
+func (p *Plan) Pending() bool {
+	for i := range p.Changes {
+		if p.Changes[i].IsApplicable() {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeResult is the result of applying a single change
+type ChangeResult struct {
+	ChangeID string       `json:"change_id"`
+	Before   string       `json:"before"`
+	After    string       `json:"after"`
+	Status   ChangeStatus `json:"status"`
+	Error    string       `json:"error,omitempty"`
+	StateID  string       `json:"state_id,omitempty"`
+	Sidecars []string     `json:"sidecars,omitempty"`
+}
+
+// IntegrationResult is the result of the refresh of a media server
+type IntegrationResult struct {
+	Name    string   `json:"name"`
+	Folders []string `json:"folders"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// ApplyResult is the result of applying a plan
+type ApplyResult struct {
+	PlanID       string              `json:"plan_id"`
+	Applied      int                 `json:"applied"`
+	Failed       int                 `json:"failed"`
+	Changes      []ChangeResult      `json:"changes"`
+	Downloaded   int                 `json:"downloaded,omitempty"`
+	Integrations []IntegrationResult `json:"integrations,omitempty"`
+}
+
+// JobProgress is the progress of a job
+type JobProgress struct {
+	Current int `json:"current"`
+	Total   int `json:"total"`
+}
+
+// Job is a snapshot of a job of the server
+type Job struct {
+	ID         string       `json:"id"`
+	Kind       JobKind      `json:"kind"`
+	Status     JobStatus    `json:"status"`
+	Progress   JobProgress  `json:"progress"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Plan       *Plan        `json:"plan,omitempty"`
+	Result     *ApplyResult `json:"result,omitempty"`
+}
+
+// JobEvent is emitted by a job while it runs
+type JobEvent struct {
+	Type      JobEventType `json:"type"`
+	Timestamp time.Time    `json:"timestamp"`
+	Status    JobStatus    `json:"status,omitempty"`
+	File      string       `json:"file,omitempty"`
+	Message   string       `json:"message,omitempty"`
+	Current   int          `json:"current,omitempty"`
+	Total     int          `json:"total,omitempty"`
+	Plan      *Plan        `json:"plan,omitempty"`
+	Result    *ApplyResult `json:"result,omitempty"`
+}
+
+// StateEntry is a rename operation recorded by the server
+type StateEntry struct {
+	ID           string          `json:"id"`
+	Timestamp    time.Time       `json:"timestamp"`
+	OriginalPath string          `json:"original_path"`
+	NewPath      string          `json:"new_path"`
+	OriginalName string          `json:"original_name"`
+	NewName      string          `json:"new_name"`
+	MediaInfo    json.RawMessage `json:"media_info,omitempty"`
+	Reverted     bool            `json:"reverted"`
+	Sidecars     []string        `json:"sidecars,omitempty"`
+}
+
+// MissingReport compares the episodes on disk with the seasons listed by the provider
+type MissingReport struct {
+	Shows   []ShowReport  `json:"shows"`
+	Unknown []UnknownFile `json:"unknown"`
+}
+
+// ShowReport is the report of a TV show directory
+type ShowReport struct {
+	Show       TVShow           `json:"show"`
+	Episodes   int              `json:"episodes"`
+	Expected   int              `json:"expected"`
+	Missing    []MissingEpisode `json:"missing"`
+	Extra      []EpisodeFile    `json:"extra"`
+	Duplicates []Duplicate      `json:"duplicates"`
+}
+
+// MissingEpisode is an episode listed by the provider but not found on disk
+type MissingEpisode struct {
+	Season  int    `json:"season"`
+	Episode int    `json:"episode"`
+	Title   string `json:"title,omitempty"`
+	AirDate string `json:"air_date,omitempty"`
+}
+
+// EpisodeFile is a file of an episode unknown to the provider
+type EpisodeFile struct {
+	Season  int    `json:"season"`
+	Episode int    `json:"episode"`
+	Path    string `json:"path"`
+}
+
+// Duplicate is an episode found in several files
+type Duplicate struct {
+	Season  int      `json:"season"`
+	Episode int      `json:"episode"`
+	Paths   []string `json:"paths"`
+}
+
+// UnknownFile is a file whose episode could not be recognized
+type UnknownFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
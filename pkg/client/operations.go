@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Health checks the server health
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/health", nil, nil, nil)
+}
+
+// Login opens a session for a local user, kept in the cookie jar of the client
+func (c *Client) Login(ctx context.Context, username, password string) (*Identity, error) {
+	var identity Identity
+	body := map[string]string{"username": username, "password": password}
+	if err := c.do(ctx, http.MethodPost, "/auth/login", nil, body, &identity); err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// Logout closes the session
+func (c *Client) Logout(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/auth/logout", nil, nil, nil)
+}
+
+// Me returns the identity of the client
+func (c *Client) Me(ctx context.Context) (*Identity, error) {
+	var identity Identity
+	if err := c.do(ctx, http.MethodGet, "/auth/me", nil, nil, &identity); err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// ListDirectory lists a directory inside the library roots
+func (c *Client) ListDirectory(ctx context.Context, path string, opts DirectoryOptions) (*Directory, error) {
+	query := url.Values{"path": {path}}
+	if opts.HideDotfiles {
+		query.Set("hide_dotfiles", "true")
+	}
+	if opts.HideUnsupported {
+		query.Set("hide_unsupported", "true")
+	}
+
+	var directory Directory
+	if err := c.do(ctx, http.MethodGet, "/directory", query, nil, &directory); err != nil {
+		return nil, err
+	}
+	return &directory, nil
+}
+
+// DefaultDirectory returns the directory browsed by default
+func (c *Client) DefaultDirectory(ctx context.Context) (string, error) {
+	var directory string
+	if err := c.do(ctx, http.MethodGet, "/directory/default", nil, nil, &directory); err != nil {
+		return "", err
+	}
+	return directory, nil
+}
+
+// Roots lists the library roots
+func (c *Client) Roots(ctx context.Context) ([]string, error) {
+	var response struct {
+		Roots []string `json:"roots"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/directory/roots", nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Roots, nil
+}
+
+// CreatePlan creates and stores the plan of a directory
+func (c *Client) CreatePlan(ctx context.Context, req PlanRequest) (*Plan, error) {
+	query := url.Values{
+		"directory": {req.Directory},
+		"recursive": {strconv.FormatBool(req.Recursive)},
+	}
+	if req.Type != "" {
+		query.Set("type", req.Type)
+	}
+	if req.Provider != "" {
+		query.Set("provider", req.Provider)
+	}
+
+	var response struct {
+		Plan *Plan `json:"plan"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/plan/create", query, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Plan, nil
+}
+
+// ListPlans lists the stored plans, newest first
+func (c *Client) ListPlans(ctx context.Context) ([]*Plan, error) {
+	var response struct {
+		Plans []*Plan `json:"plans"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/plans", nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Plans, nil
+}
+
+// GetPlan returns a stored plan
+func (c *Client) GetPlan(ctx context.Context, id string) (*Plan, error) {
+	var plan Plan
+	if err := c.do(ctx, http.MethodGet, "/plans/"+url.PathEscape(id), nil, nil, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// UpdateChange reviews a change of a stored plan and returns the updated plan
+func (c *Client) UpdateChange(ctx context.Context, planID, changeID string, req UpdateChangeRequest) (*Plan, error) {
+	var plan Plan
+	path := "/plans/" + url.PathEscape(planID) + "/changes/" + url.PathEscape(changeID)
+	if err := c.do(ctx, http.MethodPatch, path, nil, req, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// ApplyPlan applies a stored plan
+func (c *Client) ApplyPlan(ctx context.Context, id string) (*ApplyResponse, error) {
+	var response ApplyResponse
+	if err := c.do(ctx, http.MethodPost, "/plans/"+url.PathEscape(id)+"/apply", nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CreateJob starts a plan or an apply in the background
+func (c *Client) CreateJob(ctx context.Context, req JobRequest) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodPost, "/jobs", nil, req, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListJobs lists the jobs, newest first
+func (c *Client) ListJobs(ctx context.Context) ([]Job, error) {
+	var response struct {
+		Jobs []Job `json:"jobs"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/jobs", nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Jobs, nil
+}
+
+// GetJob returns a job
+func (c *Client) GetJob(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CancelJob cancels a running job
+func (c *Client) CancelJob(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodDelete, "/jobs/"+url.PathEscape(id), nil, nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SearchMovies searches movies, the year is ignored when zero
+func (c *Client) SearchMovies(ctx context.Context, query string, year int) ([]*Movie, error) {
+	var response struct {
+		Movies []*Movie `json:"movies"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/movies", searchQuery(query, year), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Movies, nil
+}
+
+// GetMovie returns a movie
+func (c *Client) GetMovie(ctx context.Context, id string) (*Movie, error) {
+	var response struct {
+		Movie *Movie `json:"movie"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/movies/"+url.PathEscape(id), nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Movie, nil
+}
+
+// SearchTVShows searches TV shows, the year is ignored when zero
+func (c *Client) SearchTVShows(ctx context.Context, query string, year int) ([]*TVShow, error) {
+	var response struct {
+		TVShows []*TVShow `json:"tvshows"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/tvshows", searchQuery(query, year), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.TVShows, nil
+}
+
+// GetTVShow returns a TV show
+func (c *Client) GetTVShow(ctx context.Context, id string) (*TVShow, error) {
+	var response struct {
+		TVShow *TVShow `json:"tvshow"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/tvshows/"+url.PathEscape(id), nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.TVShow, nil
+}
+
+// ListEpisodes lists the episodes of a season of a TV show
+func (c *Client) ListEpisodes(ctx context.Context, showID, season int) ([]*Episode, error) {
+	var response struct {
+		Episodes []*Episode `json:"episodes"`
+	}
+	query := url.Values{"season": {strconv.Itoa(season)}}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/tvshows/%d/episodes", showID), query, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Episodes, nil
+}
+
+// State lists the rename operations
+func (c *Client) State(ctx context.Context, opts StateOptions) (*State, error) {
+	query := url.Values{}
+	if opts.Active {
+		query.Set("active", "true")
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	var state State
+	if err := c.do(ctx, http.MethodGet, "/state", query, nil, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Revert reverts rename operations. When none could be reverted, the response is
+// returned along with the error.
+func (c *Client) Revert(ctx context.Context, req RevertRequest) (*RevertResponse, error) {
+	var response RevertResponse
+	err := c.do(ctx, http.MethodPost, "/state/revert", nil, req, &response)
+	if apiErr, ok := err.(*Error); ok && apiErr.Code == "unknown" && apiErr.StatusCode == http.StatusBadRequest {
+		// The server answers 400 with the revert result when every operation failed
+		if json.Unmarshal([]byte(apiErr.Message), &response) == nil && response.TotalCount > 0 {
+			return &response, fmt.Errorf("%s", response.Message)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// MissingReport reports the missing, extra and duplicated episodes of the shows of
+// the library
+func (c *Client) MissingReport(ctx context.Context, opts MissingReportOptions) (*MissingReport, error) {
+	query := url.Values{}
+	if opts.Directory != "" {
+		query.Set("directory", opts.Directory)
+	}
+	if opts.Aired {
+		query.Set("aired", "true")
+	}
+
+	var report MissingReport
+	if err := c.do(ctx, http.MethodGet, "/reports/missing", query, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// searchQuery builds the query of the searches
+func searchQuery(query string, year int) url.Values {
+	values := url.Values{"query": {query}}
+	if year > 0 {
+		values.Set("year", strconv.Itoa(year))
+	}
+	return values
+}
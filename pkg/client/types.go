@@ -0,0 +1,124 @@
+package client
+
+// Identity is an authenticated client of the API
+type Identity struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Method string `json:"method"`
+}
+
+// DirectoryOptions filters the entries of a directory
+type DirectoryOptions struct {
+	HideDotfiles    bool
+	HideUnsupported bool
+}
+
+// Directory is the content of a directory
+type Directory struct {
+	Path  string     `json:"path"`
+	Files []FileInfo `json:"files"`
+}
+
+// FileInfo is an entry of a directory
+type FileInfo struct {
+	Name      string      `json:"name"`
+	Path      string      `json:"path"`
+	IsDir     bool        `json:"isDir"`
+	Size      int64       `json:"size"`
+	ModTime   string      `json:"modTime"`
+	FileType  *FileType   `json:"fileType,omitempty"`
+	MediaType *MediaType  `json:"mediaType,omitempty"`
+	State     *StateEntry `json:"state,omitempty"`
+	Match     *FileMatch  `json:"match,omitempty"`
+}
+
+// FileMatch is the pending change of a stored plan for a file
+type FileMatch struct {
+	PlanID   string   `json:"planId"`
+	ChangeID string   `json:"changeId"`
+	Action   string   `json:"action"`
+	Target   string   `json:"target"`
+	Decision Decision `json:"decision,omitempty"`
+}
+
+// PlanRequest describes the directory to plan
+type PlanRequest struct {
+	Directory string `json:"directory"`
+	Type      string `json:"type,omitempty"`     // "auto", "movie", "tv"
+	Provider  string `json:"provider,omitempty"` // "tmdb"
+	Recursive bool   `json:"recursive"`
+}
+
+// UpdateChangeRequest reviews a change of a stored plan
+type UpdateChangeRequest struct {
+	Decision *Decision `json:"decision,omitempty"`
+	Target   string    `json:"target,omitempty"`
+	Match    *Match    `json:"match,omitempty"`
+}
+
+// Match selects the metadata a change must be matched against
+type Match struct {
+	MediaType string `json:"media_type"` // "movie", "tv"
+	ID        string `json:"id"`
+	Season    int    `json:"season,omitempty"`
+	Episode   int    `json:"episode,omitempty"`
+}
+
+// ApplyResponse is the result of the application of a plan
+type ApplyResponse struct {
+	Status  string `json:"status"`
+	Applied int    `json:"applied"`
+	Errors  []struct {
+		File    string `json:"file"`
+		Message string `json:"message"`
+	} `json:"errors,omitempty"`
+	Summary string `json:"summary"`
+}
+
+// JobRequest starts a job
+type JobRequest struct {
+	Kind   string       `json:"kind"` // "plan", "apply"
+	Lookup *PlanRequest `json:"lookup,omitempty"`
+	PlanID string       `json:"plan_id,omitempty"`
+}
+
+// StateOptions filters the rename operations
+type StateOptions struct {
+	Active bool
+	Limit  int
+}
+
+// MissingReportOptions scopes the report of the missing episodes
+type MissingReportOptions struct {
+	// Directory is the name of a library directory, all the TV show directories when empty
+	Directory string
+	Aired     bool
+}
+
+// State lists the rename operations
+type State struct {
+	Version     string       `json:"version"`
+	Entries     []StateEntry `json:"entries"`
+	ActiveCount int          `json:"active_count"`
+	TotalCount  int          `json:"total_count"`
+}
+
+// RevertRequest selects the rename operations to revert, exactly one field must be set
+type RevertRequest struct {
+	ID   string `json:"id,omitempty"`
+	Last bool   `json:"last,omitempty"`
+	All  bool   `json:"all,omitempty"`
+}
+
+// RevertResponse is the result of a revert
+type RevertResponse struct {
+	Success     bool     `json:"success"`
+	Message     string   `json:"message"`
+	RevertedIDs []string `json:"reverted_ids"`
+	Failed      []struct {
+		ID     string `json:"id"`
+		Reason string `json:"reason"`
+	} `json:"failed,omitempty"`
+	SuccessCount int `json:"success_count"`
+	TotalCount   int `json:"total_count"`
+}
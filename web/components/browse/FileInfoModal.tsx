@@ -40,6 +40,7 @@ interface PlanChange {
   };
   action: number;
   conflict_ids?: string[];
+  runtime_mismatch?: { duration: number; runtime: number };
 }
 
 interface Plan {
@@ -166,6 +167,20 @@ function FileInfoModal({ open, file, plan, onClose }: FileInfoModalProps): React
                         </TableCell>
                       </TableRow>
                     )}
+                    {change.runtime_mismatch && (
+                      <TableRow>
+                        <TableCell component="th" scope="row" sx={{ fontWeight: 'bold' }}>
+                          Runtime
+                        </TableCell>
+                        <TableCell>
+                          <Chip
+                            label={`MISMATCH: file lasts ${Math.round(change.runtime_mismatch.duration / 60e9)} min, match ${Math.round(change.runtime_mismatch.runtime / 60e9)} min`}
+                            size="small"
+                            color="warning"
+                          />
+                        </TableCell>
+                      </TableRow>
+                    )}
                   </>
                 )}
               </TableBody>
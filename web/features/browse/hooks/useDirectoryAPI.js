@@ -29,7 +29,7 @@ export function useDirectoryAPI() {
   };
 
   const applyPlanChanges = async (planId) => {
-    return applyPlan({ planId, options: {} });
+    return applyPlan({ plan_id: planId });
   };
 
   return { loadDefaultDirectory, loadDirectory, lookupPlan, applyPlanChanges };
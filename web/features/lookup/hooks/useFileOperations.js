@@ -64,8 +64,7 @@ export const useFileOperations = () => {
       };
 
       const result = await applyPlan({
-        planId: plan.id,
-        options: {}
+        plan_id: plan.id
       });
 
       showSuccess('File renamed successfully!');
@@ -31,9 +31,9 @@ export const useApiCall = () => {
       } else if (err.response?.status === 500) {
         errorMessage = 'Internal server error. Please try again later.';
       } else if (err.response?.status >= 400 && err.response?.status < 500) {
-        errorMessage = err.response?.data?.error || `Request failed with status ${err.response.status}`;
-      } else if (err.response?.data?.error) {
-        errorMessage = err.response.data.error;
+        errorMessage = err.response?.data?.error?.message || `Request failed with status ${err.response.status}`;
+      } else if (err.response?.data?.error?.message) {
+        errorMessage = err.response.data.error.message;
       } else if (err.message) {
         errorMessage = err.message;
       }
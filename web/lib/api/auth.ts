@@ -0,0 +1,20 @@
+import { Identity, LoginRequest } from '../../types/auth';
+import { apiRequest, BASE_URL } from './config';
+
+export async function login(request: LoginRequest): Promise<Identity> {
+  return apiRequest<Identity>('/api/v1/auth/login', {
+    method: 'POST',
+    body: JSON.stringify(request),
+  });
+}
+
+export async function logout(): Promise<void> {
+  await fetch(`${BASE_URL}/api/v1/auth/logout`, {
+    method: 'POST',
+    credentials: 'include',
+  });
+}
+
+export async function getCurrentIdentity(): Promise<Identity> {
+  return apiRequest<Identity>('/api/v1/auth/me');
+}
@@ -1,17 +1,30 @@
-const BASE_URL = process.env.NEXT_PUBLIC_BACKEND_URL || process.env.BACKEND_URL || 'http://localhost:8080';
+const BASE_URL = process.env.NEXT_PUBLIC_BACKEND_URL || process.env.BACKEND_URL || '';
 
 export { BASE_URL };
 
 export class ApiError extends Error {
   status: number;
+  code?: string;
+  details?: string[];
   
-  constructor(message: string, status: number = 500) {
+  constructor(message: string, status: number = 500, code?: string, details?: string[]) {
     super(message);
     this.name = 'ApiError';
     this.status = status;
+    this.code = code;
+    this.details = details;
   }
 }
 
+// Envelope of the errors returned by the API
+interface ErrorResponse {
+  error: {
+    code: string;
+    message: string;
+    details?: string[];
+  };
+}
+
 export async function apiRequest<T>(
   endpoint: string,
   options: RequestInit = {}
@@ -19,6 +32,7 @@ export async function apiRequest<T>(
   const url = `${BASE_URL}${endpoint}`;
   
   const config: RequestInit = {
+    credentials: 'include',
     headers: {
       'Content-Type': 'application/json',
       ...options.headers,
@@ -30,6 +44,10 @@ export async function apiRequest<T>(
     const response = await fetch(url, config);
     
     if (!response.ok) {
+      const body: ErrorResponse | null = await response.json().catch(() => null);
+      if (body?.error) {
+        throw new ApiError(body.error.message, response.status, body.error.code, body.error.details);
+      }
       throw new ApiError(`Request failed: ${response.statusText}`, response.status);
     }
     
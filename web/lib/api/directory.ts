@@ -1,12 +1,22 @@
-import { DirectoryResponse, DirectoryParams } from '../../types/directory';
+import { DirectoryResponse, DirectoryParams, RootsResponse } from '../../types/directory';
 import { apiRequest } from './config';
 
 export async function getDirectory(params: DirectoryParams): Promise<DirectoryResponse> {
   const searchParams = new URLSearchParams({ path: params.path });
+  if (params.hideDotfiles) {
+    searchParams.set('hide_dotfiles', 'true');
+  }
+  if (params.hideUnsupported) {
+    searchParams.set('hide_unsupported', 'true');
+  }
   
-  return apiRequest<DirectoryResponse>(`/api/directory?${searchParams}`);
+  return apiRequest<DirectoryResponse>(`/api/v1/directory?${searchParams}`);
 }
 
 export async function getDefaultDirectory(): Promise<string> {
-  return apiRequest<string>('/api/directory/default');
+  return apiRequest<string>('/api/v1/directory/default');
+}
+
+export async function getRoots(): Promise<RootsResponse> {
+  return apiRequest<RootsResponse>('/api/v1/directory/roots');
 }
@@ -4,9 +4,9 @@ import { apiRequest } from './config';
 export async function getEpisodes(query: string): Promise<Episode[]> {
   const searchParams = new URLSearchParams({ query });
   
-  return apiRequest<Episode[]>(`/api/episodes?${searchParams}`);
+  return apiRequest<Episode[]>(`/api/v1/episodes?${searchParams}`);
 }
 
 export async function getTVShowEpisodes(showId: string): Promise<Episode[]> {
-  return apiRequest<Episode[]>(`/api/tvshows/${showId}/episodes`);
+  return apiRequest<Episode[]>(`/api/v1/tvshows/${showId}/episodes`);
 }
@@ -1,11 +1,20 @@
 import { apiRequest } from './config';
 
+export type HealthCheckStatus = 'ok' | 'fail';
+
+export interface HealthCheck {
+  name: string;
+  status: HealthCheckStatus;
+  error?: string;
+  duration_ms: number;
+}
+
 export interface HealthStatus {
-  status: string;
-  timestamp: string;
-  version?: string;
+  status: HealthCheckStatus;
+  checks: HealthCheck[];
 }
 
+// Rejects with an ApiError of status 503 when the server is not ready
 export async function getHealth(): Promise<HealthStatus> {
-  return apiRequest<HealthStatus>('/api/health');
+  return apiRequest<HealthStatus>('/api/v1/health');
 }
@@ -5,6 +5,8 @@ export * from './episodes';
 export * from './search';
 export * from './directory';
 export * from './plan';
+export * from './jobs';
 export * from './state';
 export * from './health';
+export * from './auth';
 export * from './config';
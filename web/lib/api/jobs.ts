@@ -0,0 +1,38 @@
+import { Job, JobEvent, CreateJobRequest } from '../../types/job';
+import { apiRequest, BASE_URL } from './config';
+
+export async function createJob(request: CreateJobRequest): Promise<Job> {
+  return apiRequest<Job>('/api/v1/jobs', {
+    method: 'POST',
+    body: JSON.stringify(request),
+  });
+}
+
+export async function getJob(id: string): Promise<Job> {
+  return apiRequest<Job>(`/api/v1/jobs/${id}`);
+}
+
+export async function cancelJob(id: string): Promise<Job> {
+  return apiRequest<Job>(`/api/v1/jobs/${id}`, {
+    method: 'DELETE',
+  });
+}
+
+// Subscribes to the events of a job, returns a function closing the stream
+export function watchJob(id: string, onEvent: (event: JobEvent) => void): () => void {
+  const source = new EventSource(`${BASE_URL}/api/v1/jobs/${id}/events`, { withCredentials: true });
+  const types = ['status', 'progress', 'error', 'plan', 'result'];
+
+  types.forEach((type) => {
+    source.addEventListener(type, (message) => {
+      const event: JobEvent = JSON.parse((message as MessageEvent).data);
+      onEvent(event);
+
+      if (event.type === 'status' && ['succeeded', 'failed', 'cancelled'].includes(event.status || '')) {
+        source.close();
+      }
+    });
+  });
+
+  return () => source.close();
+}
@@ -7,9 +7,9 @@ export async function getMovies(query: string, year?: number): Promise<Movie[]>
     searchParams.append('year', year.toString());
   }
   
-  return apiRequest<Movie[]>(`/api/movies?${searchParams}`);
+  return apiRequest<Movie[]>(`/api/v1/movies?${searchParams}`);
 }
 
 export async function getMovie(id: string): Promise<Movie> {
-  return apiRequest<Movie>(`/api/movies/${id}`);
+  return apiRequest<Movie>(`/api/v1/movies/${id}`);
 }
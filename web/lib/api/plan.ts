@@ -1,4 +1,4 @@
-import { Plan, CreatePlanRequest, ApplyPlanRequest } from '../../types/plan';
+import { Plan, CreatePlanRequest, ApplyPlanRequest, UpdateChangeRequest } from '../../types/plan';
 import { apiRequest } from './config';
 
 export async function createPlan(request: CreatePlanRequest): Promise<Plan> {
@@ -9,14 +9,39 @@ export async function createPlan(request: CreatePlanRequest): Promise<Plan> {
     recursive: request.recursive?.toString() || 'true'
   });
   
-  return apiRequest<Plan>(`/api/plan/create?${params.toString()}`, {
+  return apiRequest<Plan>(`/api/v1/plan/create?${params.toString()}`, {
     method: 'GET',
   });
 }
 
 export async function applyPlan(request: ApplyPlanRequest): Promise<{ success: boolean; message?: string }> {
-  return apiRequest<{ success: boolean; message?: string }>('/api/plan/apply', {
+  return apiRequest<{ success: boolean; message?: string }>('/api/v1/plan/apply', {
     method: 'POST',
     body: JSON.stringify(request),
   });
 }
+
+export async function listPlans(): Promise<{ plans: Plan[] }> {
+  return apiRequest<{ plans: Plan[] }>('/api/v1/plans', {
+    method: 'GET',
+  });
+}
+
+export async function getPlan(id: string): Promise<Plan> {
+  return apiRequest<Plan>(`/api/v1/plans/${encodeURIComponent(id)}`, {
+    method: 'GET',
+  });
+}
+
+export async function updatePlanChange(planId: string, changeId: string, request: UpdateChangeRequest): Promise<Plan> {
+  return apiRequest<Plan>(`/api/v1/plans/${encodeURIComponent(planId)}/changes/${encodeURIComponent(changeId)}`, {
+    method: 'PATCH',
+    body: JSON.stringify(request),
+  });
+}
+
+export async function applyPlanByID(id: string): Promise<{ success: boolean; message?: string }> {
+  return apiRequest<{ success: boolean; message?: string }>(`/api/v1/plans/${encodeURIComponent(id)}/apply`, {
+    method: 'POST',
+  });
+}
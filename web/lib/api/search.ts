@@ -9,7 +9,7 @@ export async function searchMovies(query: string, year?: number): Promise<Movie[
     searchParams.append('year', year.toString());
   }
   
-  return apiRequest<Movie[]>(`/api/movies?${searchParams}`);
+  return apiRequest<Movie[]>(`/api/v1/movies?${searchParams}`);
 }
 
 export async function searchTVShows(query: string, year?: number): Promise<TVShow[]> {
@@ -18,11 +18,11 @@ export async function searchTVShows(query: string, year?: number): Promise<TVSho
     searchParams.append('year', year.toString());
   }
   
-  return apiRequest<TVShow[]>(`/api/tvshows?${searchParams}`);
+  return apiRequest<TVShow[]>(`/api/v1/tvshows?${searchParams}`);
 }
 
 export async function searchEpisodes(query: string): Promise<Episode[]> {
   const searchParams = new URLSearchParams({ query });
   
-  return apiRequest<Episode[]>(`/api/episodes?${searchParams}`);
+  return apiRequest<Episode[]>(`/api/v1/episodes?${searchParams}`);
 }
@@ -23,13 +23,13 @@ export async function getState(params?: StateParams): Promise<StateEntry[]> {
   }
   
   const query = searchParams.toString();
-  const endpoint = query ? `/api/state?${query}` : '/api/state';
+  const endpoint = query ? `/api/v1/state?${query}` : '/api/v1/state';
   
   return apiRequest<StateEntry[]>(endpoint);
 }
 
 export async function revertState(): Promise<{ success: boolean; message?: string }> {
-  return apiRequest<{ success: boolean; message?: string }>('/api/state/revert', {
+  return apiRequest<{ success: boolean; message?: string }>('/api/v1/state/revert', {
     method: 'POST',
   });
 }
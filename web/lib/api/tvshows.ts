@@ -7,9 +7,9 @@ export async function getTVShows(query: string, year?: number): Promise<TVShow[]
     searchParams.append('year', year.toString());
   }
   
-  return apiRequest<TVShow[]>(`/api/tvshows?${searchParams}`);
+  return apiRequest<TVShow[]>(`/api/v1/tvshows?${searchParams}`);
 }
 
 export async function getTVShow(id: string): Promise<TVShow> {
-  return apiRequest<TVShow>(`/api/tvshows/${id}`);
+  return apiRequest<TVShow>(`/api/v1/tvshows/${id}`);
 }
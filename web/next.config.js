@@ -1,18 +1,17 @@
 /** @type {import('next').NextConfig} */
 const nextConfig = {
   reactStrictMode: true,
-  swcMinify: true,
-  
-  // Environment variables for runtime
-  env: {
-    BACKEND_URL: process.env.BACKEND_URL || 'http://localhost:8080',
+
+  // Export a static bundle, embedded and served by `goru server`
+  output: 'export',
+  images: {
+    unoptimized: true,
   },
-  
-  // Add any rewrites if needed for API proxy
-  async rewrites() {
-    return [
-      // These rewrites are handled by our API routes, but keeping as fallback
-    ];
+
+  // Environment variables for build time. The exported bundle calls the API on the
+  // same origin, set NEXT_PUBLIC_BACKEND_URL to reach another server in development.
+  env: {
+    BACKEND_URL: process.env.BACKEND_URL || '',
   },
 }
 
@@ -0,0 +1,12 @@
+export type Role = 'read-only' | 'admin';
+
+export interface Identity {
+  name: string;
+  role: Role;
+  method: 'none' | 'api_key' | 'session' | 'proxy';
+}
+
+export interface LoginRequest {
+  username: string;
+  password: string;
+}
@@ -1,9 +1,24 @@
+import { StateEntry } from '../lib/api/state';
+
+export interface FileMatch {
+  planId: string;
+  changeId: string;
+  action: string;
+  target: string;
+  decision?: 'accepted' | 'rejected';
+}
+
 export interface DirectoryEntry {
   name: string;
   path: string;
-  isDirectory: boolean;
+  isDir: boolean;
   size?: number;
-  modifiedDate?: string;
+  modTime?: string;
+  // Only set for supported video files
+  fileType?: number;
+  mediaType?: number; // 0 movie, 1 TV show, 2 anime, 3 unknown
+  state?: StateEntry;
+  match?: FileMatch;
 }
 
 export interface DirectoryResponse {
@@ -13,4 +28,10 @@ export interface DirectoryResponse {
 
 export interface DirectoryParams {
   path: string;
+  hideDotfiles?: boolean;
+  hideUnsupported?: boolean;
+}
+
+export interface RootsResponse {
+  roots: string[];
 }
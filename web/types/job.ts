@@ -0,0 +1,67 @@
+import { Plan, CreatePlanRequest } from './plan';
+
+export type JobKind = 'plan' | 'apply';
+
+export type JobStatus = 'pending' | 'running' | 'succeeded' | 'failed' | 'cancelled';
+
+export type JobEventType = 'status' | 'progress' | 'error' | 'plan' | 'result';
+
+export interface JobProgress {
+  current: number;
+  total: number;
+}
+
+export interface ApplyChangeResult {
+  change_id: string;
+  before: string;
+  after: string;
+  status: 'applied' | 'failed';
+  error?: string;
+  state_id?: string;
+  sidecars?: string[];
+}
+
+export interface ApplyResult {
+  plan_id: string;
+  applied: number;
+  failed: number;
+  changes: ApplyChangeResult[];
+  integrations?: IntegrationResult[];
+}
+
+export interface IntegrationResult {
+  name: string;
+  folders: string[];
+  error?: string;
+}
+
+export interface Job {
+  id: string;
+  kind: JobKind;
+  status: JobStatus;
+  progress: JobProgress;
+  error?: string;
+  created_at: string;
+  started_at?: string;
+  finished_at?: string;
+  plan?: Plan;
+  result?: ApplyResult;
+}
+
+export interface JobEvent {
+  type: JobEventType;
+  timestamp: string;
+  status?: JobStatus;
+  file?: string;
+  message?: string;
+  current?: number;
+  total?: number;
+  plan?: Plan;
+  result?: ApplyResult;
+}
+
+export interface CreateJobRequest {
+  kind: JobKind;
+  lookup?: CreatePlanRequest;
+  plan_id?: string;
+}
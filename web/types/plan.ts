@@ -12,6 +12,8 @@ export interface PlanChange {
   before: VideoFile;
   after: VideoFile;
   conflict_ids?: string[];
+  runtime_mismatch?: { duration: number; runtime: number }; // nanoseconds
+  subtitle?: Subtitle; // set on the create changes
 }
 
 export interface VideoFile {
@@ -22,6 +24,38 @@ export interface VideoFile {
   media_type: string;
   metadata?: any;
   conflict_strategy: string;
+  media_info?: MediaInfo;
+  extra?: string; // trailer, featurette... moved to the folders of Plex
+  subtitles?: Subtitle[];
+}
+
+export interface Subtitle {
+  provider: string;
+  id: string;
+  language: string;
+  release?: string;
+  format: string;
+  convert?: string;
+  hearing_impaired?: boolean;
+  forced?: boolean;
+  hash_match?: boolean;
+  sync?: number;
+  downloads?: number;
+  score: number;
+}
+
+export interface MediaInfo {
+  container: string;
+  duration: number; // nanoseconds
+  title?: string;
+  width: number;
+  height: number;
+  video_codec: string;
+  hdr?: 'HDR10' | 'HLG' | 'Dolby Vision';
+  bitrate?: number;
+  audio_tracks?: { codec: string; language?: string; channels?: number; default?: boolean }[];
+  subtitle_tracks?: { codec: string; language?: string; forced?: boolean; default?: boolean }[];
+  truncated?: boolean;
 }
 
 export interface PlanError {
@@ -36,13 +70,21 @@ export interface CreatePlanRequest {
   recursive?: boolean;
 }
 
-export interface PlanOptions {
-  dryRun?: boolean;
-  backup?: boolean;
-  overwrite?: boolean;
+export interface ApplyPlanRequest {
+  plan_id: string;
 }
 
-export interface ApplyPlanRequest {
-  planId: string;
-  options?: PlanOptions;
+export type ChangeDecision = 'accepted' | 'rejected' | '';
+
+export interface ChangeMatch {
+  media_type: 'movie' | 'tv';
+  id: string;
+  season?: number;
+  episode?: number;
+}
+
+export interface UpdateChangeRequest {
+  decision?: ChangeDecision;
+  target?: string;
+  match?: ChangeMatch;
 }